@@ -0,0 +1,159 @@
+// Package addons holds the catalog of stack add-ons: compose fragments that
+// bolt a standard component, such as Redis or a monitoring pair, onto an
+// existing project.
+package addons
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed catalog/*.yaml
+var catalogFS embed.FS
+
+// Addon is a compose fragment from the catalog. Besides services, networks
+// and volumes, it declares the environment variables its services read, which
+// are added to .env.example.
+type Addon struct {
+	Name        string   `yaml:"name" json:"name"`
+	Description string   `yaml:"description" json:"description"`
+	Env         []EnvVar `yaml:"env,omitempty" json:"env,omitempty"`
+
+	Services []string `yaml:"-" json:"services"`
+	Networks []string `yaml:"-" json:"networks,omitempty"`
+	Volumes  []string `yaml:"-" json:"volumes,omitempty"`
+	Ports    []string `yaml:"-" json:"ports,omitempty"` // host ports the services publish
+
+	data []byte
+}
+
+// EnvVar is an environment variable an add-on needs, with its example value
+type EnvVar struct {
+	Name        string `yaml:"name" json:"name"`
+	Value       string `yaml:"value" json:"value"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// List returns every add-on in the catalog, sorted by name
+func List() ([]Addon, error) {
+	entries, err := catalogFS.ReadDir("catalog")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read add-on catalog: %w", err)
+	}
+
+	var list []Addon
+	for _, entry := range entries {
+		addon, err := load(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *addon)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Get returns the add-on with the given name
+func Get(name string) (*Addon, error) {
+	list, err := List()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(list))
+	for i, addon := range list {
+		if addon.Name == name {
+			return &list[i], nil
+		}
+		names[i] = addon.Name
+	}
+	return nil, fmt.Errorf("add-on '%s' %w (available: %s)", name, core.ErrNotFound, strings.Join(names, ", "))
+}
+
+// load reads and checks a catalog file
+func load(fileName string) (*Addon, error) {
+	data, err := catalogFS.ReadFile(path.Join("catalog", fileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read add-on %s: %w", fileName, err)
+	}
+
+	addon := &Addon{data: data}
+	if err := yaml.Unmarshal(data, addon); err != nil {
+		return nil, fmt.Errorf("invalid add-on %s: %w", fileName, err)
+	}
+	if addon.Name != strings.TrimSuffix(fileName, ".yaml") {
+		return nil, fmt.Errorf("add-on %s is named '%s'", fileName, addon.Name)
+	}
+
+	fragment, err := core.ParseComposeData(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid add-on %s: %w", fileName, err)
+	}
+	for name, service := range fragment.Services {
+		addon.Services = append(addon.Services, name)
+		for _, mapping := range service.Ports {
+			host, _, _ := strings.Cut(mapping, ":")
+			addon.Ports = append(addon.Ports, host)
+		}
+	}
+	for name := range fragment.Networks {
+		addon.Networks = append(addon.Networks, name)
+	}
+	for name := range fragment.Volumes {
+		addon.Volumes = append(addon.Volumes, name)
+	}
+	sort.Strings(addon.Services)
+	sort.Strings(addon.Networks)
+	sort.Strings(addon.Volumes)
+	sort.Strings(addon.Ports)
+	return addon, nil
+}
+
+// Fragment returns the add-on's services, networks and volumes as a compose
+// document, ready for core.ComposeFile.Merge
+func (a *Addon) Fragment() (*core.ComposeDocument, error) {
+	return core.ParseComposeDocument(a.data)
+}
+
+// EnvExample returns the content of .env.example with the add-on's variables
+// appended under a comment header. Variables already present are left alone;
+// added lists the ones that were appended.
+func (a *Addon) EnvExample(existing []byte) (content []byte, added []string, err error) {
+	envFile, err := core.ParseEnvFile(existing)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var section strings.Builder
+	for _, env := range a.Env {
+		if _, ok := envFile.Lookup(env.Name); ok {
+			continue
+		}
+		if env.Description != "" {
+			section.WriteString("# " + env.Description + "\n")
+		}
+		section.WriteString(env.Name + "=" + env.Value + "\n")
+		added = append(added, env.Name)
+	}
+	if len(added) == 0 {
+		return existing, nil, nil
+	}
+
+	var out bytes.Buffer
+	out.Write(existing)
+	if len(existing) > 0 {
+		if !bytes.HasSuffix(existing, []byte("\n")) {
+			out.WriteString("\n")
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("# " + a.Name + " (" + a.Description + ")\n")
+	out.WriteString(section.String())
+	return out.Bytes(), added, nil
+}
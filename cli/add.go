@@ -2,11 +2,14 @@ package cli
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"reflect"
+	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
-	"github.com/firasmosbahi/container-composer/core"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var addCmd = &cobra.Command{
@@ -19,7 +22,11 @@ Supports adding:
   - Networks
   - Volumes
 
-The wizard will guide you through all configuration options and show a preview before applying changes.`,
+The wizard will guide you through all configuration options and show a preview before applying changes.
+
+Use the service, network and volume subcommands to add resources
+non-interactively from flags or from a YAML/JSON fragment, and the stack
+subcommand to add a ready-made component such as Redis or monitoring.`,
 	RunE: runAdd,
 }
 
@@ -28,27 +35,25 @@ func init() {
 }
 
 func runAdd(cmd *cobra.Command, args []string) error {
-	fmt.Println("\n🚀 Add Resource Wizard\n")
-
-	// 1. Check if docker-compose.yml exists
-	composePath := "docker-compose.yml"
-	if _, err := os.Stat(composePath); os.IsNotExist(err) {
-		return fmt.Errorf("docker-compose.yml not found in current directory")
+	if err := requireTextOutput(cmd); err != nil {
+		return err
 	}
 
-	// 2. Parse existing docker-compose.yml
-	composeFile, err := core.ParseComposeFile(composePath)
+	fmt.Fprint(messageOut, "\n🚀 Add Resource Wizard\n\n")
+
+	// 1. Find and parse the existing compose file
+	composeFile, composePath, err := loadComposeFile()
 	if err != nil {
-		return fmt.Errorf("failed to parse docker-compose.yml: %w", err)
+		return err
 	}
 
-	// 3. Show resource type selection
+	// 2. Show resource type selection
 	resourceType, err := selectResourceType()
 	if err != nil {
 		return err
 	}
 
-	// 4. Run appropriate wizard based on selection
+	// 3. Run appropriate wizard based on selection
 	switch resourceType {
 	case "service":
 		return addService(composeFile, composePath)
@@ -81,9 +86,120 @@ func selectResourceType() (string, error) {
 		Help:    "Select the type of resource to add to your docker-compose.yml",
 	}
 
-	if err := survey.AskOne(prompt, &selected); err != nil {
+	if err := askOne(prompt, &selected); err != nil {
 		return "", err
 	}
 
 	return resourceMap[selected], nil
 }
+
+// readSpecFile reads a YAML/JSON fragment from a file, or from stdin when
+// path is "-"
+func readSpecFile(path string, stdin io.Reader) ([]byte, error) {
+	if path == "-" {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from stdin: %w", err)
+		}
+		return data, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
+
+// readFragment reads a --from-file fragment into value and returns the name
+// of the resource and the fragment as a YAML node. The fragment is either a
+// bare definition or a single-entry "name: {definition}" mapping, which is only
+// accepted when no name is given as an argument.
+func readFragment(cmd *cobra.Command, path, kind, name string, value interface{}) (string, *yaml.Node, error) {
+	data, err := readSpecFile(path, cmd.InOrStdin())
+	if err != nil {
+		return name, nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return name, nil, invalidError(fmt.Errorf("failed to parse %s fragment: %w", kind, err))
+	}
+	var node *yaml.Node
+	if len(document.Content) > 0 {
+		node = document.Content[0]
+	}
+	if node == nil || node.Kind != yaml.MappingNode {
+		return name, nil, invalidError(fmt.Errorf("%s fragment must be a YAML mapping", kind))
+	}
+
+	if name == "" {
+		if len(node.Content) != 2 || node.Content[1].Kind != yaml.MappingNode {
+			return name, nil, invalidError(fmt.Errorf("fragment must contain exactly one 'name: {%s}' entry when no %s name is given", kind, kind))
+		}
+		name, node = node.Content[0].Value, node.Content[1]
+	}
+
+	if err := node.Decode(value); err != nil {
+		return name, nil, invalidError(fmt.Errorf("failed to parse %s fragment: %w", kind, err))
+	}
+	return name, node, nil
+}
+
+// overlayFragment lays the fields of value that differ from the fragment's
+// own definition over the fragment node. base points to a zero value of the
+// same type, into which the fragment is decoded. Fields the typed model does
+// not know are left as they are in the fragment.
+func overlayFragment(fragment *yaml.Node, base, value interface{}) error {
+	if err := fragment.Decode(base); err != nil {
+		return fmt.Errorf("failed to parse fragment: %w", err)
+	}
+	var baseNode, valueNode yaml.Node
+	if err := baseNode.Encode(base); err != nil {
+		return fmt.Errorf("failed to encode fragment: %w", err)
+	}
+	if err := valueNode.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode fragment: %w", err)
+	}
+
+	decoded := func(node *yaml.Node, key string) interface{} {
+		var result interface{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				node.Content[i+1].Decode(&result)
+			}
+		}
+		return result
+	}
+	for i := 0; i+1 < len(valueNode.Content); i += 2 {
+		key, field := valueNode.Content[i].Value, valueNode.Content[i+1]
+		if reflect.DeepEqual(decoded(&baseNode, key), decoded(&valueNode, key)) {
+			continue
+		}
+		replaced := false
+		for j := 0; j+1 < len(fragment.Content); j += 2 {
+			if fragment.Content[j].Value == key {
+				fragment.Content[j+1] = field
+				replaced = true
+			}
+		}
+		if !replaced {
+			fragment.Content = append(fragment.Content, valueNode.Content[i], field)
+		}
+	}
+	return nil
+}
+
+// parseKeyValues parses KEY=VALUE flag values into a map
+func parseKeyValues(values []string, flagName string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, usageError(fmt.Errorf("invalid --%s value %q (expected KEY=VALUE)", flagName, value))
+		}
+		result[key] = val
+	}
+	return result, nil
+}
@@ -6,15 +6,129 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/firasmosbahi/container-composer/core"
+	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
 
+var (
+	addNetworkDriver     string
+	addNetworkExternal   bool
+	addNetworkDriverOpts []string
+	addNetworkFromFile   string
+	addNetworkDryRun     bool
+	addNetworkForce      bool
+)
+
+var addNetworkCmd = &cobra.Command{
+	Use:   "network [name]",
+	Short: "Add a network to docker-compose.yml",
+	Long: `Add a network to docker-compose.yml.
+
+Without a name or --from-file, the interactive wizard is started. Otherwise the
+network is built from flags and/or a YAML or JSON network fragment read from a file
+(or stdin with '-f -'). Flags override the fields of the fragment.
+
+Examples:
+  container-composer add network backend --driver bridge
+  container-composer add network backend --driver-opt com.docker.network.bridge.name=br0 --dry-run
+  echo 'backend: {external: true}' | container-composer add network -f -`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAddNetwork,
+}
+
+func init() {
+	flags := addNetworkCmd.Flags()
+	flags.StringVar(&addNetworkDriver, "driver", "", "network driver")
+	flags.BoolVar(&addNetworkExternal, "external", false, "the network is managed outside of this compose file")
+	flags.StringArrayVar(&addNetworkDriverOpts, "driver-opt", nil, "driver option KEY=VALUE (repeatable)")
+	flags.StringVarP(&addNetworkFromFile, "from-file", "f", "", "read a YAML/JSON network fragment from a file ('-' for stdin)")
+	flags.BoolVar(&addNetworkDryRun, "dry-run", false, "print the diff instead of writing docker-compose.yml")
+	flags.BoolVar(&addNetworkForce, "force", false, "overwrite the network if it already exists")
+
+	addCmd.AddCommand(addNetworkCmd)
+}
+
+func runAddNetwork(cmd *cobra.Command, args []string) error {
+	composeFile, composePath, err := loadComposeFile()
+	if err != nil {
+		return err
+	}
+
+	// No name and no fragment: fall back to the interactive wizard
+	if len(args) == 0 && addNetworkFromFile == "" {
+		if cmd.LocalFlags().NFlag() > 0 {
+			return usageError(fmt.Errorf("a network name is required when using flags"))
+		}
+		if err := requireTextOutput(cmd); err != nil {
+			return err
+		}
+		return addNetwork(composeFile, composePath)
+	}
+
+	name := ""
+	if len(args) > 0 {
+		name = strings.TrimSpace(args[0])
+	}
+
+	network := core.Network{}
+	var fragment *yaml.Node
+	if addNetworkFromFile != "" {
+		if name, fragment, err = readFragment(cmd, addNetworkFromFile, "network", name, &network); err != nil {
+			return err
+		}
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("driver") {
+		network.Driver = addNetworkDriver
+	}
+	if flags.Changed("external") {
+		network.External = addNetworkExternal
+	}
+	opts, err := parseKeyValues(addNetworkDriverOpts, "driver-opt")
+	if err != nil {
+		return err
+	}
+	if len(opts) > 0 && network.DriverOpts == nil {
+		network.DriverOpts = map[string]string{}
+	}
+	for key, value := range opts {
+		network.DriverOpts[key] = value
+	}
+
+	if name == "" {
+		return fmt.Errorf("network name cannot be empty")
+	}
+	if composeFile.NetworkExists(name) && !addNetworkForce {
+		return fmt.Errorf("network '%s' %w (use --force to overwrite)", name, core.ErrAlreadyExists)
+	}
+
+	composeFile.AddNetwork(name, network)
+
+	entries := composeEntries{Networks: []string{name}}
+	if fragment != nil {
+		if err := overlayFragment(fragment, &core.Network{}, network); err != nil {
+			return err
+		}
+		entries.setFragment("networks", name, fragment)
+	}
+
+	result, err := writeComposeEntries(composeFile, entries, composePath, writeOptions{dryRun: addNetworkDryRun})
+	if err != nil {
+		return err
+	}
+	if result.Written {
+		fmt.Fprintf(messageOut, "✅ Network '%s' has been added to %s\n", name, composePath)
+	}
+	return emitResult(cmd, changeResult{Kind: "network", Name: name, writeResult: result})
+}
+
 func addNetwork(composeFile *core.ComposeFile, composePath string) error {
-	fmt.Println("\n🌐 Add Network Wizard\n")
+	fmt.Fprint(messageOut, "\n🌐 Add Network Wizard\n\n")
 
 	// Step 1: Network Name
 	var networkName string
-	if err := survey.AskOne(&survey.Input{
+	if err := askOne(&survey.Input{
 		Message: "Network name:",
 		Help:    "Unique identifier for this network",
 	}, &networkName, survey.WithValidator(survey.Required)); err != nil {
@@ -26,7 +140,7 @@ func addNetwork(composeFile *core.ComposeFile, composePath string) error {
 	// Check conflict
 	if composeFile.NetworkExists(networkName) {
 		var overwrite bool
-		if err := survey.AskOne(&survey.Confirm{
+		if err := askOne(&survey.Confirm{
 			Message: fmt.Sprintf("⚠️  Network '%s' already exists. Overwrite?", networkName),
 			Default: false,
 		}, &overwrite); err != nil {
@@ -41,7 +155,7 @@ func addNetwork(composeFile *core.ComposeFile, composePath string) error {
 
 	// Step 2: Driver selection
 	var driver string
-	if err := survey.AskOne(&survey.Select{
+	if err := askOne(&survey.Select{
 		Message: "Network driver:",
 		Options: []string{"bridge", "host", "overlay", "macvlan", "none"},
 		Default: "bridge",
@@ -53,7 +167,7 @@ func addNetwork(composeFile *core.ComposeFile, composePath string) error {
 
 	// Step 3: External network?
 	var external bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Is this an external network?",
 		Default: false,
 		Help:    "External networks are managed outside of this compose file",
@@ -71,36 +185,16 @@ func addNetwork(composeFile *core.ComposeFile, composePath string) error {
 }
 
 func previewAndConfirmNetwork(composeFile *core.ComposeFile, networkName string, network core.Network, composePath string) error {
-	// Add network to compose file (temporary for preview)
+	// Add network to compose file
 	composeFile.AddNetwork(networkName, network)
 
-	// Marshal to YAML for preview
-	yamlData, err := yaml.Marshal(composeFile)
-	if err != nil {
-		return fmt.Errorf("failed to generate preview: %w", err)
-	}
-
-	fmt.Println("\n📄 Preview of docker-compose.yml:\n")
-	fmt.Println(string(yamlData))
-
-	var confirmed bool
-	if err := survey.AskOne(&survey.Confirm{
-		Message: "Apply these changes?",
-		Default: true,
-	}, &confirmed); err != nil {
+	// Show the diff, confirm and write
+	result, err := writeComposeEntries(composeFile, composeEntries{Networks: []string{networkName}}, composePath, writeOptions{confirm: true})
+	if err != nil || !result.Written {
 		return err
 	}
 
-	if !confirmed {
-		return fmt.Errorf("operation cancelled by user")
-	}
-
-	// Write to file
-	if err := composeFile.WriteComposeFile(composePath); err != nil {
-		return fmt.Errorf("failed to write docker-compose.yml: %w", err)
-	}
-
-	fmt.Println("\n✅ Network added successfully!")
-	fmt.Printf("   Network '%s' has been added to docker-compose.yml\n", networkName)
+	fmt.Fprintln(messageOut, "\n✅ Network added successfully!")
+	fmt.Fprintf(messageOut, "   Network '%s' has been added to docker-compose.yml\n", networkName)
 	return nil
 }
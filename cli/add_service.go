@@ -6,17 +6,330 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/firasmosbahi/container-composer/core"
+	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
 
+var (
+	addServiceImage               string
+	addServiceBuild               string
+	addServiceDockerfile          string
+	addServicePorts               []string
+	addServiceEnv                 []string
+	addServiceVolumes             []string
+	addServiceNetworks            []string
+	addServiceDependsOn           []string
+	addServiceRestart             string
+	addServiceCommand             string
+	addServiceWorkdir             string
+	addServiceUser                string
+	addServiceHostname            string
+	addServiceContainerName       string
+	addServiceLabels              []string
+	addServiceHealthcheckCmd      string
+	addServiceHealthcheckInterval string
+	addServiceHealthcheckTimeout  string
+	addServiceHealthcheckRetries  int
+	addServiceFromFile            string
+	addServiceDryRun              bool
+	addServiceForce               bool
+)
+
+var addServiceCmd = &cobra.Command{
+	Use:   "service [name]",
+	Short: "Add a service to docker-compose.yml",
+	Long: `Add a service to docker-compose.yml.
+
+Without a name or --from-file, the interactive wizard is started. Otherwise the
+service is built from flags and/or a YAML or JSON service fragment read from a
+file (or stdin with '-f -'). Flags override scalar fields of the fragment and
+extend its lists and maps.
+
+Networks and named volumes that are not declared yet are added to the
+top-level networks and volumes sections.
+
+Examples:
+  container-composer add service api --image node:20 --port 3000:3000 \
+      --env NODE_ENV=production --depends-on db --network backend \
+      --volume data:/var/lib/data --restart unless-stopped \
+      --healthcheck-cmd "curl -f http://localhost:3000/health"
+  container-composer add service worker -f worker.yaml --dry-run
+  cat api.json | container-composer add service api -f -`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAddService,
+}
+
+func init() {
+	flags := addServiceCmd.Flags()
+	flags.StringVar(&addServiceImage, "image", "", "docker image")
+	flags.StringVar(&addServiceBuild, "build", "", "build context path (instead of --image)")
+	flags.StringVar(&addServiceDockerfile, "dockerfile", "", "Dockerfile name (with --build)")
+	flags.StringSliceVarP(&addServicePorts, "port", "p", nil, "port mapping, e.g. 8080:80 (repeatable)")
+	flags.StringArrayVarP(&addServiceEnv, "env", "e", nil, "environment variable KEY=VALUE (repeatable)")
+	flags.StringSliceVar(&addServiceVolumes, "volume", nil, "volume mount, e.g. data:/var/lib/data (repeatable)")
+	flags.StringSliceVar(&addServiceNetworks, "network", nil, "network to connect to (repeatable)")
+	flags.StringSliceVar(&addServiceDependsOn, "depends-on", nil, "service this service depends on (repeatable)")
+	flags.StringVar(&addServiceRestart, "restart", "", "restart policy: no, always, on-failure, unless-stopped")
+	flags.StringVar(&addServiceCommand, "command", "", "override the default command")
+	flags.StringVar(&addServiceWorkdir, "workdir", "", "working directory")
+	flags.StringVar(&addServiceUser, "user", "", "user (uid:gid or username)")
+	flags.StringVar(&addServiceHostname, "hostname", "", "container hostname")
+	flags.StringVar(&addServiceContainerName, "container-name", "", "container name")
+	flags.StringArrayVar(&addServiceLabels, "label", nil, "label KEY=VALUE (repeatable)")
+	flags.StringVar(&addServiceHealthcheckCmd, "healthcheck-cmd", "", "health check command (run with CMD-SHELL)")
+	flags.StringVar(&addServiceHealthcheckInterval, "healthcheck-interval", "", "health check interval, e.g. 30s")
+	flags.StringVar(&addServiceHealthcheckTimeout, "healthcheck-timeout", "", "health check timeout, e.g. 10s")
+	flags.IntVar(&addServiceHealthcheckRetries, "healthcheck-retries", 0, "health check retries")
+	flags.StringVarP(&addServiceFromFile, "from-file", "f", "", "read a YAML/JSON service fragment from a file ('-' for stdin)")
+	flags.BoolVar(&addServiceDryRun, "dry-run", false, "print the diff instead of writing docker-compose.yml")
+	flags.BoolVar(&addServiceForce, "force", false, "overwrite the service if it already exists")
+
+	addServiceCmd.RegisterFlagCompletionFunc("network", completeComposeFlag("network", "network"))
+	addServiceCmd.RegisterFlagCompletionFunc("depends-on", completeComposeFlag("service", "depends-on"))
+	addServiceCmd.RegisterFlagCompletionFunc("restart", completeValues("no", "always", "on-failure", "unless-stopped"))
+
+	addCmd.AddCommand(addServiceCmd)
+}
+
+func runAddService(cmd *cobra.Command, args []string) error {
+	composeFile, composePath, err := loadComposeFile()
+	if err != nil {
+		return err
+	}
+
+	// No name and no fragment: fall back to the interactive wizard
+	if len(args) == 0 && addServiceFromFile == "" {
+		if cmd.LocalFlags().NFlag() > 0 {
+			return usageError(fmt.Errorf("a service name is required when using flags"))
+		}
+		if err := requireTextOutput(cmd); err != nil {
+			return err
+		}
+		return addService(composeFile, composePath)
+	}
+
+	name := ""
+	if len(args) > 0 {
+		name = strings.TrimSpace(args[0])
+	}
+
+	service, fragment, err := serviceFromFragment(cmd, name)
+	if err != nil {
+		return err
+	}
+	if err := applyServiceFlags(cmd, &service); err != nil {
+		return err
+	}
+	if err := validateNewService(composeFile, service, addServiceForce); err != nil {
+		return err
+	}
+
+	composeFile.AddService(service)
+	entries := composeEntries{Services: []string{service.Name}}
+	if fragment != nil {
+		if err := overlayFragment(fragment, &core.Service{}, service); err != nil {
+			return err
+		}
+		entries.setFragment("services", service.Name, fragment)
+	}
+	entries.Networks, entries.Volumes = declareServiceResources(composeFile, service)
+
+	result, err := writeComposeEntries(composeFile, entries, composePath, writeOptions{dryRun: addServiceDryRun})
+	if err != nil {
+		return err
+	}
+	if result.Written {
+		fmt.Fprintf(messageOut, "✅ Service '%s' has been added to %s\n", service.Name, composePath)
+	}
+	return emitResult(cmd, changeResult{Kind: "service", Name: service.Name, writeResult: result})
+}
+
+// serviceFromFragment reads the --from-file fragment, if any, and returns
+// it both typed and as a YAML node
+func serviceFromFragment(cmd *cobra.Command, name string) (core.Service, *yaml.Node, error) {
+	service := core.Service{Name: name}
+	if addServiceFromFile == "" {
+		return service, nil, nil
+	}
+	name, fragment, err := readFragment(cmd, addServiceFromFile, "service", name, &service)
+	service.Name = name
+	return service, fragment, err
+}
+
+// restartPolicies lists the restart policies accepted by --restart
+const restartPolicies = "no, always, on-failure[:N], unless-stopped"
+
+// applyServiceFlags applies the flags that were explicitly set on top of a
+// service definition
+func applyServiceFlags(cmd *cobra.Command, service *core.Service) error {
+	flags := cmd.Flags()
+
+	if flags.Changed("image") {
+		service.Image = addServiceImage
+	}
+	if flags.Changed("build") || flags.Changed("dockerfile") {
+		if service.Build == nil {
+			service.Build = &core.BuildConfig{}
+		}
+		if flags.Changed("build") {
+			service.Build.Context = addServiceBuild
+		}
+		if flags.Changed("dockerfile") {
+			service.Build.Dockerfile = addServiceDockerfile
+		}
+	}
+
+	service.Ports = append(service.Ports, addServicePorts...)
+	service.Volumes = append(service.Volumes, addServiceVolumes...)
+	service.Networks = appendUnique(service.Networks, addServiceNetworks...)
+	service.DependsOn = appendUnique(service.DependsOn, addServiceDependsOn...)
+
+	env, err := parseKeyValues(addServiceEnv, "env")
+	if err != nil {
+		return err
+	}
+	if len(env) > 0 && service.Environment == nil {
+		service.Environment = core.Environment{}
+	}
+	for key, value := range env {
+		service.Environment[key] = value
+	}
+
+	labels, err := parseKeyValues(addServiceLabels, "label")
+	if err != nil {
+		return err
+	}
+	if len(labels) > 0 && service.Labels == nil {
+		service.Labels = map[string]string{}
+	}
+	for key, value := range labels {
+		service.Labels[key] = value
+	}
+
+	if flags.Changed("restart") {
+		if !core.IsValidRestartPolicy(addServiceRestart) {
+			return usageError(fmt.Errorf("invalid --restart value %q (supported: %s)", addServiceRestart, restartPolicies))
+		}
+		service.Restart = addServiceRestart
+	}
+	if flags.Changed("command") {
+		service.Command = addServiceCommand
+	}
+	if flags.Changed("workdir") {
+		service.WorkingDir = addServiceWorkdir
+	}
+	if flags.Changed("user") {
+		service.User = addServiceUser
+	}
+	if flags.Changed("hostname") {
+		service.Hostname = addServiceHostname
+	}
+	if flags.Changed("container-name") {
+		service.ContainerName = addServiceContainerName
+	}
+
+	if flags.Changed("healthcheck-cmd") || flags.Changed("healthcheck-interval") ||
+		flags.Changed("healthcheck-timeout") || flags.Changed("healthcheck-retries") {
+		if service.HealthCheck == nil {
+			service.HealthCheck = &core.HealthCheck{}
+		}
+		if flags.Changed("healthcheck-cmd") {
+			service.HealthCheck.Test = core.HealthCheckTest{"CMD-SHELL", addServiceHealthcheckCmd}
+		}
+		if flags.Changed("healthcheck-interval") {
+			service.HealthCheck.Interval = addServiceHealthcheckInterval
+		}
+		if flags.Changed("healthcheck-timeout") {
+			service.HealthCheck.Timeout = addServiceHealthcheckTimeout
+		}
+		if flags.Changed("healthcheck-retries") {
+			service.HealthCheck.Retries = addServiceHealthcheckRetries
+		}
+	}
+
+	return nil
+}
+
+// validateNewService checks a non-interactively built service before it is
+// added to the compose file
+func validateNewService(composeFile *core.ComposeFile, service core.Service, force bool) error {
+	if service.Name == "" {
+		return usageError(fmt.Errorf("service name cannot be empty"))
+	}
+	if composeFile.ServiceExists(service.Name) && !force {
+		return fmt.Errorf("service '%s' %w (use --force to overwrite)", service.Name, core.ErrAlreadyExists)
+	}
+	if service.Image == "" && service.Build == nil {
+		return usageError(fmt.Errorf("service '%s' needs an image (--image) or a build context (--build)", service.Name))
+	}
+	for _, dep := range service.DependsOn {
+		if dep == service.Name {
+			return invalidError(fmt.Errorf("service '%s' cannot depend on itself", service.Name))
+		}
+		if !composeFile.ServiceExists(dep) {
+			return invalidError(fmt.Errorf("service '%s' depends on non-existent service '%s'", service.Name, dep))
+		}
+	}
+	if service.Restart != "" && !core.IsValidRestartPolicy(service.Restart) {
+		return invalidError(fmt.Errorf("invalid restart policy %q (supported: %s)", service.Restart, restartPolicies))
+	}
+	return nil
+}
+
+// declareServiceResources adds networks and named volumes used by a service
+// to the top-level sections when they are not declared yet, and returns the
+// names it added
+func declareServiceResources(composeFile *core.ComposeFile, service core.Service) (networks, volumes []string) {
+	for _, network := range service.Networks {
+		if !composeFile.NetworkExists(network) {
+			composeFile.AddNetwork(network, core.Network{})
+			networks = append(networks, network)
+		}
+	}
+	for _, mount := range service.Volumes {
+		if volume := namedVolume(mount); volume != "" && !composeFile.VolumeExists(volume) {
+			composeFile.AddVolume(volume, core.Volume{})
+			volumes = append(volumes, volume)
+		}
+	}
+	return networks, volumes
+}
+
+// namedVolume returns the named volume of a mount, or "" for bind mounts
+func namedVolume(mount string) string {
+	source, _, ok := strings.Cut(mount, ":")
+	if !ok || source == "" || strings.HasPrefix(source, "/") ||
+		strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~") || strings.HasPrefix(source, "$") {
+		return ""
+	}
+	return source
+}
+
+// appendUnique appends values that are not already present
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
 func addService(composeFile *core.ComposeFile, composePath string) error {
-	fmt.Println("\n🐳 Add Service Wizard\n")
+	fmt.Fprint(messageOut, "\n🐳 Add Service Wizard\n\n")
 
 	service := core.Service{}
 
 	// Step 1: Service Name
 	var serviceName string
-	if err := survey.AskOne(&survey.Input{
+	if err := askOne(&survey.Input{
 		Message: "Service name:",
 		Help:    "Unique identifier for this service",
 	}, &serviceName, survey.WithValidator(survey.Required)); err != nil {
@@ -27,16 +340,20 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 
 	// Check for conflicts
 	if composeFile.ServiceExists(serviceName) {
-		if err := handleServiceConflict(composeFile, serviceName); err != nil {
+		edit, err := handleServiceConflict(composeFile, serviceName)
+		if err != nil {
 			return err
 		}
+		if edit {
+			return editService(composeFile, composePath, serviceName)
+		}
 	}
 
 	service.Name = serviceName
 
 	// Step 2: Image or Build?
 	var useImage bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Use a pre-built image? (No = build from Dockerfile)",
 		Default: true,
 	}, &useImage); err != nil {
@@ -46,7 +363,7 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 	if useImage {
 		// Get image name
 		var image string
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "Docker image:",
 			Default: "nginx:latest",
 			Help:    "e.g., nginx:latest, postgres:15, node:20-alpine",
@@ -59,7 +376,7 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 		service.Build = &core.BuildConfig{}
 
 		var buildContext string
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "Build context path:",
 			Default: ".",
 			Help:    "Path to directory containing Dockerfile",
@@ -69,7 +386,7 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 		service.Build.Context = strings.TrimSpace(buildContext)
 
 		var dockerfile string
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "Dockerfile name:",
 			Default: "Dockerfile",
 		}, &dockerfile); err != nil {
@@ -80,7 +397,7 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 
 	// Step 3: Ports
 	var addPorts bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Expose ports?",
 		Default: true,
 	}, &addPorts); err != nil {
@@ -93,7 +410,7 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 
 	// Step 4: Environment Variables
 	var addEnv bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Add environment variables?",
 		Default: false,
 	}, &addEnv); err != nil {
@@ -106,7 +423,7 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 
 	// Step 5: Volumes
 	var addVolumes bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Mount volumes?",
 		Default: false,
 	}, &addVolumes); err != nil {
@@ -119,7 +436,7 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 
 	// Step 6: Networks
 	var addNetworks bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Connect to networks?",
 		Default: false,
 	}, &addNetworks); err != nil {
@@ -132,7 +449,7 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 
 	// Step 7: Dependencies
 	var addDependencies bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Add service dependencies (depends_on)?",
 		Default: false,
 	}, &addDependencies); err != nil {
@@ -145,10 +462,11 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 
 	// Step 8: Restart Policy
 	var restartPolicy string
-	if err := survey.AskOne(&survey.Select{
+	restartOptions := appendUnique([]string{"no", "always", "on-failure", "unless-stopped"}, defaultRestartPolicy())
+	if err := askOne(&survey.Select{
 		Message: "Restart policy:",
-		Options: []string{"no", "always", "on-failure", "unless-stopped"},
-		Default: "unless-stopped",
+		Options: restartOptions,
+		Default: defaultRestartPolicy(),
 		Help:    "Restart policy for the service",
 	}, &restartPolicy); err != nil {
 		return err
@@ -157,7 +475,7 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 
 	// Step 9: Advanced options
 	var configureAdvanced bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Configure advanced options? (command, working_dir, hostname, etc.)",
 		Default: false,
 	}, &configureAdvanced); err != nil {
@@ -180,10 +498,10 @@ func addService(composeFile *core.ComposeFile, composePath string) error {
 
 func askForPorts() []string {
 	var ports []string
-	fmt.Println("\nEnter port mappings (press Enter with empty value to finish):")
+	fmt.Fprintln(messageOut, "\nEnter port mappings (press Enter with empty value to finish):")
 	for {
 		var port string
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "Port mapping (host:container or container):",
 			Help:    "e.g., 8080:80 or 3000",
 		}, &port); err != nil || port == "" {
@@ -195,7 +513,7 @@ func askForPorts() []string {
 		}
 
 		var addMore bool
-		if err := survey.AskOne(&survey.Confirm{
+		if err := askOne(&survey.Confirm{
 			Message: "Add another port?",
 			Default: false,
 		}, &addMore); err != nil || !addMore {
@@ -207,11 +525,11 @@ func askForPorts() []string {
 
 func askForEnvironmentVars() map[string]string {
 	env := make(map[string]string)
-	fmt.Println("\nEnter environment variables (press Enter with empty name to finish):")
+	fmt.Fprintln(messageOut, "\nEnter environment variables (press Enter with empty name to finish):")
 	for {
 		var key, value string
 
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "Environment variable name:",
 			Help:    "e.g., DATABASE_URL, API_KEY",
 		}, &key); err != nil || key == "" {
@@ -223,7 +541,7 @@ func askForEnvironmentVars() map[string]string {
 			break
 		}
 
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: fmt.Sprintf("Value for %s:", key),
 		}, &value); err != nil {
 			break
@@ -232,7 +550,7 @@ func askForEnvironmentVars() map[string]string {
 		env[key] = value
 
 		var addMore bool
-		if err := survey.AskOne(&survey.Confirm{
+		if err := askOne(&survey.Confirm{
 			Message: "Add another variable?",
 			Default: false,
 		}, &addMore); err != nil || !addMore {
@@ -244,10 +562,10 @@ func askForEnvironmentVars() map[string]string {
 
 func askForVolumeMounts() []string {
 	var volumes []string
-	fmt.Println("\nEnter volume mounts (press Enter with empty value to finish):")
+	fmt.Fprintln(messageOut, "\nEnter volume mounts (press Enter with empty value to finish):")
 	for {
 		var volume string
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "Volume mount (host:container or volume:container):",
 			Help:    "e.g., ./app:/app or data:/var/lib/data",
 		}, &volume); err != nil || volume == "" {
@@ -259,7 +577,7 @@ func askForVolumeMounts() []string {
 		}
 
 		var addMore bool
-		if err := survey.AskOne(&survey.Confirm{
+		if err := askOne(&survey.Confirm{
 			Message: "Add another volume?",
 			Default: false,
 		}, &addMore); err != nil || !addMore {
@@ -279,13 +597,13 @@ func askForNetworks(composeFile *core.ComposeFile) []string {
 	var networks []string
 
 	if len(existingNetworks) == 0 {
-		fmt.Println("\nNo existing networks found. You can create networks later or enter custom network names.")
+		fmt.Fprintln(messageOut, "\nNo existing networks found. You can create networks later or enter custom network names.")
 	}
 
-	fmt.Println("\nEnter network names (press Enter with empty value to finish):")
+	fmt.Fprintln(messageOut, "\nEnter network names (press Enter with empty value to finish):")
 	for {
 		var network string
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "Network name:",
 			Help:    "Network to connect this service to",
 		}, &network); err != nil || network == "" {
@@ -297,7 +615,7 @@ func askForNetworks(composeFile *core.ComposeFile) []string {
 		}
 
 		var addMore bool
-		if err := survey.AskOne(&survey.Confirm{
+		if err := askOne(&survey.Confirm{
 			Message: "Add another network?",
 			Default: false,
 		}, &addMore); err != nil || !addMore {
@@ -317,12 +635,12 @@ func askForDependencies(composeFile *core.ComposeFile, currentService string) []
 	}
 
 	if len(existingServices) == 0 {
-		fmt.Println("\nNo other services found to depend on.")
+		fmt.Fprintln(messageOut, "\nNo other services found to depend on.")
 		return nil
 	}
 
 	var dependencies []string
-	if err := survey.AskOne(&survey.MultiSelect{
+	if err := askOne(&survey.MultiSelect{
 		Message: "Select services this service depends on:",
 		Options: existingServices,
 		Help:    "These services will be started before this one",
@@ -334,16 +652,16 @@ func askForDependencies(composeFile *core.ComposeFile, currentService string) []
 }
 
 func configureAdvancedOptions(service *core.Service) {
-	fmt.Println("\n⚙️  Advanced Configuration\n")
+	fmt.Fprint(messageOut, "\n⚙️  Advanced Configuration\n\n")
 
 	// Command
 	var setCommand bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Override default command?",
 		Default: false,
 	}, &setCommand); err == nil && setCommand {
 		var command string
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "Command:",
 			Help:    "Command to run when container starts",
 		}, &command); err == nil && command != "" {
@@ -353,12 +671,12 @@ func configureAdvancedOptions(service *core.Service) {
 
 	// Working directory
 	var setWorkdir bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Set working directory?",
 		Default: false,
 	}, &setWorkdir); err == nil && setWorkdir {
 		var workdir string
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "Working directory:",
 			Default: "/app",
 		}, &workdir); err == nil && workdir != "" {
@@ -368,12 +686,12 @@ func configureAdvancedOptions(service *core.Service) {
 
 	// User
 	var setUser bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Set user?",
 		Default: false,
 	}, &setUser); err == nil && setUser {
 		var user string
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "User (uid:gid or username):",
 			Help:    "e.g., 1000:1000 or node",
 		}, &user); err == nil && user != "" {
@@ -383,12 +701,12 @@ func configureAdvancedOptions(service *core.Service) {
 
 	// Hostname
 	var setHostname bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Set custom hostname?",
 		Default: false,
 	}, &setHostname); err == nil && setHostname {
 		var hostname string
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "Hostname:",
 		}, &hostname); err == nil && hostname != "" {
 			service.Hostname = strings.TrimSpace(hostname)
@@ -397,52 +715,44 @@ func configureAdvancedOptions(service *core.Service) {
 }
 
 func previewAndConfirmService(composeFile *core.ComposeFile, service core.Service, composePath string) error {
-	// Add service to compose file (temporary for preview)
+	// Add service to compose file
 	composeFile.AddService(service)
 
-	// Marshal to YAML for preview
-	yamlData, err := yaml.Marshal(composeFile)
-	if err != nil {
-		return fmt.Errorf("failed to generate preview: %w", err)
-	}
-
-	fmt.Println("\n📄 Preview of docker-compose.yml:\n")
-	fmt.Println(string(yamlData))
-
-	var confirmed bool
-	if err := survey.AskOne(&survey.Confirm{
-		Message: "Apply these changes?",
-		Default: true,
-	}, &confirmed); err != nil {
+	// Show the diff, confirm and write
+	result, err := writeComposeEntries(composeFile, composeEntries{Services: []string{service.Name}}, composePath, writeOptions{confirm: true})
+	if err != nil || !result.Written {
 		return err
 	}
 
-	if !confirmed {
-		return fmt.Errorf("operation cancelled by user")
-	}
-
-	// Write to file
-	if err := composeFile.WriteComposeFile(composePath); err != nil {
-		return fmt.Errorf("failed to write docker-compose.yml: %w", err)
-	}
-
-	fmt.Println("\n✅ Service added successfully!")
-	fmt.Printf("   Service '%s' has been added to docker-compose.yml\n", service.Name)
+	fmt.Fprintln(messageOut, "\n✅ Service added successfully!")
+	fmt.Fprintf(messageOut, "   Service '%s' has been added to docker-compose.yml\n", service.Name)
 	return nil
 }
 
-func handleServiceConflict(composeFile *core.ComposeFile, serviceName string) error {
-	var overwrite bool
-	if err := survey.AskOne(&survey.Confirm{
-		Message: fmt.Sprintf("⚠️  Service '%s' already exists. Overwrite?", serviceName),
-		Default: false,
-	}, &overwrite); err != nil {
-		return err
+// handleServiceConflict asks what to do with an existing service. It returns
+// true when the existing service should be edited instead of replaced.
+func handleServiceConflict(composeFile *core.ComposeFile, serviceName string) (bool, error) {
+	const (
+		editOption      = "Edit the existing service"
+		overwriteOption = "Overwrite it"
+		cancelOption    = "Cancel"
+	)
+
+	var choice string
+	if err := askOne(&survey.Select{
+		Message: fmt.Sprintf("⚠️  Service '%s' already exists. What do you want to do?", serviceName),
+		Options: []string{editOption, overwriteOption, cancelOption},
+		Default: editOption,
+	}, &choice); err != nil {
+		return false, err
+	}
+
+	switch choice {
+	case editOption:
+		return true, nil
+	case overwriteOption:
+		return false, nil
+	default:
+		return false, fmt.Errorf("service already exists and overwrite was declined")
 	}
-
-	if !overwrite {
-		return fmt.Errorf("service already exists and overwrite was declined")
-	}
-
-	return nil
 }
@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/addons"
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/spf13/cobra"
+)
+
+var (
+	addStackNetwork string
+	addStackDryRun  bool
+)
+
+var addStackCmd = &cobra.Command{
+	Use:   "stack [addon]",
+	Short: "Add a standard component (Redis, Postgres, monitoring...) to docker-compose.yml",
+	Long: `Add a stack add-on to docker-compose.yml. Add-ons are ready-made compose
+fragments for common components; run without an argument to list them.
+
+Merging an add-on:
+  - renames its services, networks and volumes when the names are taken
+    (redis becomes redis-2), along with the references inside the add-on
+  - moves host ports that another service already publishes to the next free
+    port
+  - attaches its services to --network, or to the only network of the compose
+    file when there is exactly one
+  - appends the environment variables it reads to .env.example next to the
+    compose file, skipping variables that are already there
+
+Examples:
+  container-composer add stack                   # list add-ons
+  container-composer add stack redis
+  container-composer add stack monitoring --network backend --dry-run`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeAddonNames,
+	RunE:              runAddStack,
+}
+
+func init() {
+	addStackCmd.Flags().StringVar(&addStackNetwork, "network", "", "network to attach the add-on's services to (declared if missing)")
+	addStackCmd.Flags().BoolVar(&addStackDryRun, "dry-run", false, "print the diff instead of writing docker-compose.yml and .env.example")
+	addStackCmd.RegisterFlagCompletionFunc("network", completeComposeFlag("network", "network"))
+	addCmd.AddCommand(addStackCmd)
+}
+
+// stackResult is the structured result of add stack
+type stackResult struct {
+	Addon    string   `json:"addon"`
+	Network  string   `json:"network,omitempty"`
+	EnvFile  string   `json:"env_file,omitempty"`
+	EnvAdded []string `json:"env_added"`
+	*core.MergeReport
+	writeResult
+}
+
+func runAddStack(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return listAddons(cmd)
+	}
+
+	addon, err := addons.Get(args[0])
+	if err != nil {
+		return err
+	}
+	fragment, err := addon.Fragment()
+	if err != nil {
+		return err
+	}
+
+	document, composePath, err := loadComposeDocument()
+	if err != nil {
+		return err
+	}
+	composeFile, err := document.ComposeFile()
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", composePath, err)
+	}
+
+	network := addStackNetwork
+	if network == "" && len(composeFile.Networks) == 1 {
+		for name, declared := range composeFile.Networks {
+			if !declared.External {
+				network = name
+			}
+		}
+	}
+	declareNetwork := network != "" && !composeFile.NetworkExists(network)
+	if declareNetwork {
+		composeFile.AddNetwork(network, core.Network{})
+	}
+
+	report, err := composeFile.Merge(fragment, core.MergeOptions{Network: network})
+	if err != nil {
+		return fmt.Errorf("failed to merge add-on '%s': %w", addon.Name, err)
+	}
+	printMergeReport(report, network)
+
+	if declareNetwork {
+		report.Networks = append(report.Networks, network)
+	}
+	data, err := documentWithEntries(document, composeFile, composeEntries{Services: report.Services, Networks: report.Networks, Volumes: report.Volumes})
+	if err != nil {
+		return err
+	}
+
+	result := stackResult{Addon: addon.Name, Network: network, EnvAdded: []string{}, MergeReport: report}
+	result.writeResult, err = writeComposeData(data, composePath, writeOptions{dryRun: addStackDryRun})
+	if err != nil {
+		return err
+	}
+
+	if result.Written || addStackDryRun {
+		result.EnvFile = filepath.Join(filepath.Dir(composePath), ".env.example")
+		if result.EnvAdded, err = appendAddonEnv(addon, result.EnvFile, addStackDryRun); err != nil {
+			return err
+		}
+	}
+	if result.Written {
+		fmt.Fprintf(messageOut, "✅ Add-on '%s' has been added to %s\n", addon.Name, composePath)
+	}
+	return emitResult(cmd, result)
+}
+
+// printMergeReport explains the renames and port moves of a merge
+func printMergeReport(report *core.MergeReport, network string) {
+	for _, rename := range report.Renamed {
+		fmt.Fprintf(messageOut, "↪️  %s '%s' already exists, added as '%s'\n", rename.Kind, rename.Old, rename.New)
+		for _, usage := range rename.Hostnames {
+			fmt.Fprintf(messageOut, "   %s %s: %s → %s\n", usage.Service, usage.Field, usage.Before, usage.After)
+		}
+	}
+	for _, port := range report.Ports {
+		fmt.Fprintf(messageOut, "↪️  %s: host port of %s is taken, using %s\n", port.Service, port.Old, port.New)
+	}
+	if network != "" {
+		fmt.Fprintf(messageOut, "🌐 Attaching %s to network '%s'\n", strings.Join(report.Services, ", "), network)
+	}
+}
+
+// appendAddonEnv appends the add-on's variables to the .env.example file and
+// returns the names that were added
+func appendAddonEnv(addon *addons.Addon, envPath string, dryRun bool) ([]string, error) {
+	existing, err := os.ReadFile(envPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", envPath, err)
+	}
+
+	content, added, err := addon.EnvExample(existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", envPath, err)
+	}
+	if len(added) == 0 {
+		return []string{}, nil
+	}
+	if dryRun {
+		fmt.Fprintf(messageOut, "📝 Would add %s to %s\n", strings.Join(added, ", "), envPath)
+		return added, nil
+	}
+	if err := os.WriteFile(envPath, content, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", envPath, err)
+	}
+	fmt.Fprintf(messageOut, "📝 Added %s to %s\n", strings.Join(added, ", "), envPath)
+	return added, nil
+}
+
+// listAddons prints the add-on catalog
+func listAddons(cmd *cobra.Command) error {
+	list, err := addons.List()
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		return emitResult(cmd, list)
+	}
+
+	fmt.Fprint(messageOut, "\n🧩 Available add-ons\n\n")
+	for _, addon := range list {
+		fmt.Fprintf(messageOut, "  %-12s %s\n", addon.Name, addon.Description)
+		fmt.Fprintf(messageOut, "  %-12s services: %s", "", strings.Join(addon.Services, ", "))
+		if len(addon.Ports) > 0 {
+			fmt.Fprintf(messageOut, "; ports: %s", strings.Join(addon.Ports, ", "))
+		}
+		fmt.Fprintln(messageOut)
+	}
+	fmt.Fprintln(messageOut, "\nRun 'container-composer add stack <addon>' to add one.")
+	return nil
+}
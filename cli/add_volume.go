@@ -6,15 +6,129 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/firasmosbahi/container-composer/core"
+	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
 
+var (
+	addVolumeDriver     string
+	addVolumeExternal   bool
+	addVolumeDriverOpts []string
+	addVolumeFromFile   string
+	addVolumeDryRun     bool
+	addVolumeForce      bool
+)
+
+var addVolumeCmd = &cobra.Command{
+	Use:   "volume [name]",
+	Short: "Add a volume to docker-compose.yml",
+	Long: `Add a volume to docker-compose.yml.
+
+Without a name or --from-file, the interactive wizard is started. Otherwise the
+volume is built from flags and/or a YAML or JSON volume fragment read from a file
+(or stdin with '-f -'). Flags override the fields of the fragment.
+
+Examples:
+  container-composer add volume data --driver local
+  container-composer add volume data --driver-opt type=tmpfs --dry-run
+  echo 'data: {external: true}' | container-composer add volume -f -`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAddVolume,
+}
+
+func init() {
+	flags := addVolumeCmd.Flags()
+	flags.StringVar(&addVolumeDriver, "driver", "", "volume driver")
+	flags.BoolVar(&addVolumeExternal, "external", false, "the volume is managed outside of this compose file")
+	flags.StringArrayVar(&addVolumeDriverOpts, "driver-opt", nil, "driver option KEY=VALUE (repeatable)")
+	flags.StringVarP(&addVolumeFromFile, "from-file", "f", "", "read a YAML/JSON volume fragment from a file ('-' for stdin)")
+	flags.BoolVar(&addVolumeDryRun, "dry-run", false, "print the diff instead of writing docker-compose.yml")
+	flags.BoolVar(&addVolumeForce, "force", false, "overwrite the volume if it already exists")
+
+	addCmd.AddCommand(addVolumeCmd)
+}
+
+func runAddVolume(cmd *cobra.Command, args []string) error {
+	composeFile, composePath, err := loadComposeFile()
+	if err != nil {
+		return err
+	}
+
+	// No name and no fragment: fall back to the interactive wizard
+	if len(args) == 0 && addVolumeFromFile == "" {
+		if cmd.LocalFlags().NFlag() > 0 {
+			return usageError(fmt.Errorf("a volume name is required when using flags"))
+		}
+		if err := requireTextOutput(cmd); err != nil {
+			return err
+		}
+		return addVolume(composeFile, composePath)
+	}
+
+	name := ""
+	if len(args) > 0 {
+		name = strings.TrimSpace(args[0])
+	}
+
+	volume := core.Volume{}
+	var fragment *yaml.Node
+	if addVolumeFromFile != "" {
+		if name, fragment, err = readFragment(cmd, addVolumeFromFile, "volume", name, &volume); err != nil {
+			return err
+		}
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("driver") {
+		volume.Driver = addVolumeDriver
+	}
+	if flags.Changed("external") {
+		volume.External = addVolumeExternal
+	}
+	opts, err := parseKeyValues(addVolumeDriverOpts, "driver-opt")
+	if err != nil {
+		return err
+	}
+	if len(opts) > 0 && volume.DriverOpts == nil {
+		volume.DriverOpts = map[string]string{}
+	}
+	for key, value := range opts {
+		volume.DriverOpts[key] = value
+	}
+
+	if name == "" {
+		return fmt.Errorf("volume name cannot be empty")
+	}
+	if composeFile.VolumeExists(name) && !addVolumeForce {
+		return fmt.Errorf("volume '%s' %w (use --force to overwrite)", name, core.ErrAlreadyExists)
+	}
+
+	composeFile.AddVolume(name, volume)
+
+	entries := composeEntries{Volumes: []string{name}}
+	if fragment != nil {
+		if err := overlayFragment(fragment, &core.Volume{}, volume); err != nil {
+			return err
+		}
+		entries.setFragment("volumes", name, fragment)
+	}
+
+	result, err := writeComposeEntries(composeFile, entries, composePath, writeOptions{dryRun: addVolumeDryRun})
+	if err != nil {
+		return err
+	}
+	if result.Written {
+		fmt.Fprintf(messageOut, "✅ Volume '%s' has been added to %s\n", name, composePath)
+	}
+	return emitResult(cmd, changeResult{Kind: "volume", Name: name, writeResult: result})
+}
+
 func addVolume(composeFile *core.ComposeFile, composePath string) error {
-	fmt.Println("\n💾 Add Volume Wizard\n")
+	fmt.Fprint(messageOut, "\n💾 Add Volume Wizard\n\n")
 
 	// Step 1: Volume Name
 	var volumeName string
-	if err := survey.AskOne(&survey.Input{
+	if err := askOne(&survey.Input{
 		Message: "Volume name:",
 		Help:    "Unique identifier for this volume",
 	}, &volumeName, survey.WithValidator(survey.Required)); err != nil {
@@ -26,7 +140,7 @@ func addVolume(composeFile *core.ComposeFile, composePath string) error {
 	// Check conflict
 	if composeFile.VolumeExists(volumeName) {
 		var overwrite bool
-		if err := survey.AskOne(&survey.Confirm{
+		if err := askOne(&survey.Confirm{
 			Message: fmt.Sprintf("⚠️  Volume '%s' already exists. Overwrite?", volumeName),
 			Default: false,
 		}, &overwrite); err != nil {
@@ -41,7 +155,7 @@ func addVolume(composeFile *core.ComposeFile, composePath string) error {
 
 	// Step 2: Driver selection
 	var driver string
-	if err := survey.AskOne(&survey.Select{
+	if err := askOne(&survey.Select{
 		Message: "Volume driver:",
 		Options: []string{"local", "nfs", "custom"},
 		Default: "local",
@@ -52,7 +166,7 @@ func addVolume(composeFile *core.ComposeFile, composePath string) error {
 
 	if driver == "custom" {
 		var customDriver string
-		if err := survey.AskOne(&survey.Input{
+		if err := askOne(&survey.Input{
 			Message: "Custom driver name:",
 		}, &customDriver); err != nil {
 			return err
@@ -64,7 +178,7 @@ func addVolume(composeFile *core.ComposeFile, composePath string) error {
 
 	// Step 3: External volume?
 	var external bool
-	if err := survey.AskOne(&survey.Confirm{
+	if err := askOne(&survey.Confirm{
 		Message: "Is this an external volume?",
 		Default: false,
 		Help:    "External volumes are managed outside of this compose file",
@@ -82,36 +196,16 @@ func addVolume(composeFile *core.ComposeFile, composePath string) error {
 }
 
 func previewAndConfirmVolume(composeFile *core.ComposeFile, volumeName string, volume core.Volume, composePath string) error {
-	// Add volume to compose file (temporary for preview)
+	// Add volume to compose file
 	composeFile.AddVolume(volumeName, volume)
 
-	// Marshal to YAML for preview
-	yamlData, err := yaml.Marshal(composeFile)
-	if err != nil {
-		return fmt.Errorf("failed to generate preview: %w", err)
-	}
-
-	fmt.Println("\n📄 Preview of docker-compose.yml:\n")
-	fmt.Println(string(yamlData))
-
-	var confirmed bool
-	if err := survey.AskOne(&survey.Confirm{
-		Message: "Apply these changes?",
-		Default: true,
-	}, &confirmed); err != nil {
+	// Show the diff, confirm and write
+	result, err := writeComposeEntries(composeFile, composeEntries{Volumes: []string{volumeName}}, composePath, writeOptions{confirm: true})
+	if err != nil || !result.Written {
 		return err
 	}
 
-	if !confirmed {
-		return fmt.Errorf("operation cancelled by user")
-	}
-
-	// Write to file
-	if err := composeFile.WriteComposeFile(composePath); err != nil {
-		return fmt.Errorf("failed to write docker-compose.yml: %w", err)
-	}
-
-	fmt.Println("\n✅ Volume added successfully!")
-	fmt.Printf("   Volume '%s' has been added to docker-compose.yml\n", volumeName)
+	fmt.Fprintln(messageOut, "\n✅ Volume added successfully!")
+	fmt.Fprintf(messageOut, "   Volume '%s' has been added to docker-compose.yml\n", volumeName)
 	return nil
 }
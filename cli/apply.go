@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyFile   string
+	applyType   string
+	applyStrict bool
+	applyDryRun bool
+	applyYes    bool
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply a JSON Patch or JSON Merge Patch to docker-compose.yml",
+	Long: `Apply a patch file to docker-compose.yml so compose changes can be reviewed
+and rolled out like code.
+
+Two formats are supported, written as YAML or JSON:
+  - JSON Patch (RFC 6902): a list of add, remove, replace, move, copy and
+    test operations addressed by JSON Pointers such as /services/api/ports/-
+  - JSON Merge Patch (RFC 7386): a partial compose file merged into the
+    current one, where null removes a key
+
+The format is detected from the document unless --type is given. Patches are
+idempotent: re-applying one that is already applied changes nothing. Use
+--strict for exact RFC 6902 semantics, where removing a missing value fails
+and "/-" always appends.
+
+The result is validated before it is written, with the lint rule severities
+from the config file. Only the entries the patch
+changes are rewritten, so comments and formatting elsewhere are preserved.
+The diff is shown and confirmed before writing unless --yes is given, which
+is required when the patch is read from stdin.
+
+Examples:
+  container-composer apply -f changes.yaml
+  container-composer apply -f changes.json --dry-run
+  cat changes.yaml | container-composer apply -f - --yes`,
+	Args: cobra.NoArgs,
+	RunE: runApply,
+}
+
+func init() {
+	applyCmd.Flags().StringVarP(&applyFile, "file", "f", "", "patch file (use - for stdin)")
+	applyCmd.Flags().StringVar(&applyType, "type", "", "patch format: json or merge (default: detect)")
+	applyCmd.Flags().BoolVar(&applyStrict, "strict", false, "use strict RFC 6902 semantics instead of idempotent ones")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "print the diff instead of writing docker-compose.yml")
+	applyCmd.Flags().BoolVarP(&applyYes, "yes", "y", false, "skip the confirmation prompt")
+	applyCmd.MarkFlagRequired("file")
+	applyCmd.MarkFlagFilename("file", "yaml", "yml", "json")
+	applyCmd.RegisterFlagCompletionFunc("type", completeValues(core.PatchTypeJSON, core.PatchTypeMerge))
+
+	rootCmd.AddCommand(applyCmd)
+}
+
+func runApply(cmd *cobra.Command, args []string) error {
+	// The confirmation prompt reads stdin, which the patch already consumed
+	if applyFile == "-" && !applyYes && !applyDryRun {
+		return usageError(fmt.Errorf("--yes is required when the patch is read from stdin"))
+	}
+
+	patchData, err := readSpecFile(applyFile, cmd.InOrStdin())
+	if err != nil {
+		return err
+	}
+	patch, err := core.ParsePatch(patchData, applyType)
+	if err != nil {
+		return invalidError(err)
+	}
+
+	document, composePath, err := loadComposeDocument()
+	if err != nil {
+		return err
+	}
+	current, err := document.Value()
+	if err != nil {
+		return err
+	}
+
+	patched, err := patch.Apply(current, applyStrict)
+	if err != nil {
+		return fmt.Errorf("failed to apply patch: %w", err)
+	}
+	changed, err := document.SetValue(patched)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		fmt.Fprintln(messageOut, "✅ Patch is already applied. No changes.")
+		return emitResult(cmd, applyResult{Type: patch.Type, Paths: []string{}})
+	}
+
+	data, err := document.Bytes()
+	if err != nil {
+		return err
+	}
+	composeFile, err := core.ParseComposeData(data)
+	if err != nil {
+		return invalidError(fmt.Errorf("patch produced an invalid compose file: %w", err))
+	}
+	warnings, err := composeFile.ValidateWith(settings.Lint.Rules)
+	if err != nil {
+		return fmt.Errorf("patch produced an invalid compose file: %w", err)
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "⚠️  %s\n", warning)
+	}
+
+	patchKind := "JSON Patch"
+	if patch.Type == core.PatchTypeMerge {
+		patchKind = "JSON Merge Patch"
+	}
+	fmt.Fprintf(messageOut, "\n🩹 Applying %s from %s (%d change(s))\n", patchKind, applyFile, len(changed))
+	for _, path := range changed {
+		fmt.Fprintf(messageOut, "  • %s\n", path)
+	}
+
+	result, err := writeComposeData(data, composePath, writeOptions{dryRun: applyDryRun, confirm: !applyYes})
+	if err != nil {
+		return err
+	}
+	if result.Written {
+		fmt.Fprintf(messageOut, "✅ Patch applied to %s\n", composePath)
+	}
+	return emitResult(cmd, applyResult{Type: patch.Type, Paths: changed, Warnings: warnings, writeResult: result})
+}
+
+// applyResult is the structured result of the apply command
+type applyResult struct {
+	Type     string   `json:"type"`
+	Paths    []string `json:"paths"`
+	Warnings []string `json:"warnings,omitempty"`
+	writeResult
+}
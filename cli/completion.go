@@ -0,0 +1,291 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/addons"
+	"github.com/firasmosbahi/container-composer/templates"
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Generate a shell completion script",
+	Long: `Generate a completion script for your shell. Besides commands and flags,
+it completes service, network and volume names and profiles from the compose
+file, template names and flag values such as graph formats.
+
+Bash (requires bash-completion):
+  source <(container-composer completion bash)
+  container-composer completion bash > /etc/bash_completion.d/container-composer
+
+Zsh:
+  container-composer completion zsh > "${fpath[1]}/_container-composer"
+
+Fish:
+  container-composer completion fish > ~/.config/fish/completions/container-composer.fish
+
+PowerShell:
+  container-composer completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	shell := args[0]
+	root := cmd.Root()
+
+	var buf bytes.Buffer
+	var err error
+	switch shell {
+	case "bash":
+		err = root.GenBashCompletionV2(&buf, true)
+	case "zsh":
+		err = root.GenZshCompletion(&buf)
+	case "fish":
+		err = root.GenFishCompletion(&buf, true)
+	case "powershell":
+		err = root.GenPowerShellCompletionWithDesc(&buf)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate %s completion: %w", shell, err)
+	}
+
+	if structuredOutput() {
+		return emitResult(cmd, completionResult{Shell: shell, Script: buf.String()})
+	}
+	_, err = resultOut.Write(buf.Bytes())
+	return err
+}
+
+// completionResult is the structured result of the completion command
+type completionResult struct {
+	Shell  string `json:"shell"`
+	Script string `json:"script"`
+}
+
+// completeComposeArgs completes the first n positional arguments with the
+// names of one kind of compose resource: service, network or volume
+func completeComposeArgs(kind string, n int) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if len(args) >= n {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return composeNameCompletions(kind, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeComposeFlag completes a flag value with the names of one kind of
+// compose resource. Values already given to a repeatable flag are skipped.
+func completeComposeFlag(kind, flagName string) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		var given []string
+		if values, err := cmd.Flags().GetStringSlice(flagName); err == nil {
+			given = values
+		}
+		return composeNameCompletions(kind, given, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// composeNameCompletions lists the names of one kind of resource in the
+// compose file, with a short description, excluding names already used.
+// Completion must never fail loudly, so a missing or broken compose file
+// just yields no candidates.
+func composeNameCompletions(kind string, exclude []string, toComplete string) []cobra.Completion {
+	composeFile, _, err := loadComposeFile()
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil
+	}
+
+	descriptions := make(map[string]string)
+	switch kind {
+	case "service":
+		for name, service := range composeFile.Services {
+			switch {
+			case service.Image != "":
+				descriptions[name] = service.Image
+			case service.Build != nil:
+				descriptions[name] = "build " + service.Build.Context
+			default:
+				descriptions[name] = ""
+			}
+		}
+	case "network":
+		for name, network := range composeFile.Networks {
+			descriptions[name] = resourceDescription(network.Driver, network.External)
+		}
+	case "volume":
+		for name, volume := range composeFile.Volumes {
+			descriptions[name] = resourceDescription(volume.Driver, volume.External)
+		}
+	}
+
+	used := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		used[name] = true
+	}
+
+	names := make([]string, 0, len(descriptions))
+	for name := range descriptions {
+		if !used[name] && strings.HasPrefix(name, toComplete) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	completions := make([]cobra.Completion, 0, len(names))
+	for _, name := range names {
+		completions = append(completions, cobra.CompletionWithDesc(name, descriptions[name]))
+	}
+	return completions
+}
+
+// completeProfiles completes the compose profiles used by services
+func completeProfiles(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	composeFile, _, err := loadComposeFile()
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, service := range composeFile.Services {
+		for _, profile := range service.Profiles {
+			if strings.HasPrefix(profile, toComplete) {
+				names = appendUnique(names, profile)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// resourceDescription describes a network or volume for completion
+func resourceDescription(driver string, external bool) string {
+	if external {
+		return "external"
+	}
+	if driver != "" {
+		return driver + " driver"
+	}
+	return ""
+}
+
+// completeTemplateNames completes project template names
+func completeTemplateNames(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	var completions []cobra.Completion
+	for _, tmpl := range templates.GetAvailableTemplates() {
+		if strings.HasPrefix(tmpl.Name, toComplete) {
+			completions = append(completions, cobra.CompletionWithDesc(tmpl.Name, tmpl.Description))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeRegistryNamespaces completes the namespaces of added template
+// registries
+func completeRegistryNamespaces(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	registries, err := templates.Registries()
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var completions []cobra.Completion
+	for _, registry := range registries {
+		if strings.HasPrefix(registry.Namespace, toComplete) {
+			completions = append(completions, cobra.CompletionWithDesc(registry.Namespace, registry.Source))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeEnvNames completes the variables the compose file uses and the env
+// file defines
+func completeEnvNames(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	project, err := loadEnvProject()
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := project.env.Keys()
+	for _, ref := range project.refs {
+		if _, ok := project.env.Lookup(ref.Name); !ok {
+			names = append(names, ref.Name)
+		}
+	}
+
+	var completions []cobra.Completion
+	for _, name := range names {
+		if !strings.HasPrefix(name, toComplete) {
+			continue
+		}
+		if cmd.Name() == "set" {
+			// Suggest name= so the value can be typed right away
+			completions = append(completions, name+"=")
+		} else {
+			completions = append(completions, cobra.CompletionWithDesc(name, strings.Join(project.services(name), ", ")))
+		}
+	}
+	directive := cobra.ShellCompDirectiveNoFileComp
+	if cmd.Name() == "set" {
+		directive |= cobra.ShellCompDirectiveNoSpace
+	}
+	return completions, directive
+}
+
+// completeTemplateVariables completes --set with the variables of the
+// template given with --template, as name=
+func completeTemplateVariables(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	name, _ := cmd.Flags().GetString("template")
+	if name == "" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	tmpl, err := templates.GetTemplate(name)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []cobra.Completion
+	for _, v := range tmpl.Variables {
+		if strings.HasPrefix(v.Name+"=", toComplete) {
+			completions = append(completions, cobra.CompletionWithDesc(v.Name+"=", v.Description))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeAddonNames completes stack add-on names
+func completeAddonNames(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	list, err := addons.List()
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var completions []cobra.Completion
+	for _, addon := range list {
+		if strings.HasPrefix(addon.Name, toComplete) {
+			completions = append(completions, cobra.CompletionWithDesc(addon.Name, addon.Description))
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeValues completes a flag with a fixed list of values
+func completeValues(values ...string) cobra.CompletionFunc {
+	return cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp)
+}
@@ -0,0 +1,246 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/firasmosbahi/container-composer/internal/logging"
+	"github.com/firasmosbahi/container-composer/tui"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultComposePath is the compose file commands operate on when no compose
+// files are configured
+const defaultComposePath = "docker-compose.yml"
+
+// findComposeFile returns the compose file commands operate on: the first
+// configured compose file that exists, or docker-compose.yml in the current
+// directory
+func findComposeFile() (string, error) {
+	if len(settings.ComposeFiles) == 0 {
+		if _, err := os.Stat(defaultComposePath); os.IsNotExist(err) {
+			return "", fmt.Errorf("docker-compose.yml %w in current directory", core.ErrNotFound)
+		}
+		return defaultComposePath, nil
+	}
+
+	for _, path := range settings.ComposeFiles {
+		if _, err := os.Stat(path); err == nil {
+			// Keep messages and backups short when the file is nearby
+			if wd, err := os.Getwd(); err == nil {
+				if rel, err := filepath.Rel(wd, path); err == nil {
+					return rel, nil
+				}
+			}
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("compose file %w (tried %s)", core.ErrNotFound, strings.Join(settings.ComposeFiles, ", "))
+}
+
+// loadComposeFile parses the configured compose file
+func loadComposeFile() (*core.ComposeFile, string, error) {
+	composePath, err := findComposeFile()
+	if err != nil {
+		return nil, "", err
+	}
+
+	logging.Logger().Info("using compose file", "path", composePath)
+	composeFile, err := core.ParseComposeFile(composePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", composePath, err)
+	}
+
+	return composeFile, composePath, nil
+}
+
+// loadDependencyGraph parses the compose file and builds the dependency graph
+// of the services enabled by the active profiles
+func loadDependencyGraph() (*core.ComposeFile, *core.DependencyGraph, error) {
+	composeFile, _, err := loadComposeFile()
+	if err != nil {
+		return nil, nil, err
+	}
+	composeFile = composeFile.WithProfiles(profiles)
+
+	graph, err := composeFile.BuildDependencyGraph()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build dependency graph: %w", err)
+	}
+
+	return composeFile, graph, nil
+}
+
+// loadComposeDocument reads the configured compose file as a node tree for
+// formatting-preserving edits
+func loadComposeDocument() (*core.ComposeDocument, string, error) {
+	composePath, err := findComposeFile()
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(composePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", composePath, err)
+	}
+
+	logging.Logger().Info("using compose file", "path", composePath)
+	document, err := core.ParseComposeDocument(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", composePath, err)
+	}
+
+	return document, composePath, nil
+}
+
+// writeOptions controls how changes to the compose file are saved
+type writeOptions struct {
+	dryRun  bool // print the diff without writing
+	confirm bool // print the diff and ask before writing
+}
+
+// writeResult describes what the write pipeline did; it is part of the
+// structured output of mutating commands
+type writeResult struct {
+	Changed bool   `json:"changed"`
+	Written bool   `json:"written"`
+	DryRun  bool   `json:"dry_run"`
+	Backup  string `json:"backup,omitempty"`
+	Diff    string `json:"diff,omitempty"`
+}
+
+// changeResult is the structured result of a command that changed a single
+// resource
+type changeResult struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	writeResult
+}
+
+// composeEntries names the services, networks and volumes a command added or
+// replaced in a typed compose file
+type composeEntries struct {
+	Services []string
+	Networks []string
+	Volumes  []string
+
+	// Fragments holds definitions read with --from-file, by section and name.
+	// They are written instead of the typed values, so fields the typed model
+	// does not know are kept.
+	Fragments map[string]map[string]*yaml.Node
+}
+
+// setFragment records the definition to write for an entry of a section
+func (e *composeEntries) setFragment(section, name string, fragment *yaml.Node) {
+	if fragment == nil {
+		return
+	}
+	if e.Fragments == nil {
+		e.Fragments = make(map[string]map[string]*yaml.Node)
+	}
+	if e.Fragments[section] == nil {
+		e.Fragments[section] = make(map[string]*yaml.Node)
+	}
+	e.Fragments[section][name] = fragment
+}
+
+// writeComposeEntries copies the named entries of the typed compose file
+// into the compose file on disk and saves it through writeComposeData. Only
+// those entries are rewritten, so the rest of the file keeps its comments,
+// formatting and the fields the typed model does not know.
+func writeComposeEntries(composeFile *core.ComposeFile, entries composeEntries, composePath string, opts writeOptions) (writeResult, error) {
+	data, err := os.ReadFile(composePath)
+	if err != nil {
+		return writeResult{}, fmt.Errorf("failed to read %s: %w", composePath, err)
+	}
+	document, err := core.ParseComposeDocument(data)
+	if err != nil {
+		return writeResult{}, fmt.Errorf("failed to parse %s: %w", composePath, err)
+	}
+	if data, err = documentWithEntries(document, composeFile, entries); err != nil {
+		return writeResult{}, err
+	}
+	return writeComposeData(data, composePath, opts)
+}
+
+// documentWithEntries sets the named entries of the typed compose file, or
+// their fragments, in the compose document and returns its content
+func documentWithEntries(document *core.ComposeDocument, composeFile *core.ComposeFile, entries composeEntries) ([]byte, error) {
+	sections := []struct {
+		key   string
+		names []string
+		value func(name string) interface{}
+	}{
+		{"services", entries.Services, func(name string) interface{} { return composeFile.Services[name] }},
+		{"networks", entries.Networks, func(name string) interface{} { return composeFile.Networks[name] }},
+		{"volumes", entries.Volumes, func(name string) interface{} { return composeFile.Volumes[name] }},
+	}
+	for _, section := range sections {
+		for _, name := range section.names {
+			// Typed values are encoded in field order, image first
+			var value interface{} = section.value(name)
+			if fragment := entries.Fragments[section.key][name]; fragment != nil {
+				value = fragment
+			}
+			if err := document.SetEntry(section.key, name, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return document.Bytes()
+}
+
+// writeComposeData is the write pipeline shared by every mutating command: it
+// shows a coloured unified diff against the file on disk, asks for
+// confirmation when requested, backs up the current file and replaces it
+// atomically.
+func writeComposeData(data []byte, composePath string, opts writeOptions) (writeResult, error) {
+	result := writeResult{DryRun: opts.dryRun}
+	diff, err := core.FileDiff(composePath, data)
+	if err != nil {
+		return result, err
+	}
+	if diff == "" {
+		fmt.Fprintln(messageOut, "No changes")
+		return result, nil
+	}
+	result.Changed = true
+	result.Diff = diff
+
+	if opts.dryRun || opts.confirm {
+		fmt.Fprintln(messageOut)
+		fmt.Fprint(messageOut, tui.ColorizeDiff(diff))
+		fmt.Fprintln(messageOut)
+	}
+	if opts.dryRun {
+		return result, nil
+	}
+
+	if opts.confirm {
+		var confirmed bool
+		if err := askOne(&survey.Confirm{
+			Message: "Apply these changes?",
+			Default: true,
+		}, &confirmed); err != nil {
+			return result, err
+		}
+		if !confirmed {
+			fmt.Fprintln(messageOut, "❌ Cancelled. No changes were made.")
+			return result, nil
+		}
+	}
+
+	backup, err := core.SaveFile(composePath, data)
+	if err != nil {
+		return result, fmt.Errorf("failed to write %s: %w", composePath, err)
+	}
+	result.Written = true
+	result.Backup = backup
+	if backup != "" {
+		fmt.Fprintf(messageOut, "💾 Backup saved to %s (run 'container-composer undo' to restore)\n", backup)
+	}
+	return result, nil
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"github.com/firasmosbahi/container-composer/internal/config"
+	"github.com/firasmosbahi/container-composer/templates"
+	"github.com/spf13/cobra"
+)
+
+// settings holds the configuration loaded before every command. Flags that
+// were set on the command line take precedence over it.
+var settings = &config.Config{}
+
+// loadSettings loads the user and project config files and the CC_*
+// environment variables, and applies them to the global flags that were not
+// set on the command line
+func loadSettings(cmd *cobra.Command) error {
+	loaded, err := config.Load(".")
+	if err != nil {
+		return invalidError(err)
+	}
+	settings = loaded
+	templates.SetSearchPaths(settings.TemplatePaths)
+
+	if !cmd.Flags().Changed("profile") {
+		profiles = settings.Profiles
+	}
+	return nil
+}
+
+// flagOrConfig returns the value of a bool flag if it was set on the command
+// line, the configured value if there is one, and the flag default otherwise
+func flagOrConfig(cmd *cobra.Command, name string, value bool, configured *bool) bool {
+	if cmd.Flags().Changed(name) {
+		return value
+	}
+	return config.BoolOr(configured, value)
+}
+
+// defaultRestartPolicy is the restart policy preselected by the add wizards
+func defaultRestartPolicy() string {
+	if settings.Add.Restart != "" {
+		return settings.Add.Restart
+	}
+	return "unless-stopped"
+}
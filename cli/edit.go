@@ -0,0 +1,342 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/spf13/cobra"
+)
+
+// restartNotSet is shown in the restart policy prompt when none is configured
+const restartNotSet = "(not set)"
+
+var editCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit an existing resource in docker-compose.yml",
+	Long: `Interactive wizard to edit existing resources in your docker-compose.yml file.
+
+The wizard is pre-filled with the current definition. Only the fields you
+change are written back; the rest of the file, including comments and
+formatting, is left untouched.`,
+}
+
+var editServiceCmd = &cobra.Command{
+	Use:   "service <name>",
+	Short: "Edit an existing service",
+	Long: `Edit an existing service with the add service wizard, pre-filled with its
+current values. A diff of the changes is shown before anything is written.
+
+Examples:
+  container-composer edit service api`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeComposeArgs("service", 1),
+	RunE:              runEditService,
+}
+
+func init() {
+	editCmd.AddCommand(editServiceCmd)
+	rootCmd.AddCommand(editCmd)
+}
+
+func runEditService(cmd *cobra.Command, args []string) error {
+	if err := requireTextOutput(cmd); err != nil {
+		return err
+	}
+
+	composeFile, composePath, err := loadComposeFile()
+	if err != nil {
+		return err
+	}
+	return editService(composeFile, composePath, args[0])
+}
+
+func editService(composeFile *core.ComposeFile, composePath, serviceName string) error {
+	original, exists := composeFile.Services[serviceName]
+	if !exists {
+		return fmt.Errorf("service '%s' %w", serviceName, core.ErrNotFound)
+	}
+	original.Name = serviceName
+
+	fmt.Fprintf(messageOut, "\n✏️  Edit Service '%s'\n\n", serviceName)
+
+	service := original.Clone()
+
+	// Step 1: Image or Build?
+	var useImage bool
+	if err := askOne(&survey.Confirm{
+		Message: "Use a pre-built image? (No = build from Dockerfile)",
+		Default: original.Build == nil,
+	}, &useImage); err != nil {
+		return err
+	}
+
+	if useImage {
+		var image string
+		if err := askOne(&survey.Input{
+			Message: "Docker image:",
+			Default: original.Image,
+			Help:    "e.g., nginx:latest, postgres:15, node:20-alpine",
+		}, &image, survey.WithValidator(survey.Required)); err != nil {
+			return err
+		}
+		service.Image = strings.TrimSpace(image)
+		service.Build = nil
+	} else {
+		if service.Build == nil {
+			service.Build = &core.BuildConfig{Context: ".", Dockerfile: "Dockerfile"}
+		}
+
+		var buildContext string
+		if err := askOne(&survey.Input{
+			Message: "Build context path:",
+			Default: service.Build.Context,
+			Help:    "Path to directory containing Dockerfile",
+		}, &buildContext); err != nil {
+			return err
+		}
+		service.Build.Context = strings.TrimSpace(buildContext)
+
+		var dockerfile string
+		if err := askOne(&survey.Input{
+			Message: "Dockerfile name:",
+			Default: service.Build.Dockerfile,
+		}, &dockerfile); err != nil {
+			return err
+		}
+		service.Build.Dockerfile = strings.TrimSpace(dockerfile)
+		if original.Build == nil {
+			service.Image = ""
+		}
+	}
+
+	// Steps 2-5: Ports, environment, volumes and networks
+	var err error
+	if service.Ports, err = editList("ports", service.Ports, askForPorts); err != nil {
+		return err
+	}
+	if service.Environment, err = editEnvironment(service.Environment); err != nil {
+		return err
+	}
+	if service.Volumes, err = editList("volume mounts", service.Volumes, askForVolumeMounts); err != nil {
+		return err
+	}
+	if service.Networks, err = editList("networks", service.Networks, func() []string {
+		return askForNetworks(composeFile)
+	}); err != nil {
+		return err
+	}
+
+	// Step 6: Dependencies
+	var candidates []string
+	for name := range composeFile.Services {
+		if name != serviceName {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	if len(candidates) > 0 {
+		var dependencies []string
+		if err := askOne(&survey.MultiSelect{
+			Message: "Services this service depends on:",
+			Options: candidates,
+			Default: service.DependsOn,
+			Help:    "These services will be started before this one",
+		}, &dependencies); err != nil {
+			return err
+		}
+		service.DependsOn = dependencies
+	}
+
+	// Step 7: Restart Policy
+	policies := []string{"no", "always", "on-failure", "unless-stopped"}
+	defaultPolicy := service.Restart
+	if defaultPolicy == "" {
+		policies = append([]string{restartNotSet}, policies...)
+		defaultPolicy = restartNotSet
+	} else if !slices.Contains(policies, defaultPolicy) {
+		policies = append(policies, defaultPolicy) // e.g. on-failure:3
+	}
+	var restartPolicy string
+	if err := askOne(&survey.Select{
+		Message: "Restart policy:",
+		Options: policies,
+		Default: defaultPolicy,
+		Help:    "Restart policy for the service",
+	}, &restartPolicy); err != nil {
+		return err
+	}
+	if restartPolicy == restartNotSet {
+		restartPolicy = ""
+	}
+	service.Restart = restartPolicy
+
+	// Step 8: Advanced options
+	var configureAdvanced bool
+	if err := askOne(&survey.Confirm{
+		Message: "Edit advanced options? (command, working_dir, user, hostname)",
+		Default: false,
+	}, &configureAdvanced); err != nil {
+		return err
+	}
+	if configureAdvanced {
+		if err := editAdvancedOptions(&service); err != nil {
+			return err
+		}
+	}
+
+	return previewAndConfirmServiceEdit(composePath, original, service)
+}
+
+// editList lets the user keep a subset of the current values and add new ones
+func editList(label string, current []string, ask func() []string) ([]string, error) {
+	var kept []string
+	if len(current) > 0 {
+		if err := askOne(&survey.MultiSelect{
+			Message: fmt.Sprintf("Keep which %s? (deselect to remove)", label),
+			Options: current,
+			Default: current,
+		}, &kept); err != nil {
+			return nil, err
+		}
+	}
+
+	var addMore bool
+	if err := askOne(&survey.Confirm{
+		Message: fmt.Sprintf("Add %s?", label),
+		Default: false,
+	}, &addMore); err != nil {
+		return nil, err
+	}
+	if addMore {
+		kept = append(kept, ask()...)
+	}
+
+	return kept, nil
+}
+
+// editEnvironment lets the user drop, change and add environment variables
+func editEnvironment(current core.Environment) (core.Environment, error) {
+	var keys []string
+	for key := range current {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := core.Environment{}
+	if len(keys) > 0 {
+		options := make([]string, len(keys))
+		for i, key := range keys {
+			options[i] = key + "=" + current[key]
+		}
+		var kept []int
+		if err := askOne(&survey.MultiSelect{
+			Message: "Keep which environment variables? (deselect to remove)",
+			Options: options,
+			Default: options,
+		}, &kept); err != nil {
+			return nil, err
+		}
+		for _, index := range kept {
+			env[keys[index]] = current[keys[index]]
+		}
+	}
+
+	var addMore bool
+	if err := askOne(&survey.Confirm{
+		Message: "Add or change environment variables?",
+		Default: false,
+	}, &addMore); err != nil {
+		return nil, err
+	}
+	if addMore {
+		for key, value := range askForEnvironmentVars() {
+			env[key] = value
+		}
+	}
+
+	if len(env) == 0 {
+		return nil, nil
+	}
+	return env, nil
+}
+
+// editAdvancedOptions asks for the advanced fields with their current values
+// pre-filled. Clearing a value removes the field.
+func editAdvancedOptions(service *core.Service) error {
+	fmt.Fprint(messageOut, "\n⚙️  Advanced Configuration (clear a value to remove it)\n\n")
+
+	// Only string commands can be edited as text
+	if command, ok := service.Command.(string); ok || service.Command == nil {
+		var value string
+		if err := askOne(&survey.Input{
+			Message: "Command:",
+			Default: command,
+			Help:    "Command to run when container starts",
+		}, &value); err != nil {
+			return err
+		}
+		if value = strings.TrimSpace(value); value == "" {
+			service.Command = nil
+		} else {
+			service.Command = value
+		}
+	}
+
+	fields := []struct {
+		message string
+		value   *string
+	}{
+		{"Working directory:", &service.WorkingDir},
+		{"User (uid:gid or username):", &service.User},
+		{"Hostname:", &service.Hostname},
+	}
+	for _, field := range fields {
+		var value string
+		if err := askOne(&survey.Input{
+			Message: field.message,
+			Default: *field.value,
+		}, &value); err != nil {
+			return err
+		}
+		*field.value = strings.TrimSpace(value)
+	}
+
+	return nil
+}
+
+// previewAndConfirmServiceEdit shows a diff of the edited fields and writes
+// only those fields back to the compose file
+func previewAndConfirmServiceEdit(composePath string, original, service core.Service) error {
+	document, _, err := loadComposeDocument()
+	if err != nil {
+		return err
+	}
+
+	changed, err := document.UpdateService(original.Name, original, service)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		fmt.Fprintln(messageOut, "\nNo changes.")
+		return nil
+	}
+
+	data, err := document.Bytes()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(messageOut, "\n📄 Changes to %s (%s):\n", composePath, strings.Join(changed, ", "))
+	result, err := writeComposeData(data, composePath, writeOptions{confirm: true})
+	if err != nil || !result.Written {
+		return err
+	}
+
+	fmt.Fprintln(messageOut, "\n✅ Service updated successfully!")
+	fmt.Fprintf(messageOut, "   Service '%s' has been updated in %s\n", original.Name, composePath)
+	return nil
+}
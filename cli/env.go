@@ -0,0 +1,618 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/firasmosbahi/container-composer/internal/logging"
+	"github.com/firasmosbahi/container-composer/templates"
+	"github.com/firasmosbahi/container-composer/tui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	envExampleForce  bool
+	envExampleDryRun bool
+
+	envFilePath    string
+	envSetDryRun   bool
+	envUnsetDryRun bool
+	envDiffExample string
+)
+
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Manage the environment variables of the project",
+	Long: `Manage the environment variables the compose file interpolates, such as
+${DB_PASSWORD} or ${DB_USER:-app}, and the .env files that define them.
+
+Commands read and write the .env file next to the compose file, or the file
+given with --file. Files are edited in place: comments, blank lines and the
+other variables are kept. The syntax is the one docker compose reads:
+KEY=VALUE lines with an optional export prefix, unquoted, 'single-quoted'
+(literal) or "double-quoted" (with \n escapes) values, and quoted values
+spanning several lines.`,
+}
+
+var envListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the variables of the compose file and .env, with the services using them",
+	Long: `List every variable the compose file interpolates or .env defines, with its
+value in .env, the compose default when it is not defined, and the services
+whose definitions use it.
+
+Examples:
+  container-composer env list
+  container-composer env list --file .env.production`,
+	Args: cobra.NoArgs,
+	RunE: runEnvList,
+}
+
+var envGetCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Print the value of a variable in .env",
+	Long: `Print the value a variable has in .env, as it is after unquoting. The
+command exits with code 3 when the variable is not defined.
+
+Examples:
+  container-composer env get DB_PASSWORD
+  export DB_PASSWORD=$(container-composer env get DB_PASSWORD)`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeEnvNames,
+	RunE:              runEnvGet,
+}
+
+var envSetCmd = &cobra.Command{
+	Use:   "set <name=value>...",
+	Short: "Set variables in .env",
+	Long: `Set variables in .env, creating the file when needed. A variable that is
+already defined is changed in place, keeping its export prefix and inline
+comment; new variables are appended. Values are quoted when needed.
+
+Examples:
+  container-composer env set DB_PASSWORD=s3cret
+  container-composer env set APP_NAME="My shop" DEBUG=false --dry-run`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeEnvNames,
+	RunE:              runEnvSet,
+}
+
+var envUnsetCmd = &cobra.Command{
+	Use:   "unset <name>...",
+	Short: "Remove variables from .env",
+	Long: `Remove every definition of the variables from .env. The command exits with
+code 3 when one of them is not defined.
+
+Examples:
+  container-composer env unset DEBUG
+  container-composer env unset OLD_TOKEN LEGACY_URL --dry-run`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeEnvNames,
+	RunE:              runEnvUnset,
+}
+
+var envDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the variables of .env with .env.example",
+	Long: `Compare the variables defined in .env with those of .env.example: the
+variables missing from .env, with their example value, and the variables that
+.env defines but .env.example does not list.
+
+Examples:
+  container-composer env diff
+  container-composer env diff --file .env.production --example .env.example`,
+	Args: cobra.NoArgs,
+	RunE: runEnvDiff,
+}
+
+var envCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check that every variable the compose file uses is defined",
+	Long: `Check that every variable the compose file interpolates is defined in .env
+or in the shell environment, or has a ${VAR:-default}. Variables defined in
+.env but used nowhere in the compose file are reported as warnings.
+
+The command exits with code 4 when a variable is undefined, so it can run in
+CI before docker compose.
+
+Examples:
+  container-composer env check
+  container-composer env check --file .env.production`,
+	Args: cobra.NoArgs,
+	RunE: runEnvCheck,
+}
+
+var envExampleCmd = &cobra.Command{
+	Use:   "example",
+	Short: "Generate .env.example from the variables the compose file uses",
+	Long: `Generate .env.example next to the compose file from the variables it
+interpolates. Each variable is set to the default of its ${VAR:-default}
+expression, or left empty, and listed under the first service using it, with
+the other services in a comment. Variables used outside services come last.
+
+When the project was created from a template, the variable descriptions of
+the template manifest (its env section) are added as comments.
+
+An existing .env.example that differs is only replaced with --force; the
+previous content is backed up to .container-composer/backups/.
+
+Examples:
+  container-composer env example
+  container-composer env example --dry-run
+  container-composer env example --force`,
+	Args: cobra.NoArgs,
+	RunE: runEnvExample,
+}
+
+func init() {
+	envExampleCmd.Flags().BoolVar(&envExampleForce, "force", false, "replace an existing .env.example")
+	envExampleCmd.Flags().BoolVar(&envExampleDryRun, "dry-run", false, "print the diff without writing .env.example")
+
+	for _, command := range []*cobra.Command{envListCmd, envGetCmd, envSetCmd, envUnsetCmd, envDiffCmd, envCheckCmd} {
+		command.Flags().StringVar(&envFilePath, "file", "", "env file to use (default: .env next to the compose file)")
+		command.MarkFlagFilename("file")
+	}
+	envSetCmd.Flags().BoolVar(&envSetDryRun, "dry-run", false, "print the diff without writing the env file")
+	envUnsetCmd.Flags().BoolVar(&envUnsetDryRun, "dry-run", false, "print the diff without writing the env file")
+	envDiffCmd.Flags().StringVar(&envDiffExample, "example", "", "example file to compare with (default: .env.example next to the compose file)")
+	envDiffCmd.MarkFlagFilename("example")
+
+	envCmd.AddCommand(envListCmd)
+	envCmd.AddCommand(envGetCmd)
+	envCmd.AddCommand(envSetCmd)
+	envCmd.AddCommand(envUnsetCmd)
+	envCmd.AddCommand(envDiffCmd)
+	envCmd.AddCommand(envCheckCmd)
+	envCmd.AddCommand(envExampleCmd)
+	rootCmd.AddCommand(envCmd)
+}
+
+// envExampleResult is the structured result of env example
+type envExampleResult struct {
+	File      string              `json:"file"`
+	Variables []core.EnvReference `json:"variables"`
+	writeResult
+}
+
+func runEnvExample(cmd *cobra.Command, args []string) error {
+	composePath, err := findComposeFile()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(composePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", composePath, err)
+	}
+	refs, err := core.EnvReferences(data)
+	if err != nil {
+		return invalidError(fmt.Errorf("failed to parse %s: %w", composePath, err))
+	}
+	content, err := core.EnvExample(data, templateEnvComments(filepath.Dir(composePath)))
+	if err != nil {
+		return invalidError(fmt.Errorf("failed to parse %s: %w", composePath, err))
+	}
+
+	result := envExampleResult{File: filepath.Join(filepath.Dir(composePath), ".env.example"), Variables: refs}
+	existing, err := os.ReadFile(result.File)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read %s: %w", result.File, err)
+	}
+	if err == nil && string(existing) != string(content) && !envExampleForce && !envExampleDryRun {
+		return fmt.Errorf("%s %w; use --dry-run to see the differences and --force to replace it", result.File, core.ErrAlreadyExists)
+	}
+
+	result.writeResult, err = writeEnvData(content, result.File, envExampleDryRun)
+	if err != nil {
+		return err
+	}
+	if result.Written {
+		fmt.Fprintf(messageOut, "✅ %s lists %d variable(s) used in %s\n", result.File, len(refs), composePath)
+	}
+	return emitResult(cmd, result)
+}
+
+// templateEnvComments returns the variable descriptions of the template the
+// project in dir was created from, or nil when there is none
+func templateEnvComments(dir string) map[string]string {
+	lock, err := templates.ReadLock(dir)
+	if err != nil {
+		logging.Logger().Debug("no template lock", "error", err)
+		return nil
+	}
+	tmpl, err := templates.GetTemplate(lock.Template)
+	if err != nil {
+		logging.Logger().Debug("template of the project is not available", "template", lock.Template, "error", err)
+		return nil
+	}
+	return tmpl.Env
+}
+
+// writeEnvData saves a .env-style file: it prints the diff against the file
+// on disk for a dry run, otherwise it backs up the current file and replaces
+// it atomically
+func writeEnvData(data []byte, path string, dryRun bool) (writeResult, error) {
+	result := writeResult{DryRun: dryRun}
+	diff, err := core.FileDiff(path, data)
+	if err != nil {
+		return result, err
+	}
+	if diff == "" {
+		fmt.Fprintln(messageOut, "No changes")
+		return result, nil
+	}
+	result.Changed = true
+	result.Diff = diff
+
+	if dryRun {
+		fmt.Fprintln(messageOut)
+		fmt.Fprint(messageOut, tui.ColorizeDiff(diff))
+		fmt.Fprintln(messageOut)
+		return result, nil
+	}
+
+	backup, err := core.SaveFile(path, data)
+	if err != nil {
+		return result, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	result.Written = true
+	result.Backup = backup
+	if backup != "" {
+		fmt.Fprintf(messageOut, "💾 Backup saved to %s\n", backup)
+	}
+	return result, nil
+}
+
+// envProject is the compose file and the env file the env commands work on
+type envProject struct {
+	composePath string
+	envPath     string
+	refs        []core.EnvReference
+	env         *core.EnvFile
+	envExists   bool
+}
+
+// loadEnvProject reads the variables the compose file uses and the env file
+// given with --file, or .env next to the compose file. A missing env file is
+// empty.
+func loadEnvProject() (*envProject, error) {
+	composePath, err := findComposeFile()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(composePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", composePath, err)
+	}
+	project := &envProject{composePath: composePath, envPath: envFilePath}
+	if project.refs, err = core.EnvReferences(data); err != nil {
+		return nil, invalidError(fmt.Errorf("failed to parse %s: %w", composePath, err))
+	}
+	if project.envPath == "" {
+		project.envPath = filepath.Join(filepath.Dir(composePath), ".env")
+	}
+
+	project.env, err = core.ReadEnvFile(project.envPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		project.env = &core.EnvFile{}
+	case err != nil:
+		return nil, invalidError(err)
+	default:
+		project.envExists = true
+	}
+	return project, nil
+}
+
+// reference returns how the compose file uses a variable, or nil
+func (p *envProject) reference(name string) *core.EnvReference {
+	for i := range p.refs {
+		if p.refs[i].Name == name {
+			return &p.refs[i]
+		}
+	}
+	return nil
+}
+
+// services returns the services using a variable
+func (p *envProject) services(name string) []string {
+	if ref := p.reference(name); ref != nil {
+		return ref.Services
+	}
+	return []string{}
+}
+
+// envVariable is a variable in the structured output of the env commands
+type envVariable struct {
+	Name       string   `json:"name"`
+	Value      string   `json:"value,omitempty"`
+	Defined    bool     `json:"defined"`
+	Default    string   `json:"default,omitempty"`
+	HasDefault bool     `json:"has_default"`
+	Required   bool     `json:"required"`
+	Used       bool     `json:"used"`
+	Services   []string `json:"services"`
+}
+
+// envListResult is the structured result of env list
+type envListResult struct {
+	File      string        `json:"file"`
+	Variables []envVariable `json:"variables"`
+}
+
+func runEnvList(cmd *cobra.Command, args []string) error {
+	project, err := loadEnvProject()
+	if err != nil {
+		return err
+	}
+
+	result := envListResult{File: project.envPath, Variables: []envVariable{}}
+	names := project.env.Keys()
+	for _, ref := range project.refs {
+		if _, ok := project.env.Lookup(ref.Name); !ok {
+			names = append(names, ref.Name)
+		}
+	}
+	for _, name := range names {
+		variable := envVariable{Name: name, Services: project.services(name)}
+		variable.Value, variable.Defined = project.env.Lookup(name)
+		if ref := project.reference(name); ref != nil {
+			variable.Used = true
+			variable.Default, variable.HasDefault, variable.Required = ref.Default, ref.HasDefault, ref.Required
+		}
+		result.Variables = append(result.Variables, variable)
+	}
+	if structuredOutput() {
+		return emitResult(cmd, result)
+	}
+
+	fmt.Fprintf(messageOut, "\n📋 Variables of %s and %s\n\n", project.composePath, project.envPath)
+	if len(result.Variables) == 0 {
+		fmt.Fprintln(messageOut, "No variables are used or defined.")
+		return nil
+	}
+	fmt.Fprintf(messageOut, "  %-24s %-28s %s\n", "NAME", "VALUE", "SERVICES")
+	for _, variable := range result.Variables {
+		value := core.FormatEnvValue(variable.Value)
+		switch {
+		case variable.Defined:
+		case variable.HasDefault:
+			value = "(default: " + core.FormatEnvValue(variable.Default) + ")"
+		default:
+			value = "(undefined)"
+		}
+		services := strings.Join(variable.Services, ", ")
+		switch {
+		case !variable.Used:
+			services = "(unused)"
+		case services == "":
+			services = "(outside services)"
+		}
+		fmt.Fprintf(messageOut, "  %-24s %-28s %s\n", variable.Name, value, services)
+	}
+	if !project.envExists {
+		fmt.Fprintf(messageOut, "\n%s does not exist; create it with 'container-composer env set' or from .env.example.\n", project.envPath)
+	}
+	return nil
+}
+
+// envGetResult is the structured result of env get
+type envGetResult struct {
+	Name     string   `json:"name"`
+	Value    string   `json:"value"`
+	File     string   `json:"file"`
+	Services []string `json:"services"`
+}
+
+func runEnvGet(cmd *cobra.Command, args []string) error {
+	project, err := loadEnvProject()
+	if err != nil {
+		return err
+	}
+	value, ok := project.env.Lookup(args[0])
+	if !ok {
+		return fmt.Errorf("variable '%s' %w in %s", args[0], core.ErrNotFound, project.envPath)
+	}
+	if structuredOutput() {
+		return emitResult(cmd, envGetResult{Name: args[0], Value: value, File: project.envPath, Services: project.services(args[0])})
+	}
+	fmt.Fprintln(messageOut, value)
+	return nil
+}
+
+// envSetResult is the structured result of env set and env unset
+type envSetResult struct {
+	File    string   `json:"file"`
+	Added   []string `json:"added,omitempty"`
+	Updated []string `json:"updated,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+	Unused  []string `json:"unused,omitempty"` // variables the compose file does not use
+	writeResult
+}
+
+func runEnvSet(cmd *cobra.Command, args []string) error {
+	project, err := loadEnvProject()
+	if err != nil {
+		return err
+	}
+
+	result := envSetResult{File: project.envPath}
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok || name == "" {
+			return usageError(fmt.Errorf("invalid argument %q (expected NAME=VALUE)", arg))
+		}
+		existed, err := project.env.Set(name, value)
+		if err != nil {
+			return usageError(err)
+		}
+		if existed {
+			result.Updated = append(result.Updated, name)
+			fmt.Fprintf(messageOut, "✏️  %s updated\n", name)
+		} else {
+			result.Added = append(result.Added, name)
+			fmt.Fprintf(messageOut, "➕ %s added\n", name)
+		}
+		if project.reference(name) == nil {
+			result.Unused = append(result.Unused, name)
+			fmt.Fprintf(messageOut, "⚠️  %s is not used by %s\n", name, project.composePath)
+		}
+	}
+
+	result.writeResult, err = writeEnvData(project.env.Bytes(), project.envPath, envSetDryRun)
+	if err != nil {
+		return err
+	}
+	if result.Written {
+		fmt.Fprintf(messageOut, "✅ %s has been updated\n", project.envPath)
+	}
+	return emitResult(cmd, result)
+}
+
+func runEnvUnset(cmd *cobra.Command, args []string) error {
+	project, err := loadEnvProject()
+	if err != nil {
+		return err
+	}
+
+	result := envSetResult{File: project.envPath, Removed: []string{}}
+	for _, name := range args {
+		if !project.env.Unset(name) {
+			return fmt.Errorf("variable '%s' %w in %s", name, core.ErrNotFound, project.envPath)
+		}
+		result.Removed = append(result.Removed, name)
+		fmt.Fprintf(messageOut, "➖ %s removed\n", name)
+		if ref := project.reference(name); ref != nil && !ref.HasDefault {
+			fmt.Fprintf(messageOut, "⚠️  %s is still used by %s, without a default\n", name, project.composePath)
+		}
+	}
+
+	result.writeResult, err = writeEnvData(project.env.Bytes(), project.envPath, envUnsetDryRun)
+	if err != nil {
+		return err
+	}
+	if result.Written {
+		fmt.Fprintf(messageOut, "✅ %s has been updated\n", project.envPath)
+	}
+	return emitResult(cmd, result)
+}
+
+// envMissing is a variable of .env.example that .env does not define
+type envMissing struct {
+	Name    string `json:"name"`
+	Example string `json:"example"`
+}
+
+// envDiffResult is the structured result of env diff
+type envDiffResult struct {
+	File    string       `json:"file"`
+	Example string       `json:"example"`
+	Missing []envMissing `json:"missing"`
+	Extra   []string     `json:"extra"`
+}
+
+func runEnvDiff(cmd *cobra.Command, args []string) error {
+	project, err := loadEnvProject()
+	if err != nil {
+		return err
+	}
+	examplePath := envDiffExample
+	if examplePath == "" {
+		examplePath = filepath.Join(filepath.Dir(project.composePath), ".env.example")
+	}
+	example, err := core.ReadEnvFile(examplePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("%s %w; generate it with 'container-composer env example'", examplePath, core.ErrNotFound)
+	case err != nil:
+		return invalidError(err)
+	}
+
+	result := envDiffResult{File: project.envPath, Example: examplePath, Missing: []envMissing{}, Extra: []string{}}
+	for _, name := range example.Keys() {
+		if _, ok := project.env.Lookup(name); !ok {
+			value, _ := example.Lookup(name)
+			result.Missing = append(result.Missing, envMissing{Name: name, Example: value})
+		}
+	}
+	for _, name := range project.env.Keys() {
+		if _, ok := example.Lookup(name); !ok {
+			result.Extra = append(result.Extra, name)
+		}
+	}
+
+	fmt.Fprintf(messageOut, "\n🔍 %s compared with %s\n\n", project.envPath, examplePath)
+	if len(result.Missing) > 0 {
+		fmt.Fprintf(messageOut, "❌ Missing from %s (%d):\n", project.envPath, len(result.Missing))
+		for _, missing := range result.Missing {
+			fmt.Fprintf(messageOut, "   %-24s example: %s\n", missing.Name, core.FormatEnvValue(missing.Example))
+		}
+	}
+	if len(result.Extra) > 0 {
+		fmt.Fprintf(messageOut, "➕ Not in %s (%d):\n", examplePath, len(result.Extra))
+		for _, name := range result.Extra {
+			fmt.Fprintf(messageOut, "   %s\n", name)
+		}
+	}
+	if len(result.Missing) == 0 && len(result.Extra) == 0 {
+		fmt.Fprintf(messageOut, "✅ %s defines exactly the variables of %s\n", project.envPath, examplePath)
+	}
+	return emitResult(cmd, result)
+}
+
+// envCheckResult is the structured result of env check
+type envCheckResult struct {
+	File      string        `json:"file"`
+	Undefined []envVariable `json:"undefined"`
+	Unused    []string      `json:"unused"`
+	FromShell []string      `json:"from_shell"` // variables only the shell environment defines
+}
+
+func runEnvCheck(cmd *cobra.Command, args []string) error {
+	project, err := loadEnvProject()
+	if err != nil {
+		return err
+	}
+
+	result := envCheckResult{File: project.envPath, Undefined: []envVariable{}, Unused: []string{}, FromShell: []string{}}
+	fmt.Fprintf(messageOut, "\n🔍 Checking the variables of %s against %s\n\n", project.composePath, project.envPath)
+	for _, ref := range project.refs {
+		if _, ok := project.env.Lookup(ref.Name); ok {
+			continue
+		}
+		if _, ok := os.LookupEnv(ref.Name); ok {
+			result.FromShell = append(result.FromShell, ref.Name)
+			fmt.Fprintf(messageOut, "ℹ️  %s is only defined in the shell environment\n", ref.Name)
+			continue
+		}
+		if ref.HasDefault {
+			continue
+		}
+		result.Undefined = append(result.Undefined, envVariable{Name: ref.Name, Required: ref.Required, Used: true, Services: ref.Services})
+		message := fmt.Sprintf("❌ %s is not defined", ref.Name)
+		if len(ref.Services) > 0 {
+			message += " (used by " + strings.Join(ref.Services, ", ") + ")"
+		}
+		if ref.Required {
+			message += "; docker compose refuses to start without it"
+		}
+		fmt.Fprintln(messageOut, message)
+	}
+	for _, name := range project.env.Keys() {
+		if project.reference(name) == nil {
+			result.Unused = append(result.Unused, name)
+			fmt.Fprintf(messageOut, "⚠️  %s is defined but not used by %s\n", name, project.composePath)
+		}
+	}
+
+	if len(result.Undefined) > 0 {
+		commandErr := invalidError(fmt.Errorf("%d variable(s) used by %s are not defined; set them with 'container-composer env set'", len(result.Undefined), project.composePath))
+		commandErr.Details = result
+		return commandErr
+	}
+	fmt.Fprintf(messageOut, "✅ All %d variable(s) used by %s are defined or have a default\n", len(project.refs), project.composePath)
+	return emitResult(cmd, result)
+}
@@ -3,6 +3,7 @@ package cli
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/firasmosbahi/container-composer/core"
@@ -11,16 +12,23 @@ import (
 
 var (
 	graphFormat           string
-	graphService          string
-	graphOutput           string
+	graphServices         []string
+	graphDepth            int
+	graphDirection        string
+	graphExclude          []string
+	graphOnlyTypes        []string
+	graphOutputFile       string
 	graphShowNetworks     bool
 	graphShowVolumes      bool
 	graphShowHealthChecks bool
 	graphHighlightCycles  bool
+	graphFixCycles        bool
+	graphPath             bool
+	graphShortest         bool
 )
 
 var graphCmd = &cobra.Command{
-	Use:   "graph",
+	Use:   "graph [--path <from> <to>]",
 	Short: "Visualize service dependencies and relationships",
 	Long: `Generate dependency graph visualizations showing:
   - Service dependencies (depends_on)
@@ -36,18 +44,48 @@ Examples:
   container-composer graph                           # Show ASCII graph
   container-composer graph --format=dot              # Output DOT format
   container-composer graph --service=api             # Filter by service
-  container-composer graph -o graph.dot              # Save to file
+  container-composer graph -s api -s worker --depth 1 # Multiple roots, one hop
+  container-composer graph -s db --direction down     # Services that need db
+  container-composer graph --exclude 'monitoring-*'   # Hide services by glob
+  container-composer graph --only-type depends_on     # Hide network/volume edges
+  container-composer graph --output-file graph.dot  # Save to file (-o graph.dot is deprecated)
+  container-composer graph --output json            # Nodes, edges and cycles as JSON
+  container-composer graph --fix-cycles              # Suggest depends_on edges to remove
+  container-composer graph --path gateway worker      # Every path between two services
+  container-composer graph --path gateway worker --shortest
+  container-composer graph diff git:main:./docker-compose.yml docker-compose.yml
   container-composer graph --format=dot | dot -Tpng > graph.png`,
-	RunE: runGraph,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if graphPath {
+			return cobra.ExactArgs(2)(cmd, args)
+		}
+		return cobra.NoArgs(cmd, args)
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if !graphPath {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeComposeArgs("service", 2)(cmd, args, toComplete)
+	},
+	RunE:        runGraph,
+	Annotations: map[string]string{outputFileAnnotation: "output-file"},
 }
 
 func init() {
 	graphCmd.Flags().StringVarP(&graphFormat, "format", "f", "ascii",
 		"output format: ascii or dot")
-	graphCmd.Flags().StringVarP(&graphService, "service", "s", "",
-		"filter graph to show only this service and its dependencies")
-	graphCmd.Flags().StringVarP(&graphOutput, "output", "o", "",
-		"output file (default: stdout)")
+	graphCmd.Flags().StringSliceVarP(&graphServices, "service", "s", nil,
+		"filter graph to these services and their relationships (repeatable)")
+	graphCmd.Flags().IntVar(&graphDepth, "depth", -1,
+		"maximum number of hops from --service (-1 = unlimited)")
+	graphCmd.Flags().StringVar(&graphDirection, "direction", "both",
+		"edges to follow from --service: up (dependencies), down (dependents) or both")
+	graphCmd.Flags().StringSliceVar(&graphExclude, "exclude", nil,
+		"hide services matching these glob patterns (repeatable)")
+	graphCmd.Flags().StringSliceVar(&graphOnlyTypes, "only-type", nil,
+		"show only these relationship types: depends_on, network, volume (repeatable)")
+	graphCmd.Flags().StringVar(&graphOutputFile, "output-file", "",
+		"write the graph to a file instead of stdout")
 	graphCmd.Flags().BoolVar(&graphShowNetworks, "networks", true,
 		"show network relationships")
 	graphCmd.Flags().BoolVar(&graphShowVolumes, "volumes", true,
@@ -56,51 +94,255 @@ func init() {
 		"show health check indicators")
 	graphCmd.Flags().BoolVar(&graphHighlightCycles, "highlight-cycles", true,
 		"highlight circular dependencies (dot format only)")
+	graphCmd.Flags().BoolVar(&graphFixCycles, "fix-cycles", false,
+		"print a YAML patch removing the fewest depends_on edges that break all cycles")
+	graphCmd.Flags().BoolVar(&graphPath, "path", false,
+		"show dependency paths between the two services given as arguments")
+	graphCmd.Flags().BoolVar(&graphShortest, "shortest", false,
+		"with --path, show only the shortest path")
+
+	graphCmd.RegisterFlagCompletionFunc("format", completeValues("ascii", "dot"))
+	graphCmd.RegisterFlagCompletionFunc("service", completeComposeFlag("service", "service"))
+	graphCmd.RegisterFlagCompletionFunc("exclude", completeComposeFlag("service", "exclude"))
+	graphCmd.RegisterFlagCompletionFunc("direction", completeValues("up", "down", "both"))
+	graphCmd.RegisterFlagCompletionFunc("only-type", completeValues("depends_on", "network", "volume"))
 
 	rootCmd.AddCommand(graphCmd)
 }
 
 func runGraph(cmd *cobra.Command, args []string) error {
-	// Check if docker-compose.yml exists
-	composePath := "docker-compose.yml"
-	if _, err := os.Stat(composePath); os.IsNotExist(err) {
-		return fmt.Errorf("docker-compose.yml not found in current directory")
+	graphShowNetworks = flagOrConfig(cmd, "networks", graphShowNetworks, settings.Graph.Networks)
+	graphShowVolumes = flagOrConfig(cmd, "volumes", graphShowVolumes, settings.Graph.Volumes)
+	graphShowHealthChecks = flagOrConfig(cmd, "health", graphShowHealthChecks, settings.Graph.Health)
+
+	spec, err := graphFilterSpec(cmd)
+	if err != nil {
+		return err
 	}
 
-	// Parse compose file
-	composeFile, err := core.ParseComposeFile(composePath)
+	_, graph, err := loadDependencyGraph()
 	if err != nil {
-		return fmt.Errorf("failed to parse docker-compose.yml: %w", err)
+		return err
 	}
 
-	// Build dependency graph
-	graph, err := composeFile.BuildDependencyGraph()
+	// Apply filters if specified
+	graph, err = spec.Apply(graph)
 	if err != nil {
-		return fmt.Errorf("failed to build dependency graph: %w", err)
+		return fmt.Errorf("failed to filter graph: %w", err)
 	}
 
-	// Filter by service if specified
-	if graphService != "" {
-		if !composeFile.ServiceExists(graphService) {
-			return fmt.Errorf("service '%s' not found", graphService)
-		}
-		graph, err = graph.FilterByService(graphService, -1) // -1 = unlimited depth
-		if err != nil {
-			return fmt.Errorf("failed to filter graph: %w", err)
-		}
+	if graphPath {
+		return printGraphPaths(cmd, graph, args[0], args[1])
 	}
 
 	// Warn about circular dependencies
 	if graph.HasCircularDependencies() {
 		fmt.Fprintf(os.Stderr, "\n⚠️  WARNING: Circular dependencies detected!\n")
 		fmt.Fprintf(os.Stderr, "Docker Compose will handle these, but they may cause issues.\n\n")
-		for _, cycle := range graph.CircularDeps {
+		for i, cycle := range graph.CircularDeps {
 			fmt.Fprintf(os.Stderr, "  Cycle: %s\n", formatCycle(cycle))
+			if len(graph.CycleGroups[i]) > len(cycle)-1 {
+				fmt.Fprintf(os.Stderr, "         (strongly connected: %s)\n", strings.Join(graph.CycleGroups[i], ", "))
+			}
 		}
 		fmt.Fprintf(os.Stderr, "\n")
 	}
 
 	// Generate output based on format
+	var output string
+	switch {
+	case graphFixCycles:
+		if !graph.HasCircularDependencies() {
+			fmt.Fprintln(messageOut, "✅ No circular dependencies found")
+			return emitResult(cmd, cycleFixResult{Fixes: []core.CycleFix{}})
+		}
+		fixes := graph.SuggestCycleFixes()
+		output, err = graph.FormatCycleFixPatch(fixes)
+		if err != nil {
+			return fmt.Errorf("failed to generate cycle fix: %w", err)
+		}
+		if structuredOutput() {
+			return emitResult(cmd, cycleFixResult{Fixes: fixes, Patch: output})
+		}
+
+	default:
+		output, err = formatGraph(graph)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write output
+	if graphOutputFile != "" {
+		if err := os.WriteFile(graphOutputFile, []byte(output), 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		fmt.Fprintf(messageOut, "Graph saved to %s\n", graphOutputFile)
+	} else if !structuredOutput() {
+		fmt.Fprint(messageOut, output)
+	}
+
+	return emitResult(cmd, newGraphResult(graph, graphOutputFile))
+}
+
+// graphResult is the structured result of the graph command
+type graphResult struct {
+	Services         []graphServiceResult `json:"services"`
+	Relationships    []graphRelationship  `json:"relationships"`
+	Cycles           [][]string           `json:"cycles"`
+	TopologicalOrder []string             `json:"topological_order"`
+	File             string               `json:"file,omitempty"`
+}
+
+// graphServiceResult describes a service node of the graph
+type graphServiceResult struct {
+	Name           string   `json:"name"`
+	Image          string   `json:"image,omitempty"`
+	DependsOn      []string `json:"depends_on"`
+	Networks       []string `json:"networks"`
+	Volumes        []string `json:"volumes"`
+	HasHealthCheck bool     `json:"has_healthcheck"`
+}
+
+// graphRelationship describes an edge of the graph
+type graphRelationship struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Type     string `json:"type"`
+	Metadata string `json:"metadata,omitempty"`
+}
+
+// cycleFixResult is the structured result of graph --fix-cycles
+type cycleFixResult struct {
+	Fixes []core.CycleFix `json:"fixes"`
+	Patch string          `json:"patch,omitempty"`
+}
+
+// newGraphResult converts a dependency graph into its structured result
+func newGraphResult(graph *core.DependencyGraph, file string) graphResult {
+	result := graphResult{
+		Services:         []graphServiceResult{},
+		Relationships:    []graphRelationship{},
+		Cycles:           graph.CircularDeps,
+		TopologicalOrder: graph.TopologicalOrder,
+		File:             file,
+	}
+	if result.Cycles == nil {
+		result.Cycles = [][]string{}
+	}
+
+	names := make([]string, 0, len(graph.Services))
+	for name := range graph.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		node := graph.Services[name]
+		service := graphServiceResult{
+			Name:           name,
+			DependsOn:      []string{},
+			Networks:       append([]string{}, node.Networks...),
+			Volumes:        append([]string{}, node.Volumes...),
+			HasHealthCheck: node.HasHealthCheck,
+		}
+		if node.Service != nil {
+			service.Image = node.Service.Image
+		}
+		for _, dep := range node.DependsOn {
+			service.DependsOn = append(service.DependsOn, dep.Name)
+		}
+		sort.Strings(service.DependsOn)
+		result.Services = append(result.Services, service)
+	}
+
+	for _, relationship := range graph.GetAllRelationships() {
+		result.Relationships = append(result.Relationships, graphRelationship{
+			From:     relationship.From,
+			To:       relationship.To,
+			Type:     relationship.Type.String(),
+			Metadata: relationship.Metadata,
+		})
+	}
+	return result
+}
+
+// printGraphPaths prints the dependency paths between two services
+func printGraphPaths(cmd *cobra.Command, graph *core.DependencyGraph, from, to string) error {
+	report, err := graph.FindPaths(from, to, graphShortest)
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		return emitResult(cmd, report)
+	}
+
+	fmt.Fprintf(messageOut, "\n🧭 Dependency paths between '%s' and '%s'\n\n", from, to)
+
+	if !report.Connected() {
+		fmt.Fprintln(messageOut, "The services are not connected.")
+		return nil
+	}
+
+	if len(report.Paths) > 0 {
+		if report.Reversed {
+			fmt.Fprintf(messageOut, "'%s' does not depend on '%s', but '%s' depends on '%s':\n", from, to, to, from)
+		}
+		for i, path := range report.Paths {
+			fmt.Fprintf(messageOut, "  %d. %s\n", i+1, strings.Join(path, " → "))
+		}
+		if report.Truncated {
+			fmt.Fprintf(messageOut, "  ... more paths omitted (showing the first %d)\n", len(report.Paths))
+		}
+		fmt.Fprintln(messageOut)
+	} else {
+		fmt.Fprintln(messageOut, "No depends_on path; connected only through shared resources.")
+		fmt.Fprintln(messageOut)
+	}
+
+	if len(report.SharedNetworks) > 0 {
+		fmt.Fprintf(messageOut, "🌐 Shared networks: %s\n", strings.Join(report.SharedNetworks, ", "))
+	}
+	if len(report.SharedVolumes) > 0 {
+		fmt.Fprintf(messageOut, "💾 Shared volumes: %s\n", strings.Join(report.SharedVolumes, ", "))
+	}
+
+	return nil
+}
+
+// graphFilterSpec builds the graph filter described by the command flags
+func graphFilterSpec(cmd *cobra.Command) (core.GraphFilterSpec, error) {
+	spec := core.NewGraphFilterSpec()
+	if len(graphServices) == 0 {
+		for _, name := range []string{"depth", "direction"} {
+			if cmd.Flags().Changed(name) {
+				return spec, usageError(fmt.Errorf("--%s requires --service", name))
+			}
+		}
+	}
+
+	spec.Services = graphServices
+	spec.Depth = graphDepth
+	spec.Exclude = graphExclude
+
+	direction, err := core.ParseFilterDirection(graphDirection)
+	if err != nil {
+		return spec, usageError(err)
+	}
+	spec.Direction = direction
+
+	for _, value := range graphOnlyTypes {
+		t, err := core.ParseRelationshipType(value)
+		if err != nil {
+			return spec, usageError(err)
+		}
+		spec.OnlyTypes = append(spec.OnlyTypes, t)
+	}
+
+	return spec, nil
+}
+
+// formatGraph renders the graph in the format selected with --format
+func formatGraph(graph *core.DependencyGraph) (string, error) {
 	var output string
 	switch graphFormat {
 	case "ascii":
@@ -121,20 +363,10 @@ func runGraph(cmd *cobra.Command, args []string) error {
 		output = graph.FormatDOT(options)
 
 	default:
-		return fmt.Errorf("unknown format: %s (supported: ascii, dot)", graphFormat)
-	}
-
-	// Write output
-	if graphOutput != "" {
-		if err := os.WriteFile(graphOutput, []byte(output), 0644); err != nil {
-			return fmt.Errorf("failed to write output file: %w", err)
-		}
-		fmt.Printf("Graph saved to %s\n", graphOutput)
-	} else {
-		fmt.Print(output)
+		return "", fmt.Errorf("unknown format: %s (supported: ascii, dot)", graphFormat)
 	}
 
-	return nil
+	return output, nil
 }
 
 func formatCycle(cycle []string) string {
@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/spf13/cobra"
+)
+
+var graphDiffFormat string
+
+var graphDiffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Show how the dependency topology changed between two compose files",
+	Long: `Compare the dependency graphs of two compose files and report:
+  - Added and removed services
+  - Added and removed depends_on edges
+  - Network memberships and named volume sharing
+  - Changed images
+
+Each side is either a file path or a git revision in the form git:<rev>:<path>,
+read with 'git show <rev>:<path>'. Paths starting with ./ are relative to the
+current directory, others to the repository root.
+
+Supported output formats:
+  - text: Human readable summary
+  - json: Machine readable report
+  - dot: Graphviz graph with additions in green, removals in red and image
+    changes in orange; networks and volumes are drawn as separate nodes
+  - mermaid: Mermaid flowchart, coloured like the dot output
+
+Examples:
+  container-composer graph diff old.yml docker-compose.yml
+  container-composer graph diff git:main:./docker-compose.yml docker-compose.yml
+  container-composer graph diff git:HEAD~1:./docker-compose.yml git:HEAD:./docker-compose.yml --format=mermaid`,
+	Args: cobra.ExactArgs(2),
+	RunE: runGraphDiff,
+}
+
+func init() {
+	graphDiffCmd.Flags().StringVarP(&graphDiffFormat, "format", "f", "text",
+		"output format: text, json, dot or mermaid")
+	graphDiffCmd.RegisterFlagCompletionFunc("format", completeValues("text", "json", "dot", "mermaid"))
+
+	graphCmd.AddCommand(graphDiffCmd)
+}
+
+func runGraphDiff(cmd *cobra.Command, args []string) error {
+	oldGraph, err := loadGraphSource(args[0])
+	if err != nil {
+		return err
+	}
+	newGraph, err := loadGraphSource(args[1])
+	if err != nil {
+		return err
+	}
+
+	diff := core.DiffGraphs(oldGraph, newGraph)
+	if structuredOutput() {
+		return emitResult(cmd, diff)
+	}
+
+	var output string
+	switch graphDiffFormat {
+	case "text":
+		output = diff.FormatText()
+	case "json":
+		output, err = diff.FormatJSON()
+		if err != nil {
+			return err
+		}
+	case "dot":
+		output = diff.FormatDOT()
+	case "mermaid":
+		output = diff.FormatMermaid()
+	default:
+		return fmt.Errorf("unknown format: %s (supported: text, json, dot, mermaid)", graphDiffFormat)
+	}
+
+	fmt.Fprint(messageOut, output)
+	return nil
+}
+
+// loadGraphSource builds a dependency graph from a file path or a
+// git:<rev>:<path> reference
+func loadGraphSource(source string) (*core.DependencyGraph, error) {
+	data, err := readComposeSource(source)
+	if err != nil {
+		return nil, err
+	}
+
+	composeFile, err := core.ParseComposeData(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", source, err)
+	}
+
+	graph, err := composeFile.BuildDependencyGraph()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build dependency graph for %s: %w", source, err)
+	}
+
+	return graph, nil
+}
+
+// readComposeSource reads compose file content from disk or from git
+func readComposeSource(source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "git:") {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", source, err)
+		}
+		return data, nil
+	}
+
+	rev, path, ok := strings.Cut(strings.TrimPrefix(source, "git:"), ":")
+	if !ok || rev == "" || path == "" {
+		return nil, fmt.Errorf("invalid git source %q (expected git:<rev>:<path>)", source)
+	}
+
+	output, err := exec.Command("git", "show", rev+":"+path).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git show %s:%s failed: %s", rev, path, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to run git: %w", err)
+	}
+
+	return output, nil
+}
@@ -0,0 +1,147 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/spf13/cobra"
+)
+
+var impactCmd = &cobra.Command{
+	Use:   "impact <service>",
+	Short: "Show which services break if a service goes down",
+	Long: `Walk the dependency graph downstream from a service and list everything
+that is affected when it goes down.
+
+Impact is reported in two categories:
+  - Hard: services that depend on it (directly or transitively) via depends_on
+  - Soft: services sharing a network or volume with it
+
+Examples:
+  container-composer impact postgres
+  container-composer impact redis`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeComposeArgs("service", 1),
+	RunE:              runImpact,
+}
+
+var requiresCmd = &cobra.Command{
+	Use:   "requires <service>",
+	Short: "Show every service a service needs to run",
+	Long: `Walk the dependency graph upstream from a service and list the full closure
+of services it depends on, directly or transitively, with their hop distance.
+
+Examples:
+  container-composer requires gateway`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeComposeArgs("service", 1),
+	RunE:              runRequires,
+}
+
+func init() {
+	rootCmd.AddCommand(impactCmd)
+	rootCmd.AddCommand(requiresCmd)
+}
+
+func runImpact(cmd *cobra.Command, args []string) error {
+	_, graph, err := loadDependencyGraph()
+	if err != nil {
+		return err
+	}
+
+	report, err := graph.Impact(args[0])
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		return emitResult(cmd, report)
+	}
+
+	fmt.Fprintf(messageOut, "\n💥 Impact of '%s' going down\n\n", report.Service)
+
+	if len(report.Hard) == 0 && len(report.Soft) == 0 {
+		fmt.Fprintln(messageOut, "No other service is affected.")
+		return nil
+	}
+
+	printServiceDistances("Directly affected (depends_on)", report.Direct(), " ← ")
+	printServiceDistances("Transitively affected (depends_on)", report.Transitive(), " ← ")
+
+	if len(report.Soft) > 0 {
+		fmt.Fprintln(messageOut, "Soft impact (shared resources):")
+		for _, impact := range report.Soft {
+			var shared []string
+			for _, network := range impact.Networks {
+				shared = append(shared, "🌐 "+network)
+			}
+			for _, volume := range impact.Volumes {
+				shared = append(shared, "💾 "+volume)
+			}
+			fmt.Fprintf(messageOut, "  • %s (%s)\n", impact.Name, strings.Join(shared, ", "))
+		}
+		fmt.Fprintln(messageOut)
+	}
+
+	return nil
+}
+
+func runRequires(cmd *cobra.Command, args []string) error {
+	_, graph, err := loadDependencyGraph()
+	if err != nil {
+		return err
+	}
+
+	closure, err := graph.Requires(args[0])
+	if err != nil {
+		return err
+	}
+	if structuredOutput() {
+		return emitResult(cmd, requiresResult{Service: args[0], Requires: closure})
+	}
+
+	fmt.Fprintf(messageOut, "\n🔗 Services required by '%s'\n\n", args[0])
+
+	if len(closure) == 0 {
+		fmt.Fprintln(messageOut, "No dependencies.")
+		return nil
+	}
+
+	var direct, transitive []core.ServiceDistance
+	for _, s := range closure {
+		if s.Hops == 1 {
+			direct = append(direct, s)
+		} else {
+			transitive = append(transitive, s)
+		}
+	}
+
+	printServiceDistances("Direct dependencies", direct, " → ")
+	printServiceDistances("Transitive dependencies", transitive, " → ")
+
+	return nil
+}
+
+// requiresResult is the structured result of the requires command
+type requiresResult struct {
+	Service  string                 `json:"service"`
+	Requires []core.ServiceDistance `json:"requires"`
+}
+
+// printServiceDistances prints a titled list of services with their hop
+// distance and the path used to reach them
+func printServiceDistances(title string, services []core.ServiceDistance, separator string) {
+	if len(services) == 0 {
+		return
+	}
+
+	fmt.Fprintf(messageOut, "%s:\n", title)
+	for _, s := range services {
+		hops := "hop"
+		if s.Hops > 1 {
+			hops = "hops"
+		}
+		fmt.Fprintf(messageOut, "  • %s (%d %s: %s)\n", s.Name, s.Hops, hops, strings.Join(s.Path, separator))
+	}
+	fmt.Fprintln(messageOut)
+}
@@ -1,18 +1,28 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path"
+	"strconv"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/terminal"
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/firasmosbahi/container-composer/internal/logging"
 	"github.com/firasmosbahi/container-composer/templates"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var (
 	initTemplate string
 	initNoPrompt bool
+	initForce    bool
+	initSet      []string
+	initValues   string
 )
 
 var initCmd = &cobra.Command{
@@ -21,10 +31,66 @@ var initCmd = &cobra.Command{
 	Long: `Initialize a new Container Composer project with an interactive wizard.
 You can choose from various templates like LAMP, MEAN, microservices, etc.
 
+Besides the built-in templates, every directory with a template.yaml manifest
+in ~/.config/container-composer/templates/ or in a configured template path
+(template_paths in .container-composer.yaml) is available. A manifest names
+the template and lists the files and directories it creates:
+
+  name: go-service
+  category: web
+  description: Go API with PostgreSQL
+  compose: docker-compose.yml   # compose template, rendered with {{.ProjectName}}
+  env:                          # comments for the derived .env.example
+    DB_PASSWORD: Password of the application database user
+  files:
+    - path: Dockerfile          # copied from the template directory
+    - path: config/app.yaml
+      source: files/app.yaml
+  directories: [cmd, internal]
+
+Unless the template ships its own .env.example, it is derived from the
+${VAR} references of the rendered compose file (see 'env example').
+
+The compose template and every file are rendered with Go's text/template and
+Sprig-style helpers (default, quote, upper, lower, title, kebabcase, snakecase,
+camelcase, replace, trim, indent, nindent, join, split, list, dict, ternary,
+required, toYaml, toJson, b64enc, sha256sum, randAlphaNum, add, sub, ...):
+
+  name={{ .ProjectName | kebabcase }}
+  secret={{ randAlphaNum 32 }}
+
+Paths bind-mounted in the compose file (./src:/app) must exist after
+generation: missing directories are created, missing files are an error.
+
+User templates override built-in templates with the same name. Templates of
+registries added with 'template add' are used as <namespace>/<name>.
+
+Templates can declare typed variables, used as {{.name}} in their files:
+
+  variables:
+    - name: app_port
+      type: int                 # string, int, bool, choice or secret
+      description: Host port for the application
+      default: 3000
+      min: 1
+      max: 65535
+    - name: db_password
+      type: secret              # prompted without echo, generated when empty
+    - name: redis_port
+      type: int
+      default: 6379
+      when: use_redis           # only asked when use_redis is true
+
+Variables are prompted for unless --no-prompt is given or stdin is not a
+terminal. Values passed with --set key=value or a --values YAML file are not
+prompted for.
+
 Examples:
   container-composer init                     # Interactive mode
   container-composer init my-project          # Interactive mode with project name
-  container-composer init --template=lamp     # Use LAMP template directly`,
+  container-composer init --template=lamp     # Use LAMP template directly
+  container-composer init shop -t nodejs --set app_port=8080 --set postgres_version=17
+  container-composer init shop -t nodejs --values values.yaml --no-prompt`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runInit,
 }
@@ -32,6 +98,12 @@ Examples:
 func init() {
 	initCmd.Flags().StringVarP(&initTemplate, "template", "t", "", "template to use (skip wizard)")
 	initCmd.Flags().BoolVar(&initNoPrompt, "no-prompt", false, "skip all prompts and use defaults")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "initialize the project directory even when it is not empty")
+	initCmd.Flags().StringArrayVar(&initSet, "set", nil, "set a template variable KEY=VALUE (repeatable)")
+	initCmd.Flags().StringVar(&initValues, "values", "", "read template variables from a YAML file ('-' for stdin)")
+	initCmd.RegisterFlagCompletionFunc("template", completeTemplateNames)
+	initCmd.RegisterFlagCompletionFunc("set", completeTemplateVariables)
+	initCmd.MarkFlagFilename("values", "yaml", "yml")
 	rootCmd.AddCommand(initCmd)
 }
 
@@ -45,8 +117,20 @@ func runInit(cmd *cobra.Command, args []string) error {
 		projectDir = args[0]
 	}
 
-	// If no template specified and not in no-prompt mode, run interactive wizard
-	if initTemplate == "" && !initNoPrompt {
+	// Without a terminal to answer them, prompts would fail on EOF. Only
+	// the wizard and variable prompts are skipped; a non-empty directory
+	// still needs --no-prompt or --force.
+	prompt := !initNoPrompt
+	if prompt && !stdinIsTerminal() {
+		logging.Logger().Info("stdin is not a terminal, skipping prompts")
+		prompt = false
+	}
+
+	// If no template specified and prompts are possible, run interactive wizard
+	if initTemplate == "" && prompt {
+		if err := requireTextOutput(cmd); err != nil {
+			return err
+		}
 		selectedTemplate, selectedProjectName, err := runWizard(projectName)
 		if err != nil {
 			return err
@@ -71,6 +155,26 @@ func runInit(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get template: %w", err)
 	}
 
+	// Resolve its variables from --values, --set, prompts and defaults
+	given, err := initVariableValues(cmd)
+	if err != nil {
+		return err
+	}
+	var ask func(templates.Variable) (string, error)
+	if prompt && !structuredOutput() && len(tmpl.Variables) > 0 {
+		fmt.Fprintf(messageOut, "\n⚙️  Configure template '%s'\n\n", tmpl.Name)
+		ask = askTemplateVariable
+	}
+	values, err := tmpl.ResolveVariables(given, ask)
+	switch {
+	case errors.Is(err, templates.ErrUnknownVariable):
+		return usageError(err)
+	case errors.Is(err, terminal.InterruptErr):
+		return err
+	case err != nil:
+		return invalidError(err)
+	}
+
 	// Check if directory exists and is not empty
 	if projectDir != "." {
 		if _, err := os.Stat(projectDir); err == nil {
@@ -79,13 +183,16 @@ func runInit(cmd *cobra.Command, args []string) error {
 			if err != nil {
 				return fmt.Errorf("failed to read directory: %w", err)
 			}
-			if len(entries) > 0 && !initNoPrompt {
+			if len(entries) > 0 && !initNoPrompt && !initForce {
+				if !prompt {
+					return fmt.Errorf("directory '%s' %w and is not empty (use --force to initialize it anyway)", projectDir, core.ErrAlreadyExists)
+				}
 				overwrite := false
-				prompt := &survey.Confirm{
+				confirm := &survey.Confirm{
 					Message: fmt.Sprintf("Directory '%s' is not empty. Continue anyway?", projectDir),
 					Default: false,
 				}
-				if err := survey.AskOne(prompt, &overwrite); err != nil {
+				if err := askOne(confirm, &overwrite); err != nil {
 					return err
 				}
 				if !overwrite {
@@ -96,24 +203,138 @@ func runInit(cmd *cobra.Command, args []string) error {
 	}
 
 	// Generate project from template
-	fmt.Printf("\n🚀 Initializing project '%s' with template '%s'...\n\n", projectName, initTemplate)
+	fmt.Fprintf(messageOut, "\n🚀 Initializing project '%s' with template '%s'...\n\n", projectName, initTemplate)
 
+	seed, err := templates.NewSeed()
+	if err != nil {
+		return err
+	}
 	vars := templates.TemplateVars{
 		ProjectName: projectName,
+		Values:      values,
+		Seed:        seed,
 	}
 
 	if err := tmpl.Generate(projectDir, vars); err != nil {
 		return fmt.Errorf("failed to generate project: %w", err)
 	}
 
+	// Record the template so the project can be upgraded later
+	if err := tmpl.WriteLock(projectDir, vars); err != nil {
+		return fmt.Errorf("failed to write template lock: %w", err)
+	}
+
 	// Print success message
 	printSuccessMessage(projectName, projectDir, tmpl)
 
-	return nil
+	return emitResult(cmd, initResult{
+		Project:   projectName,
+		Directory: projectDir,
+		Template: initTemplateResult{
+			Name:        tmpl.Name,
+			Description: tmpl.Description,
+			Category:    tmpl.Category,
+		},
+		Variables: publicVariables(tmpl, values),
+		Files:     append(tmpl.OutputFiles(), path.Join(templates.LockDir, templates.LockFileName)),
+	})
+}
+
+// initVariableValues collects the raw template variable values from --values
+// and --set, where --set wins
+func initVariableValues(cmd *cobra.Command) (map[string]string, error) {
+	given := make(map[string]string)
+	if initValues != "" {
+		data, err := readSpecFile(initValues, cmd.InOrStdin())
+		if err != nil {
+			return nil, err
+		}
+		var fileValues map[string]interface{}
+		if err := yaml.Unmarshal(data, &fileValues); err != nil {
+			return nil, invalidError(fmt.Errorf("failed to parse %s: %w", initValues, err))
+		}
+		for name, value := range fileValues {
+			given[name] = fmt.Sprint(value)
+		}
+	}
+	set, err := parseKeyValues(initSet, "set")
+	if err != nil {
+		return nil, usageError(err)
+	}
+	for name, value := range set {
+		given[name] = value
+	}
+	return given, nil
+}
+
+// askTemplateVariable prompts for a template variable with the survey prompt
+// matching its type
+func askTemplateVariable(v templates.Variable) (string, error) {
+	message := v.Name + ":"
+	if v.Description != "" {
+		message = v.Description + ":"
+	}
+	help := fmt.Sprintf("Template variable '%s'; set it with --set %s=VALUE", v.Name, v.Name)
+	validate := survey.WithValidator(func(answer interface{}) error {
+		_, err := v.Parse(fmt.Sprint(answer))
+		return err
+	})
+
+	var answer string
+	var err error
+	switch v.Type {
+	case templates.VarBool:
+		defaultValue, _ := strconv.ParseBool(v.Default)
+		var confirmed bool
+		err = askOne(&survey.Confirm{Message: message, Default: defaultValue, Help: help}, &confirmed)
+		answer = strconv.FormatBool(confirmed)
+	case templates.VarChoice:
+		prompt := &survey.Select{Message: message, Options: v.Choices, Help: help}
+		if v.Default != "" {
+			prompt.Default = v.Default
+		}
+		err = askOne(prompt, &answer)
+	case templates.VarSecret:
+		if !v.Required {
+			message = strings.TrimSuffix(message, ":") + " (leave empty to generate):"
+		}
+		err = askOne(&survey.Password{Message: message, Help: help}, &answer, validate)
+	default:
+		err = askOne(&survey.Input{Message: message, Default: v.Default, Help: help}, &answer, validate)
+	}
+	return answer, err
+}
+
+// publicVariables returns the variable values that are safe to print, which
+// excludes secrets
+func publicVariables(tmpl *templates.Template, values map[string]interface{}) map[string]interface{} {
+	public := make(map[string]interface{}, len(values))
+	for _, v := range tmpl.Variables {
+		if v.Type != templates.VarSecret {
+			public[v.Name] = values[v.Name]
+		}
+	}
+	return public
+}
+
+// initResult is the structured result of the init command
+type initResult struct {
+	Project   string                 `json:"project"`
+	Directory string                 `json:"directory"`
+	Template  initTemplateResult     `json:"template"`
+	Variables map[string]interface{} `json:"variables,omitempty"` // secrets are left out
+	Files     []string               `json:"files"`               // relative to Directory
+}
+
+// initTemplateResult describes the template a project was created from
+type initTemplateResult struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
 }
 
 func runWizard(defaultProjectName string) (string, string, error) {
-	fmt.Println("\n✨ Welcome to Container Composer Project Wizard! ✨\n")
+	fmt.Fprint(messageOut, "\n✨ Welcome to Container Composer Project Wizard! ✨\n\n")
 
 	// Step 1: Get category selection
 	categories := templates.GetCategories()
@@ -133,7 +354,7 @@ func runWizard(defaultProjectName string) (string, string, error) {
 		Help:    "Select the type of project you want to create",
 	}
 
-	if err := survey.AskOne(categoryPrompt, &selectedCategoryOption); err != nil {
+	if err := askOne(categoryPrompt, &selectedCategoryOption); err != nil {
 		return "", "", fmt.Errorf("category selection cancelled: %w", err)
 	}
 
@@ -161,7 +382,7 @@ func runWizard(defaultProjectName string) (string, string, error) {
 		Help:    "Select the specific template to use",
 	}
 
-	if err := survey.AskOne(templatePrompt, &selectedTemplateOption); err != nil {
+	if err := askOne(templatePrompt, &selectedTemplateOption); err != nil {
 		return "", "", fmt.Errorf("template selection cancelled: %w", err)
 	}
 
@@ -175,7 +396,7 @@ func runWizard(defaultProjectName string) (string, string, error) {
 		Help:    "This will be used in container names and the README",
 	}
 
-	if err := survey.AskOne(projectPrompt, &projectName, survey.WithValidator(survey.Required)); err != nil {
+	if err := askOne(projectPrompt, &projectName, survey.WithValidator(survey.Required)); err != nil {
 		return "", "", fmt.Errorf("project name input cancelled: %w", err)
 	}
 
@@ -188,7 +409,7 @@ func runWizard(defaultProjectName string) (string, string, error) {
 		Default: true,
 	}
 
-	if err := survey.AskOne(confirmPrompt, &confirmed); err != nil {
+	if err := askOne(confirmPrompt, &confirmed); err != nil {
 		return "", "", fmt.Errorf("confirmation cancelled: %w", err)
 	}
 
@@ -200,31 +421,32 @@ func runWizard(defaultProjectName string) (string, string, error) {
 }
 
 func printSuccessMessage(projectName, projectDir string, tmpl *templates.Template) {
-	fmt.Println("✅ Project initialized successfully!")
-	fmt.Println("\n📁 Created files:")
-	fmt.Println("   - docker-compose.yml")
-	fmt.Println("   - .env.example")
-	fmt.Println("   - README.md")
-	fmt.Println("   - .gitignore")
+	fmt.Fprintln(messageOut, "✅ Project initialized successfully!")
+	fmt.Fprintln(messageOut, "\n📁 Created files:")
+	for _, file := range tmpl.OutputFiles() {
+		if !strings.HasSuffix(file, ".gitkeep") {
+			fmt.Fprintf(messageOut, "   - %s\n", file)
+		}
+	}
 
-	fmt.Println("\n🎯 Next steps:")
+	fmt.Fprintln(messageOut, "\n🎯 Next steps:")
 
 	if projectDir != "." {
-		fmt.Printf("   1. cd %s\n", projectDir)
-		fmt.Println("   2. cp .env.example .env")
-		fmt.Println("   3. Edit .env with your configuration")
-		fmt.Println("   4. container-composer up")
+		fmt.Fprintf(messageOut, "   1. cd %s\n", projectDir)
+		fmt.Fprintln(messageOut, "   2. cp .env.example .env")
+		fmt.Fprintln(messageOut, "   3. Edit .env with your configuration")
+		fmt.Fprintln(messageOut, "   4. container-composer up")
 	} else {
-		fmt.Println("   1. cp .env.example .env")
-		fmt.Println("   2. Edit .env with your configuration")
-		fmt.Println("   3. container-composer up")
+		fmt.Fprintln(messageOut, "   1. cp .env.example .env")
+		fmt.Fprintln(messageOut, "   2. Edit .env with your configuration")
+		fmt.Fprintln(messageOut, "   3. container-composer up")
 	}
 
-	fmt.Println("\n📚 Template info:")
-	fmt.Printf("   Name: %s\n", tmpl.Name)
-	fmt.Printf("   Description: %s\n", tmpl.Description)
-	fmt.Printf("   Category: %s\n", tmpl.Category)
+	fmt.Fprintln(messageOut, "\n📚 Template info:")
+	fmt.Fprintf(messageOut, "   Name: %s\n", tmpl.Name)
+	fmt.Fprintf(messageOut, "   Description: %s\n", tmpl.Description)
+	fmt.Fprintf(messageOut, "   Category: %s\n", tmpl.Category)
 
-	fmt.Println("\n💡 Tip: Run 'container-composer status' to check service health after starting!")
-	fmt.Println()
+	fmt.Fprintln(messageOut, "\n💡 Tip: Run 'container-composer status' to check service health after starting!")
+	fmt.Fprintln(messageOut)
 }
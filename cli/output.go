@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats selected with --output
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// Exit codes are part of the CLI contract and must not change
+const (
+	ExitOK       = 0
+	ExitError    = 1 // any failure not covered below
+	ExitUsage    = 2 // unknown command, bad flags or arguments
+	ExitNotFound = 3 // compose file, service, network, volume or backup missing
+	ExitInvalid  = 4 // compose file or input fails to parse or validate
+	ExitConflict = 5 // resource already exists
+)
+
+// Error codes reported in structured output, one per exit code
+const (
+	codeError    = "error"
+	codeUsage    = "usage"
+	codeNotFound = "not_found"
+	codeInvalid  = "invalid"
+	codeConflict = "conflict"
+)
+
+// resultOut receives structured results and messageOut the human readable
+// messages. In json and yaml mode messages and prompts go to stderr, so stdout
+// carries nothing but the result document.
+var (
+	resultOut        io.Writer = os.Stdout
+	messageOut       io.Writer = os.Stdout
+	outputConfigured bool
+)
+
+// outputFileAnnotation names the flag a command's legacy "-o <file>" maps
+// to, from before -o selected the output format
+const outputFileAnnotation = "container-composer/output-file"
+
+// CommandError is an error with a stable code, exit status and optional
+// machine readable details
+type CommandError struct {
+	Code     string      `json:"code"`
+	Message  string      `json:"message"`
+	Details  interface{} `json:"details,omitempty"`
+	ExitCode int         `json:"exit_code"`
+
+	err error
+}
+
+// Error implements the error interface
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *CommandError) Unwrap() error {
+	return e.err
+}
+
+// usageError marks an error as caused by invalid command line usage
+func usageError(err error) *CommandError {
+	return &CommandError{Code: codeUsage, Message: err.Error(), ExitCode: ExitUsage, err: err}
+}
+
+// invalidError marks an error as caused by invalid input
+func invalidError(err error) *CommandError {
+	return &CommandError{Code: codeInvalid, Message: err.Error(), ExitCode: ExitInvalid, err: err}
+}
+
+// classifyError maps an error onto its code and exit status
+func classifyError(err error) *CommandError {
+	var commandErr *CommandError
+	if errors.As(err, &commandErr) {
+		return commandErr
+	}
+
+	classified := &CommandError{Code: codeError, Message: err.Error(), ExitCode: ExitError, err: err}
+	var validationErr *core.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		classified.Code, classified.ExitCode = codeInvalid, ExitInvalid
+		classified.Details = map[string]interface{}{"problems": validationErr.Problems}
+	case errors.Is(err, core.ErrInvalid):
+		classified.Code, classified.ExitCode = codeInvalid, ExitInvalid
+	case errors.Is(err, core.ErrNotFound):
+		classified.Code, classified.ExitCode = codeNotFound, ExitNotFound
+	case errors.Is(err, core.ErrAlreadyExists):
+		classified.Code, classified.ExitCode = codeConflict, ExitConflict
+	}
+	return classified
+}
+
+// ExitCode returns the process exit status for an error returned by Execute
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	return classifyError(err).ExitCode
+}
+
+// commandEnvelope wraps every structured result
+type commandEnvelope struct {
+	Command string        `json:"command"`
+	OK      bool          `json:"ok"`
+	Result  interface{}   `json:"result,omitempty"`
+	Error   *CommandError `json:"error,omitempty"`
+}
+
+// setupOutput validates --output and redirects human output in structured
+// modes. For commands annotated with outputFileAnnotation, a value that is not
+// an output format is taken as the deprecated "-o <file>".
+func setupOutput(cmd *cobra.Command) error {
+	if outputConfigured {
+		return nil
+	}
+	switch outputFormat {
+	case outputText:
+	case outputJSON, outputYAML:
+		messageOut = os.Stderr
+	default:
+		if flag := legacyOutputFile(cmd); flag != nil {
+			fmt.Fprintf(os.Stderr, "Flag --output <file> has been deprecated, use --%s instead\n", flag.Name)
+			if err := flag.Value.Set(outputFormat); err != nil {
+				return usageError(err)
+			}
+			outputFormat = outputText
+			break
+		}
+		return usageError(fmt.Errorf("unknown output format '%s' (supported: text, json, yaml)", outputFormat))
+	}
+	outputConfigured = true
+	return nil
+}
+
+// legacyOutputFile returns the flag that receives a command's deprecated
+// "-o <file>", if it has one
+func legacyOutputFile(cmd *cobra.Command) *pflag.Flag {
+	if cmd == nil || cmd.Annotations[outputFileAnnotation] == "" {
+		return nil
+	}
+	return cmd.Flags().Lookup(cmd.Annotations[outputFileAnnotation])
+}
+
+// askOne asks a survey question. In json and yaml mode the prompt is drawn
+// on stderr so stdout keeps only the result document.
+func askOne(prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+	if structuredOutput() {
+		opts = append(opts, survey.WithStdio(os.Stdin, os.Stderr, os.Stderr))
+	}
+	return survey.AskOne(prompt, response, opts...)
+}
+
+// stdinIsTerminal reports whether prompts can be answered on stdin
+func stdinIsTerminal() bool {
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// detectOutputFormat finds --output in raw arguments, ignoring every other
+// flag. It is used when cobra failed before parsing the flags.
+func detectOutputFormat(args []string) string {
+	flags := pflag.NewFlagSet("output", pflag.ContinueOnError)
+	flags.ParseErrorsAllowlist.UnknownFlags = true
+	flags.SetOutput(io.Discard)
+	format := flags.StringP("output", "o", outputText, "")
+	if err := flags.Parse(args); err != nil {
+		return outputText
+	}
+	return *format
+}
+
+// structuredOutput reports whether results are printed as JSON or YAML
+func structuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// requireTextOutput rejects --output json|yaml for interactive commands
+func requireTextOutput(cmd *cobra.Command) error {
+	if structuredOutput() {
+		return usageError(fmt.Errorf("'%s' is interactive and does not support --output %s; use its non-interactive flags instead", commandName(cmd), outputFormat))
+	}
+	return nil
+}
+
+// emitResult prints the structured result of a successful command. It does
+// nothing in text mode, where commands print their own human output.
+func emitResult(cmd *cobra.Command, result interface{}) error {
+	if !structuredOutput() {
+		return nil
+	}
+	return writeEnvelope(commandEnvelope{Command: commandName(cmd), OK: true, Result: result})
+}
+
+// reportError prints a failed command's error to stderr in text mode, or as
+// a structured document on stdout in json and yaml mode
+func reportError(cmd *cobra.Command, err error) {
+	classified := classifyError(err)
+	if !structuredOutput() {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return
+	}
+	if writeErr := writeEnvelope(commandEnvelope{Command: commandName(cmd), Error: classified}); writeErr != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+}
+
+// writeEnvelope renders an envelope in the selected format. YAML is produced
+// from the JSON encoding so both formats share the same field names.
+func writeEnvelope(envelope commandEnvelope) error {
+	data, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	if outputFormat == outputYAML {
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		clearNodeStyle(&node)
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return fmt.Errorf("failed to encode result: %w", err)
+		}
+		data = buf.Bytes()
+	} else {
+		data = append(data, '\n')
+	}
+
+	_, err = resultOut.Write(data)
+	return err
+}
+
+// clearNodeStyle drops the flow and quoting styles a node tree inherits from
+// JSON so it is rendered as block YAML
+func clearNodeStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearNodeStyle(child)
+	}
+}
+
+// commandName returns the command path without the binary name, e.g.
+// "graph diff"
+func commandName(cmd *cobra.Command) string {
+	if cmd == nil || !cmd.HasParent() {
+		return ""
+	}
+	return strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+}
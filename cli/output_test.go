@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/firasmosbahi/container-composer/core"
+)
+
+// TestClassifyError maps errors onto their structured code and exit status
+func TestClassifyError(t *testing.T) {
+	_, parseErr := core.ParseComposeData([]byte("services: [web"))
+	missingDep, err := core.ParseComposeData([]byte("services:\n  web:\n    image: nginx\n    depends_on: [db]\n"))
+	if err != nil {
+		t.Fatalf("failed to parse compose file: %v", err)
+	}
+	_, graphErr := missingDep.BuildDependencyGraph()
+
+	composeFile, err := core.ParseComposeData([]byte("services:\n  web:\n    image: nginx\n"))
+	if err != nil {
+		t.Fatalf("failed to parse compose file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		code string
+		exit int
+	}{
+		{"plain error", errors.New("boom"), codeError, ExitError},
+		{"usage", usageError(errors.New("bad flag")), codeUsage, ExitUsage},
+		{"wrapped usage", fmt.Errorf("add: %w", usageError(errors.New("bad flag"))), codeUsage, ExitUsage},
+		{"not found", fmt.Errorf("service 'web' %w", core.ErrNotFound), codeNotFound, ExitNotFound},
+		{"already exists", fmt.Errorf("network 'front' %w", core.ErrAlreadyExists), codeConflict, ExitConflict},
+		{"validation", &core.ValidationError{Problems: []string{"no services"}}, codeInvalid, ExitInvalid},
+		{"unparsable YAML", fmt.Errorf("failed to load compose file: %w", parseErr), codeInvalid, ExitInvalid},
+		{"missing dependency in graph", graphErr, codeInvalid, ExitInvalid},
+		{"empty service name", validateNewService(composeFile, core.Service{Image: "api"}, false), codeUsage, ExitUsage},
+		{"missing image", validateNewService(composeFile, core.Service{Name: "api"}, false), codeUsage, ExitUsage},
+		{"existing service", validateNewService(composeFile, core.Service{Name: "web", Image: "nginx"}, false), codeConflict, ExitConflict},
+		{"self dependency", validateNewService(composeFile, core.Service{Name: "api", Image: "api", DependsOn: []string{"api"}}, false), codeInvalid, ExitInvalid},
+		{"missing depends-on target", validateNewService(composeFile, core.Service{Name: "api", Image: "api", DependsOn: []string{"db"}}, false), codeInvalid, ExitInvalid},
+		{"unknown restart policy", validateNewService(composeFile, core.Service{Name: "api", Image: "api", Restart: "sometimes"}, false), codeInvalid, ExitInvalid},
+		{"malformed KEY=VALUE", keyValuesError(t, "NOEQUALS"), codeUsage, ExitUsage},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.err == nil {
+				t.Fatal("expected an error")
+			}
+			classified := classifyError(test.err)
+			if classified.Code != test.code || classified.ExitCode != test.exit {
+				t.Errorf("classifyError(%q) = %s/%d, want %s/%d", test.err, classified.Code, classified.ExitCode, test.code, test.exit)
+			}
+			if got := ExitCode(test.err); got != test.exit {
+				t.Errorf("ExitCode = %d, want %d", got, test.exit)
+			}
+		})
+	}
+
+	if got := ExitCode(nil); got != ExitOK {
+		t.Errorf("ExitCode(nil) = %d, want %d", got, ExitOK)
+	}
+}
+
+// keyValuesError returns the error parseKeyValues reports for value
+func keyValuesError(t *testing.T, value string) error {
+	t.Helper()
+	_, err := parseKeyValues([]string{value}, "env")
+	return err
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/spf13/cobra"
+)
+
+var (
+	removeCascade bool
+	removeYes     bool
+	removeDryRun  bool
+)
+
+var removeCmd = &cobra.Command{
+	Use:     "remove",
+	Aliases: []string{"rm"},
+	Short:   "Remove a service, network, or volume from docker-compose.yml",
+	Long: `Remove a service, network, or volume and clean up every reference to it.
+
+  - service: dropped from the depends_on lists of other services
+  - network: dropped from the networks lists of every service
+  - volume:  named volume mounts are stripped from every service
+
+The affected services and a diff of the changes are shown and confirmation is
+requested before the file is written. Volumes left without any mount are reported as orphaned.
+
+Examples:
+  container-composer remove service redis
+  container-composer remove service postgres --cascade
+  container-composer remove network backend --yes
+  container-composer remove volume cache-data --dry-run`,
+}
+
+var removeServiceCmd = &cobra.Command{
+	Use:   "service <name>",
+	Short: "Remove a service and drop it from other services' depends_on",
+	Long: `Remove a service and drop it from other services' depends_on.
+
+With --cascade, services that depend on it (directly or transitively) are
+removed as well instead of just losing the dependency.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeComposeArgs("service", 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRemove(cmd, "service", args[0])
+	},
+}
+
+var removeNetworkCmd = &cobra.Command{
+	Use:               "network <name>",
+	Short:             "Remove a network and disconnect every service from it",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeComposeArgs("network", 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRemove(cmd, "network", args[0])
+	},
+}
+
+var removeVolumeCmd = &cobra.Command{
+	Use:               "volume <name>",
+	Short:             "Remove a named volume and strip its mounts from every service",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeComposeArgs("volume", 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRemove(cmd, "volume", args[0])
+	},
+}
+
+func init() {
+	removeCmd.PersistentFlags().BoolVarP(&removeYes, "yes", "y", false, "skip the confirmation prompt")
+	removeCmd.PersistentFlags().BoolVar(&removeDryRun, "dry-run", false, "print the diff instead of writing docker-compose.yml")
+	removeServiceCmd.Flags().BoolVar(&removeCascade, "cascade", false, "also remove services that depend on the removed service")
+
+	removeCmd.AddCommand(removeServiceCmd)
+	removeCmd.AddCommand(removeNetworkCmd)
+	removeCmd.AddCommand(removeVolumeCmd)
+	rootCmd.AddCommand(removeCmd)
+}
+
+func runRemove(cmd *cobra.Command, kind, name string) error {
+	document, composePath, err := loadComposeDocument()
+	if err != nil {
+		return err
+	}
+
+	var report *core.RemovalReport
+	switch kind {
+	case "service":
+		report, err = document.RemoveService(name, removeCascade)
+	case "network":
+		report, err = document.RemoveNetwork(name)
+	case "volume":
+		report, err = document.RemoveVolume(name)
+	default:
+		return fmt.Errorf("unknown resource type: %s", kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	printRemovalReport(report)
+
+	data, err := document.Bytes()
+	if err != nil {
+		return err
+	}
+	result, err := writeComposeData(data, composePath, writeOptions{dryRun: removeDryRun, confirm: !removeYes})
+	if err != nil {
+		return err
+	}
+	if result.Written {
+		fmt.Fprintf(messageOut, "✅ %s '%s' has been removed from %s\n", capitalize(report.Kind), report.Name, composePath)
+	}
+	return emitResult(cmd, removeResult{RemovalReport: report, writeResult: result})
+}
+
+// removeResult is the structured result of the remove commands
+type removeResult struct {
+	*core.RemovalReport
+	writeResult
+}
+
+// printRemovalReport lists everything a removal touches
+func printRemovalReport(report *core.RemovalReport) {
+	fmt.Fprintf(messageOut, "\n🗑️  Removing %s '%s'\n\n", report.Kind, report.Name)
+
+	if len(report.Cascaded) > 0 {
+		fmt.Fprintln(messageOut, "Also removed (--cascade):")
+		for _, name := range report.Cascaded {
+			fmt.Fprintf(messageOut, "  • %s\n", name)
+		}
+		fmt.Fprintln(messageOut)
+	}
+
+	if len(report.Affected) > 0 {
+		fmt.Fprintln(messageOut, "Affected services:")
+		for _, affected := range report.Affected {
+			fmt.Fprintf(messageOut, "  • %s\n", affected)
+		}
+		fmt.Fprintln(messageOut)
+	} else {
+		fmt.Fprint(messageOut, "No other service references it.\n\n")
+	}
+
+	for _, volume := range report.OrphanedVolumes {
+		fmt.Fprintf(messageOut, "⚠️  Volume '%s' is no longer mounted by any service (remove it with 'remove volume %s')\n", volume, volume)
+	}
+	if len(report.OrphanedVolumes) > 0 {
+		fmt.Fprintln(messageOut)
+	}
+}
+
+// capitalize upper-cases the first letter of a resource kind
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
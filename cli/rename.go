@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/spf13/cobra"
+)
+
+var (
+	renameHostnames   bool
+	renameNoHostnames bool
+	renameDryRun      bool
+	renameYes         bool
+)
+
+var renameCmd = &cobra.Command{
+	Use:   "rename",
+	Short: "Rename a service, network, or volume and update all references",
+	Long: `Rename a service, network, or volume and rewrite every reference to it.
+
+  - service: map key, depends_on, links, extends, volumes_from and
+             network_mode/ipc/pid "service:" references
+  - network: map key and every service's networks
+  - volume:  map key and every named volume mount
+
+When renaming a service, environment values, commands, entrypoints and
+healthchecks are scanned for hostname usages such as redis://redis:6379, and
+you are offered to rewrite them.
+
+The file is edited in place, so comments and formatting are preserved. A diff
+is shown and confirmation is requested before anything is written.
+
+Examples:
+  container-composer rename service redis cache
+  container-composer rename service db postgres --rewrite-hostnames
+  container-composer rename network backend internal --dry-run
+  container-composer rename volume data app-data`,
+}
+
+var renameServiceCmd = &cobra.Command{
+	Use:               "service <old> <new>",
+	Short:             "Rename a service and rewrite every reference to it",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeComposeArgs("service", 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRename(cmd, "service", args[0], args[1])
+	},
+}
+
+var renameNetworkCmd = &cobra.Command{
+	Use:               "network <old> <new>",
+	Short:             "Rename a network and every service's reference to it",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeComposeArgs("network", 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRename(cmd, "network", args[0], args[1])
+	},
+}
+
+var renameVolumeCmd = &cobra.Command{
+	Use:               "volume <old> <new>",
+	Short:             "Rename a named volume and every mount that uses it",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeComposeArgs("volume", 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRename(cmd, "volume", args[0], args[1])
+	},
+}
+
+func init() {
+	renameCmd.PersistentFlags().BoolVarP(&renameYes, "yes", "y", false, "skip the confirmation prompt")
+	renameCmd.PersistentFlags().BoolVar(&renameDryRun, "dry-run", false, "print the diff instead of writing docker-compose.yml")
+	renameServiceCmd.Flags().BoolVar(&renameHostnames, "rewrite-hostnames", false, "rewrite every hostname usage without asking")
+	renameServiceCmd.Flags().BoolVar(&renameNoHostnames, "no-rewrite-hostnames", false, "leave hostname usages unchanged without asking")
+	renameServiceCmd.MarkFlagsMutuallyExclusive("rewrite-hostnames", "no-rewrite-hostnames")
+
+	renameCmd.AddCommand(renameServiceCmd)
+	renameCmd.AddCommand(renameNetworkCmd)
+	renameCmd.AddCommand(renameVolumeCmd)
+	rootCmd.AddCommand(renameCmd)
+}
+
+func runRename(cmd *cobra.Command, kind, oldName, newName string) error {
+	document, composePath, err := loadComposeDocument()
+	if err != nil {
+		return err
+	}
+
+	var report *core.RenameReport
+	switch kind {
+	case "service":
+		report, err = document.RenameService(oldName, newName)
+	case "network":
+		report, err = document.RenameNetwork(oldName, newName)
+	case "volume":
+		report, err = document.RenameVolume(oldName, newName)
+	default:
+		return fmt.Errorf("unknown resource type: %s", kind)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(messageOut, "\n✏️  Renaming %s '%s' to '%s'\n\n", report.Kind, report.Old, report.New)
+	if len(report.References) > 0 {
+		fmt.Fprintln(messageOut, "Updated references:")
+		for _, reference := range report.References {
+			fmt.Fprintf(messageOut, "  • %s\n", reference)
+		}
+		fmt.Fprintln(messageOut)
+	}
+
+	usages, err := selectHostnameUsages(report.Hostnames)
+	if err != nil {
+		return err
+	}
+	document.RewriteHostnames(usages)
+
+	data, err := document.Bytes()
+	if err != nil {
+		return err
+	}
+	if _, err := core.ParseComposeDocument(data); err != nil {
+		return fmt.Errorf("rename produced an invalid compose file: %w", err)
+	}
+
+	result, err := writeComposeData(data, composePath, writeOptions{dryRun: renameDryRun, confirm: !renameYes})
+	if err != nil {
+		return err
+	}
+	if result.Written {
+		fmt.Fprintf(messageOut, "✅ %s '%s' has been renamed to '%s'\n", capitalize(report.Kind), report.Old, report.New)
+	}
+	return emitResult(cmd, renameResult{RenameReport: report, RewrittenHostnames: usages, writeResult: result})
+}
+
+// renameResult is the structured result of the rename commands
+type renameResult struct {
+	*core.RenameReport
+	RewrittenHostnames []core.HostnameUsage `json:"rewritten_hostnames"`
+	writeResult
+}
+
+// selectHostnameUsages decides which hostname usages to rewrite, asking the
+// user unless a flag already decided
+func selectHostnameUsages(usages []core.HostnameUsage) ([]core.HostnameUsage, error) {
+	if len(usages) == 0 || renameNoHostnames {
+		return nil, nil
+	}
+	if renameHostnames {
+		return usages, nil
+	}
+
+	options := make([]string, len(usages))
+	for i, usage := range usages {
+		options[i] = fmt.Sprintf("%s %s: %s → %s", usage.Service, usage.Field, usage.Before, usage.After)
+	}
+
+	var selected []int
+	if err := askOne(&survey.MultiSelect{
+		Message: "Possible hostname usages found. Select the ones to rewrite:",
+		Options: options,
+		Default: options,
+	}, &selected); err != nil {
+		return nil, err
+	}
+
+	result := make([]core.HostnameUsage, len(selected))
+	for i, index := range selected {
+		result[i] = usages[index]
+	}
+	return result, nil
+}
@@ -3,7 +3,9 @@ package cli
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/firasmosbahi/container-composer/internal/logging"
 	"github.com/spf13/cobra"
 )
 
@@ -13,8 +15,16 @@ var (
 	BuildDate = "unknown"
 
 	// Global flags
-	verbose bool
-	debug   bool
+	verbose      bool
+	debug        bool
+	outputFormat string
+	logFile      string
+	logFormat    string
+	profiles     []string
+
+	// commandStarted is set once flags and arguments have been validated, so
+	// errors returned before that point are reported as usage errors
+	commandStarted bool
 )
 
 var rootCmd = &cobra.Command{
@@ -23,17 +33,108 @@ var rootCmd = &cobra.Command{
 	Long: `Container Composer enhances your Docker Compose workflow with intelligent features,
 better debugging capabilities, and an improved developer experience.
 
-It works seamlessly with your existing docker-compose.yml files without modification.`,
-	Version: Version,
+It works seamlessly with your existing docker-compose.yml files without modification.
+
+Use --output json or --output yaml to get a single machine readable result
+document on stdout; progress messages then go to stderr. Exit codes are stable:
+  0  success
+  1  error
+  2  usage error (unknown command, bad flags or arguments)
+  3  not found (compose file, service, network, volume or backup)
+  4  invalid compose file or input
+  5  conflict (resource already exists)
+
+Defaults for common flags can be kept in a .container-composer.yaml file in
+the project (found by walking up from the current directory) or in
+~/.config/container-composer/config.yaml:
+
+  compose_files: [compose.yaml, docker-compose.yml]
+  profiles: [debug]
+  graph: {networks: true, volumes: false, health: true}
+  lint:
+    rules: {undefined-volume: warning, restart-policy: off}
+  template_paths: [./templates]
+  add: {restart: unless-stopped}
+
+Flags override CC_* environment variables (CC_COMPOSE_FILES, CC_PROFILES,
+CC_GRAPH_NETWORKS, CC_GRAPH_VOLUMES, CC_GRAPH_HEALTH, CC_LINT_RULES,
+CC_TEMPLATE_PATHS, CC_ADD_RESTART), which override the project file, which
+overrides the user file.`,
+	Version:       Version,
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := setupOutput(cmd); err != nil {
+			return err
+		}
+		if err := logging.Setup(logging.Options{
+			Verbose: verbose,
+			Debug:   debug,
+			Format:  logFormat,
+			File:    logFile,
+		}); err != nil {
+			return usageError(err)
+		}
+		// Cobra checks these after the pre-run hooks; check them here so they
+		// are still reported as usage errors
+		if err := cmd.ValidateRequiredFlags(); err != nil {
+			return usageError(err)
+		}
+		if err := cmd.ValidateFlagGroups(); err != nil {
+			return usageError(err)
+		}
+
+		commandStarted = true
+		// Usage is only useful for errors in flags and arguments
+		cmd.SilenceUsage = true
+		logging.Logger().Info("command started", "command", commandName(cmd), "args", args,
+			"version", Version, "output", outputFormat)
+
+		// Configuration problems are not usage errors
+		return loadSettings(cmd)
+	},
 }
 
+// Execute runs the root command and reports a failure, either as text on
+// stderr or as a structured error document. Use ExitCode to map the returned
+// error onto the process exit status.
 func Execute() error {
-	return rootCmd.Execute()
+	defer logging.Close()
+
+	start := time.Now()
+	cmd, err := rootCmd.ExecuteC()
+	logging.Logger().Info("command finished", "command", commandName(cmd),
+		"exit_code", ExitCode(err), "duration", time.Since(start))
+	if err == nil {
+		return nil
+	}
+	if !commandStarted {
+		err = usageError(err)
+		// Flags may not have been parsed, e.g. for an unknown command
+		if !rootCmd.PersistentFlags().Changed("output") {
+			outputFormat = detectOutputFormat(os.Args[1:])
+		}
+		if setupOutput(cmd) != nil {
+			outputFormat = outputText
+		}
+	}
+	reportError(cmd, err)
+	return err
 }
 
 func init() {
-	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
-	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "debug mode with detailed logging")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "log decisions such as the files and values picked")
+	rootCmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "log everything, including timings")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", outputText, "output format: text, json or yaml")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "append logs to this file instead of stderr")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", logging.FormatText, "log format: text or json")
+	rootCmd.PersistentFlags().StringSliceVar(&profiles, "profile", nil, "compose profiles to enable in graphs and impact analysis (repeatable)")
+	rootCmd.RegisterFlagCompletionFunc("profile", completeProfiles)
+	rootCmd.RegisterFlagCompletionFunc("output", completeValues(outputText, outputJSON, outputYAML))
+	rootCmd.RegisterFlagCompletionFunc("log-format", completeValues(logging.FormatText, logging.FormatJSON))
+
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return usageError(err)
+	})
 
 	rootCmd.SetVersionTemplate(fmt.Sprintf("container-composer version %s (built on %s)\n", Version, BuildDate))
 }
@@ -48,8 +149,8 @@ func GetDebug() bool {
 	return debug
 }
 
-// Exit prints an error message and exits with status code 1
+// Exit reports an error and exits with its stable exit code
 func Exit(err error) {
-	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-	os.Exit(1)
+	reportError(nil, err)
+	os.Exit(ExitCode(err))
 }
\ No newline at end of file
@@ -0,0 +1,784 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/firasmosbahi/container-composer/templates"
+	"github.com/spf13/cobra"
+)
+
+var (
+	templateCreateFrom        string
+	templateCreateProjectName string
+	templateCreateCategory    string
+	templateCreateDescription string
+	templateCreateForce       bool
+	templateCreateDryRun      bool
+
+	templateTestSet    []string
+	templateTestUpdate bool
+	templateTestGolden string
+
+	templateUpgradeSet    []string
+	templateUpgradeDryRun bool
+
+	templateAddNamespace string
+	templateAddRef       string
+)
+
+// projectComposeFiles are the compose file names looked for in a project
+// directory given with --from
+var projectComposeFiles = []string{"docker-compose.yml", "docker-compose.yaml", "compose.yaml", "compose.yml"}
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Manage project templates",
+	Long: `Manage the templates used by init.
+
+User templates live in ~/.config/container-composer/templates/, one directory
+with a template.yaml manifest per template (see 'container-composer init --help').
+Templates published in git repositories or archives are added with
+'template add' and used as <namespace>/<name>.`,
+}
+
+var templateCreateCmd = &cobra.Command{
+	Use:   "create <name> [--from <dir>]",
+	Short: "Create a user template from an existing project",
+	Long: `Create a user template from an existing project.
+
+The compose file of the project is turned into a compose template:
+  - the project name is replaced with {{.ProjectName}} wherever it stands as a
+    word of its own, e.g. in container_name: shop_db or a shop-network network
+  - hardcoded credentials in environment values (*_PASSWORD, *_TOKEN, API_KEY,
+    *SECRET*...) become secret variables, which init generates when left empty;
+    the same value used by several services becomes a single variable
+  - {{ and }} already in the project are escaped
+
+The files the compose file references are captured into the template: bind
+mount sources (whole directories), env files, Dockerfiles of build contexts,
+config files and .env.example. Files named .env, binary files, files over
+1 MiB and node_modules, vendor and .git directories are left out.
+
+Examples:
+  container-composer template create go-service --from .
+  container-composer template create shop --from ../shop --category fullstack
+  container-composer template create shop --project-name shop --dry-run`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTemplateCreate,
+}
+
+var templateTestCmd = &cobra.Command{
+	Use:   "test [name|dir...]",
+	Short: "Render templates and check the generated projects",
+	Long: `Render templates with sample values and check the generated projects.
+
+Each template is rendered into a temporary directory with the project name
+"example", the default of every variable and a fixed sample value for secrets;
+randAlphaNum is seeded per template so the output is reproducible. Then:
+  - docker-compose.yml must parse and pass validation
+  - the dependency graph must build and have no cycles
+  - every ${VAR} used in docker-compose.yml must be defined in .env.example
+  - the output must match the golden files, if there are any
+
+Arguments are template names or template directories (containing a
+template.yaml); without arguments every available template is tested. Golden
+files of a user template are kept in its testdata/golden directory; use
+--update to write them after checking the output, and --golden to compare with
+another directory.
+
+Examples:
+  container-composer template test
+  container-composer template test nodejs --set app_port=8080
+  container-composer template test ./templates/go-service --update`,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		// Directories are valid arguments too
+		completions, _ := completeTemplateNames(cmd, args, toComplete)
+		return completions, cobra.ShellCompDirectiveDefault
+	},
+	RunE: runTemplateTest,
+}
+
+var templateUpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade the project to the current version of its template",
+	Long: `Upgrade the project in the current directory to the current version of the
+template it was created from.
+
+init records the template, its version and the variable values in
+.container-composer/template.lock, and keeps the files as the template
+rendered them in .container-composer/template-base/. upgrade renders the
+current template with the same values (new variables get their default or a
+--set value) and three-way merges every file, with the original render as the
+base:
+
+  updated     you did not modify the file; it is replaced
+  merged      you and the template changed different lines
+  conflict    you and the template changed the same lines; both versions are
+              kept between <<<<<<< project, ======= and >>>>>>> template markers
+  added       the template added the file
+  removed     the template dropped the file and you had not modified it
+  kept        the template dropped or changed a file you modified or deleted
+  unchanged   the template did not change the file
+
+The lock is meant to be committed, so it records secret variables by name
+only. upgrade reads their values from .env (under the variable name or its
+upper-case form, e.g. DB_PASSWORD for db_password) or --set, and otherwise
+asks for them; without a terminal a missing secret is an error.
+
+Afterwards the new render becomes the base for the next upgrade. The command
+exits with code 5 when there are conflicts to resolve.
+
+Examples:
+  container-composer template upgrade --dry-run
+  container-composer template upgrade --set redis_port=6380
+  container-composer template upgrade --set db_password="$DB_PASSWORD"`,
+	Args: cobra.NoArgs,
+	RunE: runTemplateUpgrade,
+}
+
+var templateAddCmd = &cobra.Command{
+	Use:   "add <git-url|path|archive>",
+	Short: "Add the templates of a git repository, an archive or a directory",
+	Long: `Add the templates of a git repository, a .tar.gz archive or a local directory.
+
+The source is cloned, downloaded or copied into
+~/.config/container-composer/registries/<namespace>/ and every directory in it
+containing a template.yaml becomes available as <namespace>/<name>, e.g.
+acme/go-service:
+
+  container-composer init shop --template acme/go-service
+
+Sources:
+  git        https://, ssh://, git:// and file:// URLs, git@host:repo
+             addresses and local bare repositories; --ref picks a branch or
+             tag, the default branch otherwise
+  archive    .tar.gz or .tgz files, as a path or an http, https or file URL
+  directory  any other local directory, copied as it is
+
+The namespace defaults to the last element of the source without .git or the
+archive extension. The cache is a snapshot: run 'template update' to fetch the
+source again.
+
+Examples:
+  container-composer template add https://git.example.com/platform/templates.git --namespace acme
+  container-composer template add git@github.com:acme/templates.git --ref v2
+  container-composer template add ./acme-templates.tar.gz`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTemplateAdd,
+}
+
+var templateListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the available templates and the added registries",
+	Long: `List every template init can use: built-in templates, user templates from
+~/.config/container-composer/templates/ and the configured template paths, and
+the namespaced templates of the registries added with 'template add'.`,
+	Args: cobra.NoArgs,
+	RunE: runTemplateList,
+}
+
+var templateUpdateCmd = &cobra.Command{
+	Use:   "update [namespace...]",
+	Short: "Fetch the sources of added registries again",
+	Long: `Fetch the sources of added registries again and replace their cached
+templates. Without arguments every registry is updated. A registry whose
+source fails to fetch or whose templates fail to load keeps its cached copy.
+
+Projects created from a registry template can then be upgraded with
+'template upgrade'.
+
+Examples:
+  container-composer template update
+  container-composer template update acme`,
+	ValidArgsFunction: completeRegistryNamespaces,
+	RunE:              runTemplateUpdate,
+}
+
+var templateRemoveCmd = &cobra.Command{
+	Use:     "remove <namespace>",
+	Aliases: []string{"rm"},
+	Short:   "Remove an added registry and its templates",
+	Long: `Remove a registry added with 'template add' and delete its cached templates.
+Projects created from its templates are not changed, but can no longer be
+upgraded until the registry is added again.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeRegistryNamespaces,
+	RunE:              runTemplateRemove,
+}
+
+func init() {
+	templateCreateCmd.Flags().StringVar(&templateCreateFrom, "from", ".", "project directory or compose file to create the template from")
+	templateCreateCmd.Flags().StringVar(&templateCreateProjectName, "project-name", "", "project name to replace with {{.ProjectName}} (default: compose name or directory name)")
+	templateCreateCmd.Flags().StringVar(&templateCreateCategory, "category", "custom", "template category")
+	templateCreateCmd.Flags().StringVar(&templateCreateDescription, "description", "", "template description")
+	templateCreateCmd.Flags().BoolVar(&templateCreateForce, "force", false, "replace an existing user template with the same name")
+	templateCreateCmd.Flags().BoolVar(&templateCreateDryRun, "dry-run", false, "print what would be created without writing the template")
+	templateCreateCmd.MarkFlagDirname("from")
+
+	templateTestCmd.Flags().StringArrayVar(&templateTestSet, "set", nil, "sample value for a template variable KEY=VALUE (repeatable)")
+	templateTestCmd.Flags().BoolVar(&templateTestUpdate, "update", false, "write the output as the golden files instead of comparing")
+	templateTestCmd.Flags().StringVar(&templateTestGolden, "golden", "", "golden file directory (default: testdata/golden in a user template)")
+	templateTestCmd.MarkFlagDirname("golden")
+
+	templateUpgradeCmd.Flags().StringArrayVar(&templateUpgradeSet, "set", nil, "set a template variable KEY=VALUE (repeatable)")
+	templateUpgradeCmd.Flags().BoolVar(&templateUpgradeDryRun, "dry-run", false, "report what would change without writing any file")
+
+	templateAddCmd.Flags().StringVar(&templateAddNamespace, "namespace", "", "namespace of the templates (default: derived from the source)")
+	templateAddCmd.Flags().StringVar(&templateAddRef, "ref", "", "git branch or tag to clone")
+
+	templateCmd.AddCommand(templateCreateCmd)
+	templateCmd.AddCommand(templateTestCmd)
+	templateCmd.AddCommand(templateUpgradeCmd)
+	templateCmd.AddCommand(templateAddCmd)
+	templateCmd.AddCommand(templateListCmd)
+	templateCmd.AddCommand(templateUpdateCmd)
+	templateCmd.AddCommand(templateRemoveCmd)
+	rootCmd.AddCommand(templateCmd)
+}
+
+// templateCreateResult is the structured result of template create
+type templateCreateResult struct {
+	Name         string   `json:"name"`
+	Directory    string   `json:"directory"`
+	ProjectName  string   `json:"project_name"`
+	Replacements int      `json:"replacements"`
+	Variables    []string `json:"variables"`
+	Files        []string `json:"files"`
+	Skipped      []string `json:"skipped,omitempty"`
+	DryRun       bool     `json:"dry_run"`
+}
+
+func runTemplateCreate(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	composePath, err := projectComposeFile(templateCreateFrom)
+	if err != nil {
+		return err
+	}
+
+	userDir, err := templates.UserTemplateDir()
+	if err != nil {
+		return fmt.Errorf("failed to locate user template directory: %w", err)
+	}
+	templateDir := filepath.Join(userDir, name)
+	if _, err := os.Stat(templateDir); err == nil && !templateCreateForce {
+		return fmt.Errorf("template '%s' %w in %s (use --force to replace it)", name, core.ErrAlreadyExists, userDir)
+	}
+
+	created, err := templates.FromProject(composePath, templates.CreateOptions{
+		Name:        name,
+		Category:    templateCreateCategory,
+		Description: templateCreateDescription,
+		ProjectName: templateCreateProjectName,
+	})
+	if err != nil {
+		return invalidError(fmt.Errorf("failed to create template: %w", err))
+	}
+
+	result := templateCreateResult{
+		Name:         name,
+		Directory:    templateDir,
+		ProjectName:  created.ProjectName,
+		Replacements: created.Replacements,
+		Variables:    []string{},
+		Files:        []string{templates.ManifestFileName, created.Manifest.Compose},
+		Skipped:      created.Skipped,
+		DryRun:       templateCreateDryRun,
+	}
+	for _, v := range created.Manifest.Variables {
+		result.Variables = append(result.Variables, v.Name)
+	}
+	for _, file := range created.Files {
+		result.Files = append(result.Files, file.Path)
+	}
+	printTemplateCreate(result, created)
+
+	if templateCreateDryRun {
+		fmt.Fprintf(messageOut, "\n%s\n", created.Compose)
+		return emitResult(cmd, result)
+	}
+
+	if templateCreateForce {
+		if err := os.RemoveAll(templateDir); err != nil {
+			return fmt.Errorf("failed to replace template '%s': %w", name, err)
+		}
+	}
+	if err := created.Write(templateDir); err != nil {
+		return fmt.Errorf("failed to write template '%s': %w", name, err)
+	}
+	if _, err := templates.LoadTemplateDir(templateDir); err != nil {
+		return fmt.Errorf("template '%s' was written to %s but does not load: %w", name, templateDir, err)
+	}
+
+	fmt.Fprintf(messageOut, "\n✅ Template '%s' has been created in %s\n", name, templateDir)
+	fmt.Fprintf(messageOut, "   Use it with: container-composer init <project> --template %s\n", name)
+	return emitResult(cmd, result)
+}
+
+// projectComposeFile returns the compose file of the project given with
+// --from: a compose file, a project directory, or the configured compose file
+// for the current directory
+func projectComposeFile(from string) (string, error) {
+	if from == "." {
+		return findComposeFile()
+	}
+	info, err := os.Stat(from)
+	if err != nil {
+		return "", fmt.Errorf("project '%s' %w", from, core.ErrNotFound)
+	}
+	if !info.IsDir() {
+		return from, nil
+	}
+	for _, name := range projectComposeFiles {
+		composePath := filepath.Join(from, name)
+		if _, err := os.Stat(composePath); err == nil {
+			return composePath, nil
+		}
+	}
+	return "", fmt.Errorf("compose file %w in %s", core.ErrNotFound, from)
+}
+
+// printTemplateCreate summarizes what a template captured from a project
+// contains
+func printTemplateCreate(result templateCreateResult, created *templates.ProjectTemplate) {
+	fmt.Fprintf(messageOut, "\n📦 Creating template '%s' from project '%s'\n\n", result.Name, result.ProjectName)
+	fmt.Fprintf(messageOut, "🔤 Replaced '%s' with {{.ProjectName}} %d time(s)\n", result.ProjectName, result.Replacements)
+	for _, v := range created.Manifest.Variables {
+		fmt.Fprintf(messageOut, "🔑 Extracted secret variable '%s' (%s)\n", v.Name, v.Description)
+	}
+	for _, file := range created.Files {
+		fmt.Fprintf(messageOut, "📄 Captured %s\n", file.Path)
+	}
+	for _, dir := range created.Manifest.Directories {
+		fmt.Fprintf(messageOut, "📁 Captured empty directory %s\n", dir)
+	}
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(messageOut, "⚠️  Skipped %s\n", skipped)
+	}
+}
+
+func runTemplateTest(cmd *cobra.Command, args []string) error {
+	set, err := parseKeyValues(templateTestSet, "set")
+	if err != nil {
+		return usageError(err)
+	}
+
+	tested, err := templatesToTest(args)
+	if err != nil {
+		return err
+	}
+	if templateTestGolden != "" && len(tested) != 1 {
+		return usageError(fmt.Errorf("--golden needs exactly one template"))
+	}
+	if err := checkSampleValues(tested, set); err != nil {
+		return usageError(err)
+	}
+
+	fmt.Fprint(messageOut, "\n🧪 Testing templates\n\n")
+	reports := []*templates.TestReport{}
+	failed := 0
+	for _, tmpl := range tested {
+		report, err := tmpl.Test(templates.TestOptions{
+			Values:    declaredValues(&tmpl, set),
+			GoldenDir: goldenDir(&tmpl),
+			Update:    templateTestUpdate,
+		})
+		if err != nil {
+			return invalidError(fmt.Errorf("failed to test template '%s': %w", tmpl.Name, err))
+		}
+		reports = append(reports, report)
+		if !report.Passed() {
+			failed++
+		}
+		printTestReport(report)
+	}
+
+	if failed > 0 {
+		commandErr := invalidError(fmt.Errorf("%d of %d template(s) failed", failed, len(reports)))
+		commandErr.Details = reports
+		return commandErr
+	}
+	fmt.Fprintf(messageOut, "\n✅ All %d template(s) passed\n", len(reports))
+	return emitResult(cmd, reports)
+}
+
+// templatesToTest loads the templates named by the arguments, which are
+// template names or template directories, or every available template
+func templatesToTest(args []string) ([]templates.Template, error) {
+	if len(args) == 0 {
+		loaded, err := templates.LoadTemplates()
+		if err != nil {
+			return nil, invalidError(fmt.Errorf("failed to load templates: %w", err))
+		}
+		return loaded, nil
+	}
+
+	var tested []templates.Template
+	for _, arg := range args {
+		var tmpl *templates.Template
+		var err error
+		if _, statErr := os.Stat(filepath.Join(arg, templates.ManifestFileName)); statErr == nil {
+			tmpl, err = templates.LoadTemplateDir(arg)
+			if err != nil {
+				err = invalidError(err)
+			}
+		} else {
+			tmpl, err = templates.GetTemplate(arg)
+		}
+		if err != nil {
+			return nil, err
+		}
+		tested = append(tested, *tmpl)
+	}
+	return tested, nil
+}
+
+// checkSampleValues rejects --set values for variables none of the tested
+// templates declares
+func checkSampleValues(tested []templates.Template, set map[string]string) error {
+	for name := range set {
+		declared := false
+		for _, tmpl := range tested {
+			for _, v := range tmpl.Variables {
+				declared = declared || v.Name == name
+			}
+		}
+		if !declared {
+			return fmt.Errorf("%w '%s': no tested template declares it", templates.ErrUnknownVariable, name)
+		}
+	}
+	return nil
+}
+
+// declaredValues returns the sample values for the variables a template
+// declares
+func declaredValues(tmpl *templates.Template, set map[string]string) map[string]string {
+	values := make(map[string]string)
+	for _, v := range tmpl.Variables {
+		if value, ok := set[v.Name]; ok {
+			values[v.Name] = value
+		}
+	}
+	return values
+}
+
+// goldenDir returns the golden file directory of a template: --golden, or
+// testdata/golden in the directory of a user template
+func goldenDir(tmpl *templates.Template) string {
+	if templateTestGolden != "" {
+		return templateTestGolden
+	}
+	if tmpl.Source == "builtin" {
+		return ""
+	}
+	return filepath.Join(tmpl.Source, "testdata", "golden")
+}
+
+// printTestReport prints the outcome of testing a template
+func printTestReport(report *templates.TestReport) {
+	switch {
+	case !report.Passed():
+		fmt.Fprintf(messageOut, "❌ %-16s %d problem(s)\n", report.Template, len(report.Problems))
+	case report.Updated:
+		fmt.Fprintf(messageOut, "✅ %-16s %d files, golden files written to %s\n", report.Template, len(report.Files), report.Golden)
+	case report.Golden != "":
+		fmt.Fprintf(messageOut, "✅ %-16s %d files, matches %s\n", report.Template, len(report.Files), report.Golden)
+	default:
+		fmt.Fprintf(messageOut, "✅ %-16s %d files\n", report.Template, len(report.Files))
+	}
+	for _, problem := range report.Problems {
+		location := problem.Check
+		if problem.File != "" {
+			location += " " + problem.File
+		}
+		fmt.Fprintf(messageOut, "   %s: %s\n", location, problem.Message)
+	}
+}
+
+func runTemplateUpgrade(cmd *cobra.Command, args []string) error {
+	set, err := parseKeyValues(templateUpgradeSet, "set")
+	if err != nil {
+		return usageError(err)
+	}
+
+	lock, err := templates.ReadLock(".")
+	if err != nil {
+		return err
+	}
+	tmpl, err := templates.GetTemplate(lock.Template)
+	if err != nil {
+		return err
+	}
+
+	given := tmpl.LockedValues(lock)
+	secrets, missing, err := tmpl.LockedSecrets(".", lock)
+	if err != nil {
+		return err
+	}
+	for name, value := range secrets {
+		given[name] = value
+	}
+	for name, value := range set {
+		given[name] = value
+	}
+
+	// The lock records secrets by name only: ask again for those .env does
+	// not hold, so the upgrade renders the same values
+	for _, v := range missing {
+		if _, ok := given[v.Name]; ok {
+			continue
+		}
+		if !stdinIsTerminal() || structuredOutput() {
+			return usageError(fmt.Errorf("secret variable '%s' is not recorded in the template lock; set it with --set %s=VALUE or as %s in .env",
+				v.Name, v.Name, strings.ToUpper(v.Name)))
+		}
+		answer, err := askTemplateVariable(v)
+		if err != nil {
+			return err
+		}
+		given[v.Name] = answer
+	}
+
+	values, err := tmpl.ResolveVariables(given, nil)
+	switch {
+	case errors.Is(err, templates.ErrUnknownVariable):
+		return usageError(err)
+	case err != nil:
+		return invalidError(err)
+	}
+
+	fmt.Fprintf(messageOut, "\n⬆️  Upgrading from template '%s' %s to %s\n\n", tmpl.Name, versionLabel(lock.Version), versionLabel(tmpl.Version))
+	report, err := tmpl.Upgrade(".", lock, values, templateUpgradeDryRun)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range report.Files {
+		icon := "✅"
+		switch file.Status {
+		case templates.UpgradeUnchanged, templates.UpgradeUpToDate:
+			icon = "  "
+		case templates.UpgradeKept:
+			icon = "↪️ "
+		case templates.UpgradeConflict:
+			icon = "⚠️ "
+		}
+		fmt.Fprintf(messageOut, "%s %-10s %s", icon, file.Status, file.Path)
+		if file.Conflicts > 0 {
+			fmt.Fprintf(messageOut, " (%d conflict(s))", file.Conflicts)
+		}
+		fmt.Fprintln(messageOut)
+	}
+	fmt.Fprintf(messageOut, "\n%s\n", report.Summary())
+
+	if report.Conflicts > 0 {
+		message := fmt.Sprintf("%d conflict(s) to resolve: edit the files marked 'conflict' and remove the <<<<<<<, ======= and >>>>>>> markers", report.Conflicts)
+		if templateUpgradeDryRun {
+			message = fmt.Sprintf("the upgrade would cause %d conflict(s)", report.Conflicts)
+		}
+		return &CommandError{Code: codeConflict, Message: message, Details: report, ExitCode: ExitConflict}
+	}
+	if templateUpgradeDryRun {
+		fmt.Fprintln(messageOut, "🔍 Dry run: no files were written")
+	} else {
+		fmt.Fprintf(messageOut, "✅ Project upgraded to template '%s' %s\n", tmpl.Name, versionLabel(tmpl.Version))
+	}
+	return emitResult(cmd, report)
+}
+
+// versionLabel formats a template version for messages
+func versionLabel(version string) string {
+	if version == "" {
+		return "(unversioned)"
+	}
+	return "v" + version
+}
+
+func runTemplateAdd(cmd *cobra.Command, args []string) error {
+	fmt.Fprintf(messageOut, "\n📥 Adding templates from %s\n\n", args[0])
+	registry, err := templates.AddRegistry(args[0], templates.RegistryOptions{
+		Namespace: templateAddNamespace,
+		Ref:       templateAddRef,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range registry.Templates {
+		fmt.Fprintf(messageOut, "📦 %s\n", name)
+	}
+	fmt.Fprintf(messageOut, "\n✅ Added %d template(s) from %s as '%s'\n", len(registry.Templates), registrySourceLabel(registry), registry.Namespace)
+	fmt.Fprintf(messageOut, "   Use one with: container-composer init <project> --template %s\n", registry.Templates[0])
+	return emitResult(cmd, registry)
+}
+
+// templateListItem is a template in the structured result of template list
+type templateListItem struct {
+	Name        string `json:"name"`
+	Category    string `json:"category"`
+	Version     string `json:"version,omitempty"`
+	Description string `json:"description"`
+	Source      string `json:"source"`
+}
+
+// templateListResult is the structured result of template list
+type templateListResult struct {
+	Templates  []templateListItem   `json:"templates"`
+	Registries []templates.Registry `json:"registries"`
+}
+
+func runTemplateList(cmd *cobra.Command, args []string) error {
+	loaded, loadErr := templates.LoadTemplates()
+	registries, err := templates.Registries()
+	if err != nil {
+		return err
+	}
+
+	result := templateListResult{Templates: []templateListItem{}, Registries: registries}
+	for _, tmpl := range loaded {
+		result.Templates = append(result.Templates, templateListItem{
+			Name:        tmpl.Name,
+			Category:    tmpl.Category,
+			Version:     tmpl.Version,
+			Description: tmpl.Description,
+			Source:      tmpl.Source,
+		})
+	}
+	if loadErr != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Some templates failed to load: %v\n", loadErr)
+	}
+	if structuredOutput() {
+		return emitResult(cmd, result)
+	}
+
+	fmt.Fprint(messageOut, "\n📦 Available templates\n\n")
+	for _, tmpl := range result.Templates {
+		fmt.Fprintf(messageOut, "  %-24s %-14s %-8s %s\n", tmpl.Name, tmpl.Category, tmpl.Version, tmpl.Source)
+	}
+
+	if len(registries) > 0 {
+		fmt.Fprint(messageOut, "\n🗂️  Registries\n\n")
+		for _, registry := range registries {
+			fmt.Fprintf(messageOut, "  %-16s %d template(s) from %s, updated %s\n", registry.Namespace,
+				len(registry.Templates), registrySourceLabel(&registry), registry.Updated.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	fmt.Fprintln(messageOut, "\nRun 'container-composer init <project> --template <name>' to use one.")
+	return nil
+}
+
+// templateUpdateResult is the structured result of updating a registry
+type templateUpdateResult struct {
+	Namespace string   `json:"namespace"`
+	Source    string   `json:"source"`
+	From      string   `json:"from,omitempty"`
+	To        string   `json:"to,omitempty"`
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+}
+
+func runTemplateUpdate(cmd *cobra.Command, args []string) error {
+	namespaces := args
+	if len(namespaces) == 0 {
+		registries, err := templates.Registries()
+		if err != nil {
+			return err
+		}
+		if len(registries) == 0 {
+			fmt.Fprintln(messageOut, "No registries to update. Add one with 'container-composer template add'.")
+			return emitResult(cmd, []templateUpdateResult{})
+		}
+		for _, registry := range registries {
+			namespaces = append(namespaces, registry.Namespace)
+		}
+	}
+
+	results := []templateUpdateResult{}
+	var errs []error
+	for _, namespace := range namespaces {
+		before, after, err := templates.UpdateRegistry(namespace)
+		if err != nil {
+			fmt.Fprintf(messageOut, "❌ %s: %v\n", namespace, err)
+			errs = append(errs, fmt.Errorf("failed to update '%s': %w", namespace, err))
+			continue
+		}
+
+		result := templateUpdateResult{
+			Namespace: namespace,
+			Source:    after.Source,
+			From:      before.Revision,
+			To:        after.Revision,
+			Added:     missingNames(after.Templates, before.Templates),
+			Removed:   missingNames(before.Templates, after.Templates),
+		}
+		results = append(results, result)
+
+		fmt.Fprintf(messageOut, "✅ %s: %d template(s)", namespace, len(after.Templates))
+		switch {
+		case result.To == "":
+		case result.From == result.To:
+			fmt.Fprintf(messageOut, ", already at %s", shortRevision(result.To))
+		default:
+			fmt.Fprintf(messageOut, ", %s → %s", shortRevision(result.From), shortRevision(result.To))
+		}
+		fmt.Fprintln(messageOut)
+		for _, name := range result.Added {
+			fmt.Fprintf(messageOut, "   + %s\n", name)
+		}
+		for _, name := range result.Removed {
+			fmt.Fprintf(messageOut, "   - %s\n", name)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	return emitResult(cmd, results)
+}
+
+func runTemplateRemove(cmd *cobra.Command, args []string) error {
+	registry, err := templates.RemoveRegistry(args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(messageOut, "✅ Removed registry '%s' and its %d template(s)\n", registry.Namespace, len(registry.Templates))
+	return emitResult(cmd, registry)
+}
+
+// registrySourceLabel describes the source of a registry, with the git ref
+// when one was given
+func registrySourceLabel(registry *templates.Registry) string {
+	label := registry.Source
+	if registry.Ref != "" {
+		label += " (" + registry.Ref + ")"
+	}
+	return label
+}
+
+// shortRevision abbreviates a git commit
+func shortRevision(revision string) string {
+	if len(revision) > 7 {
+		return revision[:7]
+	}
+	return revision
+}
+
+// missingNames returns the names that are not in other
+func missingNames(names, other []string) []string {
+	known := make(map[string]bool, len(other))
+	for _, name := range other {
+		known[name] = true
+	}
+	missing := []string{}
+	for _, name := range names {
+		if !known[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"github.com/firasmosbahi/container-composer/internal/config"
 	"github.com/firasmosbahi/container-composer/tui"
 	"github.com/spf13/cobra"
 )
@@ -25,6 +26,21 @@ func init() {
 }
 
 func runTUI(cmd *cobra.Command, args []string) error {
-	app := tui.NewApp()
+	if err := requireTextOutput(cmd); err != nil {
+		return err
+	}
+
+	opts := tui.DefaultOptions()
+	if composePath, err := findComposeFile(); err == nil {
+		opts.ComposePath = composePath
+	}
+	if settings.Add.Restart != "" {
+		opts.DefaultRestart = settings.Add.Restart
+	}
+	opts.ShowNetworks = config.BoolOr(settings.Graph.Networks, opts.ShowNetworks)
+	opts.ShowVolumes = config.BoolOr(settings.Graph.Volumes, opts.ShowVolumes)
+	opts.ShowHealthChecks = config.BoolOr(settings.Graph.Health, opts.ShowHealthChecks)
+
+	app := tui.NewApp(opts)
 	return app.Run()
 }
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/firasmosbahi/container-composer/tui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	undoList   bool
+	undoYes    bool
+	undoDryRun bool
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Restore docker-compose.yml from the last backup",
+	Long: `Restore docker-compose.yml from the most recent backup.
+
+Every command that modifies docker-compose.yml first saves the current content
+to .container-composer/backups/ (the last 20 are kept). 'undo' shows a diff,
+restores the newest backup and removes it, so running it again goes further
+back in history. The content it replaces is saved as an undo backup first;
+those are listed with --list but never restored by 'undo' itself.
+
+Examples:
+  container-composer undo
+  container-composer undo --list
+  container-composer undo --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runUndo,
+}
+
+func init() {
+	undoCmd.Flags().BoolVarP(&undoList, "list", "l", false, "list available backups")
+	undoCmd.Flags().BoolVarP(&undoYes, "yes", "y", false, "skip the confirmation prompt")
+	undoCmd.Flags().BoolVar(&undoDryRun, "dry-run", false, "print the diff without restoring")
+
+	rootCmd.AddCommand(undoCmd)
+}
+
+func runUndo(cmd *cobra.Command, args []string) error {
+	// A deleted compose file can still be restored from its backups
+	composePath, err := findComposeFile()
+	if err != nil {
+		composePath = defaultComposePath
+		if len(settings.ComposeFiles) > 0 {
+			composePath = settings.ComposeFiles[0]
+		}
+	}
+
+	backups, err := core.ListBackups(composePath)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 || (!undoList && restorable(backups) == 0) {
+		if undoList && structuredOutput() {
+			return emitResult(cmd, undoListResult{File: composePath, Backups: []core.Backup{}})
+		}
+		return fmt.Errorf("backup %w in %s", core.ErrNotFound, core.BackupDir(composePath))
+	}
+
+	if undoList {
+		if structuredOutput() {
+			return emitResult(cmd, undoListResult{File: composePath, Backups: backups})
+		}
+		fmt.Fprintf(messageOut, "\n💾 Backups of %s (newest first)\n\n", composePath)
+		for _, backup := range backups {
+			marker := ""
+			if backup.Undo {
+				marker = "  (replaced by undo)"
+			}
+			fmt.Fprintf(messageOut, "  • %s  %s%s\n", backup.Time.Format("2006-01-02 15:04:05"), backup.Path, marker)
+		}
+		fmt.Fprintln(messageOut)
+		return nil
+	}
+
+	var latest core.Backup
+	for _, backup := range backups {
+		if !backup.Undo {
+			latest = backup
+			break
+		}
+	}
+	data, err := os.ReadFile(latest.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	diff, err := core.FileDiff(composePath, data)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(messageOut, "\n⏪ Restoring %s from the backup of %s\n\n", composePath, latest.Time.Format("2006-01-02 15:04:05"))
+	if diff == "" {
+		fmt.Fprint(messageOut, "The backup is identical to the current file.\n\n")
+	} else {
+		fmt.Fprint(messageOut, tui.ColorizeDiff(diff))
+		fmt.Fprintln(messageOut)
+	}
+	result := undoResult{Backup: latest, Remaining: restorable(backups) - 1, writeResult: writeResult{Changed: diff != "", DryRun: undoDryRun, Diff: diff}}
+	if undoDryRun {
+		return emitResult(cmd, result)
+	}
+
+	if !undoYes {
+		var confirmed bool
+		if err := askOne(&survey.Confirm{
+			Message: "Restore this backup?",
+			Default: true,
+		}, &confirmed); err != nil {
+			return err
+		}
+		if !confirmed {
+			fmt.Fprintln(messageOut, "❌ Cancelled. No changes were made.")
+			return emitResult(cmd, result)
+		}
+	}
+
+	saved, err := core.RestoreBackup(composePath, latest)
+	if err != nil {
+		return err
+	}
+	result.Written = true
+	result.Saved = saved
+
+	fmt.Fprintf(messageOut, "✅ %s has been restored (%d older backup(s) left)\n", composePath, result.Remaining)
+	return emitResult(cmd, result)
+}
+
+// restorable counts the backups undo can restore, leaving out the ones undo
+// itself took
+func restorable(backups []core.Backup) int {
+	count := 0
+	for _, backup := range backups {
+		if !backup.Undo {
+			count++
+		}
+	}
+	return count
+}
+
+// undoListResult is the structured result of undo --list
+type undoListResult struct {
+	File    string        `json:"file"`
+	Backups []core.Backup `json:"backups"`
+}
+
+// undoResult is the structured result of undo
+type undoResult struct {
+	Backup    core.Backup `json:"backup"`
+	Remaining int         `json:"remaining_backups"` // backups left once this one is restored
+	Saved     string      `json:"saved,omitempty"`   // undo backup of the replaced content
+	writeResult
+}
@@ -0,0 +1,197 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/firasmosbahi/container-composer/internal/logging"
+)
+
+// BackupDirName is where backups are kept, relative to the compose file
+const BackupDirName = ".container-composer/backups"
+
+// maxBackups is how many backups are kept per file before the oldest are removed
+const maxBackups = 20
+
+// backupTimeFormat sorts lexicographically in chronological order
+const backupTimeFormat = "20060102-150405.000000000"
+
+// undoSuffix marks backups taken by RestoreBackup, i.e. the content an undo
+// replaced
+const undoSuffix = ".undo"
+
+// Backup is a saved copy of a file taken before it was overwritten
+type Backup struct {
+	Path string    `json:"path"`
+	Time time.Time `json:"time"`
+	Undo bool      `json:"undo,omitempty"` // taken by an undo before restoring an older backup
+}
+
+// BackupDir returns the backup directory for a file
+func BackupDir(path string) string {
+	return filepath.Join(filepath.Dir(path), BackupDirName)
+}
+
+// SaveFile backs up the current content of a file, if any, and then replaces
+// it atomically. It returns the path of the backup, or "" when the file did
+// not exist yet.
+func SaveFile(path string, data []byte) (string, error) {
+	backup, err := CreateBackup(path)
+	if err != nil {
+		return "", err
+	}
+	if err := WriteFileAtomic(path, data); err != nil {
+		return "", err
+	}
+	return backup, nil
+}
+
+// WriteFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never see a partially written file
+func WriteFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	logging.Logger().Info("wrote file", "path", path, "bytes", len(data), "mode", mode)
+	return nil
+}
+
+// CreateBackup copies the current content of a file into the backup directory
+// and removes the oldest backups beyond maxBackups. It returns "" when the
+// file does not exist.
+func CreateBackup(path string) (string, error) {
+	return createBackup(path, "")
+}
+
+// createBackup backs up a file under a name ending in suffix + ".bak"
+func createBackup(path, suffix string) (string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		logging.Logger().Debug("no backup needed, file does not exist yet", "path", path)
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	dir := BackupDir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s.%s%s.bak", filepath.Base(path), time.Now().Format(backupTimeFormat), suffix)
+	backupPath := filepath.Join(dir, name)
+	if err := os.WriteFile(backupPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	backups, err := ListBackups(path)
+	if err != nil {
+		return "", err
+	}
+	for _, old := range backups[min(len(backups), maxBackups):] {
+		logging.Logger().Debug("pruning old backup", "path", old.Path)
+		os.Remove(old.Path)
+	}
+
+	logging.Logger().Info("created backup", "path", path, "backup", backupPath)
+	return backupPath, nil
+}
+
+// ListBackups returns the backups of a file, newest first
+func ListBackups(path string) ([]Backup, error) {
+	dir := BackupDir(path)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	prefix := filepath.Base(path) + "."
+	var backups []Backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".bak")
+		undo := strings.HasSuffix(stamp, undoSuffix)
+		stamp = strings.TrimSuffix(stamp, undoSuffix)
+		timestamp, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: filepath.Join(dir, name), Time: timestamp, Undo: undo})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// RestoreBackup atomically replaces a file with the content of a backup and
+// deletes the backup, so repeated restores walk further back in history. The
+// replaced content is kept as an undo backup first, which later restores skip
+// but which can still be copied back by hand. It returns the path of that
+// backup, or "" when the file did not exist.
+func RestoreBackup(path string, backup Backup) (string, error) {
+	data, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+	saved, err := createBackup(path, undoSuffix)
+	if err != nil {
+		return "", err
+	}
+	if err := WriteFileAtomic(path, data); err != nil {
+		return "", err
+	}
+	if err := os.Remove(backup.Path); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to remove restored backup: %w", err)
+	}
+	logging.Logger().Info("restored backup", "path", path, "backup", backup.Path, "saved", saved)
+	return saved, nil
+}
+
+// FileDiff returns a unified diff between the content of a file on disk (empty
+// when it does not exist) and new content
+func FileDiff(path string, data []byte) (string, error) {
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return UnifiedDiff("a/"+path, "b/"+path, string(old), string(data)), nil
+}
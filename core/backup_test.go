@@ -0,0 +1,211 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSaveFile replaces files atomically and backs up their old content
+func TestSaveFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing *string // nil when the file does not exist yet
+		mode     os.FileMode
+		wantMode os.FileMode
+	}{
+		{name: "new file", wantMode: 0644},
+		{name: "existing file", existing: strPtr("old\n"), mode: 0644, wantMode: 0644},
+		{name: "permissions are kept", existing: strPtr("secret\n"), mode: 0600, wantMode: 0600},
+		{name: "empty file is backed up", existing: strPtr(""), mode: 0640, wantMode: 0640},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "docker-compose.yml")
+			if test.existing != nil {
+				writeTestFile(t, path, *test.existing, test.mode)
+			}
+
+			backup, err := SaveFile(path, []byte("new\n"))
+			if err != nil {
+				t.Fatalf("failed to save: %v", err)
+			}
+			if got := readTestFile(t, path); got != "new\n" {
+				t.Errorf("content = %q, want %q", got, "new\n")
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != test.wantMode {
+				t.Errorf("mode = %v, want %v", info.Mode().Perm(), test.wantMode)
+			}
+
+			if test.existing == nil {
+				if backup != "" {
+					t.Errorf("backup = %q, want none", backup)
+				}
+			} else if got := readTestFile(t, backup); got != *test.existing {
+				t.Errorf("backup content = %q, want %q", got, *test.existing)
+			}
+
+			// Only the file and the backup directory are left behind
+			entries, _ := os.ReadDir(filepath.Dir(path))
+			for _, entry := range entries {
+				if entry.Name() != "docker-compose.yml" && entry.Name() != ".container-composer" {
+					t.Errorf("unexpected file %s", entry.Name())
+				}
+			}
+		})
+	}
+}
+
+// TestWriteFileAtomic reports failures without touching the target
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "missing", "docker-compose.yml")
+	if err := WriteFileAtomic(path, []byte("x")); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+
+	target := filepath.Join(dir, "docker-compose.yml")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(target, []byte("x")); err == nil {
+		t.Fatal("expected an error when the target is a directory")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+// TestBackupRotation keeps the newest maxBackups backups
+func TestBackupRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docker-compose.yml")
+	writeTestFile(t, path, "0\n", 0644)
+	// Unrelated files in the backup directory are ignored and kept
+	dir := BackupDir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"other.yml.20240101-000000.000000000.bak", "docker-compose.yml.notes.bak"} {
+		writeTestFile(t, filepath.Join(dir, name), "x", 0644)
+	}
+
+	var saved []string
+	for i := 1; i <= maxBackups+5; i++ {
+		backup, err := SaveFile(path, []byte(string(rune('a'+i))))
+		if err != nil {
+			t.Fatalf("failed to save: %v", err)
+		}
+		saved = append(saved, backup)
+	}
+
+	backups, err := ListBackups(path)
+	if err != nil {
+		t.Fatalf("failed to list backups: %v", err)
+	}
+	if len(backups) != maxBackups {
+		t.Fatalf("kept %d backups, want %d", len(backups), maxBackups)
+	}
+	for i, backup := range backups {
+		if want := saved[len(saved)-1-i]; backup.Path != want {
+			t.Errorf("backup %d = %s, want %s", i, backup.Path, want)
+		}
+	}
+	for _, name := range []string{"other.yml.20240101-000000.000000000.bak", "docker-compose.yml.notes.bak"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("unrelated file %s was removed: %v", name, err)
+		}
+	}
+}
+
+// TestRestoreBackup walks back through history and keeps what each restore
+// replaced as an undo backup
+func TestRestoreBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docker-compose.yml")
+	for _, content := range []string{"v1\n", "v2\n", "v3\n"} {
+		if _, err := SaveFile(path, []byte(content)); err != nil {
+			t.Fatalf("failed to save: %v", err)
+		}
+	}
+
+	steps := []struct {
+		want  string // content after the restore
+		saved string // content of the undo backup
+	}{
+		{want: "v2\n", saved: "v3\n"},
+		{want: "v1\n", saved: "v2\n"},
+	}
+	for _, step := range steps {
+		backups, err := ListBackups(path)
+		if err != nil {
+			t.Fatalf("failed to list backups: %v", err)
+		}
+		var next *Backup
+		for i := range backups {
+			if !backups[i].Undo {
+				next = &backups[i]
+				break
+			}
+		}
+		if next == nil {
+			t.Fatalf("no backup left to restore %q", step.want)
+		}
+
+		saved, err := RestoreBackup(path, *next)
+		if err != nil {
+			t.Fatalf("failed to restore: %v", err)
+		}
+		if got := readTestFile(t, path); got != step.want {
+			t.Errorf("content = %q, want %q", got, step.want)
+		}
+		if got := readTestFile(t, saved); got != step.saved {
+			t.Errorf("undo backup = %q, want %q", got, step.saved)
+		}
+		if _, err := os.Stat(next.Path); !os.IsNotExist(err) {
+			t.Errorf("restored backup %s was not removed", next.Path)
+		}
+	}
+
+	backups, _ := ListBackups(path)
+	for _, backup := range backups {
+		if !backup.Undo {
+			t.Errorf("unexpected backup left: %+v", backup)
+		}
+	}
+	if len(backups) != 2 {
+		t.Errorf("kept %d undo backups, want 2", len(backups))
+	}
+
+	if _, err := RestoreBackup(path, Backup{Path: filepath.Join(BackupDir(path), "missing.bak")}); err == nil {
+		t.Error("expected an error for a missing backup")
+	}
+}
+
+// writeTestFile writes a file with the given mode or fails the test
+func writeTestFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTestFile reads a file or fails the test
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
+
+// strPtr returns a pointer to a string
+func strPtr(s string) *string { return &s }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/firasmosbahi/container-composer/internal/logging"
 	"gopkg.in/yaml.v3"
 )
 
@@ -102,6 +103,7 @@ type Service struct {
 	Hostname    string            `yaml:"hostname,omitempty"`
 	Labels      map[string]string `yaml:"labels,omitempty"`
 	ContainerName string          `yaml:"container_name,omitempty"`
+	Profiles    []string          `yaml:"profiles,omitempty"`
 }
 
 // BuildConfig represents build configuration for a service
@@ -139,23 +141,43 @@ func ParseComposeFile(path string) (*ComposeFile, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read compose file: %w", err)
 	}
+	logging.Logger().Debug("read compose file", "path", path, "bytes", len(data))
+
+	return ParseComposeData(data)
+}
+
+// ParseComposeData parses docker-compose.yml content
+func ParseComposeData(data []byte) (*ComposeFile, error) {
+	done := logging.Timed("parse compose data", "bytes", len(data))
 
 	var compose ComposeFile
 	if err := yaml.Unmarshal(data, &compose); err != nil {
-		return nil, fmt.Errorf("failed to parse YAML: %w", err)
+		done("error", err)
+		return nil, invalidf("failed to parse YAML: %w", err)
 	}
 
+	done("services", len(compose.Services), "networks", len(compose.Networks), "volumes", len(compose.Volumes))
 	return &compose, nil
 }
 
-// WriteComposeFile writes the compose file to disk
-func (c *ComposeFile) WriteComposeFile(path string) error {
+// Marshal renders the compose file as YAML
+func (c *ComposeFile) Marshal() ([]byte, error) {
 	data, err := yaml.Marshal(c)
 	if err != nil {
-		return fmt.Errorf("failed to marshal YAML: %w", err)
+		return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	return data, nil
+}
+
+// WriteComposeFile writes the compose file to disk atomically, keeping a
+// backup of the previous content
+func (c *ComposeFile) WriteComposeFile(path string) error {
+	data, err := c.Marshal()
+	if err != nil {
+		return err
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if _, err := SaveFile(path, data); err != nil {
 		return fmt.Errorf("failed to write file: %w", err)
 	}
 
@@ -180,6 +202,66 @@ func (c *ComposeFile) VolumeExists(name string) bool {
 	return exists
 }
 
+// Clone returns a deep copy of the service, so edits to the copy do not
+// alias the lists and maps of the original
+func (s Service) Clone() Service {
+	clone := s
+	clone.Ports = cloneStrings(s.Ports)
+	clone.Volumes = cloneStrings(s.Volumes)
+	clone.Networks = cloneStrings(s.Networks)
+	clone.DependsOn = cloneStrings(s.DependsOn)
+	clone.Profiles = cloneStrings(s.Profiles)
+	clone.Labels = cloneStringMap(s.Labels)
+	clone.Command = cloneCommand(s.Command)
+	clone.Entrypoint = cloneCommand(s.Entrypoint)
+	if s.Environment != nil {
+		clone.Environment = Environment(cloneStringMap(s.Environment))
+	}
+	if s.Build != nil {
+		build := *s.Build
+		build.Args = cloneStringMap(s.Build.Args)
+		clone.Build = &build
+	}
+	if s.HealthCheck != nil {
+		healthCheck := *s.HealthCheck
+		healthCheck.Test = HealthCheckTest(cloneStrings(s.HealthCheck.Test))
+		clone.HealthCheck = &healthCheck
+	}
+	return clone
+}
+
+// cloneStrings copies a list, keeping nil lists nil
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+// cloneStringMap copies a map, keeping nil maps nil
+func cloneStringMap(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	clone := make(map[string]string, len(values))
+	for key, value := range values {
+		clone[key] = value
+	}
+	return clone
+}
+
+// cloneCommand copies a command given as a string or a list
+func cloneCommand(command interface{}) interface{} {
+	switch value := command.(type) {
+	case []string:
+		return cloneStrings(value)
+	case []interface{}:
+		return append([]interface{}{}, value...)
+	default:
+		return command
+	}
+}
+
 // AddService adds a service to the compose file
 func (c *ComposeFile) AddService(service Service) {
 	if c.Services == nil {
@@ -204,12 +286,6 @@ func (c *ComposeFile) AddVolume(name string, volume Volume) {
 	c.Volumes[name] = volume
 }
 
-// Validate validates the compose file structure
-func (cf *ComposeFile) Validate() error {
-	// TODO: Implement validation
-	return nil
-}
-
 // GetDependencyGraph builds a dependency graph of services
 func (cf *ComposeFile) GetDependencyGraph() (map[string][]string, error) {
 	graph, err := cf.BuildDependencyGraph()
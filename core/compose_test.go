@@ -0,0 +1,63 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestServiceClone checks that edits to a clone never reach the original
+func TestServiceClone(t *testing.T) {
+	original := Service{
+		Name:        "api",
+		Image:       "api:1",
+		Build:       &BuildConfig{Context: ".", Args: map[string]string{"VERSION": "1"}},
+		Ports:       []string{"8080:80"},
+		Environment: Environment{"MODE": "prod"},
+		Volumes:     []string{"data:/data"},
+		DependsOn:   []string{"db"},
+		Networks:    []string{"backend"},
+		HealthCheck: &HealthCheck{Test: HealthCheckTest{"CMD", "true"}, Retries: 3},
+		Command:     []interface{}{"serve", "--port=80"},
+		Entrypoint:  []string{"/entrypoint.sh"},
+		Labels:      map[string]string{"team": "core"},
+		Profiles:    []string{"debug"},
+	}
+
+	tests := []struct {
+		name string
+		edit func(s *Service)
+	}{
+		{"ports", func(s *Service) { s.Ports[0] = "9090:80" }},
+		{"environment", func(s *Service) { s.Environment["MODE"] = "dev" }},
+		{"volumes", func(s *Service) { s.Volumes[0] = "other:/data" }},
+		{"depends_on", func(s *Service) { s.DependsOn[0] = "cache" }},
+		{"networks", func(s *Service) { s.Networks[0] = "frontend" }},
+		{"labels", func(s *Service) { s.Labels["team"] = "web" }},
+		{"profiles", func(s *Service) { s.Profiles[0] = "prod" }},
+		{"build", func(s *Service) { s.Build.Context = "./api"; s.Build.Args["VERSION"] = "2" }},
+		{"healthcheck", func(s *Service) { s.HealthCheck.Retries = 5; s.HealthCheck.Test[1] = "false" }},
+		{"command", func(s *Service) { s.Command.([]interface{})[0] = "worker" }},
+		{"entrypoint", func(s *Service) { s.Entrypoint.([]string)[0] = "/bin/sh" }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := original.Clone()
+			clone := original.Clone()
+			if !reflect.DeepEqual(clone, original) {
+				t.Fatalf("clone = %+v, want %+v", clone, original)
+			}
+			test.edit(&clone)
+			if !reflect.DeepEqual(original, want) {
+				t.Fatalf("editing the clone changed the original: %+v", original)
+			}
+		})
+	}
+
+	t.Run("nil fields stay nil", func(t *testing.T) {
+		clone := Service{Name: "web", Image: "nginx"}.Clone()
+		if !reflect.DeepEqual(clone, Service{Name: "web", Image: "nginx"}) {
+			t.Fatalf("clone = %#v", clone)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change
+const diffContextLines = 3
+
+// DiffOpKind identifies whether a diff line was kept, removed or inserted
+type DiffOpKind int
+
+const (
+	DiffEqual DiffOpKind = iota
+	DiffDelete
+	DiffInsert
+)
+
+// DiffLine is a single line of a line-based diff
+type DiffLine struct {
+	Kind DiffOpKind
+	Text string
+}
+
+// DiffLines computes a line-based diff between two texts using the longest
+// common subsequence of their lines
+func DiffLines(oldText, newText string) []DiffLine {
+	a, b := splitLines(oldText), splitLines(newText)
+
+	// lcs[i][j] holds the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Kind: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Kind: DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Kind: DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Kind: DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Kind: DiffInsert, Text: b[j]})
+	}
+
+	return lines
+}
+
+// UnifiedDiff renders the difference between two texts in unified diff
+// format. It returns an empty string when the texts are identical.
+func UnifiedDiff(oldName, newName, oldText, newText string) string {
+	lines := DiffLines(oldText, newText)
+
+	changed := false
+	for _, line := range lines {
+		if line.Kind != DiffEqual {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName))
+
+	// Walk the diff, grouping changes that are close together into hunks
+	oldLine, newLine := 1, 1
+	for start := 0; start < len(lines); {
+		// Find the next change
+		first := start
+		for first < len(lines) && lines[first].Kind == DiffEqual {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+
+		// Extend the hunk while changes are within two contexts of each other
+		last := first
+		for k := first; k < len(lines); k++ {
+			if lines[k].Kind != DiffEqual {
+				last = k
+			} else if k-last > 2*diffContextLines {
+				break
+			}
+		}
+
+		hunkStart := first - diffContextLines
+		if hunkStart < start {
+			hunkStart = start
+		}
+		hunkEnd := last + diffContextLines + 1
+		if hunkEnd > len(lines) {
+			hunkEnd = len(lines)
+		}
+
+		// Advance line counters to the start of the hunk
+		for k := start; k < hunkStart; k++ {
+			oldLine++
+			newLine++
+		}
+
+		oldCount, newCount := 0, 0
+		var body strings.Builder
+		for _, line := range lines[hunkStart:hunkEnd] {
+			switch line.Kind {
+			case DiffEqual:
+				body.WriteString(" " + line.Text + "\n")
+				oldCount++
+				newCount++
+			case DiffDelete:
+				body.WriteString("-" + line.Text + "\n")
+				oldCount++
+			case DiffInsert:
+				body.WriteString("+" + line.Text + "\n")
+				newCount++
+			}
+		}
+
+		builder.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(oldLine, oldCount), hunkRange(newLine, newCount)))
+		builder.WriteString(body.String())
+
+		oldLine += oldCount
+		newLine += newCount
+		start = hunkEnd
+	}
+
+	return builder.String()
+}
+
+// hunkRange formats the line range of a hunk header
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text into lines without trailing newlines
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Merge3 merges the changes ours and theirs made to base, line by line.
+// Regions changed on one side only take that side; regions changed
+// differently on both sides are conflicts, marked the way git marks them:
+//
+//	<<<<<<< oursLabel
+//	our lines
+//	=======
+//	their lines
+//	>>>>>>> theirsLabel
+//
+// It returns the merged text and the number of conflicts.
+func Merge3(base, ours, theirs, oursLabel, theirsLabel string) (string, int) {
+	b, o, t := splitLines(base), splitLines(ours), splitLines(theirs)
+	matchOurs, matchTheirs := lineMatches(base, ours), lineMatches(base, theirs)
+
+	var out []string
+	conflicts := 0
+	resolve := func(bc, oc, tc []string) {
+		switch {
+		case equalLines(oc, bc):
+			out = append(out, tc...)
+		case equalLines(tc, bc), equalLines(oc, tc):
+			out = append(out, oc...)
+		default:
+			conflicts++
+			out = append(out, "<<<<<<< "+oursLabel)
+			out = append(out, oc...)
+			out = append(out, "=======")
+			out = append(out, tc...)
+			out = append(out, ">>>>>>> "+theirsLabel)
+		}
+	}
+
+	i, j, k := 0, 0, 0
+	for {
+		// The next base line kept by both sides is stable
+		next := i
+		for next < len(b) && (matchOurs[next] < 0 || matchTheirs[next] < 0) {
+			next++
+		}
+		if next == len(b) {
+			resolve(b[i:], o[j:], t[k:])
+			break
+		}
+		if next == i && matchOurs[i] == j && matchTheirs[i] == k {
+			out = append(out, b[i])
+			i, j, k = i+1, j+1, k+1
+			continue
+		}
+		resolve(b[i:next], o[j:matchOurs[next]], t[k:matchTheirs[next]])
+		i, j, k = next, matchOurs[next], matchTheirs[next]
+	}
+
+	if len(out) == 0 {
+		return "", conflicts
+	}
+	return strings.Join(out, "\n") + "\n", conflicts
+}
+
+// lineMatches maps every line of a to the index of the line of b it is
+// matched with by DiffLines, or -1 when it was deleted
+func lineMatches(a, b string) []int {
+	matches := make([]int, len(splitLines(a)))
+	i, j := 0, 0
+	for _, line := range DiffLines(a, b) {
+		switch line.Kind {
+		case DiffEqual:
+			matches[i] = j
+			i++
+			j++
+		case DiffDelete:
+			matches[i] = -1
+			i++
+		case DiffInsert:
+			j++
+		}
+	}
+	return matches
+}
+
+// equalLines compares two line slices
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package core
+
+import "testing"
+
+// TestMerge3 merges two sides of a change line by line
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name      string
+		base      string
+		ours      string
+		theirs    string
+		want      string
+		conflicts int
+	}{
+		{
+			name:   "unchanged",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nb\nc\n",
+		},
+		{
+			name:   "only ours changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nB\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nB\nc\n",
+		},
+		{
+			name:   "only theirs changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nC\n",
+			want:   "a\nb\nC\n",
+		},
+		{
+			name:   "changes on different lines",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "A\nb\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   "A\nb\nc\nd\nE\n",
+		},
+		{
+			name:   "same change on both sides",
+			base:   "a\nb\nc\n",
+			ours:   "a\nx\nc\n",
+			theirs: "a\nx\nc\n",
+			want:   "a\nx\nc\n",
+		},
+		{
+			name:   "insertions and deletions",
+			base:   "a\nb\nc\nd\n",
+			ours:   "a\nnew\nb\nc\nd\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nnew\nb\nc\n",
+		},
+		{
+			name:      "conflicting change",
+			base:      "a\nb\nc\n",
+			ours:      "a\nours\nc\n",
+			theirs:    "a\ntheirs\nc\n",
+			want:      "a\n<<<<<<< project\nours\n=======\ntheirs\n>>>>>>> template\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "change against deletion",
+			base:      "a\nb\nc\n",
+			ours:      "a\nB\nc\n",
+			theirs:    "a\nc\n",
+			want:      "a\n<<<<<<< project\nB\n=======\n>>>>>>> template\nc\n",
+			conflicts: 1,
+		},
+		{
+			name:      "two conflicts",
+			base:      "a\nb\nc\nd\ne\n",
+			ours:      "a1\nb\nc\nd\ne1\n",
+			theirs:    "a2\nb\nc\nd\ne2\n",
+			want:      "<<<<<<< project\na1\n=======\na2\n>>>>>>> template\nb\nc\nd\n<<<<<<< project\ne1\n=======\ne2\n>>>>>>> template\n",
+			conflicts: 2,
+		},
+		{
+			name:   "everything deleted",
+			base:   "a\nb\n",
+			ours:   "a\nb\n",
+			theirs: "",
+			want:   "",
+		},
+		{
+			name:   "added to an empty base",
+			base:   "",
+			ours:   "",
+			theirs: "a\n",
+			want:   "a\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, conflicts := Merge3(test.base, test.ours, test.theirs, "project", "template")
+			if got != test.want {
+				t.Errorf("merged = %q, want %q", got, test.want)
+			}
+			if conflicts != test.conflicts {
+				t.Errorf("conflicts = %d, want %d", conflicts, test.conflicts)
+			}
+		})
+	}
+}
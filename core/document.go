@@ -0,0 +1,587 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeDocument is a docker-compose.yml kept as a YAML node tree. Edits are
+// applied to the original text whenever possible, so comments, quoting and
+// blank lines survive.
+type ComposeDocument struct {
+	source   []byte
+	root     *yaml.Node
+	edits    []textEdit
+	blocks   []blockEdit
+	reencode bool // set when an edit cannot be expressed as a text patch
+}
+
+// textEdit replaces part of a scalar value in the original source
+type textEdit struct {
+	node   *yaml.Node
+	offset int // byte offset inside the scalar value
+	old    string
+	new    string
+}
+
+// blockEdit replaces a range of whole lines in the original source
+type blockEdit struct {
+	start int // byte offset of the first replaced line
+	end   int // byte offset just past the last replaced line
+	text  string
+}
+
+// ParseComposeDocument parses compose file content into a node tree
+func ParseComposeDocument(data []byte) (*ComposeDocument, error) {
+	done := logging.Timed("parse compose document", "bytes", len(data))
+	defer done()
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, invalidf("failed to parse YAML: %w", err)
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, invalidf("compose file must contain a YAML mapping")
+	}
+
+	return &ComposeDocument{source: data, root: &root}, nil
+}
+
+// Bytes renders the document. When every edit can be located in the original
+// text it is patched in place; otherwise the node tree is re-encoded.
+func (d *ComposeDocument) Bytes() ([]byte, error) {
+	if patched, ok := d.patchSource(); ok {
+		logging.Logger().Debug("patched compose document in place", "edits", len(d.edits), "blocks", len(d.blocks))
+		return patched, nil
+	}
+	logging.Logger().Info("re-encoding compose document; formatting and comments may change",
+		"edits", len(d.edits), "blocks", len(d.blocks))
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(d.root); err != nil {
+		return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ComposeFile decodes the document into the typed compose model
+func (d *ComposeDocument) ComposeFile() (*ComposeFile, error) {
+	data, err := d.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return ParseComposeData(data)
+}
+
+// Value decodes the document into generic mappings and lists
+func (d *ComposeDocument) Value() (interface{}, error) {
+	var value interface{}
+	if err := d.root.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to decode YAML: %w", err)
+	}
+	return value, nil
+}
+
+// SetValue updates the document to match a generic value, typically a
+// modified result of Value. Only entries whose value changed are rewritten;
+// mappings are compared key by key so unrelated siblings keep their
+// formatting. It returns the JSON Pointers of the changed entries.
+func (d *ComposeDocument) SetValue(value interface{}) ([]string, error) {
+	mapping, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, invalidf("compose file must contain a YAML mapping")
+	}
+	return d.syncMapping(d.root.Content[0], mapping, "")
+}
+
+// syncMapping rewrites the entries of a mapping node that differ from value
+func (d *ComposeDocument) syncMapping(node *yaml.Node, value map[string]interface{}, pointer string) ([]string, error) {
+	var keys []string
+	existing := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keys = append(keys, node.Content[i].Value)
+		existing[node.Content[i].Value] = node.Content[i+1]
+	}
+	var added []string
+	for key := range value {
+		if _, ok := existing[key]; !ok {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+
+	var changed []string
+	for _, key := range append(keys, added...) {
+		path := pointer + "/" + strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+		newValue, keep := value[key]
+		if !keep {
+			logging.Logger().Debug("removing entry", "path", path)
+			d.setMappingValue(node, key, nil)
+			changed = append(changed, path)
+			continue
+		}
+
+		current := existing[key]
+		if current != nil {
+			var currentValue interface{}
+			if err := current.Decode(&currentValue); err == nil && reflect.DeepEqual(currentValue, newValue) {
+				continue
+			}
+			if newMapping, ok := newValue.(map[string]interface{}); ok && current.Kind == yaml.MappingNode && len(current.Content) > 0 {
+				nested, err := d.syncMapping(current, newMapping, path)
+				if err != nil {
+					return nil, err
+				}
+				changed = append(changed, nested...)
+				continue
+			}
+		}
+
+		var encoded yaml.Node
+		if err := encoded.Encode(newValue); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", path, err)
+		}
+		logging.Logger().Debug("setting entry", "path", path, "new", current == nil)
+		d.setMappingValue(node, key, &encoded)
+		changed = append(changed, path)
+	}
+	return changed, nil
+}
+
+// section returns a top-level mapping such as services, networks or volumes
+func (d *ComposeDocument) section(name string) *yaml.Node {
+	_, value := mappingEntry(d.root.Content[0], name)
+	if value == nil || value.Kind != yaml.MappingNode {
+		return nil
+	}
+	return value
+}
+
+// replaceScalar replaces the whole value of a scalar node
+func (d *ComposeDocument) replaceScalar(node *yaml.Node, value string) {
+	d.replaceInScalar(node, 0, len(node.Value), value)
+}
+
+// replaceInScalar replaces length bytes at offset inside a scalar value
+func (d *ComposeDocument) replaceInScalar(node *yaml.Node, offset, length int, value string) {
+	d.edits = append(d.edits, textEdit{
+		node:   node,
+		offset: offset,
+		old:    node.Value[offset : offset+length],
+		new:    value,
+	})
+	node.Value = node.Value[:offset] + value + node.Value[offset+length:]
+}
+
+// patchSource applies the recorded edits to the original text. It fails when
+// a value cannot be found where the parser reported it, e.g. inside block
+// scalars or escaped strings.
+func (d *ComposeDocument) patchSource() ([]byte, bool) {
+	if d.reencode {
+		logging.Logger().Debug("cannot patch source: an edit requires re-encoding")
+		return nil, false
+	}
+	lineStarts := d.lineStarts()
+
+	type patch struct {
+		start int
+		old   string
+		new   string
+	}
+
+	// Edits of the same node shift later offsets, so locate each edit relative
+	// to the original value by accounting for earlier edits on that node
+	shifts := make(map[*yaml.Node][]textEdit)
+	var patches []patch
+	for _, edit := range d.edits {
+		node := edit.node
+		if node.Line < 1 || node.Line > len(lineStarts) {
+			logging.Logger().Debug("cannot patch source: edited value has no position", "value", edit.old)
+			return nil, false
+		}
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			logging.Logger().Debug("cannot patch source: edited value is a block scalar", "line", node.Line)
+			return nil, false
+		}
+
+		offset := edit.offset
+		for _, earlier := range shifts[node] {
+			if earlier.offset < edit.offset {
+				offset -= len(earlier.new) - len(earlier.old)
+			}
+		}
+		shifts[node] = append(shifts[node], edit)
+
+		start := lineStarts[node.Line-1] + node.Column - 1 + offset
+		if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+			start++
+		}
+		end := start + len(edit.old)
+		if start < 0 || end > len(d.source) || string(d.source[start:end]) != edit.old {
+			logging.Logger().Debug("cannot patch source: value not found at its position", "line", node.Line, "value", edit.old)
+			return nil, false
+		}
+		patches = append(patches, patch{start: start, old: edit.old, new: edit.new})
+	}
+	for _, block := range d.blocks {
+		patches = append(patches, patch{start: block.start, old: string(d.source[block.start:block.end]), new: block.text})
+	}
+
+	// Apply from the end of the file backwards. Insertions at the same offset
+	// are applied last-recorded first so they end up in recording order, and
+	// after a replacement starting at that offset so they are not replaced too.
+	for i, j := 0, len(patches)-1; i < j; i, j = i+1, j-1 {
+		patches[i], patches[j] = patches[j], patches[i]
+	}
+	sort.SliceStable(patches, func(i, j int) bool {
+		if patches[i].start != patches[j].start {
+			return patches[i].start > patches[j].start
+		}
+		return patches[i].old != "" && patches[j].old == ""
+	})
+
+	result := append([]byte{}, d.source...)
+	for i, p := range patches {
+		if i > 0 && p.start+len(p.old) > patches[i-1].start {
+			logging.Logger().Debug("cannot patch source: overlapping edits", "offset", p.start)
+			return nil, false
+		}
+		result = append(result[:p.start], append([]byte(p.new), result[p.start+len(p.old):]...)...)
+	}
+	return result, true
+}
+
+// UpdateService rewrites the fields of a service that differ between two
+// versions of its definition, leaving every other field untouched. It returns
+// the names of the changed fields.
+func (d *ComposeDocument) UpdateService(name string, oldService, newService Service) ([]string, error) {
+	_, serviceNode := mappingEntry(d.section("services"), name)
+	if serviceNode == nil || serviceNode.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("service '%s' %w", name, ErrNotFound)
+	}
+
+	var oldNode, newNode yaml.Node
+	if err := oldNode.Encode(oldService); err != nil {
+		return nil, fmt.Errorf("failed to encode service: %w", err)
+	}
+	if err := newNode.Encode(newService); err != nil {
+		return nil, fmt.Errorf("failed to encode service: %w", err)
+	}
+
+	// Keys in struct order, followed by keys that were dropped
+	var keys []string
+	seen := make(map[string]bool)
+	for _, node := range []*yaml.Node{&newNode, &oldNode} {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i].Value; !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	var changed []string
+	for _, key := range keys {
+		_, oldValue := mappingEntry(&oldNode, key)
+		_, newValue := mappingEntry(&newNode, key)
+		if nodesEqual(oldValue, newValue) {
+			continue
+		}
+		d.setMappingValue(serviceNode, key, newValue)
+		changed = append(changed, key)
+	}
+
+	return changed, nil
+}
+
+// SetEntry sets an entry of a top-level section such as services, networks
+// or volumes, creating the section when it is missing. The value is a typed
+// definition or a YAML node. The rest of the file keeps its formatting.
+func (d *ComposeDocument) SetEntry(section, name string, value interface{}) error {
+	root, err := d.Value()
+	if err != nil {
+		return err
+	}
+	mapping := root.(map[string]interface{})
+	entries, _ := mapping[section].(map[string]interface{})
+	if entries == nil {
+		entries = make(map[string]interface{})
+		mapping[section] = entries
+	}
+	entries[name] = value
+
+	_, err = d.SetValue(mapping)
+	return err
+}
+
+// MapEnvironment calls fn with every environment variable of every service,
+// in map or list form, and replaces the values fn changes in place. It returns
+// the number of values changed.
+func (d *ComposeDocument) MapEnvironment(fn func(service, name, value string) string) int {
+	services := d.section("services")
+	if services == nil {
+		return 0
+	}
+
+	changed := 0
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		service := services.Content[i].Value
+		_, environment := mappingEntry(services.Content[i+1], "environment")
+		if environment != nil && environment.Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(environment.Content); j += 2 {
+				value := environment.Content[j+1]
+				if value.Kind != yaml.ScalarNode {
+					continue
+				}
+				if newValue := fn(service, environment.Content[j].Value, value.Value); newValue != value.Value {
+					d.replaceScalar(value, newValue)
+					changed++
+				}
+			}
+		}
+		for _, item := range scalarItems(environment) {
+			name, value, found := strings.Cut(item.Value, "=")
+			if !found {
+				continue
+			}
+			if newValue := fn(service, name, value); newValue != value {
+				d.replaceInScalar(item, len(name)+1, len(value), newValue)
+				changed++
+			}
+		}
+	}
+	return changed
+}
+
+// setMappingValue sets, adds or (with a nil value) removes a mapping entry.
+// Entries of block mappings are rewritten as whole lines so the rest of the
+// file keeps its formatting.
+func (d *ComposeDocument) setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	index := -1
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			index = i
+			break
+		}
+	}
+	if index < 0 && value == nil {
+		return
+	}
+
+	patchable := mapping.Style&yaml.FlowStyle == 0 && len(mapping.Content) > 0
+	var start, end, indent int
+	if patchable {
+		anchor := index
+		if anchor < 0 {
+			// Insert after the last entry that exists in the original text
+			for i := len(mapping.Content) - 2; i >= 0; i -= 2 {
+				if mapping.Content[i].Line > 0 {
+					anchor = i
+					break
+				}
+			}
+			if anchor < 0 {
+				anchor = len(mapping.Content) - 2
+			}
+		}
+		start, end, patchable = d.entrySpan(mapping.Content[anchor])
+		indent = mapping.Content[anchor].Column - 1
+		if index < 0 {
+			start = end // insert after the last entry
+		}
+	}
+
+	switch {
+	case index < 0:
+		keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+		mapping.Content = append(mapping.Content, keyNode, value)
+	case value == nil:
+		mapping.Content = append(mapping.Content[:index], mapping.Content[index+2:]...)
+	default:
+		mapping.Content[index+1] = value
+	}
+
+	if !patchable {
+		logging.Logger().Debug("mapping entry cannot be edited as lines", "key", key, "line", mapping.Line)
+		d.reencode = true
+		return
+	}
+	text := ""
+	if value != nil {
+		rendered, err := d.renderEntry(key, value, indent)
+		if err != nil {
+			logging.Logger().Debug("failed to render mapping entry", "key", key, "error", err)
+			d.reencode = true
+			return
+		}
+		text = rendered
+	}
+	d.blocks = append(d.blocks, blockEdit{start: start, end: end, text: text})
+}
+
+// entrySpan returns the byte range of the lines making up a block mapping
+// entry: the key line and every following line indented deeper than the key
+// (or a "- " item at the same indentation). Trailing blank and comment lines
+// are left out.
+func (d *ComposeDocument) entrySpan(key *yaml.Node) (int, int, bool) {
+	lineStarts := d.lineStarts()
+	if key.Line < 1 || key.Line > len(lineStarts) {
+		return 0, 0, false
+	}
+	indent := key.Column - 1
+	lineStart := lineStarts[key.Line-1]
+	if lineStart+indent > len(d.source) || strings.TrimLeft(string(d.source[lineStart:lineStart+indent]), " ") != "" {
+		return 0, 0, false // key does not start its line
+	}
+
+	line := func(n int) string {
+		end := len(d.source)
+		if n < len(lineStarts) {
+			end = lineStarts[n]
+		}
+		return strings.TrimRight(string(d.source[lineStarts[n-1]:end]), "\r\n")
+	}
+
+	last := key.Line
+	for n := key.Line + 1; n <= len(lineStarts); n++ {
+		text := line(n)
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		lineIndent := len(text) - len(trimmed)
+		if lineIndent > indent || (lineIndent == indent && (trimmed == "-" || strings.HasPrefix(trimmed, "- "))) {
+			last = n
+			continue
+		}
+		break
+	}
+
+	end := len(d.source)
+	if last < len(lineStarts) {
+		end = lineStarts[last]
+	}
+	return lineStart, end, true
+}
+
+// renderEntry encodes a single "key: value" entry at the given indentation
+func (d *ComposeDocument) renderEntry(key string, value *yaml.Node, indent int) (string, error) {
+	quoteColonNumbers(value)
+	entry := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	}}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(d.indentStep())
+	if err := encoder.Encode(entry); err != nil {
+		return "", err
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+
+	prefix := strings.Repeat(" ", indent)
+	var builder strings.Builder
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if strings.TrimSpace(line) != "" {
+			builder.WriteString(prefix)
+		}
+		builder.WriteString(line)
+	}
+	if !bytes.HasSuffix(d.source, []byte("\n")) {
+		return strings.TrimSuffix(builder.String(), "\n"), nil
+	}
+	return builder.String(), nil
+}
+
+// colonNumber matches values such as port mappings ("8080:80") that YAML 1.1
+// readers would parse as base 60 numbers unless quoted
+var colonNumber = regexp.MustCompile(`^[0-9]+(:[0-9]+)+$`)
+
+// quoteColonNumbers double-quotes every colon-separated number in a node tree
+func quoteColonNumbers(node *yaml.Node) {
+	if node.Kind == yaml.ScalarNode && colonNumber.MatchString(node.Value) {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	for _, child := range node.Content {
+		quoteColonNumbers(child)
+	}
+}
+
+// indentStep detects the indentation width used by the document
+func (d *ComposeDocument) indentStep() int {
+	mapping := d.root.Content[0]
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 && value.Content[0].Line > key.Line {
+			if step := value.Content[0].Column - key.Column; step > 0 {
+				return step
+			}
+		}
+	}
+	return 2
+}
+
+// lineStarts returns the byte offset of every line of the original source
+func (d *ComposeDocument) lineStarts() []int {
+	starts := []int{0}
+	for i, b := range d.source {
+		if b == '\n' && i+1 < len(d.source) {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// nodesEqual compares two nodes by their decoded values
+func nodesEqual(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	var va, vb interface{}
+	if a.Decode(&va) != nil || b.Decode(&vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// mappingEntry returns the key and value nodes of a mapping entry
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// scalarItems returns the scalar items of a sequence node
+func scalarItems(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	var items []*yaml.Node
+	for _, item := range node.Content {
+		if item.Kind == yaml.ScalarNode {
+			items = append(items, item)
+		}
+	}
+	return items
+}
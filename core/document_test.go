@@ -0,0 +1,182 @@
+package core
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestEntrySpan checks the lines attributed to a block mapping entry
+func TestEntrySpan(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		key    string // service whose entry is measured
+		want   string
+	}{
+		{
+			name:   "nested mapping",
+			source: "services:\n  web:\n    image: nginx\n    ports:\n      - \"80:80\"\n  db:\n    image: postgres\n",
+			key:    "web",
+			want:   "  web:\n    image: nginx\n    ports:\n      - \"80:80\"\n",
+		},
+		{
+			name:   "trailing comment and blank lines are left out",
+			source: "services:\n  web:\n    image: nginx\n\n  # the database\n  db:\n    image: postgres\n",
+			key:    "web",
+			want:   "  web:\n    image: nginx\n",
+		},
+		{
+			name:   "inner comment is kept",
+			source: "services:\n  web:\n    # pinned\n    image: nginx\n  db:\n    image: postgres\n",
+			key:    "web",
+			want:   "  web:\n    # pinned\n    image: nginx\n",
+		},
+		{
+			name:   "last entry without trailing newline",
+			source: "services:\n  web:\n    image: nginx",
+			key:    "web",
+			want:   "  web:\n    image: nginx",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := parseTestDocument(t, test.source)
+			key, _ := mappingEntry(document.section("services"), test.key)
+			start, end, ok := document.entrySpan(key)
+			if !ok {
+				t.Fatalf("entry %s has no span", test.key)
+			}
+			if got := test.source[start:end]; got != test.want {
+				t.Fatalf("span = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	t.Run("sequence items at the key indentation", func(t *testing.T) {
+		source := "services:\n  web:\n    command:\n    - serve\n    - --port=80\n    image: nginx\n"
+		document := parseTestDocument(t, source)
+		_, web := mappingEntry(document.section("services"), "web")
+		key, _ := mappingEntry(web, "command")
+		start, end, ok := document.entrySpan(key)
+		if want := "    command:\n    - serve\n    - --port=80\n"; !ok || source[start:end] != want {
+			t.Fatalf("span = %q, want %q", source[start:end], want)
+		}
+	})
+}
+
+// TestPatchSource checks that edits are applied to the original text, and
+// that edits which cannot be located fall back to re-encoding
+func TestPatchSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		edit    func(d *ComposeDocument, web *yaml.Node)
+		want    string
+		patched bool
+	}{
+		{
+			name:   "quoted scalar keeps its quotes and comment",
+			source: "services:\n  web:\n    image: \"nginx:1.25\" # pinned\n",
+			edit: func(d *ComposeDocument, web *yaml.Node) {
+				_, image := mappingEntry(web, "image")
+				d.replaceScalar(image, "nginx:1.27")
+			},
+			want:    "services:\n  web:\n    image: \"nginx:1.27\" # pinned\n",
+			patched: true,
+		},
+		{
+			name:   "several edits of one scalar",
+			source: "services:\n  web:\n    command: serve --port 80 --host web\n",
+			edit: func(d *ComposeDocument, web *yaml.Node) {
+				_, command := mappingEntry(web, "command")
+				d.replaceInScalar(command, len("serve --port "), 2, "8080")
+				d.replaceInScalar(command, len("serve --port 8080 --host "), 3, "frontend")
+			},
+			want:    "services:\n  web:\n    command: serve --port 8080 --host frontend\n",
+			patched: true,
+		},
+		{
+			name:   "removed entry takes its lines along",
+			source: "services:\n  web:\n    image: nginx\n    ports:\n      - \"80:80\"\n    restart: always # keep\n",
+			edit: func(d *ComposeDocument, web *yaml.Node) {
+				d.setMappingValue(web, "ports", nil)
+			},
+			want:    "services:\n  web:\n    image: nginx\n    restart: always # keep\n",
+			patched: true,
+		},
+		{
+			name:   "added entry goes after the last entry",
+			source: "# stack\nservices:\n  web:\n    image: nginx\n\n# end\n",
+			edit: func(d *ComposeDocument, web *yaml.Node) {
+				d.setMappingValue(web, "ports", &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: "8080:80"},
+				}})
+			},
+			want:    "# stack\nservices:\n  web:\n    image: nginx\n    ports:\n      - \"8080:80\"\n\n# end\n",
+			patched: true,
+		},
+		{
+			name:   "replaced entry keeps its neighbours",
+			source: "services:\n  web:\n    image: nginx # front\n    environment:\n      A: \"1\"\n    restart: always\n",
+			edit: func(d *ComposeDocument, web *yaml.Node) {
+				d.setMappingValue(web, "environment", &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: "B"},
+					{Kind: yaml.ScalarNode, Tag: "!!str", Value: "2"},
+				}})
+			},
+			want:    "services:\n  web:\n    image: nginx # front\n    environment:\n      B: \"2\"\n    restart: always\n",
+			patched: true,
+		},
+		{
+			name:   "block scalar is re-encoded",
+			source: "services:\n  web:\n    command: |\n      serve --port 80\n",
+			edit: func(d *ComposeDocument, web *yaml.Node) {
+				_, command := mappingEntry(web, "command")
+				d.replaceScalar(command, "serve --port 8080\n")
+			},
+			patched: false,
+		},
+		{
+			name:   "flow mapping is re-encoded",
+			source: "services:\n  web: {image: nginx}\n",
+			edit: func(d *ComposeDocument, web *yaml.Node) {
+				d.setMappingValue(web, "restart", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "always"})
+			},
+			patched: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := parseTestDocument(t, test.source)
+			_, web := mappingEntry(document.section("services"), "web")
+			test.edit(document, web)
+
+			got, ok := document.patchSource()
+			if ok != test.patched {
+				t.Fatalf("patched = %v, want %v", ok, test.patched)
+			}
+			if ok && string(got) != test.want {
+				t.Fatalf("patched source = %q, want %q", got, test.want)
+			}
+			if !ok {
+				// The fallback must still render the edit
+				if _, err := document.Bytes(); err != nil {
+					t.Fatalf("failed to re-encode: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// parseTestDocument parses a compose document or fails the test
+func parseTestDocument(t *testing.T, source string) *ComposeDocument {
+	t.Helper()
+	document, err := ParseComposeDocument([]byte(source))
+	if err != nil {
+		t.Fatalf("failed to parse document: %v", err)
+	}
+	return document
+}
@@ -0,0 +1,301 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// validEnvKey matches variable names in .env files
+var validEnvKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// EnvFile is the content of a .env file. Comments, blank lines and the
+// formatting of the entries that are not changed are kept, so editing a
+// variable leaves the rest of the file as it was.
+//
+// The syntax is the one docker compose reads: KEY=VALUE lines with an
+// optional export prefix, values unquoted (an inline comment starts at a #
+// after whitespace), single-quoted (literal) or double-quoted (with \n, \t,
+// \" and \\ escapes). Quoted values may span several lines. ${VAR}
+// references in values are kept as they are.
+type EnvFile struct {
+	lines []envLine
+}
+
+// envLine is a line of a .env file, or several for a multiline value
+type envLine struct {
+	text    string // source text without the final newline
+	key     string // empty for comments and blank lines
+	value   string
+	export  bool
+	comment string // inline comment after the value, from the #
+}
+
+// EnvEntry is a variable defined in a .env file
+type EnvEntry struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Line   int    `json:"line"` // first line of the definition, from 1
+	Export bool   `json:"export,omitempty"`
+}
+
+// ReadEnvFile reads and parses a .env file. Errors wrap os.ErrNotExist when
+// the file does not exist.
+func ReadEnvFile(path string) (*EnvFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	file, err := ParseEnvFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// ParseEnvFile parses the content of a .env file
+func ParseEnvFile(data []byte) (*EnvFile, error) {
+	file := &EnvFile{}
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if len(data) == 0 {
+		return file, nil
+	}
+
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		start := i
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			file.lines = append(file.lines, envLine{text: lines[i]})
+			continue
+		}
+
+		var entry envLine
+		rest := trimmed
+		if after, ok := strings.CutPrefix(rest, "export"); ok && (strings.HasPrefix(after, " ") || strings.HasPrefix(after, "\t")) {
+			entry.export = true
+			rest = strings.TrimLeft(after, " \t")
+		}
+		key, value, found := strings.Cut(rest, "=")
+		entry.key = strings.TrimSpace(key)
+		if !found || !validEnvKey.MatchString(entry.key) {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE, got %q", start+1, lines[i])
+		}
+		raw := value
+		value = strings.TrimLeft(value, " \t")
+
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			if index := inlineComment(raw); index >= 0 {
+				entry.comment = strings.TrimSpace(raw[index:])
+				raw = raw[:index]
+			}
+			entry.value = strings.TrimSpace(raw)
+			entry.text = lines[i]
+			file.lines = append(file.lines, entry)
+			continue
+		}
+
+		// Quoted values end at the matching quote, possibly on a later line
+		quote := value[0]
+		body := value[1:]
+		end := closingQuote(body, quote)
+		for end < 0 {
+			if i+1 == len(lines) {
+				return nil, fmt.Errorf("line %d: unterminated quoted value of %s", start+1, entry.key)
+			}
+			i++
+			body += "\n" + lines[i]
+			end = closingQuote(body, quote)
+		}
+		after := strings.TrimSpace(body[end+1:])
+		if after != "" && !strings.HasPrefix(after, "#") {
+			return nil, fmt.Errorf("line %d: unexpected %q after the quoted value of %s", i+1, after, entry.key)
+		}
+		entry.comment = after
+		entry.value = body[:end]
+		if quote == '"' {
+			entry.value = unescapeEnvValue(entry.value)
+		}
+		entry.text = strings.Join(lines[start:i+1], "\n")
+		file.lines = append(file.lines, entry)
+	}
+	return file, nil
+}
+
+// inlineComment returns the index of the # starting an inline comment in an
+// unquoted value, or -1
+func inlineComment(value string) int {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return i
+		}
+	}
+	return -1
+}
+
+// closingQuote returns the index of the quote ending a quoted value, or -1.
+// Double-quoted values may escape quotes with a backslash.
+func closingQuote(body string, quote byte) int {
+	for i := 0; i < len(body); i++ {
+		switch {
+		case body[i] == '\\' && quote == '"':
+			i++
+		case body[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeEnvValue expands the escapes of a double-quoted value. Unknown
+// escapes are kept as they are.
+func unescapeEnvValue(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+	var out strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			out.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case '"', '\\':
+			out.WriteByte(value[i])
+		default:
+			out.WriteByte('\\')
+			out.WriteByte(value[i])
+		}
+	}
+	return out.String()
+}
+
+// FormatEnvValue returns a value as it is written in a .env file: unquoted
+// when that is unambiguous, double-quoted with escapes when it contains
+// newlines or single quotes, and single-quoted otherwise
+func FormatEnvValue(value string) string {
+	if !strings.ContainsAny(value, " \t\r\n\"'#\\") {
+		return value
+	}
+	if !strings.ContainsAny(value, "\r\n'") {
+		return "'" + value + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	return `"` + replacer.Replace(value) + `"`
+}
+
+// Entries returns the variables defined in the file, in file order. A
+// variable defined several times is listed every time; the last definition
+// wins.
+func (f *EnvFile) Entries() []EnvEntry {
+	entries := []EnvEntry{}
+	line := 1
+	for _, l := range f.lines {
+		if l.key != "" {
+			entries = append(entries, EnvEntry{Key: l.key, Value: l.value, Line: line, Export: l.export})
+		}
+		line += strings.Count(l.text, "\n") + 1
+	}
+	return entries
+}
+
+// Keys returns the names of the variables defined in the file, in order of
+// first definition
+func (f *EnvFile) Keys() []string {
+	keys := []string{}
+	for _, l := range f.lines {
+		if l.key != "" && !slices.Contains(keys, l.key) {
+			keys = append(keys, l.key)
+		}
+	}
+	return keys
+}
+
+// Lookup returns the value of a variable, from its last definition
+func (f *EnvFile) Lookup(key string) (string, bool) {
+	for i := len(f.lines) - 1; i >= 0; i-- {
+		if f.lines[i].key == key {
+			return f.lines[i].value, true
+		}
+	}
+	return "", false
+}
+
+// Set defines a variable. The last definition is changed in place, keeping
+// its export prefix and inline comment, and earlier ones are removed; a new
+// variable is appended. It reports whether the variable was defined before.
+func (f *EnvFile) Set(key, value string) (bool, error) {
+	if !validEnvKey.MatchString(key) {
+		return false, fmt.Errorf("invalid variable name '%s'", key)
+	}
+	last := -1
+	for i, l := range f.lines {
+		if l.key == key {
+			last = i
+		}
+	}
+	if last < 0 {
+		entry := envLine{key: key, value: value}
+		entry.text = entry.render()
+		f.lines = append(f.lines, entry)
+		return false, nil
+	}
+
+	f.lines[last].value = value
+	f.lines[last].text = f.lines[last].render()
+	lines := f.lines[:0]
+	for i, l := range f.lines {
+		if l.key != key || i == last {
+			lines = append(lines, l)
+		}
+	}
+	f.lines = lines
+	return true, nil
+}
+
+// Unset removes every definition of a variable and reports whether there
+// was one
+func (f *EnvFile) Unset(key string) bool {
+	lines := f.lines[:0]
+	for _, l := range f.lines {
+		if l.key != key {
+			lines = append(lines, l)
+		}
+	}
+	removed := len(lines) < len(f.lines)
+	f.lines = lines
+	return removed
+}
+
+// Bytes returns the content of the file
+func (f *EnvFile) Bytes() []byte {
+	if len(f.lines) == 0 {
+		return nil
+	}
+	var out strings.Builder
+	for _, l := range f.lines {
+		out.WriteString(l.text + "\n")
+	}
+	return []byte(out.String())
+}
+
+// render formats an entry as a line
+func (l envLine) render() string {
+	text := l.key + "=" + FormatEnvValue(l.value)
+	if l.export {
+		text = "export " + text
+	}
+	if l.comment != "" {
+		text += " " + l.comment
+	}
+	return text
+}
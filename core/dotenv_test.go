@@ -0,0 +1,180 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestParseEnvFile parses the value syntaxes docker compose reads
+func TestParseEnvFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		entries []EnvEntry // nil when parsing must fail
+	}{
+		{
+			name: "unquoted values, comments and blank lines",
+			data: "# settings\n\nPORT=8080\nHOST = db \nEMPTY=\n",
+			entries: []EnvEntry{
+				{Key: "PORT", Value: "8080", Line: 3},
+				{Key: "HOST", Value: "db", Line: 4},
+				{Key: "EMPTY", Value: "", Line: 5},
+			},
+		},
+		{
+			name: "inline comments",
+			data: "A=1 # one\nB=x#y\nC=\t# empty\n",
+			entries: []EnvEntry{
+				{Key: "A", Value: "1", Line: 1},
+				{Key: "B", Value: "x#y", Line: 2},
+				{Key: "C", Value: "", Line: 3},
+			},
+		},
+		{
+			name: "quoted values",
+			data: "A='a # b \\n'\nB=\"tab\\there \\\"q\\\" \\\\ \\x\"\nC=\"x\" # note\n",
+			entries: []EnvEntry{
+				{Key: "A", Value: `a # b \n`, Line: 1},
+				{Key: "B", Value: "tab\there \"q\" \\ \\x", Line: 2},
+				{Key: "C", Value: "x", Line: 3},
+			},
+		},
+		{
+			name: "multiline value",
+			data: "KEY=\"-----BEGIN-----\nabc\n-----END-----\"\nNEXT=1\n",
+			entries: []EnvEntry{
+				{Key: "KEY", Value: "-----BEGIN-----\nabc\n-----END-----", Line: 1},
+				{Key: "NEXT", Value: "1", Line: 4},
+			},
+		},
+		{
+			name: "export prefix and references",
+			data: "export TOKEN=abc\r\nURL=http://${HOST}:${PORT}\r\n",
+			entries: []EnvEntry{
+				{Key: "TOKEN", Value: "abc", Line: 1, Export: true},
+				{Key: "URL", Value: "http://${HOST}:${PORT}", Line: 2},
+			},
+		},
+		{
+			name:    "empty file",
+			data:    "",
+			entries: []EnvEntry{},
+		},
+		{name: "missing equals sign", data: "PORT\n"},
+		{name: "invalid key", data: "1PORT=80\n"},
+		{name: "unterminated quote", data: "A=\"abc\nB=1\n"},
+		{name: "text after a quoted value", data: "A='x' y\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := ParseEnvFile([]byte(test.data))
+			if test.entries == nil {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", file.Entries())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if got := file.Entries(); !reflect.DeepEqual(got, test.entries) {
+				t.Fatalf("entries = %+v, want %+v", got, test.entries)
+			}
+		})
+	}
+}
+
+// TestEnvFileEdits checks that Set and Unset only touch the edited variable
+func TestEnvFileEdits(t *testing.T) {
+	source := "# db\nexport DB_HOST=db # primary\nDB_PORT=5432\n\nDB_PORT=5433\n"
+	tests := []struct {
+		name    string
+		edit    func(f *EnvFile) (bool, error)
+		want    string
+		defined bool
+	}{
+		{
+			name:    "set keeps export and comment",
+			edit:    func(f *EnvFile) (bool, error) { return f.Set("DB_HOST", "replica") },
+			want:    "# db\nexport DB_HOST=replica # primary\nDB_PORT=5432\n\nDB_PORT=5433\n",
+			defined: true,
+		},
+		{
+			name:    "set drops earlier definitions",
+			edit:    func(f *EnvFile) (bool, error) { return f.Set("DB_PORT", "6432") },
+			want:    "# db\nexport DB_HOST=db # primary\n\nDB_PORT=6432\n",
+			defined: true,
+		},
+		{
+			name: "set appends a new variable",
+			edit: func(f *EnvFile) (bool, error) { return f.Set("DB_PASSWORD", "p@ss word") },
+			want: source + "DB_PASSWORD='p@ss word'\n",
+		},
+		{
+			name:    "unset removes every definition",
+			edit:    func(f *EnvFile) (bool, error) { return f.Unset("DB_PORT"), nil },
+			want:    "# db\nexport DB_HOST=db # primary\n\n",
+			defined: true,
+		},
+		{
+			name: "unset of a missing variable",
+			edit: func(f *EnvFile) (bool, error) { return f.Unset("DB_USER"), nil },
+			want: source,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file, err := ParseEnvFile([]byte(source))
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			defined, err := test.edit(file)
+			if err != nil {
+				t.Fatalf("failed to edit: %v", err)
+			}
+			if defined != test.defined {
+				t.Errorf("defined = %v, want %v", defined, test.defined)
+			}
+			if got := string(file.Bytes()); got != test.want {
+				t.Errorf("content = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	t.Run("invalid name", func(t *testing.T) {
+		file, _ := ParseEnvFile([]byte(source))
+		if _, err := file.Set("DB HOST", "x"); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+// TestFormatEnvValue checks that formatted values parse back unchanged
+func TestFormatEnvValue(t *testing.T) {
+	tests := map[string]string{
+		"plain":       "plain",
+		"":            "",
+		"two words":   "'two words'",
+		"a#b":         "'a#b'",
+		`say "hi"`:    `'say "hi"'`,
+		"it's":        `"it's"`,
+		"line\nbreak": `"line\nbreak"`,
+		`back\slash`:  `'back\slash'`,
+	}
+	for value, want := range tests {
+		got := FormatEnvValue(value)
+		if got != want {
+			t.Errorf("FormatEnvValue(%q) = %q, want %q", value, got, want)
+		}
+		file, err := ParseEnvFile([]byte("KEY=" + got + "\n"))
+		if err != nil {
+			t.Errorf("failed to parse KEY=%s: %v", got, err)
+			continue
+		}
+		if parsed, _ := file.Lookup("KEY"); parsed != value {
+			t.Errorf("KEY=%s parses as %q, want %q", got, parsed, value)
+		}
+	}
+}
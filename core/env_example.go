@@ -0,0 +1,66 @@
+package core
+
+import "strings"
+
+// envExampleHeader starts every generated .env.example
+const envExampleHeader = `# Environment variables interpolated in the compose file, by service.
+# Copy this file to .env and adjust the values.
+`
+
+// EnvExample returns .env.example content for compose file content: every
+// variable it interpolates, set to the default of its ${VAR:-default}
+// expression or left empty. Variables are grouped under the first service
+// using them, in order of use; the variables used outside services come
+// last. descriptions, by variable name, are written as comments.
+func EnvExample(data []byte, descriptions map[string]string) ([]byte, error) {
+	refs, err := EnvReferences(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []string
+	byGroup := make(map[string][]EnvReference)
+	for _, ref := range refs {
+		group := ""
+		if len(ref.Services) > 0 {
+			group = ref.Services[0]
+		}
+		if _, ok := byGroup[group]; !ok && group != "" {
+			groups = append(groups, group)
+		}
+		byGroup[group] = append(byGroup[group], ref)
+	}
+	if _, ok := byGroup[""]; ok {
+		groups = append(groups, "")
+	}
+
+	var out strings.Builder
+	out.WriteString(envExampleHeader)
+	for _, group := range groups {
+		if group == "" {
+			out.WriteString("\n# Other\n")
+		} else {
+			out.WriteString("\n# Service: " + group + "\n")
+		}
+		for _, ref := range byGroup[group] {
+			var notes []string
+			if len(ref.Services) > 1 {
+				notes = append(notes, "also used by "+strings.Join(ref.Services[1:], ", "))
+			}
+			if ref.Required {
+				notes = append(notes, "required")
+			}
+			note := strings.Join(notes, "; ")
+			switch description := descriptions[ref.Name]; {
+			case description != "" && note != "":
+				out.WriteString("# " + description + " (" + note + ")\n")
+			case description != "":
+				out.WriteString("# " + description + "\n")
+			case note != "":
+				out.WriteString("# " + strings.ToUpper(note[:1]) + note[1:] + "\n")
+			}
+			out.WriteString(ref.Name + "=" + FormatEnvValue(ref.Default) + "\n")
+		}
+	}
+	return []byte(out.String()), nil
+}
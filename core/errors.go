@@ -0,0 +1,32 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors wrapped by lookups and additions so callers can tell the
+// failure apart from other errors with errors.Is. Messages read naturally when
+// wrapped, e.g. fmt.Errorf("service '%s' %w", name, ErrNotFound).
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrInvalid marks compose content that cannot be parsed or whose
+	// dependencies cannot be resolved. It is attached with invalidf rather
+	// than wrapped, so messages stay as they were.
+	ErrInvalid = errors.New("invalid compose file")
+)
+
+// invalidError is an error that also matches ErrInvalid
+type invalidError struct {
+	err error
+}
+
+func (e *invalidError) Error() string { return e.err.Error() }
+
+func (e *invalidError) Unwrap() []error { return []error{e.err, ErrInvalid} }
+
+// invalidf formats an error like fmt.Errorf and marks it as ErrInvalid
+func invalidf(format string, args ...interface{}) error {
+	return &invalidError{err: fmt.Errorf(format, args...)}
+}
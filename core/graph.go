@@ -1,14 +1,16 @@
 package core
 
 import (
-	"fmt"
 	"sort"
+
+	"github.com/firasmosbahi/container-composer/internal/logging"
 )
 
 // DependencyGraph represents the complete dependency graph
 type DependencyGraph struct {
 	Services         map[string]*ServiceNode
 	CircularDeps     [][]string
+	CycleGroups      [][]string
 	TopologicalOrder []string
 }
 
@@ -46,6 +48,8 @@ type Relationship struct {
 
 // BuildDependencyGraph creates a complete dependency graph
 func (cf *ComposeFile) BuildDependencyGraph() (*DependencyGraph, error) {
+	done := logging.Timed("build dependency graph", "services", len(cf.Services))
+
 	graph := &DependencyGraph{
 		Services: make(map[string]*ServiceNode),
 	}
@@ -73,7 +77,8 @@ func (cf *ComposeFile) BuildDependencyGraph() (*DependencyGraph, error) {
 		for _, depName := range node.Service.DependsOn {
 			depNode, exists := graph.Services[depName]
 			if !exists {
-				return nil, fmt.Errorf("service '%s' depends on non-existent service '%s'", name, depName)
+				done("error", "missing dependency", "service", name, "dependency", depName)
+				return nil, invalidf("service '%s' depends on non-existent service '%s'", name, depName)
 			}
 			node.DependsOn = append(node.DependsOn, depNode)
 			depNode.DependedBy = append(depNode.DependedBy, node)
@@ -87,7 +92,7 @@ func (cf *ComposeFile) BuildDependencyGraph() (*DependencyGraph, error) {
 	graph.buildVolumeRelationships()
 
 	// Step 5: Detect circular dependencies
-	graph.CircularDeps = graph.detectCircularDependencies()
+	graph.detectCycles()
 
 	// Step 6: Calculate topological order (if no cycles)
 	if len(graph.CircularDeps) == 0 {
@@ -95,8 +100,11 @@ func (cf *ComposeFile) BuildDependencyGraph() (*DependencyGraph, error) {
 		if err == nil {
 			graph.TopologicalOrder = order
 		}
+	} else {
+		logging.Logger().Info("skipping topological order because of dependency cycles", "cycles", graph.CircularDeps)
 	}
 
+	done("cycles", len(graph.CircularDeps))
 	return graph, nil
 }
 
@@ -152,8 +160,8 @@ func extractVolumeName(volumeMount string) string {
 	if len(volumeMount) == 0 {
 		return ""
 	}
-	if volumeMount[0] == '/' || volumeMount[0] == '.' {
-		return "" // bind mount or relative path
+	if volumeMount[0] == '/' || volumeMount[0] == '.' || volumeMount[0] == '~' || volumeMount[0] == '$' {
+		return "" // bind mount, relative or home path, or variable
 	}
 
 	// Named volume - extract the part before the colon
@@ -166,58 +174,6 @@ func extractVolumeName(volumeMount string) string {
 	return volumeMount
 }
 
-// detectCircularDependencies finds all circular dependency chains using DFS
-func (g *DependencyGraph) detectCircularDependencies() [][]string {
-	var cycles [][]string
-	visited := make(map[string]bool)
-	recStack := make(map[string]bool)
-
-	for serviceName := range g.Services {
-		if !visited[serviceName] {
-			g.dfsDetectCycle(serviceName, visited, recStack, []string{}, &cycles)
-		}
-	}
-
-	return cycles
-}
-
-// dfsDetectCycle performs DFS to detect cycles
-func (g *DependencyGraph) dfsDetectCycle(
-	current string,
-	visited map[string]bool,
-	recStack map[string]bool,
-	path []string,
-	cycles *[][]string,
-) {
-	visited[current] = true
-	recStack[current] = true
-	path = append(path, current)
-
-	node := g.Services[current]
-	for _, dep := range node.DependsOn {
-		if !visited[dep.Name] {
-			g.dfsDetectCycle(dep.Name, visited, recStack, path, cycles)
-		} else if recStack[dep.Name] {
-			// Found cycle - extract the cycle from path
-			cycleStart := -1
-			for i, s := range path {
-				if s == dep.Name {
-					cycleStart = i
-					break
-				}
-			}
-			if cycleStart >= 0 {
-				cycle := make([]string, len(path)-cycleStart)
-				copy(cycle, path[cycleStart:])
-				cycle = append(cycle, dep.Name) // Complete the cycle
-				*cycles = append(*cycles, cycle)
-			}
-		}
-	}
-
-	recStack[current] = false
-}
-
 // topologicalSort orders services by dependency using Kahn's algorithm
 func (g *DependencyGraph) topologicalSort() ([]string, error) {
 	// Calculate in-degree for each node
@@ -267,76 +223,16 @@ func (g *DependencyGraph) topologicalSort() ([]string, error) {
 
 	// If result doesn't contain all nodes, there's a cycle
 	if len(result) != len(g.Services) {
-		return nil, fmt.Errorf("circular dependencies detected")
+		return nil, invalidf("circular dependencies detected")
 	}
 
 	return result, nil
 }
 
-// FilterByService returns a subgraph containing only the specified service and its dependencies
+// FilterByService returns a subgraph containing only the specified service,
+// its dependencies and its dependents, up to depth hops (-1 = unlimited)
 func (g *DependencyGraph) FilterByService(serviceName string, depth int) (*DependencyGraph, error) {
-	node, exists := g.Services[serviceName]
-	if !exists {
-		return nil, fmt.Errorf("service '%s' not found", serviceName)
-	}
-
-	filtered := &DependencyGraph{
-		Services: make(map[string]*ServiceNode),
-	}
-
-	visited := make(map[string]bool)
-	g.collectDependencies(node, filtered, visited, depth, 0)
-
-	// Rebuild relationships in the filtered graph
-	filtered.rebuildRelationships()
-
-	// Detect cycles in filtered graph
-	filtered.CircularDeps = filtered.detectCircularDependencies()
-
-	return filtered, nil
-}
-
-// collectDependencies recursively collects a service and its dependencies
-func (g *DependencyGraph) collectDependencies(
-	node *ServiceNode,
-	filtered *DependencyGraph,
-	visited map[string]bool,
-	maxDepth int,
-	currentDepth int,
-) {
-	if visited[node.Name] {
-		return
-	}
-	if maxDepth >= 0 && currentDepth > maxDepth {
-		return
-	}
-
-	visited[node.Name] = true
-
-	// Create a copy of the node for the filtered graph
-	nodeCopy := &ServiceNode{
-		Name:           node.Name,
-		Service:        node.Service,
-		DependsOn:      []*ServiceNode{},
-		DependedBy:     []*ServiceNode{},
-		Networks:       node.Networks,
-		NetworkPeers:   make(map[string][]*ServiceNode),
-		Volumes:        node.Volumes,
-		VolumePeers:    make(map[string][]*ServiceNode),
-		HasHealthCheck: node.HasHealthCheck,
-		HealthCheck:    node.HealthCheck,
-	}
-	filtered.Services[node.Name] = nodeCopy
-
-	// Recursively collect dependencies
-	for _, dep := range node.DependsOn {
-		g.collectDependencies(dep, filtered, visited, maxDepth, currentDepth+1)
-	}
-
-	// Also collect dependents (services that depend on this one)
-	for _, dependent := range node.DependedBy {
-		g.collectDependencies(dependent, filtered, visited, maxDepth, currentDepth+1)
-	}
+	return g.Apply(FilterRoots([]string{serviceName}, depth, DirectionBoth))
 }
 
 // rebuildRelationships rebuilds dependency relationships in a filtered graph
@@ -410,6 +306,21 @@ func (g *DependencyGraph) GetAllRelationships() []Relationship {
 		}
 	}
 
+	// Sort for consistent output
+	sort.Slice(relationships, func(i, j int) bool {
+		a, b := relationships[i], relationships[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Metadata < b.Metadata
+	})
+
 	return relationships
 }
 
@@ -0,0 +1,428 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxExhaustiveCycleEdges bounds the number of edges inside a single strongly
+// connected component for which the minimal edge-removal set is searched
+// exhaustively. Larger components fall back to removing DFS back edges.
+const maxExhaustiveCycleEdges = 16
+
+// DependencyEdge represents a single depends_on edge between two services
+type DependencyEdge struct {
+	From                 string `json:"from"`
+	To                   string `json:"to"`
+	TargetHasHealthCheck bool   `json:"target_has_health_check"`
+}
+
+// CycleFix describes the depends_on edges to remove to break every cycle
+// inside one strongly connected component
+type CycleFix struct {
+	Services []string         `json:"services"`
+	Remove   []DependencyEdge `json:"remove"`
+}
+
+// detectCycles finds strongly connected components using Tarjan's algorithm
+// and records every component that contains a cycle, together with one
+// representative cycle per component
+func (g *DependencyGraph) detectCycles() {
+	g.CycleGroups = g.stronglyConnectedComponents()
+	g.CircularDeps = nil
+	for _, group := range g.CycleGroups {
+		g.CircularDeps = append(g.CircularDeps, g.representativeCycle(group))
+	}
+}
+
+// stronglyConnectedComponents returns all components that contain a cycle
+// (more than one service, or a service depending on itself). Services inside
+// a component are sorted and components are ordered by their first service.
+func (g *DependencyGraph) stronglyConnectedComponents() [][]string {
+	index := 0
+	indices := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var strongConnect func(name string)
+	strongConnect = func(name string) {
+		indices[name] = index
+		lowLink[name] = index
+		index++
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, dep := range g.sortedDependencies(name) {
+			if _, seen := indices[dep]; !seen {
+				strongConnect(dep)
+				if lowLink[dep] < lowLink[name] {
+					lowLink[name] = lowLink[dep]
+				}
+			} else if onStack[dep] && indices[dep] < lowLink[name] {
+				lowLink[name] = indices[dep]
+			}
+		}
+
+		if lowLink[name] != indices[name] {
+			return
+		}
+
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == name {
+				break
+			}
+		}
+
+		if len(component) > 1 || g.dependsOnItself(name) {
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+
+	for _, name := range g.sortedServiceNames() {
+		if _, seen := indices[name]; !seen {
+			strongConnect(name)
+		}
+	}
+
+	sort.Slice(components, func(i, j int) bool {
+		return components[i][0] < components[j][0]
+	})
+
+	return components
+}
+
+// representativeCycle returns the shortest cycle through the first service of
+// a component, closed by repeating the starting service
+func (g *DependencyGraph) representativeCycle(group []string) []string {
+	start := group[0]
+	members := make(map[string]bool)
+	for _, name := range group {
+		members[name] = true
+	}
+
+	// BFS restricted to the component until we get back to the start
+	parent := make(map[string]string)
+	queue := []string{start}
+	visited := map[string]bool{start: true}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range g.sortedDependencies(current) {
+			if !members[dep] {
+				continue
+			}
+			if dep == start {
+				cycle := []string{start}
+				for at := current; at != start; at = parent[at] {
+					cycle = append([]string{at}, cycle...)
+				}
+				cycle = append([]string{start}, cycle...)
+				return cycle
+			}
+			if !visited[dep] {
+				visited[dep] = true
+				parent[dep] = current
+				queue = append(queue, dep)
+			}
+		}
+	}
+
+	return append([]string{}, group...)
+}
+
+// SuggestCycleFixes suggests, for each cyclic component, the smallest set of
+// depends_on edges whose removal breaks all cycles in it. Among sets of equal
+// size, edges pointing at services without a health check are preferred,
+// since those dependencies only order startup and carry no readiness gate.
+func (g *DependencyGraph) SuggestCycleFixes() []CycleFix {
+	var fixes []CycleFix
+	for _, group := range g.CycleGroups {
+		edges := g.componentEdges(group)
+
+		var remove []DependencyEdge
+		if len(edges) <= maxExhaustiveCycleEdges {
+			remove = g.minimalFeedbackEdges(group, edges)
+		} else {
+			remove = g.backEdges(group)
+		}
+
+		fixes = append(fixes, CycleFix{
+			Services: group,
+			Remove:   remove,
+		})
+	}
+	return fixes
+}
+
+// componentEdges returns all depends_on edges between services of a component,
+// edges to services without a health check first
+func (g *DependencyGraph) componentEdges(group []string) []DependencyEdge {
+	members := make(map[string]bool)
+	for _, name := range group {
+		members[name] = true
+	}
+
+	var edges []DependencyEdge
+	for _, name := range group {
+		for _, dep := range g.sortedDependencies(name) {
+			if members[dep] {
+				edges = append(edges, DependencyEdge{
+					From:                 name,
+					To:                   dep,
+					TargetHasHealthCheck: g.Services[dep].HasHealthCheck,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(edges, func(i, j int) bool {
+		return !edges[i].TargetHasHealthCheck && edges[j].TargetHasHealthCheck
+	})
+
+	return edges
+}
+
+// minimalFeedbackEdges searches removal sets of increasing size and returns
+// the first size that makes the component acyclic, picking the set that
+// removes the fewest edges to health-checked services
+func (g *DependencyGraph) minimalFeedbackEdges(group []string, edges []DependencyEdge) []DependencyEdge {
+	for size := 1; size <= len(edges); size++ {
+		var best []int
+		bestScore := -1
+
+		combination := make([]int, size)
+		var search func(start, depth int)
+		search = func(start, depth int) {
+			if depth == size {
+				if !g.isAcyclicWithout(group, edges, combination) {
+					return
+				}
+				score := 0
+				for _, i := range combination {
+					if edges[i].TargetHasHealthCheck {
+						score++
+					}
+				}
+				if bestScore < 0 || score < bestScore {
+					bestScore = score
+					best = append([]int{}, combination...)
+				}
+				return
+			}
+			for i := start; i < len(edges); i++ {
+				combination[depth] = i
+				search(i+1, depth+1)
+			}
+		}
+		search(0, 0)
+
+		if best != nil {
+			result := make([]DependencyEdge, len(best))
+			for i, idx := range best {
+				result[i] = edges[idx]
+			}
+			return result
+		}
+	}
+	return nil
+}
+
+// isAcyclicWithout checks whether a component becomes acyclic once the
+// selected edges are removed, using Kahn's algorithm on the component
+func (g *DependencyGraph) isAcyclicWithout(group []string, edges []DependencyEdge, removed []int) bool {
+	skip := make(map[int]bool)
+	for _, i := range removed {
+		skip[i] = true
+	}
+
+	inDegree := make(map[string]int)
+	adjacency := make(map[string][]string)
+	for _, name := range group {
+		inDegree[name] = 0
+	}
+	for i, edge := range edges {
+		if skip[i] {
+			continue
+		}
+		adjacency[edge.From] = append(adjacency[edge.From], edge.To)
+		inDegree[edge.To]++
+	}
+
+	var queue []string
+	for _, name := range group {
+		if inDegree[name] == 0 {
+			queue = append(queue, name)
+		}
+	}
+
+	processed := 0
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		processed++
+		for _, next := range adjacency[current] {
+			inDegree[next]--
+			if inDegree[next] == 0 {
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return processed == len(group)
+}
+
+// backEdges returns the back edges of a deterministic DFS over a component.
+// Removing them always breaks every cycle, although not necessarily with the
+// smallest number of edges.
+func (g *DependencyGraph) backEdges(group []string) []DependencyEdge {
+	members := make(map[string]bool)
+	for _, name := range group {
+		members[name] = true
+	}
+
+	visited := make(map[string]bool)
+	onPath := make(map[string]bool)
+	var result []DependencyEdge
+
+	var visit func(name string)
+	visit = func(name string) {
+		visited[name] = true
+		onPath[name] = true
+		for _, dep := range g.sortedDependencies(name) {
+			if !members[dep] {
+				continue
+			}
+			if onPath[dep] {
+				result = append(result, DependencyEdge{
+					From:                 name,
+					To:                   dep,
+					TargetHasHealthCheck: g.Services[dep].HasHealthCheck,
+				})
+			} else if !visited[dep] {
+				visit(dep)
+			}
+		}
+		onPath[name] = false
+	}
+
+	for _, name := range group {
+		if !visited[name] {
+			visit(name)
+		}
+	}
+
+	return result
+}
+
+// FormatCycleFixPatch renders suggested fixes as a YAML patch that replaces
+// the depends_on list of every affected service
+func (g *DependencyGraph) FormatCycleFixPatch(fixes []CycleFix) (string, error) {
+	removed := make(map[string]map[string]bool)
+	var comments []string
+
+	for i, fix := range fixes {
+		cycle := strings.Join(fix.Services, ", ")
+		if i < len(g.CircularDeps) && len(g.CircularDeps[i]) == len(fix.Services)+1 {
+			cycle = strings.Join(g.CircularDeps[i], " → ")
+		}
+		comments = append(comments, "Cycle: "+cycle)
+		for _, edge := range fix.Remove {
+			if removed[edge.From] == nil {
+				removed[edge.From] = make(map[string]bool)
+			}
+			removed[edge.From][edge.To] = true
+
+			reason := "target has no healthcheck"
+			if edge.TargetHasHealthCheck {
+				reason = "target has a healthcheck"
+			}
+			comments = append(comments, fmt.Sprintf("  remove %s → %s (%s)", edge.From, edge.To, reason))
+		}
+	}
+
+	var names []string
+	for name := range removed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	servicesNode := &yaml.Node{Kind: yaml.MappingNode}
+	for _, name := range names {
+		dependsOn := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, dep := range g.Services[name].Service.DependsOn {
+			if !removed[name][dep] {
+				dependsOn.Content = append(dependsOn.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: dep})
+			}
+		}
+		if len(dependsOn.Content) == 0 {
+			dependsOn.Style = yaml.FlowStyle
+		}
+
+		serviceNode := &yaml.Node{Kind: yaml.MappingNode}
+		serviceNode.Content = append(serviceNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "depends_on"}, dependsOn)
+		servicesNode.Content = append(servicesNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: name}, serviceNode)
+	}
+
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	root.Content = append(root.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: "services", HeadComment: strings.Join(comments, "\n")},
+		servicesNode)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return "", fmt.Errorf("failed to encode patch: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", fmt.Errorf("failed to encode patch: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// sortedServiceNames returns all service names in alphabetical order
+func (g *DependencyGraph) sortedServiceNames() []string {
+	names := make([]string, 0, len(g.Services))
+	for name := range g.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortedDependencies returns the names of a service's dependencies in
+// alphabetical order
+func (g *DependencyGraph) sortedDependencies(name string) []string {
+	node := g.Services[name]
+	deps := make([]string, 0, len(node.DependsOn))
+	for _, dep := range node.DependsOn {
+		deps = append(deps, dep.Name)
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// dependsOnItself checks whether a service lists itself in depends_on
+func (g *DependencyGraph) dependsOnItself(name string) bool {
+	for _, dep := range g.Services[name].DependsOn {
+		if dep.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestStronglyConnectedComponents checks which services are reported as
+// cyclic components and the representative cycle of each
+func TestStronglyConnectedComponents(t *testing.T) {
+	tests := []struct {
+		name   string
+		deps   map[string][]string
+		groups [][]string
+		cycles [][]string
+	}{
+		{
+			name: "acyclic",
+			deps: map[string][]string{"api": {"db"}, "web": {"api", "db"}, "db": nil},
+		},
+		{
+			name:   "self dependency",
+			deps:   map[string][]string{"api": {"api"}, "db": nil},
+			groups: [][]string{{"api"}},
+			cycles: [][]string{{"api", "api"}},
+		},
+		{
+			name:   "two services",
+			deps:   map[string][]string{"a": {"b"}, "b": {"a"}},
+			groups: [][]string{{"a", "b"}},
+			cycles: [][]string{{"a", "b", "a"}},
+		},
+		{
+			name:   "shortest cycle through the first service",
+			deps:   map[string][]string{"a": {"b"}, "b": {"c", "a"}, "c": {"a"}},
+			groups: [][]string{{"a", "b", "c"}},
+			cycles: [][]string{{"a", "b", "a"}},
+		},
+		{
+			name: "separate components",
+			deps: map[string][]string{
+				"a": {"b"}, "b": {"a"},
+				"c": {"d"}, "d": {"e"}, "e": {"c"},
+				"f": {"a", "c"},
+			},
+			groups: [][]string{{"a", "b"}, {"c", "d", "e"}},
+			cycles: [][]string{{"a", "b", "a"}, {"c", "d", "e", "c"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			graph := buildTestGraph(t, test.deps, nil)
+			if !reflect.DeepEqual(graph.CycleGroups, test.groups) {
+				t.Errorf("components = %v, want %v", graph.CycleGroups, test.groups)
+			}
+			if !reflect.DeepEqual(graph.CircularDeps, test.cycles) {
+				t.Errorf("cycles = %v, want %v", graph.CircularDeps, test.cycles)
+			}
+		})
+	}
+}
+
+// TestSuggestCycleFixes checks that the fewest edges are removed and that
+// edges to services without a health check are preferred
+func TestSuggestCycleFixes(t *testing.T) {
+	tests := []struct {
+		name    string
+		deps    map[string][]string
+		healthy []string
+		remove  []string // "from -> to"
+	}{
+		{
+			name:   "self dependency",
+			deps:   map[string][]string{"api": {"api"}},
+			remove: []string{"api -> api"},
+		},
+		{
+			name:    "edge to a service without health check",
+			deps:    map[string][]string{"api": {"db"}, "db": {"api"}},
+			healthy: []string{"db"},
+			remove:  []string{"db -> api"},
+		},
+		{
+			name:   "one edge shared by two cycles",
+			deps:   map[string][]string{"a": {"b"}, "b": {"c", "d"}, "c": {"a"}, "d": {"a"}},
+			remove: []string{"a -> b"},
+		},
+		{
+			name:    "fewest edges win over health checks",
+			deps:    map[string][]string{"a": {"b"}, "b": {"c", "d"}, "c": {"a"}, "d": {"a"}},
+			healthy: []string{"b"},
+			remove:  []string{"a -> b"},
+		},
+		{
+			name:   "two edge-disjoint cycles in one component",
+			deps:   map[string][]string{"a": {"b"}, "b": {"a", "c"}, "c": {"d"}, "d": {"c", "a"}},
+			remove: []string{"a -> b", "c -> d"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			graph := buildTestGraph(t, test.deps, test.healthy)
+			var remove []string
+			for _, fix := range graph.SuggestCycleFixes() {
+				for _, edge := range fix.Remove {
+					remove = append(remove, edge.From+" -> "+edge.To)
+				}
+			}
+			sort.Strings(remove)
+			if !reflect.DeepEqual(remove, test.remove) {
+				t.Fatalf("removed edges = %v, want %v", remove, test.remove)
+			}
+		})
+	}
+}
+
+// buildTestGraph builds the dependency graph of services with the given
+// depends_on lists; healthy services get a health check
+func buildTestGraph(t *testing.T, deps map[string][]string, healthy []string) *DependencyGraph {
+	t.Helper()
+	var compose strings.Builder
+	compose.WriteString("services:\n")
+	for _, name := range sortedKeys(toSet(deps)) {
+		fmt.Fprintf(&compose, "  %s:\n    image: alpine\n", name)
+		if len(deps[name]) > 0 {
+			fmt.Fprintf(&compose, "    depends_on: [%s]\n", strings.Join(deps[name], ", "))
+		}
+		if slices.Contains(healthy, name) {
+			compose.WriteString("    healthcheck:\n      test: [\"CMD\", \"true\"]\n")
+		}
+	}
+
+	composeFile, err := ParseComposeData([]byte(compose.String()))
+	if err != nil {
+		t.Fatalf("failed to parse compose file: %v", err)
+	}
+	graph, err := composeFile.BuildDependencyGraph()
+	if err != nil {
+		t.Fatalf("failed to build graph: %v", err)
+	}
+	return graph
+}
+
+// toSet returns the keys of a map as a set
+func toSet(m map[string][]string) map[string]bool {
+	set := make(map[string]bool)
+	for key := range m {
+		set[key] = true
+	}
+	return set
+}
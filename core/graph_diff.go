@@ -0,0 +1,567 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EdgeChange is a depends_on edge added or removed between two graphs
+type EdgeChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// MembershipChange is a service joining or leaving a network or named volume
+type MembershipChange struct {
+	Service  string `json:"service"`
+	Resource string `json:"resource"`
+}
+
+// ImageChange is a service whose image changed between two graphs
+type ImageChange struct {
+	Service string `json:"service"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+}
+
+// GraphDiff describes how the topology changed between two dependency graphs
+type GraphDiff struct {
+	AddedServices       []string           `json:"added_services"`
+	RemovedServices     []string           `json:"removed_services"`
+	AddedDependencies   []EdgeChange       `json:"added_dependencies"`
+	RemovedDependencies []EdgeChange       `json:"removed_dependencies"`
+	AddedNetworks       []MembershipChange `json:"added_network_memberships"`
+	RemovedNetworks     []MembershipChange `json:"removed_network_memberships"`
+	AddedVolumes        []MembershipChange `json:"added_volume_sharing"`
+	RemovedVolumes      []MembershipChange `json:"removed_volume_sharing"`
+	ChangedImages       []ImageChange      `json:"changed_images"`
+
+	old     *DependencyGraph
+	new     *DependencyGraph
+	oldSets graphSets
+	newSets graphSets
+}
+
+// graphSets holds the edges, memberships and resources of one graph, built
+// once so the formatters can look up the status of each element directly
+type graphSets struct {
+	edges       map[EdgeChange]bool
+	memberships map[string]map[MembershipChange]bool // by resource kind
+	resources   map[string]map[string]bool           // by resource kind
+}
+
+// newGraphSets collects the sets of a graph
+func newGraphSets(g *DependencyGraph) graphSets {
+	sets := graphSets{
+		edges:       dependencyEdgeSet(g),
+		memberships: make(map[string]map[MembershipChange]bool),
+		resources:   make(map[string]map[string]bool),
+	}
+	for _, kind := range diffResourceKinds {
+		memberships := membershipSet(g, kind.memberships)
+		resources := make(map[string]bool)
+		for m := range memberships {
+			resources[m.Resource] = true
+		}
+		sets.memberships[kind.name] = memberships
+		sets.resources[kind.name] = resources
+	}
+	return sets
+}
+
+// DiffGraphs compares two dependency graphs
+func DiffGraphs(oldGraph, newGraph *DependencyGraph) *GraphDiff {
+	diff := &GraphDiff{
+		AddedServices:       []string{},
+		RemovedServices:     []string{},
+		AddedDependencies:   []EdgeChange{},
+		RemovedDependencies: []EdgeChange{},
+		AddedNetworks:       []MembershipChange{},
+		RemovedNetworks:     []MembershipChange{},
+		AddedVolumes:        []MembershipChange{},
+		RemovedVolumes:      []MembershipChange{},
+		ChangedImages:       []ImageChange{},
+		old:                 oldGraph,
+		new:                 newGraph,
+		oldSets:             newGraphSets(oldGraph),
+		newSets:             newGraphSets(newGraph),
+	}
+
+	for _, name := range newGraph.sortedServiceNames() {
+		if _, exists := oldGraph.Services[name]; !exists {
+			diff.AddedServices = append(diff.AddedServices, name)
+		}
+	}
+	for _, name := range oldGraph.sortedServiceNames() {
+		oldNode := oldGraph.Services[name]
+		newNode, exists := newGraph.Services[name]
+		if !exists {
+			diff.RemovedServices = append(diff.RemovedServices, name)
+			continue
+		}
+		if oldNode.Service.Image != newNode.Service.Image {
+			diff.ChangedImages = append(diff.ChangedImages, ImageChange{
+				Service: name,
+				Old:     oldNode.Service.Image,
+				New:     newNode.Service.Image,
+			})
+		}
+	}
+
+	oldSets, newSets := diff.oldSets, diff.newSets
+	diff.AddedDependencies = edgeSetDifference(newSets.edges, oldSets.edges)
+	diff.RemovedDependencies = edgeSetDifference(oldSets.edges, newSets.edges)
+
+	oldNetworks, newNetworks := oldSets.memberships["network"], newSets.memberships["network"]
+	diff.AddedNetworks = membershipSetDifference(newNetworks, oldNetworks)
+	diff.RemovedNetworks = membershipSetDifference(oldNetworks, newNetworks)
+
+	oldVolumes, newVolumes := oldSets.memberships["volume"], newSets.memberships["volume"]
+	diff.AddedVolumes = membershipSetDifference(newVolumes, oldVolumes)
+	diff.RemovedVolumes = membershipSetDifference(oldVolumes, newVolumes)
+
+	return diff
+}
+
+// IsEmpty reports whether the graphs have the same topology
+func (d *GraphDiff) IsEmpty() bool {
+	return len(d.AddedServices) == 0 && len(d.RemovedServices) == 0 &&
+		len(d.AddedDependencies) == 0 && len(d.RemovedDependencies) == 0 &&
+		len(d.AddedNetworks) == 0 && len(d.RemovedNetworks) == 0 &&
+		len(d.AddedVolumes) == 0 && len(d.RemovedVolumes) == 0 &&
+		len(d.ChangedImages) == 0
+}
+
+// FormatText renders the diff as a human readable summary
+func (d *GraphDiff) FormatText() string {
+	if d.IsEmpty() {
+		return "No topology changes\n"
+	}
+
+	var builder strings.Builder
+	builder.WriteString("Topology Diff\n")
+	builder.WriteString(strings.Repeat("=", 80) + "\n")
+
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		builder.WriteString("\n" + title + ":\n")
+		for _, line := range lines {
+			builder.WriteString("  " + line + "\n")
+		}
+	}
+
+	var lines []string
+	for _, name := range d.AddedServices {
+		lines = append(lines, "+ "+name)
+	}
+	for _, name := range d.RemovedServices {
+		lines = append(lines, "- "+name)
+	}
+	section("Services", lines)
+
+	lines = nil
+	for _, edge := range d.AddedDependencies {
+		lines = append(lines, fmt.Sprintf("+ %s → %s", edge.From, edge.To))
+	}
+	for _, edge := range d.RemovedDependencies {
+		lines = append(lines, fmt.Sprintf("- %s → %s", edge.From, edge.To))
+	}
+	section("Dependencies", lines)
+
+	lines = nil
+	for _, m := range d.AddedNetworks {
+		lines = append(lines, fmt.Sprintf("+ %s joins %s", m.Service, m.Resource))
+	}
+	for _, m := range d.RemovedNetworks {
+		lines = append(lines, fmt.Sprintf("- %s leaves %s", m.Service, m.Resource))
+	}
+	section("Networks", lines)
+
+	lines = nil
+	for _, m := range d.AddedVolumes {
+		lines = append(lines, fmt.Sprintf("+ %s mounts %s", m.Service, m.Resource))
+	}
+	for _, m := range d.RemovedVolumes {
+		lines = append(lines, fmt.Sprintf("- %s unmounts %s", m.Service, m.Resource))
+	}
+	section("Volumes", lines)
+
+	lines = nil
+	for _, c := range d.ChangedImages {
+		lines = append(lines, fmt.Sprintf("~ %s: %s → %s", c.Service, displayImage(c.Old), displayImage(c.New)))
+	}
+	section("Images", lines)
+
+	return builder.String()
+}
+
+// FormatJSON renders the diff as indented JSON
+func (d *GraphDiff) FormatJSON() (string, error) {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal diff: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// FormatDOT renders the union of both graphs in Graphviz DOT format, with
+// additions in green, removals in red and image changes in orange. Networks
+// and named volumes are drawn as separate nodes linked to their services.
+func (d *GraphDiff) FormatDOT() string {
+	var builder strings.Builder
+
+	builder.WriteString("digraph diff {\n")
+	builder.WriteString("  rankdir=LR;\n")
+	builder.WriteString("  node [shape=box, style=rounded];\n\n")
+
+	builder.WriteString("  // Nodes\n")
+	for _, name := range d.serviceNames() {
+		label, attrs := name, ""
+		switch d.serviceStatus(name) {
+		case diffAdded:
+			attrs = ", color=green, fontcolor=green, penwidth=2"
+		case diffRemoved:
+			attrs = ", color=red, fontcolor=red, style=\"rounded,dashed\", penwidth=2"
+		default:
+			if change, ok := d.imageChange(name); ok {
+				label += fmt.Sprintf("\\n%s → %s", displayImage(change.Old), displayImage(change.New))
+				attrs = ", color=orange, fontcolor=orange, penwidth=2"
+			}
+		}
+		builder.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\"%s];\n", name, label, attrs))
+	}
+
+	builder.WriteString("\n  // Dependencies\n")
+	for _, edge := range d.edges() {
+		style := ""
+		switch d.edgeStatus(edge) {
+		case diffAdded:
+			style = " [color=green, penwidth=2]"
+		case diffRemoved:
+			style = " [color=red, style=dashed, penwidth=2]"
+		}
+		builder.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\"%s;\n", edge.From, edge.To, style))
+	}
+
+	for _, kind := range diffResourceKinds {
+		resources := d.resourceNames(kind)
+		if len(resources) == 0 {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("\n  // %ss\n", strings.ToUpper(kind.name[:1])+kind.name[1:]))
+		for _, resource := range resources {
+			attrs := ""
+			switch d.resourceStatus(kind, resource) {
+			case diffAdded:
+				attrs = ", color=green, fontcolor=green, penwidth=2"
+			case diffRemoved:
+				attrs = ", color=red, fontcolor=red, style=dashed, penwidth=2"
+			}
+			builder.WriteString(fmt.Sprintf("  \"%s:%s\" [label=\"%s\", shape=%s%s];\n", kind.name, resource, resource, kind.shape, attrs))
+		}
+		for _, m := range d.memberships(kind) {
+			style := "arrowhead=none, style=dotted"
+			switch d.membershipStatus(kind, m) {
+			case diffAdded:
+				style = "arrowhead=none, color=green, penwidth=2"
+			case diffRemoved:
+				style = "arrowhead=none, color=red, style=dashed, penwidth=2"
+			}
+			builder.WriteString(fmt.Sprintf("  \"%s\" -> \"%s:%s\" [%s];\n", m.Service, kind.name, m.Resource, style))
+		}
+	}
+
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// FormatMermaid renders the union of both graphs as a Mermaid flowchart, with
+// additions in green, removals in red and image changes in orange. Networks
+// and named volumes are drawn as separate nodes linked to their services.
+func (d *GraphDiff) FormatMermaid() string {
+	var builder strings.Builder
+
+	builder.WriteString("graph LR\n")
+	builder.WriteString("  classDef added fill:#d4f8d4,stroke:#2da44e,color:#116329\n")
+	builder.WriteString("  classDef removed fill:#ffd7d5,stroke:#cf222e,color:#82071e,stroke-dasharray:5 5\n")
+	builder.WriteString("  classDef changed fill:#fff1c7,stroke:#bf8700,color:#7d4e00\n")
+
+	ids := make(map[string]string)
+	for i, name := range d.serviceNames() {
+		ids[name] = fmt.Sprintf("s%d", i)
+		label, class := name, ""
+		switch d.serviceStatus(name) {
+		case diffAdded:
+			class = ":::added"
+		case diffRemoved:
+			class = ":::removed"
+		default:
+			if change, ok := d.imageChange(name); ok {
+				label += fmt.Sprintf("<br/>%s → %s", displayImage(change.Old), displayImage(change.New))
+				class = ":::changed"
+			}
+		}
+		builder.WriteString(fmt.Sprintf("  %s[\"%s\"]%s\n", ids[name], label, class))
+	}
+
+	var linkStyles []string
+	link := 0
+	addLinkStyle := func(status diffStatus) {
+		switch status {
+		case diffAdded:
+			linkStyles = append(linkStyles, fmt.Sprintf("  linkStyle %d stroke:#2da44e,stroke-width:2px", link))
+		case diffRemoved:
+			linkStyles = append(linkStyles, fmt.Sprintf("  linkStyle %d stroke:#cf222e,stroke-width:2px,stroke-dasharray:5 5", link))
+		}
+		link++
+	}
+	for _, edge := range d.edges() {
+		builder.WriteString(fmt.Sprintf("  %s --> %s\n", ids[edge.From], ids[edge.To]))
+		addLinkStyle(d.edgeStatus(edge))
+	}
+
+	for _, kind := range diffResourceKinds {
+		resourceIDs := make(map[string]string)
+		for i, resource := range d.resourceNames(kind) {
+			resourceIDs[resource] = fmt.Sprintf("%s%d", kind.name[:1], i)
+			class := ""
+			switch d.resourceStatus(kind, resource) {
+			case diffAdded:
+				class = ":::added"
+			case diffRemoved:
+				class = ":::removed"
+			}
+			builder.WriteString(fmt.Sprintf("  %s%s\"%s\"%s%s\n", resourceIDs[resource], kind.open, resource, kind.close, class))
+		}
+		for _, m := range d.memberships(kind) {
+			builder.WriteString(fmt.Sprintf("  %s -.- %s\n", ids[m.Service], resourceIDs[m.Resource]))
+			addLinkStyle(d.membershipStatus(kind, m))
+		}
+	}
+
+	for _, style := range linkStyles {
+		builder.WriteString(style + "\n")
+	}
+
+	return builder.String()
+}
+
+type diffStatus int
+
+const (
+	diffUnchanged diffStatus = iota
+	diffAdded
+	diffRemoved
+)
+
+// serviceNames returns the union of service names of both graphs
+func (d *GraphDiff) serviceNames() []string {
+	set := make(map[string]bool)
+	for name := range d.old.Services {
+		set[name] = true
+	}
+	for name := range d.new.Services {
+		set[name] = true
+	}
+	return sortedKeys(set)
+}
+
+// serviceStatus reports whether a service was added, removed or kept
+func (d *GraphDiff) serviceStatus(name string) diffStatus {
+	_, inOld := d.old.Services[name]
+	_, inNew := d.new.Services[name]
+	return presenceStatus(inOld, inNew)
+}
+
+// edges returns the union of depends_on edges of both graphs
+func (d *GraphDiff) edges() []EdgeChange {
+	set := make(map[EdgeChange]bool)
+	for edge := range d.oldSets.edges {
+		set[edge] = true
+	}
+	for edge := range d.newSets.edges {
+		set[edge] = true
+	}
+	return sortedEdges(set)
+}
+
+// edgeStatus reports whether an edge was added, removed or kept
+func (d *GraphDiff) edgeStatus(edge EdgeChange) diffStatus {
+	return presenceStatus(d.oldSets.edges[edge], d.newSets.edges[edge])
+}
+
+// imageChange returns the image change of a service, if any
+func (d *GraphDiff) imageChange(name string) (ImageChange, bool) {
+	for _, change := range d.ChangedImages {
+		if change.Service == name {
+			return change, true
+		}
+	}
+	return ImageChange{}, false
+}
+
+// diffResourceKind describes how a kind of shared resource is drawn
+type diffResourceKind struct {
+	name        string
+	memberships func(*ServiceNode) []string
+	shape       string // DOT node shape
+	open, close string // Mermaid node delimiters
+}
+
+// diffResourceKinds are the shared resources drawn in graph diffs
+var diffResourceKinds = []diffResourceKind{
+	{name: "network", memberships: networkMemberships, shape: "ellipse", open: "([", close: "])"},
+	{name: "volume", memberships: volumeMemberships, shape: "cylinder", open: "[(", close: ")]"},
+}
+
+// memberships returns the union of the memberships of both graphs, ordered by
+// service, then resource
+func (d *GraphDiff) memberships(kind diffResourceKind) []MembershipChange {
+	set := make(map[MembershipChange]bool)
+	for m := range d.oldSets.memberships[kind.name] {
+		set[m] = true
+	}
+	for m := range d.newSets.memberships[kind.name] {
+		set[m] = true
+	}
+	return sortedMemberships(set)
+}
+
+// membershipStatus reports whether a membership was added, removed or kept
+func (d *GraphDiff) membershipStatus(kind diffResourceKind, m MembershipChange) diffStatus {
+	return presenceStatus(d.oldSets.memberships[kind.name][m], d.newSets.memberships[kind.name][m])
+}
+
+// resourceNames returns the union of the resources used in both graphs
+func (d *GraphDiff) resourceNames(kind diffResourceKind) []string {
+	set := make(map[string]bool)
+	for resource := range d.oldSets.resources[kind.name] {
+		set[resource] = true
+	}
+	for resource := range d.newSets.resources[kind.name] {
+		set[resource] = true
+	}
+	return sortedKeys(set)
+}
+
+// resourceStatus reports whether a resource is only used in the new graph
+// (added) or only in the old one (removed)
+func (d *GraphDiff) resourceStatus(kind diffResourceKind, resource string) diffStatus {
+	return presenceStatus(d.oldSets.resources[kind.name][resource], d.newSets.resources[kind.name][resource])
+}
+
+// presenceStatus reports an element present only in the new graph as
+// added and one present only in the old graph as removed
+func presenceStatus(inOld, inNew bool) diffStatus {
+	switch {
+	case inNew && !inOld:
+		return diffAdded
+	case inOld && !inNew:
+		return diffRemoved
+	default:
+		return diffUnchanged
+	}
+}
+
+// dependencyEdgeSet returns all depends_on edges of a graph
+func dependencyEdgeSet(g *DependencyGraph) map[EdgeChange]bool {
+	set := make(map[EdgeChange]bool)
+	for name, node := range g.Services {
+		for _, dep := range node.DependsOn {
+			set[EdgeChange{From: name, To: dep.Name}] = true
+		}
+	}
+	return set
+}
+
+// edgeSetDifference returns the edges of a that are not in b
+func edgeSetDifference(a, b map[EdgeChange]bool) []EdgeChange {
+	result := make(map[EdgeChange]bool)
+	for edge := range a {
+		if !b[edge] {
+			result[edge] = true
+		}
+	}
+	return sortedEdges(result)
+}
+
+// sortedEdges returns a set of edges ordered by source, then target
+func sortedEdges(set map[EdgeChange]bool) []EdgeChange {
+	edges := []EdgeChange{}
+	for edge := range set {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// networkMemberships lists the networks a service is attached to
+func networkMemberships(node *ServiceNode) []string {
+	return node.Networks
+}
+
+// volumeMemberships lists the named volumes a service mounts
+func volumeMemberships(node *ServiceNode) []string {
+	var volumes []string
+	for _, mount := range node.Volumes {
+		if name := extractVolumeName(mount); name != "" {
+			volumes = append(volumes, name)
+		}
+	}
+	return volumes
+}
+
+// membershipSet returns all service/resource memberships of a graph
+func membershipSet(g *DependencyGraph, resources func(*ServiceNode) []string) map[MembershipChange]bool {
+	set := make(map[MembershipChange]bool)
+	for name, node := range g.Services {
+		for _, resource := range resources(node) {
+			set[MembershipChange{Service: name, Resource: resource}] = true
+		}
+	}
+	return set
+}
+
+// membershipSetDifference returns the memberships of a that are not in b
+func membershipSetDifference(a, b map[MembershipChange]bool) []MembershipChange {
+	result := make(map[MembershipChange]bool)
+	for m := range a {
+		if !b[m] {
+			result[m] = true
+		}
+	}
+	return sortedMemberships(result)
+}
+
+// sortedMemberships returns a set of memberships ordered by service, then
+// resource
+func sortedMemberships(set map[MembershipChange]bool) []MembershipChange {
+	memberships := []MembershipChange{}
+	for m := range set {
+		memberships = append(memberships, m)
+	}
+	sort.Slice(memberships, func(i, j int) bool {
+		if memberships[i].Service != memberships[j].Service {
+			return memberships[i].Service < memberships[j].Service
+		}
+		return memberships[i].Resource < memberships[j].Resource
+	})
+	return memberships
+}
+
+// displayImage renders an image reference, marking services built locally
+func displayImage(image string) string {
+	if image == "" {
+		return "(build)"
+	}
+	return image
+}
@@ -0,0 +1,173 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDiffGraphs compares two topologies and checks the summary of changes
+func TestDiffGraphs(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want []string // lines of FormatText after the header; nil when unchanged
+	}{
+		{
+			name: "unchanged",
+			old:  "services:\n  web:\n    image: nginx\n    depends_on: [api]\n  api:\n    image: api\n",
+			new:  "services:\n  api:\n    image: api\n  web:\n    depends_on: [api]\n    image: nginx\n",
+		},
+		{
+			name: "service added and removed",
+			old:  "services:\n  web:\n    image: nginx\n    depends_on: [api]\n  api:\n    image: api\n",
+			new:  "services:\n  web:\n    image: nginx\n    depends_on: [backend]\n  backend:\n    image: api\n",
+			want: []string{
+				"Services:", "  + backend", "  - api",
+				"Dependencies:", "  + web → backend", "  - web → api",
+			},
+		},
+		{
+			name: "dependency added",
+			old:  "services:\n  web:\n    image: nginx\n  api:\n    image: api\n  db:\n    image: postgres\n",
+			new:  "services:\n  web:\n    image: nginx\n    depends_on: [api, db]\n  api:\n    image: api\n  db:\n    image: postgres\n",
+			want: []string{"Dependencies:", "  + web → api", "  + web → db"},
+		},
+		{
+			name: "network memberships",
+			old:  "services:\n  web:\n    image: nginx\n    networks: [front]\n  api:\n    image: api\n    networks: [front]\n",
+			new:  "services:\n  web:\n    image: nginx\n    networks: [front]\n  api:\n    image: api\n    networks: [back]\n",
+			want: []string{"Networks:", "  + api joins back", "  - api leaves front"},
+		},
+		{
+			name: "named volumes only",
+			old:  "services:\n  db:\n    image: postgres\n    volumes: [\"./init:/docker-entrypoint-initdb.d\"]\n",
+			new:  "services:\n  db:\n    image: postgres\n    volumes: [\"./init:/docker-entrypoint-initdb.d\", \"data:/var/lib/postgresql/data\"]\n",
+			want: []string{"Volumes:", "  + db mounts data"},
+		},
+		{
+			name: "image changes",
+			old:  "services:\n  web:\n    image: nginx:1.25\n  api:\n    build:\n      context: .\n",
+			new:  "services:\n  web:\n    image: nginx:1.27\n  api:\n    image: api:1.0\n",
+			want: []string{"Images:", "  ~ api: (build) → api:1.0", "  ~ web: nginx:1.25 → nginx:1.27"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := DiffGraphs(buildComposeGraph(t, test.old), buildComposeGraph(t, test.new))
+			if diff.IsEmpty() != (test.want == nil) {
+				t.Fatalf("empty = %v, want %v", diff.IsEmpty(), test.want == nil)
+			}
+			text := diff.FormatText()
+			if test.want == nil {
+				if text != "No topology changes\n" {
+					t.Fatalf("text = %q", text)
+				}
+				return
+			}
+			var got []string
+			for _, line := range strings.Split(text, "\n")[2:] {
+				if line != "" {
+					got = append(got, line)
+				}
+			}
+			if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+				t.Fatalf("text =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+// TestGraphDiffFormats checks how DOT and Mermaid mark added, removed and
+// unchanged services, edges and resources
+func TestGraphDiffFormats(t *testing.T) {
+	oldGraph := buildComposeGraph(t, "services:\n"+
+		"  web:\n    image: nginx\n    depends_on: [api]\n    networks: [front]\n"+
+		"  api:\n    image: api:1\n    depends_on: [cache]\n    volumes: [\"data:/data\"]\n"+
+		"  cache:\n    image: redis\n")
+	newGraph := buildComposeGraph(t, "services:\n"+
+		"  web:\n    image: nginx\n    depends_on: [api]\n    networks: [front, back]\n"+
+		"  api:\n    image: api:2\n    depends_on: [db]\n    networks: [back]\n"+
+		"  db:\n    image: postgres\n")
+	diff := DiffGraphs(oldGraph, newGraph)
+
+	tests := []struct {
+		name   string
+		output string
+		lines  []string
+	}{
+		{
+			name:   "dot",
+			output: diff.FormatDOT(),
+			lines: []string{
+				`  "api" [label="api\napi:1 → api:2", color=orange, fontcolor=orange, penwidth=2];`,
+				`  "cache" [label="cache", color=red, fontcolor=red, style="rounded,dashed", penwidth=2];`,
+				`  "db" [label="db", color=green, fontcolor=green, penwidth=2];`,
+				`  "web" [label="web"];`,
+				`  "api" -> "cache" [color=red, style=dashed, penwidth=2];`,
+				`  "api" -> "db" [color=green, penwidth=2];`,
+				`  "web" -> "api";`,
+				`  "network:back" [label="back", shape=ellipse, color=green, fontcolor=green, penwidth=2];`,
+				`  "network:front" [label="front", shape=ellipse];`,
+				`  "web" -> "network:front" [arrowhead=none, style=dotted];`,
+				`  "web" -> "network:back" [arrowhead=none, color=green, penwidth=2];`,
+				`  "volume:data" [label="data", shape=cylinder, color=red, fontcolor=red, style=dashed, penwidth=2];`,
+				`  "api" -> "volume:data" [arrowhead=none, color=red, style=dashed, penwidth=2];`,
+			},
+		},
+		{
+			name:   "mermaid",
+			output: diff.FormatMermaid(),
+			lines: []string{
+				`  s0["api<br/>api:1 → api:2"]:::changed`,
+				`  s1["cache"]:::removed`,
+				`  s2["db"]:::added`,
+				`  s3["web"]`,
+				`  n0(["back"]):::added`,
+				`  n1(["front"])`,
+				`  v0[("data")]:::removed`,
+				// Links: api→cache, api→db, web→api, api-back, web-back, web-front, api-data
+				`  linkStyle 0 stroke:#cf222e,stroke-width:2px,stroke-dasharray:5 5`,
+				`  linkStyle 1 stroke:#2da44e,stroke-width:2px`,
+				`  linkStyle 3 stroke:#2da44e,stroke-width:2px`,
+				`  linkStyle 4 stroke:#2da44e,stroke-width:2px`,
+				`  linkStyle 6 stroke:#cf222e,stroke-width:2px,stroke-dasharray:5 5`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, line := range test.lines {
+				if !strings.Contains(test.output, line+"\n") {
+					t.Errorf("missing line %q in\n%s", line, test.output)
+				}
+			}
+		})
+	}
+
+	t.Run("unchanged links are not styled", func(t *testing.T) {
+		output := diff.FormatMermaid()
+		for _, link := range []string{"linkStyle 2 ", "linkStyle 5 "} {
+			if strings.Contains(output, link) {
+				t.Errorf("unexpected %q in\n%s", link, output)
+			}
+		}
+	})
+}
+
+// buildComposeGraph parses a compose file and builds its dependency graph or
+// fails the test
+func buildComposeGraph(t *testing.T, compose string) *DependencyGraph {
+	t.Helper()
+	composeFile, err := ParseComposeData([]byte(compose))
+	if err != nil {
+		t.Fatalf("failed to parse compose file: %v", err)
+	}
+	graph, err := composeFile.BuildDependencyGraph()
+	if err != nil {
+		t.Fatalf("failed to build graph: %v", err)
+	}
+	return graph
+}
@@ -0,0 +1,349 @@
+package core
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// FilterDirection selects which dependency edges are followed from a root
+type FilterDirection int
+
+const (
+	// DirectionBoth follows dependencies and dependents
+	DirectionBoth FilterDirection = iota
+	// DirectionUp follows depends_on towards the services a root needs
+	DirectionUp
+	// DirectionDown follows depends_on in reverse towards the services that need a root
+	DirectionDown
+)
+
+// ParseFilterDirection parses "up", "down" or "both"
+func ParseFilterDirection(value string) (FilterDirection, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "both":
+		return DirectionBoth, nil
+	case "up":
+		return DirectionUp, nil
+	case "down":
+		return DirectionDown, nil
+	default:
+		return DirectionBoth, fmt.Errorf("unknown direction: %s (supported: up, down, both)", value)
+	}
+}
+
+// String returns the direction as used on the command line
+func (d FilterDirection) String() string {
+	switch d {
+	case DirectionUp:
+		return "up"
+	case DirectionDown:
+		return "down"
+	default:
+		return "both"
+	}
+}
+
+// ParseRelationshipType parses "depends_on", "network" or "volume"
+func ParseRelationshipType(value string) (RelationshipType, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "depends_on", "depends-on":
+		return RelationshipDependsOn, nil
+	case "network", "networks":
+		return RelationshipNetwork, nil
+	case "volume", "volumes":
+		return RelationshipVolume, nil
+	default:
+		return RelationshipDependsOn, fmt.Errorf("unknown relationship type: %s (supported: depends_on, network, volume)", value)
+	}
+}
+
+// GraphFilter transforms a dependency graph into a filtered copy
+type GraphFilter func(*DependencyGraph) (*DependencyGraph, error)
+
+// Apply runs the given filters in order and returns the resulting graph.
+// The original graph is never modified.
+func (g *DependencyGraph) Apply(filters ...GraphFilter) (*DependencyGraph, error) {
+	result := g
+	for _, filter := range filters {
+		filtered, err := filter(result)
+		if err != nil {
+			return nil, err
+		}
+		result = filtered
+	}
+	return result, nil
+}
+
+// FilterRoots keeps the given services plus everything reachable from them
+// in the chosen direction, up to depth hops (-1 = unlimited)
+func FilterRoots(services []string, depth int, direction FilterDirection) GraphFilter {
+	return func(g *DependencyGraph) (*DependencyGraph, error) {
+		keep := make(map[string]bool)
+		for _, name := range services {
+			node, exists := g.Services[name]
+			if !exists {
+				return nil, fmt.Errorf("service '%s' %w", name, ErrNotFound)
+			}
+			if direction == DirectionUp || direction == DirectionBoth {
+				collectReachable(node, keep, depth, func(n *ServiceNode) []*ServiceNode { return n.DependsOn })
+			}
+			if direction == DirectionDown || direction == DirectionBoth {
+				collectReachable(node, keep, depth, func(n *ServiceNode) []*ServiceNode { return n.DependedBy })
+			}
+		}
+		return g.subgraph(func(node *ServiceNode) bool { return keep[node.Name] }, nil), nil
+	}
+}
+
+// FilterExclude drops every service whose name matches one of the glob patterns
+func FilterExclude(patterns []string) GraphFilter {
+	return func(g *DependencyGraph) (*DependencyGraph, error) {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid exclude pattern '%s': %w", pattern, err)
+			}
+		}
+		return g.subgraph(func(node *ServiceNode) bool {
+			for _, pattern := range patterns {
+				if matched, _ := path.Match(pattern, node.Name); matched {
+					return false
+				}
+			}
+			return true
+		}, nil), nil
+	}
+}
+
+// FilterEdgeTypes keeps only relationships of the given types
+func FilterEdgeTypes(types []RelationshipType) GraphFilter {
+	return func(g *DependencyGraph) (*DependencyGraph, error) {
+		allowed := make(map[RelationshipType]bool)
+		for _, t := range types {
+			allowed[t] = true
+		}
+		return g.subgraph(nil, func(node *ServiceNode) {
+			if !allowed[RelationshipDependsOn] {
+				service := *node.Service
+				service.DependsOn = nil
+				node.Service = &service
+			}
+			if !allowed[RelationshipNetwork] {
+				node.Networks = nil
+			}
+			if !allowed[RelationshipVolume] {
+				node.Volumes = nil
+			}
+		}), nil
+	}
+}
+
+// collectReachable marks every service reachable from node through next,
+// stopping after maxDepth hops when maxDepth >= 0
+func collectReachable(node *ServiceNode, keep map[string]bool, maxDepth int, next func(*ServiceNode) []*ServiceNode) {
+	depths := map[string]int{node.Name: 0}
+	queue := []*ServiceNode{node}
+	keep[node.Name] = true
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if maxDepth >= 0 && depths[current.Name] >= maxDepth {
+			continue
+		}
+		for _, neighbour := range next(current) {
+			if _, seen := depths[neighbour.Name]; seen {
+				continue
+			}
+			depths[neighbour.Name] = depths[current.Name] + 1
+			keep[neighbour.Name] = true
+			queue = append(queue, neighbour)
+		}
+	}
+}
+
+// subgraph copies the services accepted by keep (all when nil), lets adjust
+// modify each copy, and rebuilds relationships, cycles and ordering
+func (g *DependencyGraph) subgraph(keep func(*ServiceNode) bool, adjust func(*ServiceNode)) *DependencyGraph {
+	filtered := &DependencyGraph{
+		Services: make(map[string]*ServiceNode),
+	}
+
+	for name, node := range g.Services {
+		if keep != nil && !keep(node) {
+			continue
+		}
+		nodeCopy := &ServiceNode{
+			Name:           node.Name,
+			Service:        node.Service,
+			DependsOn:      []*ServiceNode{},
+			DependedBy:     []*ServiceNode{},
+			Networks:       node.Networks,
+			NetworkPeers:   make(map[string][]*ServiceNode),
+			Volumes:        node.Volumes,
+			VolumePeers:    make(map[string][]*ServiceNode),
+			HasHealthCheck: node.HasHealthCheck,
+			HealthCheck:    node.HealthCheck,
+		}
+		if adjust != nil {
+			adjust(nodeCopy)
+		}
+		filtered.Services[name] = nodeCopy
+	}
+
+	filtered.rebuildRelationships()
+	filtered.detectCycles()
+	if len(filtered.CircularDeps) == 0 {
+		if order, err := filtered.topologicalSort(); err == nil {
+			filtered.TopologicalOrder = order
+		}
+	}
+
+	return filtered
+}
+
+// GraphFilterSpec holds a declarative set of graph filters. It is shared by
+// the graph command flags and the TUI filter prompt.
+type GraphFilterSpec struct {
+	Services  []string
+	Depth     int
+	Direction FilterDirection
+	Exclude   []string
+	OnlyTypes []RelationshipType
+}
+
+// NewGraphFilterSpec returns a spec that keeps the whole graph
+func NewGraphFilterSpec() GraphFilterSpec {
+	return GraphFilterSpec{Depth: -1, Direction: DirectionBoth}
+}
+
+// IsEmpty reports whether the spec filters nothing
+func (s GraphFilterSpec) IsEmpty() bool {
+	return len(s.Services) == 0 && len(s.Exclude) == 0 && len(s.OnlyTypes) == 0
+}
+
+// Filters returns the composable filters described by the spec
+func (s GraphFilterSpec) Filters() []GraphFilter {
+	var filters []GraphFilter
+	if len(s.Services) > 0 {
+		filters = append(filters, FilterRoots(s.Services, s.Depth, s.Direction))
+	}
+	if len(s.Exclude) > 0 {
+		filters = append(filters, FilterExclude(s.Exclude))
+	}
+	if len(s.OnlyTypes) > 0 {
+		filters = append(filters, FilterEdgeTypes(s.OnlyTypes))
+	}
+	return filters
+}
+
+// Apply filters a graph according to the spec
+func (s GraphFilterSpec) Apply(g *DependencyGraph) (*DependencyGraph, error) {
+	return g.Apply(s.Filters()...)
+}
+
+// String renders the spec in the expression syntax accepted by ParseGraphFilter
+func (s GraphFilterSpec) String() string {
+	var parts []string
+	if len(s.Services) > 0 {
+		parts = append(parts, "service="+strings.Join(s.Services, ","))
+		if s.Depth >= 0 {
+			parts = append(parts, "depth="+strconv.Itoa(s.Depth))
+		}
+		if s.Direction != DirectionBoth {
+			parts = append(parts, "direction="+s.Direction.String())
+		}
+	}
+	if len(s.Exclude) > 0 {
+		parts = append(parts, "exclude="+strings.Join(s.Exclude, ","))
+	}
+	if len(s.OnlyTypes) > 0 {
+		var types []string
+		for _, t := range s.OnlyTypes {
+			types = append(types, t.String())
+		}
+		parts = append(parts, "only-type="+strings.Join(types, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseGraphFilter parses a filter expression such as
+//
+//	service=api,worker depth=2 direction=down exclude=*-db only-type=depends_on
+//
+// Keys may repeat and values are comma separated. Bare words are treated as
+// service names, so "api" alone filters to the api service.
+func ParseGraphFilter(expression string) (GraphFilterSpec, error) {
+	spec := NewGraphFilterSpec()
+
+	for _, token := range strings.Fields(expression) {
+		key, value, hasValue := strings.Cut(token, "=")
+		if !hasValue {
+			key, value = "service", token
+		}
+
+		switch strings.ToLower(key) {
+		case "service", "services":
+			spec.Services = append(spec.Services, splitList(value)...)
+
+		case "depth":
+			depth, err := strconv.Atoi(value)
+			if err != nil {
+				return spec, fmt.Errorf("invalid depth: %s", value)
+			}
+			spec.Depth = depth
+
+		case "direction":
+			direction, err := ParseFilterDirection(value)
+			if err != nil {
+				return spec, err
+			}
+			spec.Direction = direction
+
+		case "exclude":
+			spec.Exclude = append(spec.Exclude, splitList(value)...)
+
+		case "only-type", "type":
+			for _, item := range splitList(value) {
+				t, err := ParseRelationshipType(item)
+				if err != nil {
+					return spec, err
+				}
+				spec.OnlyTypes = append(spec.OnlyTypes, t)
+			}
+
+		default:
+			return spec, fmt.Errorf("unknown filter key: %s (supported: service, depth, direction, exclude, only-type)", key)
+		}
+	}
+
+	return spec, nil
+}
+
+// String returns the relationship type as used on the command line
+func (t RelationshipType) String() string {
+	switch t {
+	case RelationshipDependsOn:
+		return "depends_on"
+	case RelationshipNetwork:
+		return "network"
+	case RelationshipVolume:
+		return "volume"
+	case RelationshipHealthCheck:
+		return "healthcheck"
+	default:
+		return "unknown"
+	}
+}
+
+// splitList splits a comma separated value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,303 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestFilterRoots keeps the services reachable from the roots
+func TestFilterRoots(t *testing.T) {
+	deps := map[string][]string{
+		"web":    {"api"},
+		"api":    {"db", "cache"},
+		"worker": {"db"},
+		"db":     nil,
+		"cache":  nil,
+		"admin":  nil,
+	}
+	tests := []struct {
+		name      string
+		roots     []string
+		depth     int
+		direction FilterDirection
+		want      []string
+		err       error
+	}{
+		{
+			name:      "both directions",
+			roots:     []string{"api"},
+			depth:     -1,
+			direction: DirectionBoth,
+			want:      []string{"api", "cache", "db", "web"},
+		},
+		{
+			name:      "dependencies only",
+			roots:     []string{"web"},
+			depth:     -1,
+			direction: DirectionUp,
+			want:      []string{"api", "cache", "db", "web"},
+		},
+		{
+			name:      "dependents only",
+			roots:     []string{"db"},
+			depth:     -1,
+			direction: DirectionDown,
+			want:      []string{"api", "db", "web", "worker"},
+		},
+		{
+			name:      "limited depth",
+			roots:     []string{"db"},
+			depth:     1,
+			direction: DirectionDown,
+			want:      []string{"api", "db", "worker"},
+		},
+		{
+			name:      "depth zero keeps only the roots",
+			roots:     []string{"web", "admin"},
+			depth:     0,
+			direction: DirectionBoth,
+			want:      []string{"admin", "web"},
+		},
+		{
+			name:      "unknown root",
+			roots:     []string{"missing"},
+			depth:     -1,
+			direction: DirectionBoth,
+			err:       ErrNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			graph := buildTestGraph(t, deps, nil)
+			filtered, err := graph.Apply(FilterRoots(test.roots, test.depth, test.direction))
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to filter: %v", err)
+			}
+			if got := graphServiceNames(filtered); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("services = %v, want %v", got, test.want)
+			}
+			if len(graph.Services) != len(deps) {
+				t.Errorf("original graph lost services: %v", graphServiceNames(graph))
+			}
+		})
+	}
+}
+
+// TestFilterExclude drops services matching glob patterns
+func TestFilterExclude(t *testing.T) {
+	deps := map[string][]string{
+		"web":       {"api"},
+		"api":       {"users-db", "orders-db"},
+		"users-db":  nil,
+		"orders-db": nil,
+	}
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []string
+		wantDeps []string // depends_on edges left on api
+		fails    bool
+	}{
+		{
+			name:     "no patterns",
+			want:     []string{"api", "orders-db", "users-db", "web"},
+			wantDeps: []string{"orders-db", "users-db"},
+		},
+		{
+			name:     "glob drops services and their edges",
+			patterns: []string{"*-db"},
+			want:     []string{"api", "web"},
+		},
+		{
+			name:     "several patterns",
+			patterns: []string{"users-*", "web"},
+			want:     []string{"api", "orders-db"},
+			wantDeps: []string{"orders-db"},
+		},
+		{
+			name:     "invalid pattern",
+			patterns: []string{"[db"},
+			fails:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			graph := buildTestGraph(t, deps, nil)
+			filtered, err := graph.Apply(FilterExclude(test.patterns))
+			if test.fails {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to filter: %v", err)
+			}
+			if got := graphServiceNames(filtered); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("services = %v, want %v", got, test.want)
+			}
+			var gotDeps []string
+			for _, dep := range filtered.Services["api"].DependsOn {
+				gotDeps = append(gotDeps, dep.Name)
+			}
+			sort.Strings(gotDeps)
+			if !reflect.DeepEqual(gotDeps, test.wantDeps) {
+				t.Errorf("api depends on %v, want %v", gotDeps, test.wantDeps)
+			}
+		})
+	}
+}
+
+// TestFilterEdgeTypes keeps only the selected relationship types
+func TestFilterEdgeTypes(t *testing.T) {
+	compose := "services:\n" +
+		"  api:\n    image: api\n    depends_on: [db]\n    networks: [backend]\n    volumes: [\"data:/srv\"]\n" +
+		"  db:\n    image: postgres\n    networks: [backend]\n    volumes: [\"data:/var/lib/postgresql/data\"]\n" +
+		"networks:\n  backend:\nvolumes:\n  data:\n"
+	tests := []struct {
+		name  string
+		types []RelationshipType
+		want  []RelationshipType
+	}{
+		{
+			name:  "dependencies",
+			types: []RelationshipType{RelationshipDependsOn},
+			want:  []RelationshipType{RelationshipDependsOn},
+		},
+		{
+			name:  "networks",
+			types: []RelationshipType{RelationshipNetwork},
+			want:  []RelationshipType{RelationshipNetwork},
+		},
+		{
+			name:  "networks and volumes",
+			types: []RelationshipType{RelationshipVolume, RelationshipNetwork},
+			want:  []RelationshipType{RelationshipNetwork, RelationshipVolume},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			composeFile, err := ParseComposeData([]byte(compose))
+			if err != nil {
+				t.Fatalf("failed to parse compose file: %v", err)
+			}
+			graph, err := composeFile.BuildDependencyGraph()
+			if err != nil {
+				t.Fatalf("failed to build graph: %v", err)
+			}
+			filtered, err := graph.Apply(FilterEdgeTypes(test.types))
+			if err != nil {
+				t.Fatalf("failed to filter: %v", err)
+			}
+
+			seen := make(map[RelationshipType]bool)
+			for _, relationship := range filtered.GetAllRelationships() {
+				seen[relationship.Type] = true
+			}
+			var got []RelationshipType
+			for _, relationshipType := range []RelationshipType{RelationshipDependsOn, RelationshipNetwork, RelationshipVolume} {
+				if seen[relationshipType] {
+					got = append(got, relationshipType)
+				}
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("relationship types = %v, want %v", got, test.want)
+			}
+			if len(composeFile.Services["api"].DependsOn) != 1 {
+				t.Errorf("filter modified the compose file: %+v", composeFile.Services["api"])
+			}
+		})
+	}
+}
+
+// TestParseGraphFilter parses filter expressions and renders them back
+func TestParseGraphFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       GraphFilterSpec
+		canonical  string // String() of the result; empty when parsing must fail
+	}{
+		{
+			name:       "empty",
+			expression: "",
+			want:       NewGraphFilterSpec(),
+			canonical:  "",
+		},
+		{
+			name:       "bare service names",
+			expression: "api worker",
+			want:       GraphFilterSpec{Services: []string{"api", "worker"}, Depth: -1},
+			canonical:  "service=api,worker",
+		},
+		{
+			name:       "every key",
+			expression: "service=api,worker depth=2 direction=down exclude=*-db only-type=depends_on,network",
+			want: GraphFilterSpec{
+				Services:  []string{"api", "worker"},
+				Depth:     2,
+				Direction: DirectionDown,
+				Exclude:   []string{"*-db"},
+				OnlyTypes: []RelationshipType{RelationshipDependsOn, RelationshipNetwork},
+			},
+			canonical: "service=api,worker depth=2 direction=down exclude=*-db only-type=depends_on,network",
+		},
+		{
+			name:       "repeated keys and aliases",
+			expression: "services=api service=web, type=volumes exclude=a,,b",
+			want: GraphFilterSpec{
+				Services:  []string{"api", "web"},
+				Depth:     -1,
+				Exclude:   []string{"a", "b"},
+				OnlyTypes: []RelationshipType{RelationshipVolume},
+			},
+			canonical: "service=api,web exclude=a,b only-type=volume",
+		},
+		{name: "invalid depth", expression: "service=api depth=two"},
+		{name: "invalid direction", expression: "service=api direction=sideways"},
+		{name: "invalid type", expression: "only-type=links"},
+		{name: "unknown key", expression: "colour=red"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spec, err := ParseGraphFilter(test.expression)
+			if test.canonical == "" && test.expression != "" {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if !reflect.DeepEqual(spec, test.want) {
+				t.Errorf("spec = %+v, want %+v", spec, test.want)
+			}
+			if got := spec.String(); got != test.canonical {
+				t.Errorf("String() = %q, want %q", got, test.canonical)
+			}
+		})
+	}
+}
+
+// graphServiceNames returns the sorted service names of a graph
+func graphServiceNames(g *DependencyGraph) []string {
+	var names []string
+	for name := range g.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
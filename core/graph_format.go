@@ -286,8 +286,8 @@ func (g *DependencyGraph) FormatDOT(options DOTOptions) string {
 
 // isInCycle checks if a service is part of any circular dependency
 func (g *DependencyGraph) isInCycle(serviceName string) bool {
-	for _, cycle := range g.CircularDeps {
-		for _, name := range cycle {
+	for _, group := range g.CycleGroups {
+		for _, name := range group {
 			if name == serviceName {
 				return true
 			}
@@ -296,14 +296,23 @@ func (g *DependencyGraph) isInCycle(serviceName string) bool {
 	return false
 }
 
-// isCycleEdge checks if an edge is part of a circular dependency
+// isCycleEdge checks if an edge is part of a circular dependency.
+// Every edge between two services of the same strongly connected
+// component lies on at least one cycle.
 func (g *DependencyGraph) isCycleEdge(from, to string) bool {
-	for _, cycle := range g.CircularDeps {
-		for i := 0; i < len(cycle)-1; i++ {
-			if cycle[i] == from && cycle[i+1] == to {
-				return true
+	for _, group := range g.CycleGroups {
+		hasFrom, hasTo := false, false
+		for _, name := range group {
+			if name == from {
+				hasFrom = true
+			}
+			if name == to {
+				hasTo = true
 			}
 		}
+		if hasFrom && hasTo {
+			return true
+		}
 	}
 	return false
 }
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// maxDependencyPaths limits how many paths FindPaths enumerates, since the
+// number of simple paths can grow exponentially in dense graphs
+const maxDependencyPaths = 100
+
+// PathReport describes how two services are connected
+type PathReport struct {
+	From           string     `json:"from"`
+	To             string     `json:"to"`
+	Paths          [][]string `json:"paths"`     // depends_on paths, each starting at From
+	Reversed       bool       `json:"reversed"`  // true when the paths lead from To to From instead
+	Truncated      bool       `json:"truncated"` // true when more than maxDependencyPaths paths exist
+	SharedNetworks []string   `json:"shared_networks"`
+	SharedVolumes  []string   `json:"shared_volumes"`
+}
+
+// Connected reports whether the services are related in any way
+func (r *PathReport) Connected() bool {
+	return len(r.Paths) > 0 || len(r.SharedNetworks) > 0 || len(r.SharedVolumes) > 0
+}
+
+// SharedOnly reports whether the services are connected only through a
+// shared network or volume, without any depends_on path between them
+func (r *PathReport) SharedOnly() bool {
+	return len(r.Paths) == 0 && r.Connected()
+}
+
+// Services returns every service that lies on one of the paths
+func (r *PathReport) Services() []string {
+	seen := make(map[string]bool)
+	var services []string
+	for _, path := range r.Paths {
+		for _, name := range path {
+			if !seen[name] {
+				seen[name] = true
+				services = append(services, name)
+			}
+		}
+	}
+	sort.Strings(services)
+	return services
+}
+
+// FindPaths returns every depends_on path from one service to another. When
+// there is none, paths in the opposite direction are returned instead and the
+// report is marked as reversed. With shortest set, only the shortest path is
+// kept. Shared networks and volumes are reported either way.
+func (g *DependencyGraph) FindPaths(from, to string, shortest bool) (*PathReport, error) {
+	if _, exists := g.Services[from]; !exists {
+		return nil, fmt.Errorf("service '%s' %w", from, ErrNotFound)
+	}
+	if _, exists := g.Services[to]; !exists {
+		return nil, fmt.Errorf("service '%s' %w", to, ErrNotFound)
+	}
+
+	report := &PathReport{From: from, To: to}
+
+	adjacency := make(map[string][]string)
+	networks := make(map[string]bool)
+	volumes := make(map[string]bool)
+	for _, rel := range g.GetAllRelationships() {
+		switch rel.Type {
+		case RelationshipDependsOn:
+			adjacency[rel.From] = append(adjacency[rel.From], rel.To)
+		case RelationshipNetwork:
+			if rel.From == from && rel.To == to {
+				networks[rel.Metadata] = true
+			}
+		case RelationshipVolume:
+			if rel.From == from && rel.To == to {
+				volumes[rel.Metadata] = true
+			}
+		}
+	}
+	report.SharedNetworks = sortedKeys(networks)
+	report.SharedVolumes = sortedKeys(volumes)
+
+	if from == to {
+		report.Paths = [][]string{{from}}
+		return report, nil
+	}
+
+	find := func(start, end string) ([][]string, bool) {
+		if shortest {
+			if path := shortestPath(adjacency, start, end); path != nil {
+				return [][]string{path}, false
+			}
+			return nil, false
+		}
+		return allPaths(adjacency, start, end)
+	}
+
+	report.Paths, report.Truncated = find(from, to)
+	if len(report.Paths) == 0 {
+		report.Paths, report.Truncated = find(to, from)
+		report.Reversed = len(report.Paths) > 0
+	}
+
+	return report, nil
+}
+
+// allPaths enumerates simple paths between two services with a DFS, shortest
+// paths first
+func allPaths(adjacency map[string][]string, start, end string) ([][]string, bool) {
+	var paths [][]string
+	truncated := false
+	onPath := map[string]bool{start: true}
+
+	var visit func(path []string)
+	visit = func(path []string) {
+		if truncated {
+			return
+		}
+		current := path[len(path)-1]
+		for _, next := range adjacency[current] {
+			if next == end {
+				if len(paths) == maxDependencyPaths {
+					truncated = true
+					return
+				}
+				paths = append(paths, append(append([]string{}, path...), end))
+				continue
+			}
+			if onPath[next] {
+				continue
+			}
+			onPath[next] = true
+			visit(append(path, next))
+			onPath[next] = false
+		}
+	}
+	visit([]string{start})
+
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i]) < len(paths[j])
+	})
+
+	return paths, truncated
+}
+
+// shortestPath finds the shortest path between two services with a BFS
+func shortestPath(adjacency map[string][]string, start, end string) []string {
+	parent := map[string]string{start: ""}
+	queue := []string{start}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range adjacency[current] {
+			if _, seen := parent[next]; seen {
+				continue
+			}
+			parent[next] = current
+			if next == end {
+				path := []string{end}
+				for at := current; at != ""; at = parent[at] {
+					path = append([]string{at}, path...)
+				}
+				return path
+			}
+			queue = append(queue, next)
+		}
+	}
+
+	return nil
+}
+
+// sortedKeys returns the keys of a set in alphabetical order
+func sortedKeys(set map[string]bool) []string {
+	var keys []string
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// TestFindPaths checks the depends_on paths found between two services
+func TestFindPaths(t *testing.T) {
+	deps := map[string][]string{
+		"web":    {"api", "cache"},
+		"api":    {"db", "cache"},
+		"cache":  {"db"},
+		"db":     nil,
+		"worker": {"db"},
+	}
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		shortest bool
+		paths    [][]string
+		reversed bool
+		err      error
+	}{
+		{
+			name:  "every path, shortest first",
+			from:  "web",
+			to:    "db",
+			paths: [][]string{{"web", "api", "db"}, {"web", "cache", "db"}, {"web", "api", "cache", "db"}},
+		},
+		{
+			name:     "shortest path only",
+			from:     "web",
+			to:       "db",
+			shortest: true,
+			paths:    [][]string{{"web", "api", "db"}},
+		},
+		{
+			name:     "paths in the opposite direction",
+			from:     "cache",
+			to:       "web",
+			paths:    [][]string{{"web", "cache"}, {"web", "api", "cache"}},
+			reversed: true,
+		},
+		{
+			name:  "same service",
+			from:  "api",
+			to:    "api",
+			paths: [][]string{{"api"}},
+		},
+		{
+			name: "unrelated services",
+			from: "worker",
+			to:   "web",
+		},
+		{
+			name: "unknown source",
+			from: "missing",
+			to:   "db",
+			err:  ErrNotFound,
+		},
+		{
+			name: "unknown target",
+			from: "web",
+			to:   "missing",
+			err:  ErrNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			graph := buildTestGraph(t, deps, nil)
+			report, err := graph.FindPaths(test.from, test.to, test.shortest)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to find paths: %v", err)
+			}
+			if !reflect.DeepEqual(report.Paths, test.paths) {
+				t.Errorf("paths = %v, want %v", report.Paths, test.paths)
+			}
+			if report.Reversed != test.reversed {
+				t.Errorf("reversed = %v, want %v", report.Reversed, test.reversed)
+			}
+			if report.Connected() != (test.paths != nil) {
+				t.Errorf("connected = %v with paths %v", report.Connected(), report.Paths)
+			}
+		})
+	}
+}
+
+// TestFindPathsSharedResources checks that shared networks and volumes are
+// reported whether or not a depends_on path exists
+func TestFindPathsSharedResources(t *testing.T) {
+	compose := "services:\n" +
+		"  api:\n    image: api\n    depends_on: [db]\n    networks: [backend, frontend]\n" +
+		"  db:\n    image: postgres\n    networks: [backend]\n    volumes: [\"data:/var/lib/postgresql/data\"]\n" +
+		"  backup:\n    image: restic\n    volumes: [\"data:/data:ro\"]\n" +
+		"networks:\n  backend:\n  frontend:\nvolumes:\n  data:\n"
+	tests := []struct {
+		name       string
+		from       string
+		to         string
+		networks   []string
+		volumes    []string
+		sharedOnly bool
+	}{
+		{name: "path and shared network", from: "api", to: "db", networks: []string{"backend"}},
+		{name: "either order", from: "db", to: "api", networks: []string{"backend"}},
+		{name: "shared volume only", from: "backup", to: "db", volumes: []string{"data"}, sharedOnly: true},
+		{name: "nothing shared", from: "api", to: "backup"},
+	}
+
+	composeFile, err := ParseComposeData([]byte(compose))
+	if err != nil {
+		t.Fatalf("failed to parse compose file: %v", err)
+	}
+	graph, err := composeFile.BuildDependencyGraph()
+	if err != nil {
+		t.Fatalf("failed to build graph: %v", err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := graph.FindPaths(test.from, test.to, false)
+			if err != nil {
+				t.Fatalf("failed to find paths: %v", err)
+			}
+			if !reflect.DeepEqual(report.SharedNetworks, test.networks) {
+				t.Errorf("shared networks = %v, want %v", report.SharedNetworks, test.networks)
+			}
+			if !reflect.DeepEqual(report.SharedVolumes, test.volumes) {
+				t.Errorf("shared volumes = %v, want %v", report.SharedVolumes, test.volumes)
+			}
+			if report.SharedOnly() != test.sharedOnly {
+				t.Errorf("shared only = %v, want %v", report.SharedOnly(), test.sharedOnly)
+			}
+		})
+	}
+}
+
+// TestFindPathsTruncated checks that path enumeration stops at
+// maxDependencyPaths in dense graphs
+func TestFindPathsTruncated(t *testing.T) {
+	// Three layers of five services between start and end give 125 paths
+	deps := map[string][]string{"end": nil}
+	previous := []string{"start"}
+	for layer := 0; layer < 3; layer++ {
+		var current []string
+		for i := 0; i < 5; i++ {
+			current = append(current, fmt.Sprintf("l%d-%d", layer, i))
+		}
+		for _, name := range previous {
+			deps[name] = current
+		}
+		previous = current
+	}
+	for _, name := range previous {
+		deps[name] = []string{"end"}
+	}
+
+	graph := buildTestGraph(t, deps, nil)
+	report, err := graph.FindPaths("start", "end", false)
+	if err != nil {
+		t.Fatalf("failed to find paths: %v", err)
+	}
+	if !report.Truncated || len(report.Paths) != maxDependencyPaths {
+		t.Fatalf("found %d paths (truncated = %v), want %d truncated", len(report.Paths), report.Truncated, maxDependencyPaths)
+	}
+	// The first 100 paths run through the first four services of layer 0
+	if services := report.Services(); len(services) != 16 || slices.Contains(services, "l0-4") {
+		t.Errorf("services on the paths = %v, want all but l0-4", services)
+	}
+}
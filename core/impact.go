@@ -0,0 +1,163 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ServiceDistance describes a service reached while walking the graph
+type ServiceDistance struct {
+	Name string   `json:"name"`
+	Hops int      `json:"hops"`
+	Path []string `json:"path"` // services traversed from the starting service, inclusive
+}
+
+// SoftImpact describes a service that shares resources with another service
+// without depending on it
+type SoftImpact struct {
+	Name     string   `json:"name"`
+	Networks []string `json:"networks"`
+	Volumes  []string `json:"volumes"`
+}
+
+// ImpactReport describes which services are affected when a service goes down
+type ImpactReport struct {
+	Service string            `json:"service"`
+	Hard    []ServiceDistance `json:"hard"`
+	Soft    []SoftImpact      `json:"soft"`
+}
+
+// Direct returns hard-impacted services one hop away
+func (r *ImpactReport) Direct() []ServiceDistance {
+	var direct []ServiceDistance
+	for _, s := range r.Hard {
+		if s.Hops == 1 {
+			direct = append(direct, s)
+		}
+	}
+	return direct
+}
+
+// Transitive returns hard-impacted services more than one hop away
+func (r *ImpactReport) Transitive() []ServiceDistance {
+	var transitive []ServiceDistance
+	for _, s := range r.Hard {
+		if s.Hops > 1 {
+			transitive = append(transitive, s)
+		}
+	}
+	return transitive
+}
+
+// Impact returns every service that breaks if the given service goes down.
+// Hard impact follows depends_on edges in reverse (DependedBy), soft impact
+// lists services sharing a network or volume with it.
+func (g *DependencyGraph) Impact(serviceName string) (*ImpactReport, error) {
+	node, exists := g.Services[serviceName]
+	if !exists {
+		return nil, fmt.Errorf("service '%s' %w", serviceName, ErrNotFound)
+	}
+
+	report := &ImpactReport{
+		Service: serviceName,
+		Hard: g.walk(node, func(n *ServiceNode) []*ServiceNode {
+			return n.DependedBy
+		}),
+		Soft: []SoftImpact{},
+	}
+
+	hard := make(map[string]bool)
+	for _, s := range report.Hard {
+		hard[s.Name] = true
+	}
+
+	soft := make(map[string]*SoftImpact)
+	for network, peers := range node.NetworkPeers {
+		for _, peer := range peers {
+			if peer.Name == serviceName || hard[peer.Name] {
+				continue
+			}
+			if soft[peer.Name] == nil {
+				soft[peer.Name] = &SoftImpact{Name: peer.Name, Networks: []string{}, Volumes: []string{}}
+			}
+			soft[peer.Name].Networks = append(soft[peer.Name].Networks, network)
+		}
+	}
+	for volume, peers := range node.VolumePeers {
+		for _, peer := range peers {
+			if peer.Name == serviceName || hard[peer.Name] {
+				continue
+			}
+			if soft[peer.Name] == nil {
+				soft[peer.Name] = &SoftImpact{Name: peer.Name, Networks: []string{}, Volumes: []string{}}
+			}
+			soft[peer.Name].Volumes = append(soft[peer.Name].Volumes, volume)
+		}
+	}
+
+	for _, impact := range soft {
+		sort.Strings(impact.Networks)
+		sort.Strings(impact.Volumes)
+		report.Soft = append(report.Soft, *impact)
+	}
+	sort.Slice(report.Soft, func(i, j int) bool {
+		return report.Soft[i].Name < report.Soft[j].Name
+	})
+
+	return report, nil
+}
+
+// Requires returns the full upstream closure of a service: every service it
+// depends on, directly or transitively
+func (g *DependencyGraph) Requires(serviceName string) ([]ServiceDistance, error) {
+	node, exists := g.Services[serviceName]
+	if !exists {
+		return nil, fmt.Errorf("service '%s' %w", serviceName, ErrNotFound)
+	}
+
+	return g.walk(node, func(n *ServiceNode) []*ServiceNode {
+		return n.DependsOn
+	}), nil
+}
+
+// walk performs a breadth-first traversal from a node, recording the hop
+// distance and shortest path to every reachable service. Results are sorted
+// by distance, then by name.
+func (g *DependencyGraph) walk(start *ServiceNode, next func(*ServiceNode) []*ServiceNode) []ServiceDistance {
+	paths := map[string][]string{start.Name: {start.Name}}
+	queue := []*ServiceNode{start}
+	result := []ServiceDistance{}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		neighbours := append([]*ServiceNode{}, next(current)...)
+		sort.Slice(neighbours, func(i, j int) bool {
+			return neighbours[i].Name < neighbours[j].Name
+		})
+
+		for _, neighbour := range neighbours {
+			if _, seen := paths[neighbour.Name]; seen {
+				continue
+			}
+			path := append(append([]string{}, paths[current.Name]...), neighbour.Name)
+			paths[neighbour.Name] = path
+			result = append(result, ServiceDistance{
+				Name: neighbour.Name,
+				Hops: len(path) - 1,
+				Path: path,
+			})
+			queue = append(queue, neighbour)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Hops != result[j].Hops {
+			return result[i].Hops < result[j].Hops
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
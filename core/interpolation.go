@@ -0,0 +1,166 @@
+package core
+
+import (
+	"slices"
+
+	"github.com/firasmosbahi/container-composer/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvReference is a variable a compose file interpolates, such as ${DB_USER},
+// ${DB_USER:-app} or $DB_USER
+type EnvReference struct {
+	Name       string   `json:"name"`
+	Default    string   `json:"default,omitempty"` // value after :- or -
+	HasDefault bool     `json:"has_default"`
+	Required   bool     `json:"required"` // ${VAR:?message}: compose fails when it is unset
+	Services   []string `json:"services"` // services whose definition uses the variable
+}
+
+// EnvReferences returns the variables interpolated in compose file content,
+// in order of first use, with the services using them. Comments and $$
+// escapes are ignored.
+func EnvReferences(data []byte) ([]EnvReference, error) {
+	done := logging.Timed("collect env references", "bytes", len(data))
+	defer done()
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, invalidf("failed to parse YAML: %w", err)
+	}
+
+	var refs []EnvReference
+	index := make(map[string]int)
+	add := func(ref EnvReference, service string) {
+		i, seen := index[ref.Name]
+		if !seen {
+			i = len(refs)
+			index[ref.Name] = i
+			refs = append(refs, EnvReference{Name: ref.Name, Services: []string{}})
+		}
+		// The first default wins, as it is the one documented for the
+		// variable
+		if ref.HasDefault && !refs[i].HasDefault {
+			logging.Logger().Debug("taking default for variable", "name", ref.Name, "default", ref.Default, "service", service)
+			refs[i].Default, refs[i].HasDefault = ref.Default, true
+		} else if ref.HasDefault && ref.Default != refs[i].Default {
+			logging.Logger().Info("ignoring a different default for variable",
+				"name", ref.Name, "default", ref.Default, "kept", refs[i].Default, "service", service)
+		}
+		refs[i].Required = refs[i].Required || ref.Required
+		if service != "" && !slices.Contains(refs[i].Services, service) {
+			refs[i].Services = append(refs[i].Services, service)
+		}
+	}
+
+	var walk func(node *yaml.Node, service string)
+	walk = func(node *yaml.Node, service string) {
+		if node.Kind == yaml.ScalarNode {
+			for _, ref := range interpolations(node.Value) {
+				add(ref, service)
+			}
+		}
+		for _, child := range node.Content {
+			walk(child, service)
+		}
+	}
+
+	// Service definitions are walked with their name, everything else
+	// without one
+	document := &root
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		document = root.Content[0]
+	}
+	if document.Kind != yaml.MappingNode {
+		walk(document, "")
+		return refs, nil
+	}
+	for i := 0; i+1 < len(document.Content); i += 2 {
+		key, value := document.Content[i], document.Content[i+1]
+		if key.Value != "services" || value.Kind != yaml.MappingNode {
+			walk(value, "")
+			continue
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			walk(value.Content[j+1], value.Content[j].Value)
+		}
+	}
+	return refs, nil
+}
+
+// interpolations returns the variable references in a value, including those
+// nested in defaults such as ${A:-${B}}
+func interpolations(value string) []EnvReference {
+	var refs []EnvReference
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			continue
+		}
+		if value[i+1] == '$' {
+			i++
+			continue
+		}
+
+		if value[i+1] != '{' {
+			if end := nameEnd(value, i+1); end > i+1 {
+				refs = append(refs, EnvReference{Name: value[i+1 : end]})
+				i = end - 1
+			}
+			continue
+		}
+
+		end := nameEnd(value, i+2)
+		if end == i+2 {
+			continue
+		}
+		ref := EnvReference{Name: value[i+2 : end]}
+		closing := matchingBrace(value, i+1)
+		if closing < 0 {
+			continue
+		}
+		modifier := value[end:closing]
+		switch {
+		case len(modifier) >= 2 && modifier[:2] == ":-":
+			ref.Default, ref.HasDefault = modifier[2:], true
+		case len(modifier) >= 2 && modifier[:2] == ":?", len(modifier) >= 1 && modifier[0] == '?':
+			ref.Required = true
+		case len(modifier) >= 1 && modifier[0] == '-':
+			ref.Default, ref.HasDefault = modifier[1:], true
+		}
+		refs = append(refs, ref)
+		// Continue inside the modifier so nested references are found
+		i = end - 1
+	}
+	return refs
+}
+
+// nameEnd returns the end of the variable name starting at start
+func nameEnd(value string, start int) int {
+	end := start
+	for end < len(value) {
+		c := value[end]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || end > start && c >= '0' && c <= '9' {
+			end++
+			continue
+		}
+		break
+	}
+	return end
+}
+
+// matchingBrace returns the index of the } closing the { at open
+func matchingBrace(value string, open int) int {
+	depth := 0
+	for i := open; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,223 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/internal/logging"
+)
+
+// MergeOptions controls how a fragment is merged into a compose file
+type MergeOptions struct {
+	// Network, when set, is joined by every merged service. It must exist in
+	// the compose file or be declared by the fragment.
+	Network string
+}
+
+// MergeReport describes the outcome of merging a fragment
+type MergeReport struct {
+	Services []string       `json:"services"` // names as added, after renames
+	Networks []string       `json:"networks"`
+	Volumes  []string       `json:"volumes"`
+	Renamed  []RenameReport `json:"renamed"` // fragment resources renamed to avoid collisions
+	Ports    []PortChange   `json:"ports"`   // host ports moved to avoid collisions
+}
+
+// PortChange is a host port of a merged service that was already published
+// by another service
+type PortChange struct {
+	Service string `json:"service"`
+	Old     string `json:"old"` // the mapping as declared, e.g. "6379:6379"
+	New     string `json:"new"`
+}
+
+// Merge adds the services, networks and volumes of a fragment to the compose
+// file. Fragment resources whose names are taken are renamed along with their
+// references, including hostname usages inside the fragment; a network that
+// is declared identically on both sides is shared instead. Host ports already
+// published by another service are moved to the next free port.
+func (c *ComposeFile) Merge(fragment *ComposeDocument, opts MergeOptions) (*MergeReport, error) {
+	done := logging.Timed("merge fragment", "network", opts.Network)
+	report := &MergeReport{Services: []string{}, Networks: []string{}, Volumes: []string{},
+		Renamed: []RenameReport{}, Ports: []PortChange{}}
+	defer func() {
+		done("services", len(report.Services), "renamed", len(report.Renamed), "ports moved", len(report.Ports))
+	}()
+
+	incoming, err := fragment.ComposeFile()
+	if err != nil {
+		return nil, fmt.Errorf("invalid fragment: %w", err)
+	}
+	if len(incoming.Services) == 0 {
+		return nil, fmt.Errorf("fragment has no services")
+	}
+
+	// Rename colliding fragment resources, in the document so references
+	// follow
+	for _, section := range []struct {
+		kind     string
+		existing []string
+		incoming []string
+		shared   func(name string) bool
+		rename   func(oldName, newName string) (*RenameReport, error)
+	}{
+		{"service", mapKeys(c.Services), mapKeys(incoming.Services), nil, fragment.RenameService},
+		{"network", mapKeys(c.Networks), mapKeys(incoming.Networks), func(name string) bool {
+			return reflect.DeepEqual(c.Networks[name], incoming.Networks[name])
+		}, fragment.RenameNetwork},
+		{"volume", mapKeys(c.Volumes), mapKeys(incoming.Volumes), nil, fragment.RenameVolume},
+	} {
+		existing := nameSet(section.existing)
+		names := nameSet(section.existing, section.incoming)
+		for _, name := range section.incoming {
+			if !existing[name] {
+				continue
+			}
+			if section.shared != nil && section.shared(name) {
+				logging.Logger().Info("sharing identical "+section.kind+" with the compose file", "name", name)
+				continue
+			}
+			newName := uniqueName(name, names)
+			names[newName] = true
+			logging.Logger().Info("renaming fragment "+section.kind+" to avoid a collision", "old", name, "new", newName)
+			rename, err := section.rename(name, newName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to rename %s '%s': %w", section.kind, name, err)
+			}
+			fragment.RewriteHostnames(rename.Hostnames)
+			report.Renamed = append(report.Renamed, *rename)
+		}
+	}
+	if incoming, err = fragment.ComposeFile(); err != nil {
+		return nil, fmt.Errorf("invalid fragment: %w", err)
+	}
+
+	if opts.Network != "" && !c.NetworkExists(opts.Network) {
+		if _, declared := incoming.Networks[opts.Network]; !declared {
+			return nil, fmt.Errorf("network '%s' %w", opts.Network, ErrNotFound)
+		}
+	}
+
+	used := c.publishedPorts()
+	for _, name := range mapKeys(incoming.Services) {
+		service := incoming.Services[name]
+		service.Name = name
+		for i, mapping := range service.Ports {
+			moved, changed := movePort(mapping, used)
+			if changed {
+				logging.Logger().Info("moving host port already published by another service",
+					"service", name, "old", mapping, "new", moved)
+				report.Ports = append(report.Ports, PortChange{Service: name, Old: mapping, New: moved})
+				service.Ports[i] = moved
+			}
+		}
+		if opts.Network != "" && !slices.Contains(service.Networks, opts.Network) {
+			service.Networks = append(service.Networks, opts.Network)
+		}
+		c.AddService(service)
+		report.Services = append(report.Services, name)
+	}
+	for _, name := range mapKeys(incoming.Networks) {
+		if !c.NetworkExists(name) {
+			c.AddNetwork(name, incoming.Networks[name])
+			report.Networks = append(report.Networks, name)
+		}
+	}
+	for _, name := range mapKeys(incoming.Volumes) {
+		c.AddVolume(name, incoming.Volumes[name])
+		report.Volumes = append(report.Volumes, name)
+	}
+
+	return report, nil
+}
+
+// publishedPorts returns the host ports published by the services
+func (c *ComposeFile) publishedPorts() map[int]bool {
+	used := make(map[int]bool)
+	for _, service := range c.Services {
+		for _, mapping := range service.Ports {
+			if _, port, _, ok := splitHostPort(mapping); ok {
+				used[port] = true
+			}
+		}
+	}
+	return used
+}
+
+// movePort returns the port mapping with its host port moved to the next
+// port not in used, and marks the port it keeps as used
+func movePort(mapping string, used map[int]bool) (string, bool) {
+	prefix, port, rest, ok := splitHostPort(mapping)
+	if !ok {
+		return mapping, false
+	}
+	moved := port
+	for used[moved] && moved < 65535 {
+		moved++
+	}
+	used[moved] = true
+	if moved == port {
+		return mapping, false
+	}
+	return prefix + strconv.Itoa(moved) + rest, true
+}
+
+// splitHostPort splits a short port mapping around its host port:
+// "127.0.0.1:8080:80/tcp" gives "127.0.0.1:", 8080 and ":80/tcp". Mappings
+// without a single numeric host port, such as "80" or ranges, are not split.
+func splitHostPort(mapping string) (string, int, string, bool) {
+	parts := strings.Split(mapping, ":")
+	var index int
+	switch len(parts) {
+	case 2:
+		index = 0
+	case 3:
+		index = 1
+	default:
+		return "", 0, "", false
+	}
+	port, err := strconv.Atoi(parts[index])
+	if err != nil {
+		return "", 0, "", false
+	}
+	prefix := strings.Join(parts[:index], ":")
+	if prefix != "" {
+		prefix += ":"
+	}
+	return prefix, port, ":" + strings.Join(parts[index+1:], ":"), true
+}
+
+// uniqueName returns name, or name-2, name-3... when it is taken
+func uniqueName(name string, taken map[string]bool) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// mapKeys returns the sorted keys of a map
+func mapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// nameSet returns the set of names in the lists
+func nameSet(lists ...[]string) map[string]bool {
+	set := make(map[string]bool)
+	for _, list := range lists {
+		for _, name := range list {
+			set[name] = true
+		}
+	}
+	return set
+}
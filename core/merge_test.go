@@ -0,0 +1,169 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMerge merges fragments into a compose file and checks the renamed
+// resources, moved ports and rewritten references
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		compose  string
+		fragment string
+		network  string
+		renamed  []string          // "kind old → new"
+		ports    []string          // "service old → new"
+		fields   map[string]string // "service.field" → comma-separated value
+		err      error
+	}{
+		{
+			name:     "no collisions",
+			compose:  "services:\n  web:\n    image: nginx\n",
+			fragment: "services:\n  redis:\n    image: redis\n",
+			fields:   map[string]string{"redis.image": "redis", "web.image": "nginx"},
+		},
+		{
+			name:    "service renamed with its references and hostnames",
+			compose: "services:\n  redis:\n    image: redis:6\n",
+			fragment: "services:\n  redis:\n    image: redis:7\n  app:\n    image: app\n" +
+				"    depends_on: [redis]\n    environment:\n      REDIS_URL: redis://redis:6379\n",
+			renamed: []string{"service redis → redis-2"},
+			fields: map[string]string{
+				"redis.image":               "redis:6",
+				"redis-2.image":             "redis:7",
+				"app.depends_on":            "redis-2",
+				"app.environment.REDIS_URL": "redis://redis-2:6379",
+			},
+		},
+		{
+			name:     "taken host ports move to the next free port",
+			compose:  "services:\n  web:\n    image: nginx\n    ports: [\"8080:80\", \"127.0.0.1:8081:81\"]\n",
+			fragment: "services:\n  api:\n    image: api\n    ports: [\"8080:3000\", \"9000:9000/udp\", \"3000\"]\n  admin:\n    image: admin\n    ports: [\"9000:80\"]\n",
+			ports:    []string{"api 8080:3000 → 8082:3000", "api 9000:9000/udp → 9001:9000/udp"},
+			fields: map[string]string{
+				"admin.ports": "9000:80", // merged first, in name order
+				"api.ports":   "8082:3000,9001:9000/udp,3000",
+				"web.ports":   "8080:80,127.0.0.1:8081:81",
+			},
+		},
+		{
+			name:     "identical network is shared",
+			compose:  "services:\n  web:\n    image: nginx\n    networks: [backend]\nnetworks:\n  backend:\n    driver: bridge\n",
+			fragment: "services:\n  db:\n    image: postgres\n    networks: [backend]\nnetworks:\n  backend:\n    driver: bridge\n",
+			fields:   map[string]string{"db.networks": "backend"},
+		},
+		{
+			name:     "different network and volume are renamed",
+			compose:  "services:\n  web:\n    image: nginx\n    networks: [backend]\n    volumes: [\"data:/srv\"]\nnetworks:\n  backend:\n    driver: bridge\nvolumes:\n  data:\n",
+			fragment: "services:\n  db:\n    image: postgres\n    networks: [backend]\n    volumes: [\"data:/var/lib/postgresql/data\"]\nnetworks:\n  backend:\n    driver: overlay\nvolumes:\n  data:\n",
+			renamed:  []string{"network backend → backend-2", "volume data → data-2"},
+			fields: map[string]string{
+				"db.networks": "backend-2",
+				"db.volumes":  "data-2:/var/lib/postgresql/data",
+			},
+		},
+		{
+			name:     "services join the requested network",
+			compose:  "services:\n  web:\n    image: nginx\n    networks: [frontend]\nnetworks:\n  frontend:\n",
+			fragment: "services:\n  api:\n    image: api\n",
+			network:  "frontend",
+			fields:   map[string]string{"api.networks": "frontend"},
+		},
+		{
+			name:     "requested network must exist",
+			compose:  "services:\n  web:\n    image: nginx\n",
+			fragment: "services:\n  api:\n    image: api\n",
+			network:  "frontend",
+			err:      ErrNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			composeFile, err := ParseComposeData([]byte(test.compose))
+			if err != nil {
+				t.Fatalf("failed to parse compose file: %v", err)
+			}
+			fragment := parseTestDocument(t, test.fragment)
+
+			report, err := composeFile.Merge(fragment, MergeOptions{Network: test.network})
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to merge: %v", err)
+			}
+
+			var renamed []string
+			for _, rename := range report.Renamed {
+				renamed = append(renamed, rename.Kind+" "+rename.Old+" → "+rename.New)
+			}
+			if !reflect.DeepEqual(renamed, test.renamed) {
+				t.Errorf("renamed = %v, want %v", renamed, test.renamed)
+			}
+			var ports []string
+			for _, change := range report.Ports {
+				ports = append(ports, change.Service+" "+change.Old+" → "+change.New)
+			}
+			if !reflect.DeepEqual(ports, test.ports) {
+				t.Errorf("ports = %v, want %v", ports, test.ports)
+			}
+			for field, want := range test.fields {
+				if got := serviceField(composeFile, field); got != want {
+					t.Errorf("%s = %q, want %q", field, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestSplitHostPort splits port mappings around their host port
+func TestSplitHostPort(t *testing.T) {
+	tests := map[string][]interface{}{
+		"8080:80":               {"", 8080, ":80", true},
+		"127.0.0.1:8080:80/tcp": {"127.0.0.1:", 8080, ":80/tcp", true},
+		"80":                    {"", 0, "", false},
+		"8000-8010:8000-8010":   {"", 0, "", false},
+		"${PORT}:80":            {"", 0, "", false},
+	}
+	for mapping, want := range tests {
+		prefix, port, rest, ok := splitHostPort(mapping)
+		if got := []interface{}{prefix, port, rest, ok}; !reflect.DeepEqual(got, want) {
+			t.Errorf("splitHostPort(%q) = %v, want %v", mapping, got, want)
+		}
+	}
+}
+
+// serviceField returns a service field such as "api.ports" or
+// "api.environment.KEY" as a comma-separated string
+func serviceField(composeFile *ComposeFile, field string) string {
+	parts := strings.SplitN(field, ".", 3)
+	service, ok := composeFile.Services[parts[0]]
+	if !ok || len(parts) < 2 {
+		return ""
+	}
+	switch parts[1] {
+	case "image":
+		return service.Image
+	case "ports":
+		return strings.Join(service.Ports, ",")
+	case "networks":
+		return strings.Join(service.Networks, ",")
+	case "volumes":
+		return strings.Join(service.Volumes, ",")
+	case "depends_on":
+		return strings.Join(service.DependsOn, ",")
+	case "environment":
+		if len(parts) == 3 {
+			return service.Environment[parts[2]]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,419 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// Patch formats understood by ParsePatch
+const (
+	PatchTypeJSON  = "json"  // JSON Patch, RFC 6902
+	PatchTypeMerge = "merge" // JSON Merge Patch, RFC 7386
+)
+
+// PatchOperation is a single JSON Patch operation
+type PatchOperation struct {
+	Op    string
+	Path  string
+	From  string
+	Value interface{}
+}
+
+// String describes the operation for error messages
+func (op PatchOperation) String() string {
+	if op.From != "" {
+		return fmt.Sprintf("%s %s → %s", op.Op, op.From, op.Path)
+	}
+	return fmt.Sprintf("%s %s", op.Op, op.Path)
+}
+
+// Patch is a parsed JSON Patch or JSON Merge Patch document. Both formats can
+// be written as JSON or YAML.
+type Patch struct {
+	Type       string
+	Operations []PatchOperation // JSON Patch
+	Merge      interface{}      // JSON Merge Patch
+}
+
+// ParsePatch parses a patch document. With an empty patchType the format is
+// detected: a list of operations is a JSON Patch, a mapping is a Merge Patch.
+func ParsePatch(data []byte, patchType string) (*Patch, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse patch: %w", err)
+	}
+
+	if patchType == "" {
+		switch raw.(type) {
+		case []interface{}:
+			patchType = PatchTypeJSON
+		case map[string]interface{}:
+			patchType = PatchTypeMerge
+		default:
+			return nil, fmt.Errorf("patch must be a list of JSON Patch operations or a merge patch mapping")
+		}
+		logging.Logger().Info("detected patch type", "type", patchType)
+	}
+
+	switch patchType {
+	case PatchTypeMerge:
+		if _, ok := raw.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("merge patch must be a mapping")
+		}
+		return &Patch{Type: PatchTypeMerge, Merge: raw}, nil
+	case PatchTypeJSON:
+		items, ok := raw.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("JSON Patch must be a list of operations")
+		}
+		patch := &Patch{Type: PatchTypeJSON}
+		for i, item := range items {
+			op, err := parsePatchOperation(item)
+			if err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i+1, err)
+			}
+			patch.Operations = append(patch.Operations, op)
+		}
+		return patch, nil
+	default:
+		return nil, fmt.Errorf("unknown patch type '%s' (use %s or %s)", patchType, PatchTypeJSON, PatchTypeMerge)
+	}
+}
+
+// parsePatchOperation checks the members required by each operation
+func parsePatchOperation(item interface{}) (PatchOperation, error) {
+	fields, ok := item.(map[string]interface{})
+	if !ok {
+		return PatchOperation{}, fmt.Errorf("operation must be a mapping")
+	}
+
+	member := func(name string) (string, error) {
+		value, ok := fields[name].(string)
+		if !ok {
+			return "", fmt.Errorf("missing or non-string '%s'", name)
+		}
+		return value, nil
+	}
+
+	var op PatchOperation
+	var err error
+	if op.Op, err = member("op"); err != nil {
+		return op, err
+	}
+	if op.Path, err = member("path"); err != nil {
+		return op, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		value, ok := fields["value"]
+		if !ok {
+			return op, fmt.Errorf("'%s' requires a value", op.Op)
+		}
+		op.Value = value
+	case "move", "copy":
+		if op.From, err = member("from"); err != nil {
+			return op, err
+		}
+	case "remove":
+	default:
+		return op, fmt.Errorf("unknown op '%s'", op.Op)
+	}
+	return op, nil
+}
+
+// Apply applies the patch to a decoded YAML document and returns the result;
+// the input is not modified. Operations are applied all or nothing.
+//
+// Unless strict is set, JSON Patch operations are made idempotent so a patch
+// can be re-applied safely: removing a missing value, appending ("/-") a value
+// the list already contains and moving from a missing path once the target
+// exists are no-ops. Merge patches are idempotent by definition.
+func (p *Patch) Apply(doc interface{}, strict bool) (interface{}, error) {
+	done := logging.Timed("apply patch", "type", p.Type, "operations", len(p.Operations), "strict", strict)
+	defer done()
+
+	doc = deepCopy(doc)
+	if p.Type == PatchTypeMerge {
+		return mergePatch(doc, p.Merge), nil
+	}
+
+	for i, op := range p.Operations {
+		logging.Logger().Debug("applying patch operation", "index", i+1, "op", op.Op, "path", op.Path, "from", op.From)
+		var err error
+		if doc, err = applyOperation(doc, op, strict); err != nil {
+			return nil, fmt.Errorf("operation %d (%s): %w", i+1, op, err)
+		}
+	}
+	return doc, nil
+}
+
+// applyOperation applies a single JSON Patch operation
+func applyOperation(doc interface{}, op PatchOperation, strict bool) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		return pointerAdd(doc, path, deepCopy(op.Value), strict)
+	case "remove":
+		if _, err := pointerGet(doc, path); err != nil && !strict {
+			logging.Logger().Info("skipping remove of a missing value", "path", op.Path)
+			return doc, nil
+		}
+		return pointerRemove(doc, path)
+	case "replace":
+		if _, err := pointerGet(doc, path); err != nil {
+			return nil, err
+		}
+		if len(path) == 0 {
+			return deepCopy(op.Value), nil
+		}
+		if doc, err = pointerRemove(doc, path); err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, path, deepCopy(op.Value), true)
+	case "test":
+		value, err := pointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(value, op.Value) {
+			return nil, fmt.Errorf("test failed: value differs")
+		}
+		return doc, nil
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := pointerGet(doc, from)
+		if err != nil {
+			if _, targetErr := pointerGet(doc, path); op.Op == "move" && !strict && targetErr == nil {
+				logging.Logger().Info("skipping move that was already applied", "from", op.From, "path", op.Path)
+				return doc, nil
+			}
+			return nil, err
+		}
+		if op.Op == "move" {
+			if op.From == op.Path {
+				return doc, nil
+			}
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, fmt.Errorf("cannot move a value into one of its children")
+			}
+			if doc, err = pointerRemove(doc, from); err != nil {
+				return nil, err
+			}
+		}
+		return pointerAdd(doc, path, deepCopy(value), strict)
+	}
+	return nil, fmt.Errorf("unknown op '%s'", op.Op)
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into unescaped tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON Pointer '%s': must start with '/'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses a list index token; "-" is only accepted when allowed
+// and yields len(list)
+func arrayIndex(token string, list []interface{}, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return len(list), nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid list index '%s'", token)
+	}
+	limit := len(list)
+	if allowEnd {
+		limit++
+	}
+	if index >= limit {
+		return 0, fmt.Errorf("list index %d out of range", index)
+	}
+	return index, nil
+}
+
+// pointerGet returns the value a pointer refers to
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	for i, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path '%s' %w", formatPointer(path[:i+1]), ErrNotFound)
+			}
+			doc = value
+		case []interface{}:
+			index, err := arrayIndex(token, node, false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[index]
+		default:
+			return nil, fmt.Errorf("path '%s' %w", formatPointer(path[:i+1]), ErrNotFound)
+		}
+	}
+	return doc, nil
+}
+
+// pointerAdd adds a value at a pointer and returns the updated document.
+// Unless strict is set, appending a value a list already contains is a no-op.
+func pointerAdd(doc interface{}, path []string, value interface{}, strict bool) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parentPath, last := path[:len(path)-1], path[len(path)-1]
+	parent, err := pointerGet(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		index, err := arrayIndex(last, node, true)
+		if err != nil {
+			return nil, err
+		}
+		if last == "-" && !strict {
+			for _, item := range node {
+				if reflect.DeepEqual(item, value) {
+					logging.Logger().Info("skipping append of a value the list already contains", "path", formatPointer(path))
+					return doc, nil
+				}
+			}
+		}
+		list := append(node[:index:index], append([]interface{}{value}, node[index:]...)...)
+		return replaceAt(doc, parentPath, list)
+	default:
+		return nil, fmt.Errorf("path '%s' is not a mapping or list", formatPointer(parentPath))
+	}
+}
+
+// pointerRemove removes the value at a pointer and returns the updated document
+func pointerRemove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+	parentPath, last := path[:len(path)-1], path[len(path)-1]
+	parent, err := pointerGet(doc, parentPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[last]; !ok {
+			return nil, fmt.Errorf("path '%s' %w", formatPointer(path), ErrNotFound)
+		}
+		delete(node, last)
+		return doc, nil
+	case []interface{}:
+		index, err := arrayIndex(last, node, false)
+		if err != nil {
+			return nil, err
+		}
+		list := append(node[:index:index], node[index+1:]...)
+		return replaceAt(doc, parentPath, list)
+	default:
+		return nil, fmt.Errorf("path '%s' %w", formatPointer(path), ErrNotFound)
+	}
+}
+
+// replaceAt stores a value at an existing pointer. Lists change length when
+// items are added or removed, so their parent has to be updated.
+func replaceAt(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		node[last] = value
+	case []interface{}:
+		index, err := arrayIndex(last, node, false)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = value
+	}
+	return doc, nil
+}
+
+// formatPointer renders tokens back into an escaped JSON Pointer
+func formatPointer(path []string) string {
+	var builder strings.Builder
+	for _, token := range path {
+		builder.WriteString("/")
+		builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return builder.String()
+}
+
+// mergePatch implements the JSON Merge Patch algorithm of RFC 7386: mappings
+// are merged recursively, null removes a key and anything else replaces the
+// target value
+func mergePatch(target, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return deepCopy(patch)
+	}
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = make(map[string]interface{})
+	}
+	for key, value := range patchMap {
+		if value == nil {
+			delete(targetMap, key)
+			continue
+		}
+		targetMap[key] = mergePatch(targetMap[key], value)
+	}
+	return targetMap
+}
+
+// deepCopy copies the mappings and lists of a decoded YAML value
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	default:
+		return value
+	}
+}
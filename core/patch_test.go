@@ -0,0 +1,255 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestJSONPatch applies RFC 6902 operations, mostly the examples of its
+// appendix A
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		doc    string
+		patch  string
+		strict bool
+		want   string // empty when the patch must fail
+	}{
+		{
+			name:  "add an object member",
+			doc:   `{foo: bar}`,
+			patch: `[{op: add, path: /baz, value: qux}]`,
+			want:  `{foo: bar, baz: qux}`,
+		},
+		{
+			name:  "add an array element",
+			doc:   `{foo: [bar, baz]}`,
+			patch: `[{op: add, path: /foo/1, value: qux}]`,
+			want:  `{foo: [bar, qux, baz]}`,
+		},
+		{
+			name:  "remove an object member",
+			doc:   `{baz: qux, foo: bar}`,
+			patch: `[{op: remove, path: /baz}]`,
+			want:  `{foo: bar}`,
+		},
+		{
+			name:  "remove an array element",
+			doc:   `{foo: [bar, qux, baz]}`,
+			patch: `[{op: remove, path: /foo/1}]`,
+			want:  `{foo: [bar, baz]}`,
+		},
+		{
+			name:  "replace a value",
+			doc:   `{baz: qux, foo: bar}`,
+			patch: `[{op: replace, path: /baz, value: boo}]`,
+			want:  `{baz: boo, foo: bar}`,
+		},
+		{
+			name:  "move a value",
+			doc:   `{foo: {bar: baz, waldo: fred}, qux: {corge: grault}}`,
+			patch: `[{op: move, from: /foo/waldo, path: /qux/thud}]`,
+			want:  `{foo: {bar: baz}, qux: {corge: grault, thud: fred}}`,
+		},
+		{
+			name:  "move an array element",
+			doc:   `{foo: [all, grass, cows, eat]}`,
+			patch: `[{op: move, from: /foo/1, path: /foo/3}]`,
+			want:  `{foo: [all, cows, eat, grass]}`,
+		},
+		{
+			name:  "copy a value",
+			doc:   `{foo: {bar: baz}}`,
+			patch: `[{op: copy, from: /foo, path: /qux}]`,
+			want:  `{foo: {bar: baz}, qux: {bar: baz}}`,
+		},
+		{
+			name:  "test a value",
+			doc:   `{baz: qux, foo: [a, 2, c]}`,
+			patch: `[{op: test, path: /baz, value: qux}, {op: test, path: /foo/1, value: 2}]`,
+			want:  `{baz: qux, foo: [a, 2, c]}`,
+		},
+		{
+			name:  "failed test",
+			doc:   `{baz: qux}`,
+			patch: `[{op: test, path: /baz, value: bar}]`,
+		},
+		{
+			name:  "append to an array",
+			doc:   `{foo: [bar]}`,
+			patch: `[{op: add, path: /foo/-, value: [abc, def]}]`,
+			want:  `{foo: [bar, [abc, def]]}`,
+		},
+		{
+			name:  "escaped pointer tokens",
+			doc:   `{a/b: 1, m~n: 2}`,
+			patch: `[{op: replace, path: /a~1b, value: 3}, {op: remove, path: /m~0n}]`,
+			want:  `{a/b: 3}`,
+		},
+		{
+			name:  "add to a missing parent",
+			doc:   `{foo: bar}`,
+			patch: `[{op: add, path: /baz/bat, value: qux}]`,
+		},
+		{
+			name:  "index with a leading zero",
+			doc:   `{foo: [a, b]}`,
+			patch: `[{op: replace, path: /foo/01, value: c}]`,
+		},
+		{
+			name:  "operations are all or nothing",
+			doc:   `{foo: bar}`,
+			patch: `[{op: add, path: /baz, value: qux}, {op: replace, path: /missing, value: 1}]`,
+		},
+		{
+			name:  "removing a missing value is a no-op",
+			doc:   `{foo: bar}`,
+			patch: `[{op: remove, path: /baz}]`,
+			want:  `{foo: bar}`,
+		},
+		{
+			name:   "removing a missing value fails when strict",
+			doc:    `{foo: bar}`,
+			patch:  `[{op: remove, path: /baz}]`,
+			strict: true,
+		},
+		{
+			name:  "appending a contained value is a no-op",
+			doc:   `{foo: [bar]}`,
+			patch: `[{op: add, path: /foo/-, value: bar}]`,
+			want:  `{foo: [bar]}`,
+		},
+		{
+			name:   "appending a contained value when strict",
+			doc:    `{foo: [bar]}`,
+			patch:  `[{op: add, path: /foo/-, value: bar}]`,
+			strict: true,
+			want:   `{foo: [bar, bar]}`,
+		},
+		{
+			name:  "repeated move is a no-op",
+			doc:   `{qux: fred}`,
+			patch: `[{op: move, from: /waldo, path: /qux}]`,
+			want:  `{qux: fred}`,
+		},
+		{
+			name:  "move into a child",
+			doc:   `{foo: {bar: baz}}`,
+			patch: `[{op: move, from: /foo, path: /foo/bar/qux}]`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, err := ParsePatch([]byte(test.patch), PatchTypeJSON)
+			if err != nil {
+				t.Fatalf("failed to parse patch: %v", err)
+			}
+			doc := decodeTestYAML(t, test.doc)
+			got, err := patch.Apply(doc, test.strict)
+			if test.want == "" {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				if !reflect.DeepEqual(doc, decodeTestYAML(t, test.doc)) {
+					t.Fatalf("failed patch modified the input: %v", doc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to apply patch: %v", err)
+			}
+			if want := decodeTestYAML(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Fatalf("result = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestMergePatch applies the RFC 7386 examples of its appendix A
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		{`{a: b}`, `{a: c}`, `{a: c}`},
+		{`{a: b}`, `{b: c}`, `{a: b, b: c}`},
+		{`{a: b}`, `{a: null}`, `{}`},
+		{`{a: b, b: c}`, `{a: null}`, `{b: c}`},
+		{`{a: [b]}`, `{a: c}`, `{a: c}`},
+		{`{a: c}`, `{a: [b]}`, `{a: [b]}`},
+		{`{a: {b: c}}`, `{a: {b: d, c: null}}`, `{a: {b: d}}`},
+		{`{a: [{b: c}]}`, `{a: [1]}`, `{a: [1]}`},
+		{`{e: null}`, `{a: 1}`, `{e: null, a: 1}`},
+		{`{}`, `{a: {bb: {ccc: null}}}`, `{a: {bb: {}}}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.doc+" + "+test.patch, func(t *testing.T) {
+			patch, err := ParsePatch([]byte(test.patch), "")
+			if err != nil {
+				t.Fatalf("failed to parse patch: %v", err)
+			}
+			if patch.Type != PatchTypeMerge {
+				t.Fatalf("detected type %s, want %s", patch.Type, PatchTypeMerge)
+			}
+			got, err := patch.Apply(decodeTestYAML(t, test.doc), false)
+			if err != nil {
+				t.Fatalf("failed to apply patch: %v", err)
+			}
+			if want := decodeTestYAML(t, test.want); !reflect.DeepEqual(got, want) {
+				t.Fatalf("result = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestParsePatch checks format detection and operation validation
+func TestParsePatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		patch     string
+		patchType string
+		want      string // detected type; empty when parsing must fail
+	}{
+		{name: "operation list", patch: `[{op: remove, path: /a}]`, want: PatchTypeJSON},
+		{name: "mapping", patch: `{a: 1}`, want: PatchTypeMerge},
+		{name: "scalar", patch: `a`},
+		{name: "merge patch that is a list", patch: `[]`, patchType: PatchTypeMerge},
+		{name: "unknown op", patch: `[{op: frob, path: /a}]`},
+		{name: "add without value", patch: `[{op: add, path: /a}]`},
+		{name: "move without from", patch: `[{op: move, path: /a}]`},
+		{name: "unknown type", patch: `{a: 1}`, patchType: "strategic"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			patch, err := ParsePatch([]byte(test.patch), test.patchType)
+			if test.want == "" {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", patch)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse patch: %v", err)
+			}
+			if patch.Type != test.want {
+				t.Fatalf("type = %s, want %s", patch.Type, test.want)
+			}
+		})
+	}
+}
+
+// decodeTestYAML decodes a YAML value or fails the test
+func decodeTestYAML(t *testing.T, data string) interface{} {
+	t.Helper()
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(data), &value); err != nil {
+		t.Fatalf("failed to decode %q: %v", data, err)
+	}
+	return value
+}
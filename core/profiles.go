@@ -0,0 +1,53 @@
+package core
+
+import "github.com/firasmosbahi/container-composer/internal/logging"
+
+// WithProfiles returns a copy of the compose file containing only the
+// services enabled by the given compose profiles. Services without profiles
+// are always enabled and "*" enables every profile. Services that an enabled
+// service depends on stay enabled, as they do when docker compose starts it.
+// Without profiles the compose file is returned unchanged.
+func (cf *ComposeFile) WithProfiles(profiles []string) *ComposeFile {
+	if len(profiles) == 0 {
+		return cf
+	}
+
+	active := make(map[string]bool, len(profiles))
+	for _, profile := range profiles {
+		active[profile] = true
+	}
+
+	enabled := make(map[string]bool)
+	var enable func(name string)
+	enable = func(name string) {
+		service, exists := cf.Services[name]
+		if !exists || enabled[name] {
+			return
+		}
+		enabled[name] = true
+		for _, dep := range service.DependsOn {
+			enable(dep)
+		}
+	}
+	for name, service := range cf.Services {
+		if len(service.Profiles) == 0 || active["*"] {
+			enable(name)
+			continue
+		}
+		for _, profile := range service.Profiles {
+			if active[profile] {
+				enable(name)
+				break
+			}
+		}
+	}
+
+	filtered := *cf
+	filtered.Services = make(map[string]Service, len(enabled))
+	for name := range enabled {
+		filtered.Services[name] = cf.Services[name]
+	}
+	logging.Logger().Info("applied compose profiles", "profiles", profiles,
+		"services", len(filtered.Services), "disabled", len(cf.Services)-len(filtered.Services))
+	return &filtered
+}
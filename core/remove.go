@@ -0,0 +1,362 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AffectedService describes how removing a resource touches another service
+type AffectedService struct {
+	Name    string   `json:"name"`
+	Reasons []string `json:"reasons"` // e.g. "depends_on", "network backend", "volume data"
+}
+
+// RemovalReport describes the outcome of removing a service, network or volume
+type RemovalReport struct {
+	Kind            string            `json:"kind"` // "service", "network" or "volume"
+	Name            string            `json:"name"`
+	Affected        []AffectedService `json:"affected"`
+	Cascaded        []string          `json:"cascaded"`         // services removed because they required a removed service
+	OrphanedVolumes []string          `json:"orphaned_volumes"` // declared volumes no longer mounted by any service
+}
+
+// RemoveService deletes a service and drops it from the depends_on lists of
+// the remaining services. With cascade set, services that (transitively)
+// depend on it are removed as well.
+func (c *ComposeFile) RemoveService(name string, cascade bool) (*RemovalReport, error) {
+	graph, err := c.BuildDependencyGraph()
+	if err != nil {
+		return nil, err
+	}
+	node, exists := graph.Services[name]
+	if !exists {
+		return nil, fmt.Errorf("service '%s' %w", name, ErrNotFound)
+	}
+
+	report := &RemovalReport{Kind: "service", Name: name}
+
+	affected := make(map[string]map[string]bool)
+	addReason := func(service, reason string) {
+		if affected[service] == nil {
+			affected[service] = make(map[string]bool)
+		}
+		affected[service][reason] = true
+	}
+	for _, dependent := range node.DependedBy {
+		addReason(dependent.Name, "depends_on")
+	}
+	for volume, peers := range node.VolumePeers {
+		for _, peer := range peers {
+			if peer.Name != name {
+				addReason(peer.Name, "volume "+volume)
+			}
+		}
+	}
+
+	removed := map[string]bool{name: true}
+	if cascade {
+		impact, err := graph.Impact(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, dependent := range impact.Hard {
+			removed[dependent.Name] = true
+			report.Cascaded = append(report.Cascaded, dependent.Name)
+		}
+		sort.Strings(report.Cascaded)
+	}
+
+	// Volumes mounted by removed services are candidates for orphaning
+	candidates := make(map[string]bool)
+	for serviceName := range removed {
+		for _, mount := range c.Services[serviceName].Volumes {
+			if volume := extractVolumeName(mount); volume != "" && c.VolumeExists(volume) {
+				candidates[volume] = true
+			}
+		}
+		delete(c.Services, serviceName)
+		delete(affected, serviceName)
+	}
+
+	for serviceName, service := range c.Services {
+		filtered := service.DependsOn[:0]
+		for _, dep := range service.DependsOn {
+			if !removed[dep] {
+				filtered = append(filtered, dep)
+			}
+		}
+		if len(filtered) == 0 {
+			filtered = nil
+		}
+		service.DependsOn = filtered
+		c.Services[serviceName] = service
+	}
+
+	for volume := range candidates {
+		if !c.volumeInUse(volume) {
+			report.OrphanedVolumes = append(report.OrphanedVolumes, volume)
+		}
+	}
+	sort.Strings(report.OrphanedVolumes)
+
+	report.Affected = sortedAffected(affected)
+	return report, nil
+}
+
+// RemoveNetwork deletes a network and disconnects every service from it
+func (c *ComposeFile) RemoveNetwork(name string) (*RemovalReport, error) {
+	graph, err := c.BuildDependencyGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	// Services may reference networks that were never declared, so only
+	// fail when nothing refers to the name at all
+	members := networkMembers(graph, name)
+	if !c.NetworkExists(name) && len(members) == 0 {
+		return nil, fmt.Errorf("network '%s' %w", name, ErrNotFound)
+	}
+
+	report := &RemovalReport{Kind: "network", Name: name}
+	affected := make(map[string]map[string]bool)
+	for _, member := range members {
+		affected[member] = map[string]bool{"network " + name: true}
+
+		service := c.Services[member]
+		var networks []string
+		for _, network := range service.Networks {
+			if network != name {
+				networks = append(networks, network)
+			}
+		}
+		service.Networks = networks
+		c.Services[member] = service
+	}
+	delete(c.Networks, name)
+
+	report.Affected = sortedAffected(affected)
+	return report, nil
+}
+
+// RemoveVolume deletes a named volume and strips its mounts from every service
+func (c *ComposeFile) RemoveVolume(name string) (*RemovalReport, error) {
+	graph, err := c.BuildDependencyGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	members := volumeMembers(graph, name)
+	if !c.VolumeExists(name) && len(members) == 0 {
+		return nil, fmt.Errorf("volume '%s' %w", name, ErrNotFound)
+	}
+
+	report := &RemovalReport{Kind: "volume", Name: name}
+	affected := make(map[string]map[string]bool)
+	for _, member := range members {
+		affected[member] = map[string]bool{"volume " + name: true}
+
+		service := c.Services[member]
+		var mounts []string
+		for _, mount := range service.Volumes {
+			if extractVolumeName(mount) != name {
+				mounts = append(mounts, mount)
+			}
+		}
+		service.Volumes = mounts
+		c.Services[member] = service
+	}
+	delete(c.Volumes, name)
+
+	report.Affected = sortedAffected(affected)
+	return report, nil
+}
+
+// RemoveService deletes a service entry and drops it from the depends_on of
+// the remaining services, like ComposeFile.RemoveService, but as node edits
+// so the rest of the file keeps its formatting and comments.
+func (d *ComposeDocument) RemoveService(name string, cascade bool) (*RemovalReport, error) {
+	composeFile, err := d.ComposeFile()
+	if err != nil {
+		return nil, err
+	}
+	report, err := composeFile.RemoveService(name, cascade)
+	if err != nil {
+		return nil, err
+	}
+
+	removed := map[string]bool{name: true}
+	for _, cascaded := range report.Cascaded {
+		removed[cascaded] = true
+	}
+
+	services := d.section("services")
+	for serviceName := range removed {
+		d.setMappingValue(services, serviceName, nil)
+	}
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		// depends_on: short list or long mapping form
+		d.removeItems(services.Content[i+1], "depends_on", func(item *yaml.Node) bool {
+			return item.Kind == yaml.ScalarNode && removed[item.Value]
+		})
+	}
+
+	return report, nil
+}
+
+// RemoveNetwork deletes a network and disconnects every service from it as
+// node edits
+func (d *ComposeDocument) RemoveNetwork(name string) (*RemovalReport, error) {
+	composeFile, err := d.ComposeFile()
+	if err != nil {
+		return nil, err
+	}
+	report, err := composeFile.RemoveNetwork(name)
+	if err != nil {
+		return nil, err
+	}
+
+	services := d.section("services")
+	for i := 0; services != nil && i+1 < len(services.Content); i += 2 {
+		d.removeItems(services.Content[i+1], "networks", func(item *yaml.Node) bool {
+			return item.Kind == yaml.ScalarNode && item.Value == name
+		})
+	}
+	d.removeItems(d.root.Content[0], "networks", func(item *yaml.Node) bool {
+		return item.Value == name
+	})
+
+	return report, nil
+}
+
+// RemoveVolume deletes a named volume and strips its mounts from every
+// service as node edits
+func (d *ComposeDocument) RemoveVolume(name string) (*RemovalReport, error) {
+	composeFile, err := d.ComposeFile()
+	if err != nil {
+		return nil, err
+	}
+	report, err := composeFile.RemoveVolume(name)
+	if err != nil {
+		return nil, err
+	}
+
+	services := d.section("services")
+	for i := 0; services != nil && i+1 < len(services.Content); i += 2 {
+		d.removeItems(services.Content[i+1], "volumes", func(mount *yaml.Node) bool {
+			switch mount.Kind {
+			case yaml.ScalarNode:
+				// Short syntax: "name:/path[:mode]"
+				return extractVolumeName(mount.Value) == name
+			case yaml.MappingNode:
+				// Long syntax: {type: volume, source: name, target: /path}
+				_, mountType := mappingEntry(mount, "type")
+				_, source := mappingEntry(mount, "source")
+				return source != nil && source.Value == name && (mountType == nil || mountType.Value == "volume")
+			}
+			return false
+		})
+	}
+	d.removeItems(d.root.Content[0], "volumes", func(item *yaml.Node) bool {
+		return item.Value == name
+	})
+
+	return report, nil
+}
+
+// removeItems drops the items of a list field, or the keys of a mapping
+// field, that match drop. Mapping keys are removed line by line; a list is
+// re-rendered without the dropped items. A field left empty is removed
+// altogether.
+func (d *ComposeDocument) removeItems(parent *yaml.Node, field string, drop func(*yaml.Node) bool) {
+	_, value := mappingEntry(parent, field)
+	if value == nil {
+		return
+	}
+
+	switch value.Kind {
+	case yaml.MappingNode:
+		var keys []string
+		for i := 0; i+1 < len(value.Content); i += 2 {
+			if drop(value.Content[i]) {
+				keys = append(keys, value.Content[i].Value)
+			}
+		}
+		if len(keys) > 0 && len(keys) == len(value.Content)/2 {
+			d.setMappingValue(parent, field, nil)
+			return
+		}
+		for _, key := range keys {
+			d.setMappingValue(value, key, nil)
+		}
+	case yaml.SequenceNode:
+		kept := &yaml.Node{Kind: yaml.SequenceNode, Tag: value.Tag, Style: value.Style}
+		for _, item := range value.Content {
+			if !drop(item) {
+				kept.Content = append(kept.Content, item)
+			}
+		}
+		switch {
+		case len(kept.Content) == len(value.Content):
+		case len(kept.Content) == 0:
+			d.setMappingValue(parent, field, nil)
+		default:
+			d.setMappingValue(parent, field, kept)
+		}
+	}
+}
+
+// networkMembers returns the services connected to a network
+func networkMembers(graph *DependencyGraph, network string) []string {
+	var members []string
+	for name, node := range graph.Services {
+		if _, ok := node.NetworkPeers[network]; ok {
+			members = append(members, name)
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// volumeMembers returns the services mounting a named volume
+func volumeMembers(graph *DependencyGraph, volume string) []string {
+	var members []string
+	for name, node := range graph.Services {
+		if _, ok := node.VolumePeers[volume]; ok {
+			members = append(members, name)
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// volumeInUse reports whether any service mounts a named volume
+func (c *ComposeFile) volumeInUse(volume string) bool {
+	for _, service := range c.Services {
+		for _, mount := range service.Volumes {
+			if extractVolumeName(mount) == volume {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortedAffected turns a service -> reasons set into a sorted list
+func sortedAffected(affected map[string]map[string]bool) []AffectedService {
+	var result []AffectedService
+	for name, reasons := range affected {
+		result = append(result, AffectedService{Name: name, Reasons: sortedKeys(reasons)})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// String summarizes how a service is affected, e.g. "api (depends_on, volume data)"
+func (a AffectedService) String() string {
+	return fmt.Sprintf("%s (%s)", a.Name, strings.Join(a.Reasons, ", "))
+}
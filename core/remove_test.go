@@ -0,0 +1,155 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestRemove removes resources from a compose document and checks the report
+// and the rewritten references
+func TestRemove(t *testing.T) {
+	source := "services:\n" +
+		"  web:\n    image: nginx\n    depends_on: [api, db]\n    networks: [frontend, backend]\n" +
+		"  api:\n    image: api # pinned\n    depends_on:\n      - db\n    networks:\n      - backend\n    volumes:\n      - data:/srv/data:ro\n" +
+		"  db:\n    image: postgres\n    networks: [backend]\n    volumes:\n      - data:/var/lib/postgresql/data\n      - ./init:/docker-entrypoint-initdb.d\n" +
+		"  worker:\n    image: worker\n    networks: [jobs]\n" +
+		"networks:\n  frontend:\n  backend:\n    driver: bridge\n" +
+		"volumes:\n  data:\n  cache:\n"
+
+	tests := []struct {
+		name     string
+		remove   func(d *ComposeDocument) (*RemovalReport, error)
+		want     string
+		affected []string // AffectedService.String()
+		cascaded []string
+		orphaned []string
+		err      error
+	}{
+		{
+			name:   "service with dependents",
+			remove: func(d *ComposeDocument) (*RemovalReport, error) { return d.RemoveService("db", false) },
+			want: "services:\n" +
+				"  web:\n    image: nginx\n    depends_on: [api]\n    networks: [frontend, backend]\n" +
+				"  api:\n    image: api # pinned\n    networks:\n      - backend\n    volumes:\n      - data:/srv/data:ro\n" +
+				"  worker:\n    image: worker\n    networks: [jobs]\n" +
+				"networks:\n  frontend:\n  backend:\n    driver: bridge\n" +
+				"volumes:\n  data:\n  cache:\n",
+			affected: []string{"api (depends_on, volume data)", "web (depends_on)"},
+		},
+		{
+			name:   "cascade removes dependents and orphans their volumes",
+			remove: func(d *ComposeDocument) (*RemovalReport, error) { return d.RemoveService("db", true) },
+			want: "services:\n" +
+				"  worker:\n    image: worker\n    networks: [jobs]\n" +
+				"networks:\n  frontend:\n  backend:\n    driver: bridge\n" +
+				"volumes:\n  data:\n  cache:\n",
+			cascaded: []string{"api", "web"},
+			orphaned: []string{"data"},
+		},
+		{
+			name:   "service without dependents",
+			remove: func(d *ComposeDocument) (*RemovalReport, error) { return d.RemoveService("web", false) },
+			want: "services:\n" +
+				"  api:\n    image: api # pinned\n    depends_on:\n      - db\n    networks:\n      - backend\n    volumes:\n      - data:/srv/data:ro\n" +
+				"  db:\n    image: postgres\n    networks: [backend]\n    volumes:\n      - data:/var/lib/postgresql/data\n      - ./init:/docker-entrypoint-initdb.d\n" +
+				"  worker:\n    image: worker\n    networks: [jobs]\n" +
+				"networks:\n  frontend:\n  backend:\n    driver: bridge\n" +
+				"volumes:\n  data:\n  cache:\n",
+		},
+		{
+			name:   "network",
+			remove: func(d *ComposeDocument) (*RemovalReport, error) { return d.RemoveNetwork("backend") },
+			want: "services:\n" +
+				"  web:\n    image: nginx\n    depends_on: [api, db]\n    networks: [frontend]\n" +
+				"  api:\n    image: api # pinned\n    depends_on:\n      - db\n    volumes:\n      - data:/srv/data:ro\n" +
+				"  db:\n    image: postgres\n    volumes:\n      - data:/var/lib/postgresql/data\n      - ./init:/docker-entrypoint-initdb.d\n" +
+				"  worker:\n    image: worker\n    networks: [jobs]\n" +
+				"networks:\n  frontend:\n" +
+				"volumes:\n  data:\n  cache:\n",
+			affected: []string{"api (network backend)", "db (network backend)", "web (network backend)"},
+		},
+		{
+			name:   "undeclared network",
+			remove: func(d *ComposeDocument) (*RemovalReport, error) { return d.RemoveNetwork("jobs") },
+			want: "services:\n" +
+				"  web:\n    image: nginx\n    depends_on: [api, db]\n    networks: [frontend, backend]\n" +
+				"  api:\n    image: api # pinned\n    depends_on:\n      - db\n    networks:\n      - backend\n    volumes:\n      - data:/srv/data:ro\n" +
+				"  db:\n    image: postgres\n    networks: [backend]\n    volumes:\n      - data:/var/lib/postgresql/data\n      - ./init:/docker-entrypoint-initdb.d\n" +
+				"  worker:\n    image: worker\n" +
+				"networks:\n  frontend:\n  backend:\n    driver: bridge\n" +
+				"volumes:\n  data:\n  cache:\n",
+			affected: []string{"worker (network jobs)"},
+		},
+		{
+			name:   "volume keeps bind mounts",
+			remove: func(d *ComposeDocument) (*RemovalReport, error) { return d.RemoveVolume("data") },
+			want: "services:\n" +
+				"  web:\n    image: nginx\n    depends_on: [api, db]\n    networks: [frontend, backend]\n" +
+				"  api:\n    image: api # pinned\n    depends_on:\n      - db\n    networks:\n      - backend\n" +
+				"  db:\n    image: postgres\n    networks: [backend]\n    volumes:\n      - ./init:/docker-entrypoint-initdb.d\n" +
+				"  worker:\n    image: worker\n    networks: [jobs]\n" +
+				"networks:\n  frontend:\n  backend:\n    driver: bridge\n" +
+				"volumes:\n  cache:\n",
+			affected: []string{"api (volume data)", "db (volume data)"},
+		},
+		{
+			name:   "unused volume",
+			remove: func(d *ComposeDocument) (*RemovalReport, error) { return d.RemoveVolume("cache") },
+			want:   source[:len(source)-len("  cache:\n")],
+		},
+		{
+			name:   "unknown service",
+			remove: func(d *ComposeDocument) (*RemovalReport, error) { return d.RemoveService("missing", true) },
+			err:    ErrNotFound,
+		},
+		{
+			name:   "unknown network",
+			remove: func(d *ComposeDocument) (*RemovalReport, error) { return d.RemoveNetwork("missing") },
+			err:    ErrNotFound,
+		},
+		{
+			name:   "unknown volume",
+			remove: func(d *ComposeDocument) (*RemovalReport, error) { return d.RemoveVolume("missing") },
+			err:    ErrNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := parseTestDocument(t, source)
+			report, err := test.remove(document)
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error = %v, want %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to remove: %v", err)
+			}
+
+			var affected []string
+			for _, service := range report.Affected {
+				affected = append(affected, service.String())
+			}
+			if !reflect.DeepEqual(affected, test.affected) {
+				t.Errorf("affected = %v, want %v", affected, test.affected)
+			}
+			if !reflect.DeepEqual(report.Cascaded, test.cascaded) {
+				t.Errorf("cascaded = %v, want %v", report.Cascaded, test.cascaded)
+			}
+			if !reflect.DeepEqual(report.OrphanedVolumes, test.orphaned) {
+				t.Errorf("orphaned volumes = %v, want %v", report.OrphanedVolumes, test.orphaned)
+			}
+
+			got, err := document.Bytes()
+			if err != nil {
+				t.Fatalf("failed to render: %v", err)
+			}
+			if string(got) != test.want {
+				t.Errorf("content =\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// validResourceName matches the names compose accepts for services, networks
+// and volumes
+var validResourceName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// HostnameUsage is a place where a renamed service appears to be used as a
+// hostname, e.g. inside an environment URL or a command string
+type HostnameUsage struct {
+	Service string `json:"service"`
+	Field   string `json:"field"` // e.g. "environment.REDIS_URL" or "command"
+	Before  string `json:"before"`
+	After   string `json:"after"`
+
+	node    *yaml.Node
+	offsets []int
+	oldName string
+	newName string
+}
+
+// RenameReport describes the changes made by a rename
+type RenameReport struct {
+	Kind       string          `json:"kind"` // "service", "network" or "volume"
+	Old        string          `json:"old"`
+	New        string          `json:"new"`
+	References []string        `json:"references"` // structural references that were rewritten, e.g. "api: depends_on"
+	Hostnames  []HostnameUsage `json:"hostnames"`
+}
+
+// RenameService renames a service and rewrites its depends_on, links,
+// extends, volumes_from and service: references. Possible hostname usages are
+// collected in the report but only rewritten by RewriteHostnames.
+func (d *ComposeDocument) RenameService(oldName, newName string) (*RenameReport, error) {
+	services := d.section("services")
+	if err := checkRename(services, "service", oldName, newName); err != nil {
+		return nil, err
+	}
+
+	report := &RenameReport{Kind: "service", Old: oldName, New: newName}
+	keyNode, _ := mappingEntry(services, oldName)
+	d.replaceScalar(keyNode, newName)
+
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		serviceName := services.Content[i].Value
+		service := services.Content[i+1]
+		reference := func(field string) {
+			report.References = append(report.References, serviceName+": "+field)
+		}
+
+		// depends_on: short list or long mapping form
+		_, dependsOn := mappingEntry(service, "depends_on")
+		for _, item := range scalarItems(dependsOn) {
+			if item.Value == oldName {
+				d.replaceScalar(item, newName)
+				reference("depends_on")
+			}
+		}
+		if key, _ := mappingEntry(dependsOn, oldName); key != nil {
+			d.replaceScalar(key, newName)
+			reference("depends_on")
+		}
+
+		// links: "service" or "service:alias"
+		_, links := mappingEntry(service, "links")
+		for _, item := range scalarItems(links) {
+			if item.Value == oldName || strings.HasPrefix(item.Value, oldName+":") {
+				d.replaceInScalar(item, 0, len(oldName), newName)
+				reference("links")
+			}
+		}
+
+		// extends: "service" or {service: name} without a file
+		_, extends := mappingEntry(service, "extends")
+		if extends != nil && extends.Kind == yaml.ScalarNode && extends.Value == oldName {
+			d.replaceScalar(extends, newName)
+			reference("extends")
+		}
+		if _, file := mappingEntry(extends, "file"); file == nil {
+			if _, target := mappingEntry(extends, "service"); target != nil && target.Value == oldName {
+				d.replaceScalar(target, newName)
+				reference("extends")
+			}
+		}
+
+		// volumes_from: "service", "service:ro" or "service:name"
+		_, volumesFrom := mappingEntry(service, "volumes_from")
+		for _, item := range scalarItems(volumesFrom) {
+			switch {
+			case strings.HasPrefix(item.Value, "service:"+oldName) && serviceRefEnds(item.Value, len("service:"+oldName)):
+				d.replaceInScalar(item, len("service:"), len(oldName), newName)
+				reference("volumes_from")
+			case strings.HasPrefix(item.Value, oldName) && serviceRefEnds(item.Value, len(oldName)):
+				d.replaceInScalar(item, 0, len(oldName), newName)
+				reference("volumes_from")
+			}
+		}
+
+		// network_mode, ipc and pid: "service:name"
+		for _, field := range []string{"network_mode", "ipc", "pid"} {
+			if _, value := mappingEntry(service, field); value != nil && value.Value == "service:"+oldName {
+				d.replaceInScalar(value, len("service:"), len(oldName), newName)
+				reference(field)
+			}
+		}
+
+		report.Hostnames = append(report.Hostnames, findHostnameUsages(serviceName, service, oldName, newName)...)
+	}
+
+	return report, nil
+}
+
+// RenameNetwork renames a network and every service's reference to it
+func (d *ComposeDocument) RenameNetwork(oldName, newName string) (*RenameReport, error) {
+	networks := d.section("networks")
+	if err := checkRename(networks, "network", oldName, newName); err != nil {
+		return nil, err
+	}
+
+	report := &RenameReport{Kind: "network", Old: oldName, New: newName}
+	keyNode, _ := mappingEntry(networks, oldName)
+	d.replaceScalar(keyNode, newName)
+
+	services := d.section("services")
+	for i := 0; services != nil && i+1 < len(services.Content); i += 2 {
+		serviceName := services.Content[i].Value
+		_, serviceNetworks := mappingEntry(services.Content[i+1], "networks")
+
+		for _, item := range scalarItems(serviceNetworks) {
+			if item.Value == oldName {
+				d.replaceScalar(item, newName)
+				report.References = append(report.References, serviceName+": networks")
+			}
+		}
+		if key, _ := mappingEntry(serviceNetworks, oldName); key != nil {
+			d.replaceScalar(key, newName)
+			report.References = append(report.References, serviceName+": networks")
+		}
+	}
+
+	return report, nil
+}
+
+// RenameVolume renames a named volume and every mount that uses it
+func (d *ComposeDocument) RenameVolume(oldName, newName string) (*RenameReport, error) {
+	volumes := d.section("volumes")
+	if err := checkRename(volumes, "volume", oldName, newName); err != nil {
+		return nil, err
+	}
+
+	report := &RenameReport{Kind: "volume", Old: oldName, New: newName}
+	keyNode, _ := mappingEntry(volumes, oldName)
+	d.replaceScalar(keyNode, newName)
+
+	services := d.section("services")
+	for i := 0; services != nil && i+1 < len(services.Content); i += 2 {
+		serviceName := services.Content[i].Value
+		_, mounts := mappingEntry(services.Content[i+1], "volumes")
+		if mounts == nil || mounts.Kind != yaml.SequenceNode {
+			continue
+		}
+
+		for _, mount := range mounts.Content {
+			switch mount.Kind {
+			case yaml.ScalarNode:
+				// Short syntax: "name:/path[:mode]"
+				if extractVolumeName(mount.Value) == oldName {
+					d.replaceInScalar(mount, 0, len(oldName), newName)
+					report.References = append(report.References, serviceName+": volumes")
+				}
+			case yaml.MappingNode:
+				// Long syntax: {type: volume, source: name, target: /path}
+				_, mountType := mappingEntry(mount, "type")
+				_, source := mappingEntry(mount, "source")
+				if source != nil && source.Value == oldName && (mountType == nil || mountType.Value == "volume") {
+					d.replaceScalar(source, newName)
+					report.References = append(report.References, serviceName+": volumes")
+				}
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// RewriteHostnames applies hostname usages collected by RenameService
+func (d *ComposeDocument) RewriteHostnames(usages []HostnameUsage) {
+	for _, usage := range usages {
+		// Replace from the end so earlier offsets stay valid
+		for i := len(usage.offsets) - 1; i >= 0; i-- {
+			d.replaceInScalar(usage.node, usage.offsets[i], len(usage.oldName), usage.newName)
+		}
+	}
+}
+
+// checkRename validates a rename inside a top-level section
+func checkRename(section *yaml.Node, kind, oldName, newName string) error {
+	if key, _ := mappingEntry(section, oldName); key == nil {
+		return fmt.Errorf("%s '%s' %w", kind, oldName, ErrNotFound)
+	}
+	if oldName == newName {
+		return fmt.Errorf("%s is already named '%s'", kind, newName)
+	}
+	if !validResourceName.MatchString(newName) {
+		return fmt.Errorf("invalid %s name '%s' (use letters, digits, '_', '.' and '-')", kind, newName)
+	}
+	if key, _ := mappingEntry(section, newName); key != nil {
+		return fmt.Errorf("%s '%s' %w", kind, newName, ErrAlreadyExists)
+	}
+	return nil
+}
+
+// serviceRefEnds reports whether a service reference ends at position i,
+// i.e. it is followed by nothing or by a ":mode" suffix
+func serviceRefEnds(value string, i int) bool {
+	return i == len(value) || value[i] == ':'
+}
+
+// findHostnameUsages scans environment values, command, entrypoint and
+// healthcheck test of a service for hostname usages of a service name
+func findHostnameUsages(serviceName string, service *yaml.Node, oldName, newName string) []HostnameUsage {
+	var usages []HostnameUsage
+	check := func(field string, node *yaml.Node, valueStart int) {
+		offsets := hostnameOffsets(node.Value[valueStart:], oldName)
+		if len(offsets) == 0 {
+			return
+		}
+		for i := range offsets {
+			offsets[i] += valueStart
+		}
+		after := node.Value
+		for i := len(offsets) - 1; i >= 0; i-- {
+			after = after[:offsets[i]] + newName + after[offsets[i]+len(oldName):]
+		}
+		usages = append(usages, HostnameUsage{
+			Service: serviceName,
+			Field:   field,
+			Before:  node.Value,
+			After:   after,
+			node:    node,
+			offsets: offsets,
+			oldName: oldName,
+			newName: newName,
+		})
+	}
+
+	_, environment := mappingEntry(service, "environment")
+	if environment != nil && environment.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(environment.Content); i += 2 {
+			if value := environment.Content[i+1]; value.Kind == yaml.ScalarNode {
+				check("environment."+environment.Content[i].Value, value, 0)
+			}
+		}
+	}
+	for _, item := range scalarItems(environment) {
+		if key, _, ok := strings.Cut(item.Value, "="); ok {
+			check("environment."+key, item, len(key)+1)
+		}
+	}
+
+	_, command := mappingEntry(service, "command")
+	_, entrypoint := mappingEntry(service, "entrypoint")
+	_, healthcheck := mappingEntry(service, "healthcheck")
+	_, test := mappingEntry(healthcheck, "test")
+	for _, field := range []struct {
+		name string
+		node *yaml.Node
+	}{{"command", command}, {"entrypoint", entrypoint}, {"healthcheck.test", test}} {
+		if field.node == nil {
+			continue
+		}
+		if field.node.Kind == yaml.ScalarNode {
+			check(field.name, field.node, 0)
+		}
+		for _, item := range scalarItems(field.node) {
+			check(field.name, item, 0)
+		}
+	}
+
+	return usages
+}
+
+// hostnameOffsets finds occurrences of name that look like a hostname: not
+// part of a longer word, not a URL scheme ("redis://"), not a user name or
+// password ("user:pass@") and not a path segment ("/etc/redis"). A following
+// ':' must start a port number.
+func hostnameOffsets(value, name string) []int {
+	var offsets []int
+	for start := 0; ; {
+		i := strings.Index(value[start:], name)
+		if i < 0 {
+			break
+		}
+		i += start
+		end := i + len(name)
+		start = i + 1
+
+		if i > 0 && isHostnameChar(value[i-1]) {
+			continue
+		}
+		if i > 0 && value[i-1] == '/' && (i < 2 || value[i-2] != '/') {
+			continue
+		}
+		if end < len(value) {
+			next := value[end]
+			if isHostnameChar(next) || next == '@' {
+				continue
+			}
+			if next == ':' && (end+1 >= len(value) || value[end+1] < '0' || value[end+1] > '9') {
+				continue
+			}
+		}
+		offsets = append(offsets, i)
+	}
+	return offsets
+}
+
+// isHostnameChar reports whether a byte can be part of a hostname
+func isHostnameChar(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' ||
+		b == '-' || b == '_' || b == '.'
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists every problem found in a compose file
+type ValidationError struct {
+	Problems []string
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return "invalid compose file: " + e.Problems[0]
+	}
+	return fmt.Sprintf("invalid compose file (%d problems):\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// Validation rules, configurable through ValidationRules
+const (
+	RuleServiceName         = "service-name"         // service names are valid identifiers
+	RuleServiceSource       = "service-source"       // every service has an image or build context
+	RuleUndefinedDependency = "undefined-dependency" // depends_on refers to defined services
+	RuleUndefinedNetwork    = "undefined-network"    // networks used by services are declared
+	RuleUndefinedVolume     = "undefined-volume"     // named volumes used by services are declared
+	RuleRestartPolicy       = "restart-policy"       // restart policies are valid
+	RuleDependencyCycle     = "dependency-cycle"     // depends_on has no cycles
+)
+
+// Rule severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityOff     = "off"
+)
+
+// ValidationRules maps rule names to severities. Rules that are not listed
+// are errors.
+type ValidationRules map[string]string
+
+// Check rejects unknown rules and severities
+func (r ValidationRules) Check() error {
+	for rule, severity := range r {
+		switch rule {
+		case RuleServiceName, RuleServiceSource, RuleUndefinedDependency, RuleUndefinedNetwork,
+			RuleUndefinedVolume, RuleRestartPolicy, RuleDependencyCycle:
+		default:
+			return fmt.Errorf("unknown lint rule '%s'", rule)
+		}
+		switch severity {
+		case SeverityError, SeverityWarning, SeverityOff:
+		default:
+			return fmt.Errorf("invalid severity '%s' for lint rule '%s' (supported: error, warning, off)", severity, rule)
+		}
+	}
+	return nil
+}
+
+// Validate checks that every service has an image or build context, that
+// dependencies, networks and named volumes refer to defined resources, that
+// restart policies are valid and that there are no dependency cycles. It
+// returns a *ValidationError listing all problems.
+func (cf *ComposeFile) Validate() error {
+	_, err := cf.ValidateWith(nil)
+	return err
+}
+
+// ValidateWith runs the same checks as Validate with configurable rule
+// severities. Problems from rules set to warning are returned as warnings
+// instead of failing validation; rules set to off are skipped.
+func (cf *ComposeFile) ValidateWith(rules ValidationRules) ([]string, error) {
+	var problems, warnings []string
+	problem := func(rule, format string, args ...interface{}) {
+		switch rules[rule] {
+		case SeverityOff:
+		case SeverityWarning:
+			warnings = append(warnings, fmt.Sprintf(format, args...)+" ("+rule+")")
+		default:
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	names := make([]string, 0, len(cf.Services))
+	for name := range cf.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		service := cf.Services[name]
+		if !validResourceName.MatchString(name) {
+			problem(RuleServiceName, "invalid service name '%s'", name)
+		}
+		if service.Image == "" && service.Build == nil {
+			problem(RuleServiceSource, "service '%s' has neither an image nor a build context", name)
+		}
+		for _, dep := range service.DependsOn {
+			switch {
+			case dep == name:
+				problem(RuleDependencyCycle, "service '%s' depends on itself", name)
+			case !cf.ServiceExists(dep):
+				problem(RuleUndefinedDependency, "service '%s' depends on undefined service '%s'", name, dep)
+			}
+		}
+		for _, network := range service.Networks {
+			if network != "default" && !cf.NetworkExists(network) {
+				problem(RuleUndefinedNetwork, "service '%s' uses undefined network '%s'", name, network)
+			}
+		}
+		for _, mount := range service.Volumes {
+			if volume := extractVolumeName(mount); volume != "" && strings.Contains(mount, ":") && !cf.VolumeExists(volume) {
+				problem(RuleUndefinedVolume, "service '%s' uses undefined volume '%s'", name, volume)
+			}
+		}
+		if service.Restart != "" && !IsValidRestartPolicy(service.Restart) {
+			problem(RuleRestartPolicy, "service '%s' has invalid restart policy '%s'", name, service.Restart)
+		}
+	}
+
+	// Dangling references were reported above, so only look for cycles when
+	// the graph can be built
+	if graph, err := cf.BuildDependencyGraph(); err == nil {
+		for _, cycle := range graph.CircularDeps {
+			if len(cycle) == 2 && cycle[0] == cycle[1] {
+				continue // self-dependency, reported above
+			}
+			problem(RuleDependencyCycle, "circular dependency: %s", strings.Join(cycle, " → "))
+		}
+	}
+
+	if len(problems) > 0 {
+		return warnings, &ValidationError{Problems: problems}
+	}
+	return warnings, nil
+}
+
+// IsValidRestartPolicy checks a restart policy value
+func IsValidRestartPolicy(policy string) bool {
+	switch policy {
+	case "no", "always", "on-failure", "unless-stopped":
+		return true
+	}
+	return strings.HasPrefix(policy, "on-failure:")
+}
@@ -0,0 +1,302 @@
+// Package config loads persistent settings so common flags don't have to be
+// retyped. Settings come from, in increasing order of precedence, the user
+// config file, the project .container-composer.yaml found by walking up from
+// the working directory, and CC_* environment variables. Command line flags
+// override all of them.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/firasmosbahi/container-composer/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFileName is the project config file, looked up from the working
+// directory towards the filesystem root
+const ProjectFileName = ".container-composer.yaml"
+
+// Config holds the merged settings
+type Config struct {
+	// ComposeFiles are the compose files to look for, in order; the first
+	// one that exists is used. Relative paths are resolved against the
+	// project directory.
+	ComposeFiles []string `yaml:"compose_files,omitempty" json:"compose_files,omitempty"`
+	// Profiles are the compose profiles considered active
+	Profiles []string `yaml:"profiles,omitempty" json:"profiles,omitempty"`
+	// Graph holds defaults for the graph command and the TUI graph view
+	Graph GraphConfig `yaml:"graph,omitempty" json:"graph"`
+	// Lint configures the severity of validation rules
+	Lint LintConfig `yaml:"lint,omitempty" json:"lint"`
+	// TemplatePaths are extra directories searched for project templates
+	TemplatePaths []string `yaml:"template_paths,omitempty" json:"template_paths,omitempty"`
+	// Add holds defaults for the add wizards
+	Add AddConfig `yaml:"add,omitempty" json:"add"`
+
+	// ProjectDir is the directory of the project config file, or the
+	// working directory when there is none
+	ProjectDir string `yaml:"-" json:"project_dir"`
+	// Sources lists the config files that were loaded, lowest precedence
+	// first
+	Sources []string `yaml:"-" json:"sources"`
+}
+
+// GraphConfig holds graph display defaults. Unset values keep the built-in
+// defaults.
+type GraphConfig struct {
+	Networks *bool `yaml:"networks,omitempty" json:"networks,omitempty"`
+	Volumes  *bool `yaml:"volumes,omitempty" json:"volumes,omitempty"`
+	Health   *bool `yaml:"health,omitempty" json:"health,omitempty"`
+}
+
+// LintConfig holds validation rule settings
+type LintConfig struct {
+	Rules core.ValidationRules `yaml:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// AddConfig holds defaults for the add wizards
+type AddConfig struct {
+	Restart string `yaml:"restart,omitempty" json:"restart,omitempty"`
+}
+
+// UserDir returns the user config directory, ~/.config/container-composer
+// or $XDG_CONFIG_HOME/container-composer
+func UserDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "container-composer"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "container-composer"), nil
+}
+
+// FindProjectFile walks up from dir and returns the path of the nearest
+// project config file, or "" if there is none
+func FindProjectFile(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, ProjectFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the user config, the project config found from dir and the
+// CC_* environment variables, and merges them
+func Load(dir string) (*Config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &Config{ProjectDir: dir}
+
+	userDir, err := UserDir()
+	if err != nil {
+		logging.Logger().Debug("skipping user config", "error", err)
+	} else if err := cfg.mergeFile(filepath.Join(userDir, "config.yaml"), false); err != nil {
+		return nil, err
+	}
+
+	projectFile, err := FindProjectFile(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %s: %w", ProjectFileName, err)
+	}
+	if projectFile != "" {
+		cfg.ProjectDir = filepath.Dir(projectFile)
+		if err := cfg.mergeFile(projectFile, true); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.mergeEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	for i, path := range cfg.ComposeFiles {
+		if !filepath.IsAbs(path) {
+			cfg.ComposeFiles[i] = filepath.Join(cfg.ProjectDir, path)
+		}
+	}
+	if err := cfg.check(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// mergeFile merges a config file on top of the current settings. Missing
+// files are skipped. Template paths are resolved against the file's
+// directory; compose files are resolved later against the project directory.
+func (c *Config) mergeFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && !required {
+		logging.Logger().Debug("no config file", "path", path)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var layer Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&layer); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	for i, templatePath := range layer.TemplatePaths {
+		layer.TemplatePaths[i] = resolvePath(filepath.Dir(path), templatePath)
+	}
+
+	c.merge(layer)
+	c.Sources = append(c.Sources, path)
+	logging.Logger().Info("loaded config file", "path", path)
+	return nil
+}
+
+// mergeEnv merges the CC_* environment variables. Lists are comma
+// separated, except CC_TEMPLATE_PATHS which uses the OS path list separator,
+// and CC_LINT_RULES is a list of rule=severity pairs.
+func (c *Config) mergeEnv(lookup func(string) (string, bool)) error {
+	var layer Config
+	if value, ok := lookup("CC_COMPOSE_FILES"); ok {
+		layer.ComposeFiles = splitList(value)
+	}
+	if value, ok := lookup("CC_PROFILES"); ok {
+		layer.Profiles = splitList(value)
+	}
+	for name, target := range map[string]**bool{
+		"CC_GRAPH_NETWORKS": &layer.Graph.Networks,
+		"CC_GRAPH_VOLUMES":  &layer.Graph.Volumes,
+		"CC_GRAPH_HEALTH":   &layer.Graph.Health,
+	} {
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: '%s' is not a boolean", name, value)
+		}
+		*target = &enabled
+	}
+	if value, ok := lookup("CC_LINT_RULES"); ok {
+		layer.Lint.Rules = make(core.ValidationRules)
+		for _, pair := range splitList(value) {
+			rule, severity, found := strings.Cut(pair, "=")
+			if !found {
+				return fmt.Errorf("invalid CC_LINT_RULES: expected rule=severity, got '%s'", pair)
+			}
+			layer.Lint.Rules[strings.TrimSpace(rule)] = strings.TrimSpace(severity)
+		}
+	}
+	if value, ok := lookup("CC_TEMPLATE_PATHS"); ok {
+		for _, path := range filepath.SplitList(value) {
+			if path != "" {
+				layer.TemplatePaths = append(layer.TemplatePaths, resolvePath(".", path))
+			}
+		}
+	}
+	if value, ok := lookup("CC_ADD_RESTART"); ok {
+		layer.Add.Restart = value
+	}
+
+	c.merge(layer)
+	return nil
+}
+
+// merge overrides the settings that are set in layer. Lint rules are merged
+// rule by rule.
+func (c *Config) merge(layer Config) {
+	if layer.ComposeFiles != nil {
+		c.ComposeFiles = layer.ComposeFiles
+	}
+	if layer.Profiles != nil {
+		c.Profiles = layer.Profiles
+	}
+	if layer.Graph.Networks != nil {
+		c.Graph.Networks = layer.Graph.Networks
+	}
+	if layer.Graph.Volumes != nil {
+		c.Graph.Volumes = layer.Graph.Volumes
+	}
+	if layer.Graph.Health != nil {
+		c.Graph.Health = layer.Graph.Health
+	}
+	for rule, severity := range layer.Lint.Rules {
+		if c.Lint.Rules == nil {
+			c.Lint.Rules = make(core.ValidationRules)
+		}
+		c.Lint.Rules[rule] = severity
+	}
+	if layer.TemplatePaths != nil {
+		c.TemplatePaths = layer.TemplatePaths
+	}
+	if layer.Add.Restart != "" {
+		c.Add.Restart = layer.Add.Restart
+	}
+}
+
+// check validates the merged settings
+func (c *Config) check() error {
+	if err := c.Lint.Rules.Check(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if c.Add.Restart != "" && !core.IsValidRestartPolicy(c.Add.Restart) {
+		return fmt.Errorf("invalid config: unknown restart policy '%s'", c.Add.Restart)
+	}
+	return nil
+}
+
+// BoolOr returns the value of an optional setting, or fallback when unset
+func BoolOr(value *bool, fallback bool) bool {
+	if value == nil {
+		return fallback
+	}
+	return *value
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// resolvePath expands a leading ~ and makes a relative path absolute
+// against base
+func resolvePath(base, path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,96 @@
+// Package logging provides the structured logger shared by the CLI, core and
+// templates packages. It stays silent until Setup enables it from the
+// --verbose, --debug and --log-file flags.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Log formats accepted by Setup
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Options configures the logger
+type Options struct {
+	Verbose bool   // log decisions at info level
+	Debug   bool   // log everything, including timings, at debug level
+	Format  string // FormatText or FormatJSON
+	File    string // append to this file instead of stderr
+}
+
+var (
+	logger  = slog.New(slog.DiscardHandler)
+	logFile *os.File
+)
+
+// Logger returns the shared logger
+func Logger() *slog.Logger {
+	return logger
+}
+
+// Setup configures the shared logger. Without --verbose, --debug or a log
+// file, logging stays disabled. A log file without either flag records debug
+// output, since nothing else would be worth writing to it.
+func Setup(opts Options) error {
+	if !opts.Verbose && !opts.Debug && opts.File == "" {
+		logger = slog.New(slog.DiscardHandler)
+		return nil
+	}
+
+	level := slog.LevelInfo
+	if opts.Debug || (!opts.Verbose && opts.File != "") {
+		level = slog.LevelDebug
+	}
+
+	var out io.Writer = os.Stderr
+	if opts.File != "" {
+		file, err := os.OpenFile(opts.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		logFile = file
+		out = file
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	switch opts.Format {
+	case FormatText, "":
+		logger = slog.New(slog.NewTextHandler(out, handlerOpts))
+	case FormatJSON:
+		logger = slog.New(slog.NewJSONHandler(out, handlerOpts))
+	default:
+		Close()
+		return fmt.Errorf("unknown log format '%s' (supported: text, json)", opts.Format)
+	}
+	return nil
+}
+
+// Close flushes and closes the log file, if any, and disables logging
+func Close() {
+	logger = slog.New(slog.DiscardHandler)
+	if logFile != nil {
+		logFile.Close()
+		logFile = nil
+	}
+}
+
+// Timed logs the start of an operation at debug level and returns a function
+// that logs its completion with the elapsed time and any extra attributes,
+// e.g.
+//
+//	done := logging.Timed("parse compose file", "path", path)
+//	defer done("services", len(services))
+func Timed(operation string, args ...any) func(args ...any) {
+	start := time.Now()
+	logger.Debug(operation+" started", args...)
+	return func(extra ...any) {
+		logger.Debug(operation+" finished", append(append(args, extra...), "duration", time.Since(start))...)
+	}
+}
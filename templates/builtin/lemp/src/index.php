@@ -0,0 +1,24 @@
+<?php
+// {{.ProjectName}}: replace this page with your application
+
+$dsn = sprintf('mysql:host=db;dbname=%s;charset=utf8mb4', getenv('DB_DATABASE') ?: 'myapp');
+
+try {
+    $pdo = new PDO($dsn, getenv('DB_USER') ?: 'dbuser', getenv('DB_PASSWORD') ?: 'dbpassword', [
+        PDO::ATTR_ERRMODE => PDO::ERRMODE_EXCEPTION,
+    ]);
+    $database = 'connected to MySQL ' . $pdo->query('SELECT VERSION()')->fetchColumn();
+} catch (PDOException $e) {
+    $database = 'not connected: ' . $e->getMessage();
+}
+?>
+<!DOCTYPE html>
+<html>
+<head>
+    <title>{{.ProjectName}}</title>
+</head>
+<body>
+    <h1>{{.ProjectName}}</h1>
+    <p>PHP <?= PHP_VERSION ?>, <?= htmlspecialchars($database) ?></p>
+</body>
+</html>
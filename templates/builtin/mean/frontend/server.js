@@ -0,0 +1,24 @@
+// Serves the static frontend until it is replaced with an Angular app
+// (npx @angular/cli new). API_URL is set in docker-compose.yml.
+const fs = require("fs");
+const http = require("http");
+const path = require("path");
+
+const port = Number(process.env.PORT || 4200);
+const apiUrl = process.env.API_URL || "http://localhost:3000";
+
+const server = http.createServer((req, res) => {
+  if (req.url === "/config.json") {
+    res.writeHead(200, { "Content-Type": "application/json" });
+    res.end(JSON.stringify({ apiUrl }));
+    return;
+  }
+  fs.readFile(path.join(__dirname, "index.html"), (err, html) => {
+    res.writeHead(err ? 500 : 200, { "Content-Type": "text/html" });
+    res.end(err ? "index.html not found" : html);
+  });
+});
+
+server.listen(port, () => console.log(`Frontend listening on port ${port}`));
+
+process.on("SIGTERM", () => server.close(() => process.exit(0)));
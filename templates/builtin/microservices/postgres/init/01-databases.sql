@@ -0,0 +1,6 @@
+-- Creates one database per service on the first start of the postgres
+-- container. Scripts in this directory run in alphabetical order and only
+-- when the data volume is empty.
+CREATE DATABASE users;
+CREATE DATABASE products;
+CREATE DATABASE orders;
@@ -0,0 +1,25 @@
+// Minimal service: replace with your own code. SERVICE_NAME, DATABASE_URL and
+// REDIS_URL are set in docker-compose.yml.
+const http = require("http");
+
+const name = process.env.SERVICE_NAME || "service";
+const port = Number(process.env.PORT || 3000);
+let requests = 0;
+
+const server = http.createServer((req, res) => {
+  requests++;
+
+  if (req.url === "/metrics") {
+    res.writeHead(200, { "Content-Type": "text/plain; version=0.0.4" });
+    res.end(`# TYPE http_requests_total counter\nhttp_requests_total{service="${name}"} ${requests}\n`);
+    return;
+  }
+
+  const body = req.url === "/health" ? { status: "ok" } : { service: name, path: req.url };
+  res.writeHead(200, { "Content-Type": "application/json" });
+  res.end(JSON.stringify(body));
+});
+
+server.listen(port, () => console.log(`${name} listening on port ${port}`));
+
+process.on("SIGTERM", () => server.close(() => process.exit(0)));
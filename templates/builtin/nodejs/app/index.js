@@ -0,0 +1,17 @@
+// {{.ProjectName}}: replace with your application. DATABASE_URL and REDIS_URL
+// are set in docker-compose.yml.
+const http = require("http");
+
+const port = Number(process.env.PORT || 3000);
+
+const server = http.createServer((req, res) => {
+  const body = req.url === "/health"
+    ? { status: "ok" }
+    : { name: "{{.ProjectName}}", environment: process.env.NODE_ENV };
+  res.writeHead(200, { "Content-Type": "application/json" });
+  res.end(JSON.stringify(body));
+});
+
+server.listen(port, () => console.log(`{{.ProjectName}} listening on port ${port}`));
+
+process.on("SIGTERM", () => server.close(() => process.exit(0)));
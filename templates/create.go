@@ -0,0 +1,485 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/firasmosbahi/container-composer/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// maxCapturedFileSize is the size above which project files are not captured
+// into a template
+const maxCapturedFileSize = 1 << 20
+
+// credentialName matches environment variable names whose values are
+// credentials, e.g. MYSQL_ROOT_PASSWORD, API_KEY or SECRET_KEY_BASE
+var credentialName = regexp.MustCompile(`(?i)((^|_)(password|passwd|pass|pwd|token|api_?key|access_?key|private_?key|credentials?)$|(^|_)secret(_|$))`)
+
+// skippedDirs are directories never captured from bind-mounted directories
+var skippedDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true, "__pycache__": true, ".venv": true}
+
+// escapeActions makes text from a project safe to use as a template, so
+// {{ and }} in captured files are rendered literally
+var escapeActions = strings.NewReplacer("{{", `{{"{{"}}`, "}}", `{{"}}"}}`)
+
+// CreateOptions controls how a template is created from a project
+type CreateOptions struct {
+	Name        string
+	Category    string
+	Description string
+	// ProjectName is replaced with {{.ProjectName}}. It defaults to the name
+	// set in the compose file, then to the project directory name.
+	ProjectName string
+}
+
+// ProjectTemplate is a template captured from an existing project
+type ProjectTemplate struct {
+	Manifest     Manifest
+	Compose      []byte
+	Files        []TemplateFile
+	ProjectName  string   // the name replaced with {{.ProjectName}}
+	Replacements int      // occurrences of ProjectName replaced
+	Skipped      []string // referenced files left out, with the reason
+}
+
+// credential is a hardcoded credential value extracted into a variable
+type credential struct {
+	variable string
+	token    string
+	value    string
+	names    []string
+}
+
+// FromProject creates a template from the project of a compose file. The
+// project name is replaced with {{.ProjectName}}, hardcoded credentials in
+// environment values become secret variables, and the files the compose file
+// references (bind mounts, env files, Dockerfiles and configs) are captured.
+func FromProject(composePath string, opts CreateOptions) (*ProjectTemplate, error) {
+	done := logging.Timed("create template", "name", opts.Name, "compose", composePath)
+	defer done()
+
+	data, err := os.ReadFile(composePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", composePath, err)
+	}
+	document, err := core.ParseComposeDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", composePath, err)
+	}
+	composeFile, err := document.ComposeFile()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", composePath, err)
+	}
+	value, err := document.Value()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", composePath, err)
+	}
+	root, _ := value.(map[string]interface{})
+
+	projectDir, err := filepath.Abs(filepath.Dir(composePath))
+	if err != nil {
+		return nil, err
+	}
+	projectName := opts.ProjectName
+	if projectName == "" {
+		projectName, _ = root["name"].(string)
+	}
+	if projectName == "" {
+		projectName = filepath.Base(projectDir)
+	}
+
+	result := &ProjectTemplate{
+		Manifest: Manifest{
+			Name:        opts.Name,
+			Category:    opts.Category,
+			Description: opts.Description,
+			Compose:     "docker-compose.yml",
+		},
+		ProjectName: projectName,
+	}
+	if result.Manifest.Description == "" {
+		result.Manifest.Description = "Created from project " + projectName
+	}
+
+	// Hardcoded credentials become secret variables. Their values are first
+	// replaced with brace-free tokens so escaping leaves them alone.
+	credentials := extractCredentials(composeFile)
+	document.MapEnvironment(func(service, name, value string) string {
+		for _, c := range credentials {
+			if value == c.value && containsName(c.names, name) {
+				return c.token
+			}
+		}
+		// Passwords inside URLs, e.g. postgres://user:password@db/app
+		for _, c := range credentials {
+			value = strings.ReplaceAll(value, ":"+c.value+"@", ":"+c.token+"@")
+		}
+		return value
+	})
+	compose, err := document.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", composePath, err)
+	}
+	compose = result.templatize(compose)
+	for _, c := range credentials {
+		compose = bytes.ReplaceAll(compose, []byte(c.token), []byte("{{."+c.variable+"}}"))
+		result.Manifest.Variables = append(result.Manifest.Variables, Variable{
+			Name:        c.variable,
+			Type:        VarSecret,
+			Description: "Value of " + strings.Join(c.names, ", "),
+		})
+	}
+	result.Compose = compose
+
+	captured := make(map[string][]byte)
+	for _, ref := range referencedFiles(root) {
+		if err := result.capture(projectDir, ref, captured); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, ".env.example")); err == nil {
+		if err := result.capture(projectDir, ".env.example", captured); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range sortedKeys(captured) {
+		result.Files = append(result.Files, TemplateFile{Path: file, Content: string(captured[file])})
+		result.Manifest.Files = append(result.Manifest.Files, ManifestFile{Path: file})
+	}
+
+	if err := result.check(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// extractCredentials collects the hardcoded credential values of the
+// services' environments. Services sharing a value share the variable, which
+// is named after the first variable holding it.
+func extractCredentials(composeFile *core.ComposeFile) []*credential {
+	var credentials []*credential
+	byValue := make(map[string]*credential)
+	taken := make(map[string]bool)
+	for _, serviceName := range sortedKeys(composeFile.Services) {
+		environment := composeFile.Services[serviceName].Environment
+		for _, name := range sortedKeys(environment) {
+			value := environment[name]
+			if !credentialName.MatchString(name) || strings.HasSuffix(strings.ToUpper(name), "_FILE") ||
+				value == "" || strings.Contains(value, "$") {
+				continue
+			}
+			if c, ok := byValue[value]; ok {
+				if !containsName(c.names, name) {
+					c.names = append(c.names, name)
+				}
+				continue
+			}
+
+			variable := variableName(name)
+			for i := 2; taken[variable]; i++ {
+				variable = variableName(name) + "_" + strconv.Itoa(i)
+			}
+			taken[variable] = true
+			c := &credential{
+				variable: variable,
+				token:    "⟦" + strconv.Itoa(len(credentials)) + "⟧",
+				value:    value,
+				names:    []string{name},
+			}
+			byValue[value] = c
+			credentials = append(credentials, c)
+		}
+	}
+	return credentials
+}
+
+// variableName turns an environment variable name into a template variable
+// name, e.g. DB-PASSWORD into db_password
+func variableName(name string) string {
+	runes := []rune(strings.ToLower(name))
+	for i, r := range runes {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			runes[i] = '_'
+		}
+	}
+	variable := string(runes)
+	if !validVariableName.MatchString(variable) {
+		variable = "_" + variable
+	}
+	return variable
+}
+
+// referencedFiles returns the project files a compose file references:
+// relative bind mount sources, env files, Dockerfiles of build contexts and
+// config files
+func referencedFiles(root map[string]interface{}) []string {
+	var refs []string
+	add := func(p string) {
+		if !strings.HasPrefix(p, "/") && isRelativePath(p) {
+			refs = append(refs, path.Clean(p))
+		}
+	}
+
+	services, _ := root["services"].(map[string]interface{})
+	for _, name := range sortedKeys(services) {
+		service, _ := services[name].(map[string]interface{})
+
+		volumes, _ := service["volumes"].([]interface{})
+		for _, volume := range volumes {
+			if source := bindMountSource(volume); source != "" {
+				add(source)
+			}
+		}
+
+		switch envFile := service["env_file"].(type) {
+		case string:
+			add(envFile)
+		case []interface{}:
+			for _, item := range envFile {
+				switch v := item.(type) {
+				case string:
+					add(v)
+				case map[string]interface{}:
+					if p, ok := v["path"].(string); ok {
+						add(p)
+					}
+				}
+			}
+		}
+
+		context, dockerfile := "", "Dockerfile"
+		switch build := service["build"].(type) {
+		case string:
+			context = build
+		case map[string]interface{}:
+			context, _ = build["context"].(string)
+			if file, ok := build["dockerfile"].(string); ok {
+				dockerfile = file
+			}
+		}
+		if context != "" && !strings.Contains(context, "://") {
+			add(path.Join(context, dockerfile))
+			add(path.Join(context, ".dockerignore"))
+		}
+	}
+
+	configs, _ := root["configs"].(map[string]interface{})
+	for _, name := range sortedKeys(configs) {
+		config, _ := configs[name].(map[string]interface{})
+		if file, ok := config["file"].(string); ok {
+			add(file)
+		}
+	}
+	return refs
+}
+
+// capture reads a referenced file, or every file below a referenced
+// directory, into captured. Env files named .env hold the project's own
+// secrets and are never captured.
+func (p *ProjectTemplate) capture(projectDir, ref string, captured map[string][]byte) error {
+	if _, done := captured[ref]; done {
+		return nil
+	}
+	if path.Base(ref) == ".env" {
+		p.skip(ref, "may contain secrets")
+		return nil
+	}
+
+	root := filepath.Join(projectDir, filepath.FromSlash(ref))
+	info, err := os.Stat(root)
+	if errors.Is(err, os.ErrNotExist) {
+		// Optional references such as .dockerignore
+		logging.Logger().Debug("referenced path does not exist", "path", ref)
+		return nil
+	}
+	if err != nil {
+		p.skip(ref, err.Error())
+		return nil
+	}
+	if !info.IsDir() {
+		p.captureFile(root, ref, info, captured)
+		return nil
+	}
+
+	before := len(captured)
+	err = filepath.WalkDir(root, func(file string, entry fs.DirEntry, err error) error {
+		rel, relErr := filepath.Rel(projectDir, file)
+		if relErr != nil {
+			return relErr
+		}
+		rel = filepath.ToSlash(rel)
+		if err != nil {
+			p.skip(rel, err.Error())
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			if skippedDirs[entry.Name()] {
+				p.skip(rel, "dependency or VCS directory")
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.Type().IsRegular() || path.Base(rel) == ".env" {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			p.skip(rel, err.Error())
+			return nil
+		}
+		p.captureFile(file, rel, info, captured)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", ref, err)
+	}
+	if len(captured) == before && !containsName(p.Manifest.Directories, ref) {
+		p.Manifest.Directories = append(p.Manifest.Directories, ref)
+	}
+	return nil
+}
+
+// captureFile reads a text file into captured, leaving out large and binary
+// files
+func (p *ProjectTemplate) captureFile(file, rel string, info fs.FileInfo, captured map[string][]byte) {
+	if info.Size() > maxCapturedFileSize {
+		p.skip(rel, "larger than 1 MiB")
+		return
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		p.skip(rel, err.Error())
+		return
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		p.skip(rel, "binary file")
+		return
+	}
+	captured[rel] = p.templatize(data)
+	logging.Logger().Debug("captured file", "path", rel, "bytes", len(data))
+}
+
+// skip records a referenced file that is left out of the template
+func (p *ProjectTemplate) skip(rel, reason string) {
+	logging.Logger().Info("not capturing file", "path", rel, "reason", reason)
+	p.Skipped = append(p.Skipped, rel+" ("+reason+")")
+}
+
+// templatize escapes template actions in project content and replaces the
+// project name with {{.ProjectName}} where it stands as a word of its own,
+// e.g. in shop_db or shop-network but not in workshop
+func (p *ProjectTemplate) templatize(data []byte) []byte {
+	text := escapeActions.Replace(string(data))
+	if p.ProjectName == "" {
+		return []byte(text)
+	}
+
+	isWordChar := func(b byte) bool {
+		return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+	}
+	var out strings.Builder
+	for {
+		i := strings.Index(text, p.ProjectName)
+		if i < 0 {
+			break
+		}
+		end := i + len(p.ProjectName)
+		if (i > 0 && isWordChar(text[i-1])) || (end < len(text) && isWordChar(text[end])) {
+			out.WriteString(text[:end])
+		} else {
+			out.WriteString(text[:i] + "{{.ProjectName}}")
+			p.Replacements++
+		}
+		text = text[end:]
+	}
+	out.WriteString(text)
+	return []byte(out.String())
+}
+
+// check validates the manifest and renders the template, so a broken
+// template is reported before it is written
+func (p *ProjectTemplate) check() error {
+	if err := p.Manifest.check(); err != nil {
+		return err
+	}
+
+	tmpl := Template{Name: p.Manifest.Name, Variables: p.Manifest.Variables}
+	data, err := tmpl.renderData(TemplateVars{ProjectName: p.ProjectName})
+	if err != nil {
+		return err
+	}
+	compose, err := render("docker-compose.yml", string(p.Compose), data, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := core.ParseComposeData(compose); err != nil {
+		return fmt.Errorf("created compose template does not render to a valid compose file: %w", err)
+	}
+	for _, file := range p.Files {
+		if _, err := render(file.Path, file.Content, data, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write writes the template directory: the manifest, the compose template and
+// the captured files
+func (p *ProjectTemplate) Write(dir string) error {
+	var manifest bytes.Buffer
+	encoder := yaml.NewEncoder(&manifest)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(p.Manifest); err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	files := append([]TemplateFile{
+		{Path: ManifestFileName, Content: manifest.String()},
+		{Path: p.Manifest.Compose, Content: string(p.Compose)},
+	}, p.Files...)
+	for _, file := range files {
+		target := filepath.Join(dir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", file.Path, err)
+		}
+		if err := writeFile(target, []byte(file.Content)); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.Path, err)
+		}
+	}
+	return nil
+}
+
+// sortedKeys returns the sorted keys of a map
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containsName reports whether names contains name
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,240 @@
+package templates
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// funcMap holds the helper functions available in template files. Names and
+// argument order follow Sprig, so {{ .name | default "x" | quote }} works as
+// it does in Helm charts.
+var funcMap = template.FuncMap{
+	// Strings
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"title":      title,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"repeat":     func(n int, s string) string { return strings.Repeat(s, n) },
+	"quote":      func(v interface{}) string { return fmt.Sprintf("%q", fmt.Sprint(v)) },
+	"squote":     func(v interface{}) string { return "'" + fmt.Sprint(v) + "'" },
+	"indent":     indent,
+	"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+	"snakecase":  func(s string) string { return joinWords(s, "_") },
+	"kebabcase":  func(s string) string { return joinWords(s, "-") },
+	"camelcase":  func(s string) string { return strings.ReplaceAll(title(joinWords(s, " ")), " ", "") },
+	"join":       join,
+	"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+
+	// Defaults and conditions
+	"default":  func(def, value interface{}) interface{} { return ternary(value, def, !empty(value)) },
+	"empty":    empty,
+	"coalesce": coalesce,
+	"ternary":  ternary,
+	"required": required,
+
+	// Collections
+	"list": func(items ...interface{}) []interface{} { return items },
+	"dict": dict,
+
+	// Arithmetic
+	"add": func(a, b int) int { return a + b },
+	"sub": func(a, b int) int { return a - b },
+	"mul": func(a, b int) int { return a * b },
+
+	// Encoding
+	"b64enc":    func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"b64dec":    b64dec,
+	"sha256sum": sha256sum,
+	"toJson":    toJSON,
+	"toYaml":    toYAML,
+
+	// Random values, e.g. for passwords in .env.example
+	"randAlphaNum": func(n int) (string, error) { return randAlphaNum(rand.Reader, n) },
+}
+
+// title upper-cases the first letter of every word
+func title(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if i == 0 || !unicode.IsLetter(runes[i-1]) && !unicode.IsDigit(runes[i-1]) {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+// indent prefixes every line of s with n spaces
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// joinWords splits s into lower-case words at case changes, spaces, dashes
+// and underscores, and joins them with sep
+func joinWords(s, sep string) string {
+	var words []string
+	var word []rune
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case r == ' ' || r == '-' || r == '_' || r == '.':
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		case unicode.IsUpper(r) && i > 0 && len(word) > 0 && !unicode.IsUpper(runes[i-1]):
+			words = append(words, string(word))
+			word = nil
+		}
+		word = append(word, unicode.ToLower(r))
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return strings.Join(words, sep)
+}
+
+// join joins the items of a list, whatever their type
+func join(sep string, list interface{}) string {
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return fmt.Sprint(list)
+	}
+	items := make([]string, value.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(value.Index(i).Interface())
+	}
+	return strings.Join(items, sep)
+}
+
+// empty reports whether a value is the zero value of its type
+func empty(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// coalesce returns the first non-empty value
+func coalesce(values ...interface{}) interface{} {
+	for _, value := range values {
+		if !empty(value) {
+			return value
+		}
+	}
+	return nil
+}
+
+// ternary returns a if condition holds, b otherwise
+func ternary(a, b interface{}, condition bool) interface{} {
+	if condition {
+		return a
+	}
+	return b
+}
+
+// required fails rendering with message when value is empty
+func required(message string, value interface{}) (interface{}, error) {
+	if empty(value) {
+		return nil, errors.New(message)
+	}
+	return value, nil
+}
+
+// dict builds a map from alternating keys and values
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, errors.New("dict expects key and value pairs")
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		m[fmt.Sprint(pairs[i])] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// b64dec decodes a base64 string
+func b64dec(s string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// sha256sum returns the hex SHA-256 digest of s
+func sha256sum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// toJSON encodes a value as compact JSON
+func toJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	return string(data), err
+}
+
+// toYAML encodes a value as YAML, without the trailing newline
+func toYAML(value interface{}) (string, error) {
+	data, err := yaml.Marshal(value)
+	return strings.TrimSuffix(string(data), "\n"), err
+}
+
+// randAlphaNum returns a random string of n letters and digits read from
+// random
+func randAlphaNum(random io.Reader, n int) (string, error) {
+	const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	buf := make([]byte, n)
+	for i := range buf {
+		index, err := rand.Int(random, big.NewInt(int64(len(chars))))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate random string: %w", err)
+		}
+		buf[i] = chars[index.Int64()]
+	}
+	return string(buf), nil
+}
+
+// render executes a template file with the helper functions. Referencing an
+// undefined variable is an error. A non-nil random replaces crypto/rand as
+// the source of randAlphaNum, for reproducible output.
+func render(name, content string, data map[string]interface{}, random io.Reader) ([]byte, error) {
+	tmpl := template.New(name).Option("missingkey=error").Funcs(funcMap)
+	if random != nil {
+		tmpl.Funcs(template.FuncMap{"randAlphaNum": func(n int) (string, error) { return randAlphaNum(random, n) }})
+	}
+	tmpl, err := tmpl.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return []byte(out.String()), nil
+}
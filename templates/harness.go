@@ -0,0 +1,272 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/firasmosbahi/container-composer/internal/logging"
+)
+
+// TestProjectName is the project name templates are rendered with by Test
+const TestProjectName = "example"
+
+// Checks run by Test
+const (
+	CheckRender   = "render"   // the template generates a project
+	CheckParse    = "parse"    // the compose file parses
+	CheckValidate = "validate" // the compose file passes validation
+	CheckGraph    = "graph"    // the dependency graph builds and has no cycles
+	CheckEnv      = "env"      // every ${VAR} is defined in .env.example and described variables are used
+	CheckGolden   = "golden"   // the output matches the golden files
+)
+
+// TestOptions controls how a template is tested
+type TestOptions struct {
+	// Values are sample variable values, as raw strings. Variables without a
+	// value keep their default; secrets without a default get a fixed sample.
+	Values map[string]string
+	// GoldenDir holds the expected output. Golden files are not compared when
+	// it is empty or, unless Update is set, does not exist.
+	GoldenDir string
+	// Update rewrites the golden files with the output instead of comparing
+	Update bool
+}
+
+// TestReport is the outcome of testing a template
+type TestReport struct {
+	Template string        `json:"template"`
+	Source   string        `json:"source"`
+	Files    []string      `json:"files"`
+	Golden   string        `json:"golden,omitempty"` // golden directory compared with or updated
+	Updated  bool          `json:"updated"`
+	Problems []TestProblem `json:"problems"`
+}
+
+// TestProblem is a failed check
+type TestProblem struct {
+	Check   string `json:"check"`
+	File    string `json:"file,omitempty"`
+	Message string `json:"message"`
+}
+
+// Passed reports whether every check passed
+func (r *TestReport) Passed() bool {
+	return len(r.Problems) == 0
+}
+
+// problem records a failed check
+func (r *TestReport) problem(check, file, format string, args ...interface{}) {
+	r.Problems = append(r.Problems, TestProblem{Check: check, File: file, Message: fmt.Sprintf(format, args...)})
+}
+
+// Test renders the template with sample values into a temporary directory
+// and checks the result: the compose file must parse, validate and have an
+// acyclic dependency graph, every interpolated variable must be defined in
+// .env.example, and the output must match the golden files. Rendering is
+// reproducible: randAlphaNum is seeded from the template name.
+func (t *Template) Test(opts TestOptions) (*TestReport, error) {
+	done := logging.Timed("test template", "name", t.Name)
+	defer done()
+
+	report := &TestReport{Template: t.Name, Source: t.Source, Files: []string{}, Problems: []TestProblem{}}
+
+	values, err := t.ResolveVariables(t.sampleValues(opts.Values), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	outputDir, err := os.MkdirTemp("", "container-composer-template-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	vars := TemplateVars{ProjectName: TestProjectName, Values: values, Seed: t.Name}
+	if err := t.Generate(outputDir, vars); err != nil {
+		report.problem(CheckRender, "", "%v", err)
+		return report, nil
+	}
+	output, err := readTree(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	report.Files = sortedKeys(output)
+
+	compose := output["docker-compose.yml"]
+	composeFile, err := core.ParseComposeData(compose)
+	if err != nil {
+		report.problem(CheckParse, "docker-compose.yml", "%v", err)
+		return report, nil
+	}
+
+	// Cycles are reported by the graph check
+	_, err = composeFile.ValidateWith(core.ValidationRules{core.RuleDependencyCycle: core.SeverityOff})
+	var validationErr *core.ValidationError
+	if errors.As(err, &validationErr) {
+		for _, problem := range validationErr.Problems {
+			report.problem(CheckValidate, "docker-compose.yml", "%s", problem)
+		}
+	}
+
+	graph, err := composeFile.BuildDependencyGraph()
+	if err != nil {
+		report.problem(CheckGraph, "docker-compose.yml", "%v", err)
+	} else {
+		for _, cycle := range graph.CircularDeps {
+			report.problem(CheckGraph, "docker-compose.yml", "circular dependency: %s", strings.Join(cycle, " → "))
+		}
+	}
+
+	refs, err := core.EnvReferences(compose)
+	if err != nil {
+		report.problem(CheckParse, "docker-compose.yml", "%v", err)
+	}
+	envExample, err := core.ParseEnvFile(output[".env.example"])
+	if err != nil {
+		report.problem(CheckEnv, ".env.example", "%v", err)
+		envExample = &core.EnvFile{}
+	}
+	defined := make(map[string]bool)
+	for _, name := range envExample.Keys() {
+		defined[name] = true
+	}
+	used := make(map[string]bool)
+	for _, ref := range refs {
+		used[ref.Name] = true
+		if !defined[ref.Name] {
+			report.problem(CheckEnv, ".env.example", "${%s} is used in docker-compose.yml but not defined", ref.Name)
+		}
+	}
+	for _, name := range sortedKeys(t.Env) {
+		if !used[name] {
+			report.problem(CheckEnv, ManifestFileName, "env describes %s, which docker-compose.yml does not use", name)
+		}
+	}
+
+	if opts.GoldenDir != "" {
+		if err := report.golden(output, opts.GoldenDir, opts.Update); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// golden compares the output with the golden files in dir, or replaces them
+// when update is set
+func (r *TestReport) golden(output map[string][]byte, dir string, update bool) error {
+	if update {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to clear golden files: %w", err)
+		}
+		for name, data := range output {
+			target := filepath.Join(dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return fmt.Errorf("failed to write golden files: %w", err)
+			}
+			if err := os.WriteFile(target, data, 0644); err != nil {
+				return fmt.Errorf("failed to write golden files: %w", err)
+			}
+		}
+		r.Golden, r.Updated = dir, true
+		return nil
+	}
+
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		logging.Logger().Debug("no golden files", "template", r.Template, "dir", dir)
+		return nil
+	}
+	expected, err := readTree(dir)
+	if err != nil {
+		return err
+	}
+	r.Golden = dir
+
+	for _, name := range sortedKeys(output) {
+		want, ok := expected[name]
+		switch {
+		case !ok:
+			r.problem(CheckGolden, name, "file is generated but has no golden file")
+		case !bytes.Equal(want, output[name]):
+			r.problem(CheckGolden, name, "output differs from the golden file:\n%s",
+				core.UnifiedDiff("golden/"+name, "output/"+name, string(want), string(output[name])))
+		}
+	}
+	for _, name := range sortedKeys(expected) {
+		if _, ok := output[name]; !ok {
+			r.problem(CheckGolden, name, "golden file is not generated")
+		}
+	}
+	return nil
+}
+
+// sampleValues returns the raw values templates are tested with: the given
+// values, and a fixed sample for secrets that would otherwise be random
+func (t *Template) sampleValues(given map[string]string) map[string]string {
+	values := make(map[string]string, len(given))
+	for name, value := range given {
+		values[name] = value
+	}
+	for _, v := range t.Variables {
+		if _, ok := values[v.Name]; !ok && v.Type == VarSecret && v.Default == "" {
+			values[v.Name] = "sample-" + strings.ReplaceAll(v.Name, "_", "-")
+		}
+	}
+	return values
+}
+
+// readTree reads every file below dir, keyed by slash-separated relative path
+func readTree(dir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	return files, nil
+}
+
+// seededReader is a deterministic byte stream: SHA-256 of a seed and a
+// counter
+type seededReader struct {
+	seed    string
+	counter int
+	buf     []byte
+}
+
+// newSeededReader returns a reader that always yields the same bytes for a
+// seed
+func newSeededReader(seed string) *seededReader {
+	return &seededReader{seed: seed}
+}
+
+// Read implements io.Reader
+func (r *seededReader) Read(p []byte) (int, error) {
+	for len(r.buf) < len(p) {
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", r.seed, r.counter)))
+		r.counter++
+		r.buf = append(r.buf, sum[:]...)
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
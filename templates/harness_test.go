@@ -0,0 +1,128 @@
+package templates
+
+import (
+	"flag"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// TestBuiltinTemplates renders every built-in template and compares the
+// output with testdata/golden/<name>. Run with -update after changing a
+// template to rewrite the golden files.
+func TestBuiltinTemplates(t *testing.T) {
+	for _, name := range builtinOrder {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := loadTemplate(builtinFS, path.Join("builtin", name), "builtin")
+			if err != nil {
+				t.Fatalf("failed to load template: %v", err)
+			}
+
+			goldenDir := filepath.Join("testdata", "golden", name)
+			if _, err := os.Stat(goldenDir); err != nil && !*update {
+				t.Fatalf("no golden files in %s; run go test ./templates -update", goldenDir)
+			}
+
+			report, err := tmpl.Test(TestOptions{GoldenDir: goldenDir, Update: *update})
+			if err != nil {
+				t.Fatalf("failed to test template: %v", err)
+			}
+			for _, problem := range report.Problems {
+				t.Errorf("%s %s: %s", problem.Check, problem.File, problem.Message)
+			}
+		})
+	}
+}
+
+// TestTemplateTestReportsProblems checks that broken templates fail the
+// checks they break
+func TestTemplateTestReportsProblems(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		files   []TemplateFile
+		env     map[string]string
+		check   string
+	}{
+		{
+			name:    "invalid compose file",
+			content: "services:\n  web:\n    image: nginx\n    ports: {http: 80}\n",
+			check:   CheckParse,
+		},
+		{
+			name:    "undefined network",
+			content: "services:\n  web:\n    image: nginx\n    networks: [front]\n",
+			check:   CheckValidate,
+		},
+		{
+			name:    "dependency cycle",
+			content: "services:\n  a:\n    image: nginx\n    depends_on: [b]\n  b:\n    image: nginx\n    depends_on: [a]\n",
+			check:   CheckGraph,
+		},
+		{
+			name:    "undefined variable",
+			content: "services:\n  web:\n    image: nginx:${NGINX_VERSION:-alpine}\n    environment:\n      PORT: ${PORT}\n",
+			files:   []TemplateFile{{Path: ".env.example", Content: "PORT=8080\n"}},
+			check:   CheckEnv,
+		},
+		{
+			name:    "described variable not used",
+			content: "services:\n  web:\n    image: nginx:${NGINX_VERSION:-alpine}\n",
+			env:     map[string]string{"NGINX_VERSION": "nginx image tag", "PORT": "listening port"},
+			check:   CheckEnv,
+		},
+		{
+			name:    "missing render variable",
+			content: "services:\n  web:\n    image: {{.image}}\n",
+			check:   CheckRender,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tmpl := &Template{Name: "broken", Content: test.content, Files: test.files, Env: test.env}
+			report, err := tmpl.Test(TestOptions{})
+			if err != nil {
+				t.Fatalf("failed to test template: %v", err)
+			}
+			if len(report.Problems) != 1 || report.Problems[0].Check != test.check {
+				t.Fatalf("expected a single %s problem, got %+v", test.check, report.Problems)
+			}
+		})
+	}
+}
+
+// TestTemplateTestGolden checks that golden files are written with -update
+// semantics and that changed output is reported
+func TestTemplateTestGolden(t *testing.T) {
+	goldenDir := t.TempDir()
+	tmpl := &Template{
+		Name:    "golden",
+		Content: "services:\n  web:\n    image: nginx\n    container_name: {{.ProjectName}}_web\n",
+		Files:   []TemplateFile{{Path: "secret.txt", Content: "{{randAlphaNum 16}}\n"}},
+	}
+
+	if _, err := tmpl.Test(TestOptions{GoldenDir: goldenDir, Update: true}); err != nil {
+		t.Fatalf("failed to write golden files: %v", err)
+	}
+	report, err := tmpl.Test(TestOptions{GoldenDir: goldenDir})
+	if err != nil {
+		t.Fatalf("failed to test template: %v", err)
+	}
+	if !report.Passed() {
+		t.Fatalf("expected output to be reproducible, got %+v", report.Problems)
+	}
+
+	tmpl.Content = strings.Replace(tmpl.Content, "nginx", "caddy", 1)
+	report, err = tmpl.Test(TestOptions{GoldenDir: goldenDir})
+	if err != nil {
+		t.Fatalf("failed to test template: %v", err)
+	}
+	if len(report.Problems) != 1 || report.Problems[0].Check != CheckGolden || report.Problems[0].File != "docker-compose.yml" {
+		t.Fatalf("expected docker-compose.yml to differ from its golden file, got %+v", report.Problems)
+	}
+}
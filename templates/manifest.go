@@ -0,0 +1,259 @@
+package templates
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/internal/config"
+	"github.com/firasmosbahi/container-composer/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestFileName is the manifest every template directory contains
+const ManifestFileName = "template.yaml"
+
+//go:embed all:builtin
+var builtinFS embed.FS
+
+// builtinOrder is the order built-in templates are listed in
+var builtinOrder = []string{"lamp", "lemp", "mean", "nodejs", "django", "rails", "microservices", "empty"}
+
+// searchPaths are extra template directories, e.g. from the project config
+var searchPaths []string
+
+// validTemplateName matches template names
+var validTemplateName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Manifest describes a template directory
+type Manifest struct {
+	Name        string            `yaml:"name"`
+	Category    string            `yaml:"category"`
+	Description string            `yaml:"description"`
+	Version     string            `yaml:"version,omitempty"` // recorded in projects for template upgrade
+	Compose     string            `yaml:"compose,omitempty"` // compose template, default docker-compose.yml
+	Variables   []Variable        `yaml:"variables,omitempty"`
+	Env         map[string]string `yaml:"env,omitempty"` // comments of the variables in the derived .env.example
+	Files       []ManifestFile    `yaml:"files,omitempty"`
+	Directories []string          `yaml:"directories,omitempty"`
+}
+
+// ManifestFile is a file a template creates. Its content is inline or read
+// from Source, a path in the template directory that defaults to Path.
+type ManifestFile struct {
+	Path    string `yaml:"path"`
+	Source  string `yaml:"source,omitempty"`
+	Content string `yaml:"content,omitempty"`
+}
+
+// SetSearchPaths sets extra directories searched for templates, after the
+// user template directory. Templates found later override earlier ones with
+// the same name, including built-ins.
+func SetSearchPaths(paths []string) {
+	searchPaths = paths
+}
+
+// SearchPaths returns the directories searched for user templates: the user
+// template directory followed by the configured search paths
+func SearchPaths() []string {
+	var paths []string
+	if dir, err := UserTemplateDir(); err == nil {
+		paths = append(paths, dir)
+	}
+	return append(paths, searchPaths...)
+}
+
+// UserTemplateDir returns ~/.config/container-composer/templates
+func UserTemplateDir() (string, error) {
+	dir, err := config.UserDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "templates"), nil
+}
+
+// LoadTemplates returns the built-in templates merged with the templates in
+// the search paths, followed by the templates of the added registries.
+// Templates that fail to load are skipped and reported in the returned error.
+func LoadTemplates() ([]Template, error) {
+	done := logging.Timed("load templates")
+
+	var loaded []Template
+	var errs []error
+	for _, name := range builtinOrder {
+		tmpl, err := loadTemplate(builtinFS, path.Join("builtin", name), "builtin")
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		loaded = append(loaded, *tmpl)
+	}
+
+	for _, dir := range SearchPaths() {
+		found, err := loadTemplateDirs(dir)
+		errs = append(errs, err)
+		for _, tmpl := range found {
+			loaded = addTemplate(loaded, tmpl)
+		}
+	}
+
+	// Registry templates are namespaced, so they never override others
+	found, err := loadRegistries()
+	errs = append(errs, err)
+	loaded = append(loaded, found...)
+
+	done("templates", len(loaded))
+	return loaded, errors.Join(errs...)
+}
+
+// addTemplate appends a template, replacing one with the same name
+func addTemplate(loaded []Template, tmpl Template) []Template {
+	for i, existing := range loaded {
+		if existing.Name == tmpl.Name {
+			logging.Logger().Info("template overrides another", "name", tmpl.Name,
+				"source", tmpl.Source, "overridden", existing.Source)
+			loaded[i] = tmpl
+			return loaded
+		}
+	}
+	return append(loaded, tmpl)
+}
+
+// loadTemplateDirs loads every template directory directly inside dir
+func loadTemplateDirs(dir string) ([]Template, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		logging.Logger().Debug("no template directory", "path", dir)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template directory: %w", err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var found []Template
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		templateDir := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(filepath.Join(templateDir, ManifestFileName)); err != nil {
+			continue
+		}
+		tmpl, err := LoadTemplateDir(templateDir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		found = append(found, *tmpl)
+	}
+	return found, errors.Join(errs...)
+}
+
+// LoadTemplateDir loads a template from a directory containing a manifest
+func LoadTemplateDir(dir string) (*Template, error) {
+	return loadTemplate(os.DirFS(dir), ".", dir)
+}
+
+// loadTemplate reads the manifest in dir and every file it references
+func loadTemplate(fsys fs.FS, dir, source string) (*Template, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, ManifestFileName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", source, err)
+	}
+	var manifest Manifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest in %s: %w", source, err)
+	}
+	if err := manifest.check(); err != nil {
+		return nil, fmt.Errorf("invalid manifest in %s: %w", source, err)
+	}
+
+	content, err := fs.ReadFile(fsys, path.Join(dir, manifest.Compose))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose template of %s: %w", source, err)
+	}
+
+	tmpl := &Template{
+		Name:        manifest.Name,
+		Description: manifest.Description,
+		Category:    manifest.Category,
+		Version:     manifest.Version,
+		Content:     string(content),
+		Variables:   manifest.Variables,
+		Env:         manifest.Env,
+		Directories: manifest.Directories,
+		Source:      source,
+	}
+	for _, file := range manifest.Files {
+		fileContent := file.Content
+		if fileContent == "" {
+			fileSource := file.Source
+			if fileSource == "" {
+				fileSource = file.Path
+			}
+			data, err := fs.ReadFile(fsys, path.Join(dir, fileSource))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file %s of %s: %w", fileSource, source, err)
+			}
+			fileContent = string(data)
+		}
+		tmpl.Files = append(tmpl.Files, TemplateFile{Path: file.Path, Content: fileContent})
+	}
+
+	logging.Logger().Debug("loaded template", "name", tmpl.Name, "source", source, "files", len(tmpl.Files))
+	return tmpl, nil
+}
+
+// check validates a manifest and fills in defaults
+func (m *Manifest) check() error {
+	if !validTemplateName.MatchString(m.Name) {
+		return fmt.Errorf("invalid template name '%s'", m.Name)
+	}
+	if m.Category == "" {
+		return fmt.Errorf("template '%s' has no category", m.Name)
+	}
+	if m.Compose == "" {
+		m.Compose = "docker-compose.yml"
+	}
+	if !isRelativePath(m.Compose) {
+		return fmt.Errorf("compose path '%s' must be relative to the template directory", m.Compose)
+	}
+	for _, file := range m.Files {
+		if !isRelativePath(file.Path) || (file.Source != "" && !isRelativePath(file.Source)) {
+			return fmt.Errorf("file path '%s' must be relative and stay inside the project", file.Path)
+		}
+	}
+	for _, dir := range m.Directories {
+		if !isRelativePath(dir) {
+			return fmt.Errorf("directory '%s' must be relative and stay inside the project", dir)
+		}
+	}
+
+	declared := make(map[string]bool)
+	for i := range m.Variables {
+		if err := m.Variables[i].check(declared); err != nil {
+			return err
+		}
+		declared[m.Variables[i].Name] = true
+	}
+	return nil
+}
+
+// isRelativePath reports whether p is a relative slash-separated path that
+// does not leave its base directory
+func isRelativePath(p string) bool {
+	if p == "" || path.IsAbs(p) || filepath.IsAbs(p) {
+		return false
+	}
+	clean := path.Clean(p)
+	return clean != ".." && !strings.HasPrefix(clean, "../")
+}
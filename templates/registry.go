@@ -0,0 +1,562 @@
+package templates
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/firasmosbahi/container-composer/internal/config"
+	"github.com/firasmosbahi/container-composer/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// registryIndexName is the file in the registry directory that records the
+// added registries
+const registryIndexName = "registries.yaml"
+
+// Kinds of registry sources
+const (
+	RegistryGit     = "git"       // a git repository, cloned
+	RegistryArchive = "archive"   // a .tar.gz archive, local or downloaded
+	RegistryLocal   = "directory" // a local directory, copied
+)
+
+// validNamespace matches registry namespaces
+var validNamespace = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// registrySkippedDirs are directories not searched for templates
+var registrySkippedDirs = map[string]bool{".git": true, "testdata": true, "node_modules": true}
+
+// Registry is a collection of templates fetched from a git repository, an
+// archive or a directory into the local cache. Its templates are available
+// as <namespace>/<name>.
+type Registry struct {
+	Namespace string    `yaml:"namespace" json:"namespace"`
+	Source    string    `yaml:"source" json:"source"`
+	Kind      string    `yaml:"kind" json:"kind"`
+	Ref       string    `yaml:"ref,omitempty" json:"ref,omitempty"`           // git branch or tag
+	Revision  string    `yaml:"revision,omitempty" json:"revision,omitempty"` // git commit fetched
+	Templates []string  `yaml:"templates" json:"templates"`
+	Updated   time.Time `yaml:"updated" json:"updated"`
+}
+
+// RegistryOptions controls how a registry is added
+type RegistryOptions struct {
+	// Namespace defaults to the last element of the source, without .git or
+	// the archive extension
+	Namespace string
+	// Ref is the git branch or tag to clone, the default branch when empty
+	Ref string
+}
+
+// RegistryDir returns ~/.config/container-composer/registries, the cache of
+// added registries
+func RegistryDir() (string, error) {
+	dir, err := config.UserDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "registries"), nil
+}
+
+// Dir returns the cache directory of the registry
+func (r *Registry) Dir() (string, error) {
+	dir, err := RegistryDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, r.Namespace), nil
+}
+
+// Registries returns the added registries, sorted by namespace
+func Registries() ([]Registry, error) {
+	dir, err := RegistryDir()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, registryIndexName))
+	if errors.Is(err, os.ErrNotExist) {
+		return []Registry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry index: %w", err)
+	}
+	var registries []Registry
+	if err := yaml.Unmarshal(data, &registries); err != nil {
+		return nil, fmt.Errorf("invalid registry index %s: %w", filepath.Join(dir, registryIndexName), err)
+	}
+	sort.Slice(registries, func(i, j int) bool { return registries[i].Namespace < registries[j].Namespace })
+	return registries, nil
+}
+
+// GetRegistry returns the registry with a namespace
+func GetRegistry(namespace string) (*Registry, error) {
+	registries, err := Registries()
+	if err != nil {
+		return nil, err
+	}
+	for _, registry := range registries {
+		if registry.Namespace == namespace {
+			return &registry, nil
+		}
+	}
+	return nil, fmt.Errorf("registry '%s' %w", namespace, core.ErrNotFound)
+}
+
+// writeRegistries replaces the registry index
+func writeRegistries(registries []Registry) error {
+	dir, err := RegistryDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create registry directory: %w", err)
+	}
+	data, err := yaml.Marshal(registries)
+	if err != nil {
+		return fmt.Errorf("failed to encode registry index: %w", err)
+	}
+	if err := writeFile(filepath.Join(dir, registryIndexName), data); err != nil {
+		return fmt.Errorf("failed to write registry index: %w", err)
+	}
+	return nil
+}
+
+// AddRegistry fetches the templates of a git URL, a .tar.gz archive (a path
+// or an http, https or file URL) or a local directory into the cache and
+// records the registry. Every directory of the source containing a
+// template.yaml is a template.
+func AddRegistry(source string, opts RegistryOptions) (*Registry, error) {
+	done := logging.Timed("add registry", "source", source)
+	defer done()
+
+	registry := &Registry{Source: source, Kind: registryKind(source), Ref: opts.Ref, Namespace: opts.Namespace}
+	if isLocalPath(source) {
+		abs, err := filepath.Abs(source)
+		if err != nil {
+			return nil, err
+		}
+		registry.Source = abs
+	}
+	if registry.Ref != "" && registry.Kind != RegistryGit {
+		return nil, fmt.Errorf("a ref can only be given for git repositories")
+	}
+	if registry.Namespace == "" {
+		registry.Namespace = defaultNamespace(source)
+	}
+	if !validNamespace.MatchString(registry.Namespace) {
+		return nil, fmt.Errorf("invalid namespace '%s': use letters, digits, - and _", registry.Namespace)
+	}
+
+	registries, err := Registries()
+	if err != nil {
+		return nil, err
+	}
+	for _, existing := range registries {
+		if existing.Namespace == registry.Namespace {
+			return nil, fmt.Errorf("registry '%s' %w (from %s); use 'template update' to refresh it", registry.Namespace, core.ErrAlreadyExists, existing.Source)
+		}
+	}
+
+	if err := registry.fetch(); err != nil {
+		return nil, err
+	}
+	if err := writeRegistries(append(registries, *registry)); err != nil {
+		return nil, err
+	}
+	logging.Logger().Info("added registry", "namespace", registry.Namespace, "templates", len(registry.Templates))
+	return registry, nil
+}
+
+// UpdateRegistry fetches the source of a registry again and replaces its
+// cached templates. It returns the registry before and after the update.
+func UpdateRegistry(namespace string) (before, after *Registry, err error) {
+	done := logging.Timed("update registry", "namespace", namespace)
+	defer done()
+
+	registries, err := Registries()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range registries {
+		if registries[i].Namespace != namespace {
+			continue
+		}
+		previous := registries[i]
+		if err := registries[i].fetch(); err != nil {
+			return nil, nil, err
+		}
+		if err := writeRegistries(registries); err != nil {
+			return nil, nil, err
+		}
+		return &previous, &registries[i], nil
+	}
+	return nil, nil, fmt.Errorf("registry '%s' %w", namespace, core.ErrNotFound)
+}
+
+// RemoveRegistry deletes a registry and its cached templates
+func RemoveRegistry(namespace string) (*Registry, error) {
+	registries, err := Registries()
+	if err != nil {
+		return nil, err
+	}
+	for i, registry := range registries {
+		if registry.Namespace != namespace {
+			continue
+		}
+		dir, err := registry.Dir()
+		if err != nil {
+			return nil, err
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return nil, fmt.Errorf("failed to remove registry '%s': %w", namespace, err)
+		}
+		if err := writeRegistries(append(registries[:i], registries[i+1:]...)); err != nil {
+			return nil, err
+		}
+		logging.Logger().Info("removed registry", "namespace", namespace)
+		return &registry, nil
+	}
+	return nil, fmt.Errorf("registry '%s' %w", namespace, core.ErrNotFound)
+}
+
+// fetch downloads the source into a new directory, loads its templates and
+// replaces the cached copy. The cache is left untouched when anything fails.
+func (r *Registry) fetch() error {
+	dir, err := r.Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return fmt.Errorf("failed to create registry directory: %w", err)
+	}
+	fetchDir, err := os.MkdirTemp(filepath.Dir(dir), "."+r.Namespace+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(fetchDir)
+
+	switch r.Kind {
+	case RegistryGit:
+		r.Revision, err = cloneGit(r.Source, r.Ref, fetchDir)
+	case RegistryArchive:
+		err = extractArchive(r.Source, fetchDir)
+	default:
+		err = copyDir(r.Source, fetchDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	loaded, err := loadRegistryTemplates(fetchDir, r.Namespace)
+	if err != nil {
+		return fmt.Errorf("invalid templates in %s: %w", r.Source, err)
+	}
+	if len(loaded) == 0 {
+		return fmt.Errorf("no templates (directories with a %s) found in %s", ManifestFileName, r.Source)
+	}
+	r.Templates = []string{}
+	for _, tmpl := range loaded {
+		r.Templates = append(r.Templates, tmpl.Name)
+	}
+	r.Updated = time.Now().UTC().Truncate(time.Second)
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to replace cached templates of '%s': %w", r.Namespace, err)
+	}
+	if err := os.Rename(fetchDir, dir); err != nil {
+		return fmt.Errorf("failed to replace cached templates of '%s': %w", r.Namespace, err)
+	}
+	return nil
+}
+
+// loadRegistries loads the templates of every added registry
+func loadRegistries() ([]Template, error) {
+	registries, err := Registries()
+	if err != nil {
+		return nil, err
+	}
+	var loaded []Template
+	var errs []error
+	for _, registry := range registries {
+		dir, err := registry.Dir()
+		if err != nil {
+			return nil, err
+		}
+		found, err := loadRegistryTemplates(dir, registry.Namespace)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("registry '%s': %w", registry.Namespace, err))
+		}
+		loaded = append(loaded, found...)
+	}
+	return loaded, errors.Join(errs...)
+}
+
+// loadRegistryTemplates loads every template below dir, named
+// <namespace>/<name>. Template directories are not searched further.
+func loadRegistryTemplates(dir, namespace string) ([]Template, error) {
+	var loaded []Template
+	var errs []error
+	err := filepath.WalkDir(dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return err
+		}
+		if p != dir && registrySkippedDirs[entry.Name()] {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(p, ManifestFileName)); err != nil {
+			return nil
+		}
+		tmpl, err := LoadTemplateDir(p)
+		if err != nil {
+			errs = append(errs, err)
+			return filepath.SkipDir
+		}
+		tmpl.Name = namespace + "/" + tmpl.Name
+		for _, other := range loaded {
+			if other.Name == tmpl.Name {
+				errs = append(errs, fmt.Errorf("template '%s' is defined in both %s and %s", tmpl.Name, other.Source, tmpl.Source))
+			}
+		}
+		loaded = append(loaded, *tmpl)
+		return filepath.SkipDir
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("cached templates %w in %s; run 'container-composer template update %s'", core.ErrNotFound, dir, namespace)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].Name < loaded[j].Name })
+	return loaded, errors.Join(errs...)
+}
+
+// registryKind tells the kind of a registry source: archives by their
+// extension, local directories that are not bare repositories, and git for
+// everything else
+func registryKind(source string) string {
+	lower := strings.ToLower(strings.TrimSuffix(source, "/"))
+	if strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") {
+		return RegistryArchive
+	}
+	if isLocalPath(source) {
+		if info, err := os.Stat(source); err == nil && info.IsDir() && !isBareRepository(source) {
+			return RegistryLocal
+		}
+	}
+	return RegistryGit
+}
+
+// isLocalPath reports whether a source is a path rather than a URL or an
+// scp-like git address (git@host:repo)
+func isLocalPath(source string) bool {
+	if strings.Contains(source, "://") {
+		return false
+	}
+	before, _, found := strings.Cut(source, ":")
+	return !found || strings.Contains(before, "/") || filepath.VolumeName(source) != ""
+}
+
+// isBareRepository reports whether dir is a bare git repository
+func isBareRepository(dir string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultNamespace derives a namespace from the last element of a source,
+// e.g. acme from git@github.com:platform/acme.git
+func defaultNamespace(source string) string {
+	name := strings.TrimRight(source, "/")
+	if u, err := url.Parse(name); err == nil && u.Scheme != "" {
+		name = u.Path
+	}
+	name = path.Base(filepath.ToSlash(name))
+	if _, after, found := strings.Cut(name, ":"); found {
+		name = path.Base(after)
+	}
+	for _, suffix := range []string{".git", ".tar.gz", ".tgz"} {
+		name = strings.TrimSuffix(name, suffix)
+	}
+	return name
+}
+
+// cloneGit clones a repository into dir and returns the commit cloned. The
+// .git directory is removed afterwards; updates clone again.
+func cloneGit(source, ref, dir string) (string, error) {
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	if err := runGit("", append(args, "--", source, dir)...); err != nil {
+		return "", err
+	}
+	revision, err := gitOutput(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	if err := os.RemoveAll(filepath.Join(dir, ".git")); err != nil {
+		return "", fmt.Errorf("failed to clean up clone: %w", err)
+	}
+	return revision, nil
+}
+
+// runGit runs a git command in dir
+func runGit(dir string, args ...string) error {
+	_, err := gitOutput(dir, args...)
+	return err
+}
+
+// gitOutput runs a git command in dir and returns its trimmed output. Git
+// never prompts for credentials.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("failed to run git: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// extractArchive extracts the regular files and directories of a .tar.gz
+// archive into dir. Entries leaving dir are rejected; links and other special
+// files are skipped.
+func extractArchive(source, dir string) error {
+	reader, err := openArchive(source)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read archive %s: %w", source, err)
+	}
+	defer gz.Close()
+
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive %s: %w", source, err)
+		}
+		name := strings.TrimPrefix(header.Name, "./")
+		if name == "" || name == "." {
+			continue
+		}
+		if !isRelativePath(name) {
+			return fmt.Errorf("archive %s contains an entry outside its root: %s", source, header.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to extract %s: %w", name, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return fmt.Errorf("failed to extract %s: %w", name, err)
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", name, err)
+			}
+			_, err = io.Copy(file, archive)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %w", name, err)
+			}
+		default:
+			logging.Logger().Debug("skipped archive entry", "name", name, "type", header.Typeflag)
+		}
+	}
+}
+
+// openArchive opens a local archive or downloads one from an http, https or
+// file URL
+func openArchive(source string) (io.ReadCloser, error) {
+	u, err := url.Parse(source)
+	if err != nil || !strings.Contains(source, "://") {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+		return file, nil
+	}
+
+	switch u.Scheme {
+	case "file":
+		file, err := os.Open(filepath.FromSlash(u.Path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive: %w", err)
+		}
+		return file, nil
+	case "http", "https":
+		resp, err := http.Get(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download archive: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to download archive %s: %s", source, resp.Status)
+		}
+		return resp.Body, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive URL scheme '%s'", u.Scheme)
+	}
+}
+
+// copyDir copies the regular files below src into dir, without .git
+func copyDir(src, dir string) error {
+	return filepath.WalkDir(src, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", src, err)
+		}
+		if entry.IsDir() && entry.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dir, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", rel, err)
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+}
@@ -0,0 +1,202 @@
+package templates
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/firasmosbahi/container-composer/core"
+)
+
+// TestGitRegistry adds a bare repository by file URL, updates it after a
+// push and removes it
+func TestGitRegistry(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	work := t.TempDir()
+	writeTemplate(t, filepath.Join(work, "templates", "go-service"), "go-service", "1.0.0")
+	git(t, work, "init", "--quiet")
+	git(t, work, "add", "-A")
+	git(t, work, "commit", "--quiet", "-m", "Add go-service")
+	bare := filepath.Join(t.TempDir(), "acme.git")
+	git(t, work, "clone", "--quiet", "--bare", work, bare)
+
+	registry, err := AddRegistry("file://"+filepath.ToSlash(bare), RegistryOptions{})
+	if err != nil {
+		t.Fatalf("failed to add registry: %v", err)
+	}
+	if registry.Namespace != "acme" || registry.Kind != RegistryGit || registry.Revision == "" {
+		t.Fatalf("unexpected registry %+v", registry)
+	}
+	tmpl, err := GetTemplate("acme/go-service")
+	if err != nil {
+		t.Fatalf("failed to get template: %v", err)
+	}
+	if tmpl.Version != "1.0.0" {
+		t.Errorf("expected version 1.0.0, got %s", tmpl.Version)
+	}
+	if _, err := AddRegistry(bare, RegistryOptions{}); !errors.Is(err, core.ErrAlreadyExists) {
+		t.Errorf("expected the namespace to be taken, got %v", err)
+	}
+
+	writeTemplate(t, filepath.Join(work, "templates", "go-service"), "go-service", "1.1.0")
+	writeTemplate(t, filepath.Join(work, "templates", "worker"), "worker", "1.0.0")
+	git(t, work, "add", "-A")
+	git(t, work, "commit", "--quiet", "-m", "Add worker")
+	git(t, work, "push", "--quiet", bare, "HEAD")
+
+	before, after, err := UpdateRegistry("acme")
+	if err != nil {
+		t.Fatalf("failed to update registry: %v", err)
+	}
+	if before.Revision == after.Revision || len(after.Templates) != 2 {
+		t.Errorf("expected a new revision with two templates, got %+v", after)
+	}
+	if tmpl, err := GetTemplate("acme/go-service"); err != nil || tmpl.Version != "1.1.0" {
+		t.Errorf("expected version 1.1.0 after the update, got %v %v", tmpl, err)
+	}
+
+	if _, err := RemoveRegistry("acme"); err != nil {
+		t.Fatalf("failed to remove registry: %v", err)
+	}
+	if _, err := GetTemplate("acme/worker"); !errors.Is(err, core.ErrNotFound) {
+		t.Errorf("expected the template to be gone, got %v", err)
+	}
+	if _, err := RemoveRegistry("acme"); !errors.Is(err, core.ErrNotFound) {
+		t.Errorf("expected the registry to be gone, got %v", err)
+	}
+}
+
+// TestArchiveRegistry adds a .tar.gz archive with a top-level directory
+func TestArchiveRegistry(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	src := t.TempDir()
+	writeTemplate(t, filepath.Join(src, "templates-main", "go-service"), "go-service", "2.0.0")
+	archive := filepath.Join(t.TempDir(), "templates.tar.gz")
+	writeArchive(t, archive, src, nil)
+
+	registry, err := AddRegistry("file://"+filepath.ToSlash(archive), RegistryOptions{Namespace: "acme"})
+	if err != nil {
+		t.Fatalf("failed to add registry: %v", err)
+	}
+	if registry.Kind != RegistryArchive || len(registry.Templates) != 1 || registry.Templates[0] != "acme/go-service" {
+		t.Fatalf("unexpected registry %+v", registry)
+	}
+	if _, err := GetTemplate("acme/go-service"); err != nil {
+		t.Fatalf("failed to get template: %v", err)
+	}
+
+	if _, err := AddRegistry(archive, RegistryOptions{Namespace: "acme", Ref: "main"}); err == nil {
+		t.Errorf("expected a ref to be rejected for an archive")
+	}
+	if _, err := AddRegistry(archive, RegistryOptions{Namespace: "acme/x"}); err == nil {
+		t.Errorf("expected an invalid namespace to be rejected")
+	}
+}
+
+// TestArchiveRegistryRejectsEscapingEntries checks that archive entries
+// cannot be written outside the cache
+func TestArchiveRegistryRejectsEscapingEntries(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	src := t.TempDir()
+	writeTemplate(t, filepath.Join(src, "go-service"), "go-service", "1.0.0")
+	archive := filepath.Join(t.TempDir(), "evil.tgz")
+	writeArchive(t, archive, src, map[string]string{"../escaped.txt": "boom"})
+
+	if _, err := AddRegistry(archive, RegistryOptions{}); err == nil {
+		t.Fatalf("expected the archive to be rejected")
+	}
+	registries, err := Registries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(registries) != 0 {
+		t.Errorf("expected no registry to be recorded, got %+v", registries)
+	}
+}
+
+// TestDefaultNamespace derives namespaces from sources
+func TestDefaultNamespace(t *testing.T) {
+	tests := map[string]string{
+		"https://git.example.com/platform/acme.git": "acme",
+		"git@github.com:acme/templates.git":         "templates",
+		"git@github.com:acme.git":                   "acme",
+		"file:///srv/git/acme.git/":                 "acme",
+		"./archives/acme.tar.gz":                    "acme",
+		"https://example.com/acme.tgz":              "acme",
+	}
+	for source, want := range tests {
+		if got := defaultNamespace(source); got != want {
+			t.Errorf("defaultNamespace(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
+
+// writeTemplate writes a minimal template directory
+func writeTemplate(t *testing.T, dir, name, version string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := "name: " + name + "\ncategory: web\ndescription: " + name + "\nversion: " + version + "\n"
+	compose := "services:\n  app:\n    image: alpine:" + version + "\n"
+	for file, content := range map[string]string{ManifestFileName: manifest, "docker-compose.yml": compose} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeArchive writes the files below dir, and extra entries, to a .tar.gz
+func writeArchive(t *testing.T, archive, dir string, extra map[string]string) {
+	t.Helper()
+	file, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	writer := tar.NewWriter(gz)
+
+	files, err := readTree(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range extra {
+		files[name] = []byte(content)
+	}
+	for _, name := range sortedKeys(files) {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write(files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// git runs a git command in dir
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
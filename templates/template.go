@@ -1,44 +1,31 @@
 package templates
 
 import (
-	_ "embed"
 	"fmt"
+	"io"
 	"os"
+	"path"
 	"path/filepath"
-	"text/template"
-)
-
-//go:embed lamp.yaml
-var lampTemplate string
-
-//go:embed lemp.yaml
-var lempTemplate string
-
-//go:embed mean.yaml
-var meanTemplate string
-
-//go:embed django.yaml
-var djangoTemplate string
-
-//go:embed rails.yaml
-var railsTemplate string
-
-//go:embed nodejs.yaml
-var nodejsTemplate string
+	"sort"
+	"strings"
 
-//go:embed microservices.yaml
-var microservicesTemplate string
-
-//go:embed empty.yaml
-var emptyTemplate string
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/firasmosbahi/container-composer/internal/logging"
+	"gopkg.in/yaml.v3"
+)
 
 // Template represents a project template
 type Template struct {
 	Name        string
 	Description string
 	Category    string
+	Version     string
 	Content     string
 	Files       []TemplateFile
+	Variables   []Variable
+	Env         map[string]string // comments of the variables in the derived .env.example
+	Directories []string
+	Source      string // "builtin" or the template directory
 }
 
 // TemplateFile represents a file to be created
@@ -56,69 +43,29 @@ const (
 	CategoryStarter      = "starter"
 )
 
-// GetAvailableTemplates returns all available project templates
+// GetAvailableTemplates returns the built-in templates merged with the user
+// templates. Templates that fail to load are logged and skipped.
 func GetAvailableTemplates() []Template {
-	return []Template{
-		{
-			Name:        "lamp",
-			Description: "Linux, Apache, MySQL, PHP - Classic web stack",
-			Category:    CategoryFullStack,
-			Content:     lampTemplate,
-		},
-		{
-			Name:        "lemp",
-			Description: "Linux, Nginx, MySQL, PHP - Modern web stack",
-			Category:    CategoryFullStack,
-			Content:     lempTemplate,
-		},
-		{
-			Name:        "mean",
-			Description: "MongoDB, Express, Angular, Node.js - JavaScript full-stack",
-			Category:    CategoryFullStack,
-			Content:     meanTemplate,
-		},
-		{
-			Name:        "nodejs",
-			Description: "Node.js with PostgreSQL and Redis - Modern backend",
-			Category:    CategoryWeb,
-			Content:     nodejsTemplate,
-		},
-		{
-			Name:        "django",
-			Description: "Django with PostgreSQL and Redis - Python web framework",
-			Category:    CategoryWeb,
-			Content:     djangoTemplate,
-		},
-		{
-			Name:        "rails",
-			Description: "Ruby on Rails with PostgreSQL and Sidekiq",
-			Category:    CategoryWeb,
-			Content:     railsTemplate,
-		},
-		{
-			Name:        "microservices",
-			Description: "Microservices with API Gateway, monitoring, and message queue",
-			Category:    CategoryMicroservice,
-			Content:     microservicesTemplate,
-		},
-		{
-			Name:        "empty",
-			Description: "Empty project with basic Docker Compose structure",
-			Category:    CategoryStarter,
-			Content:     emptyTemplate,
-		},
+	templates, err := LoadTemplates()
+	if err != nil {
+		logging.Logger().Warn("some templates failed to load", "error", err)
 	}
+	return templates
 }
 
 // GetTemplate returns a specific template by name
 func GetTemplate(name string) (*Template, error) {
-	templates := GetAvailableTemplates()
+	templates, loadErr := LoadTemplates()
 	for _, tmpl := range templates {
 		if tmpl.Name == name {
+			logging.Logger().Debug("found template", "name", name, "category", tmpl.Category, "source", tmpl.Source)
 			return &tmpl, nil
 		}
 	}
-	return nil, fmt.Errorf("template '%s' not found", name)
+	if loadErr != nil {
+		return nil, fmt.Errorf("template '%s' %w (some templates failed to load: %v)", name, core.ErrNotFound, loadErr)
+	}
+	return nil, fmt.Errorf("template '%s' %w", name, core.ErrNotFound)
 }
 
 // CategoryInfo holds display information for a category
@@ -128,9 +75,10 @@ type CategoryInfo struct {
 	Description string // Category description
 }
 
-// GetCategories returns all available categories with display info in preferred order
+// GetCategories returns all available categories with display info in preferred order.
+// Categories only used by user templates come last.
 func GetCategories() []CategoryInfo {
-	return []CategoryInfo{
+	categories := []CategoryInfo{
 		{
 			Key:         CategoryStarter,
 			DisplayName: "📦 Starter",
@@ -152,6 +100,22 @@ func GetCategories() []CategoryInfo {
 			Description: "Distributed systems with multiple services",
 		},
 	}
+
+	known := make(map[string]bool)
+	for _, category := range categories {
+		known[category.Key] = true
+	}
+	for _, tmpl := range GetAvailableTemplates() {
+		if !known[tmpl.Category] {
+			known[tmpl.Category] = true
+			categories = append(categories, CategoryInfo{
+				Key:         tmpl.Category,
+				DisplayName: "📁 " + tmpl.Category,
+				Description: "User templates",
+			})
+		}
+	}
+	return categories
 }
 
 // GetTemplatesByCategory returns templates for a specific category
@@ -171,46 +135,90 @@ func GetTemplatesByCategory(category string) []Template {
 // TemplateVars holds variables for template generation
 type TemplateVars struct {
 	ProjectName string
+	Values      map[string]interface{} // template variables by name, see ResolveVariables
+	Seed        string                 // seeds randAlphaNum for reproducible output; crypto/rand when empty
+}
+
+// random returns the source for randAlphaNum, nil for crypto/rand
+func (v TemplateVars) random() io.Reader {
+	if v.Seed == "" {
+		return nil
+	}
+	return newSeededReader(v.Seed)
+}
+
+// renderData returns the data templates are rendered with: ProjectName and
+// every template variable, with defaults for the variables missing from vars
+func (t *Template) renderData(vars TemplateVars) (map[string]interface{}, error) {
+	data := map[string]interface{}{"ProjectName": vars.ProjectName}
+	for _, v := range t.Variables {
+		value, ok := vars.Values[v.Name]
+		if !ok {
+			var err error
+			if value, err = v.DefaultValue(); err != nil {
+				return nil, fmt.Errorf("invalid default for variable '%s': %w", v.Name, err)
+			}
+		}
+		data[v.Name] = value
+	}
+	return data, nil
 }
 
 // Generate generates project files from a template
 func (t *Template) Generate(outputDir string, vars TemplateVars) error {
+	done := logging.Timed("generate project", "template", t.Name, "dir", outputDir)
+	defer done()
+
 	// Create output directory if it doesn't exist
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
 		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// Parse and execute the docker-compose template
-	tmpl, err := template.New("compose").Parse(t.Content)
+	data, err := t.renderData(vars)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return err
 	}
 
-	// Create docker-compose.yml file
-	composePath := filepath.Join(outputDir, "docker-compose.yml")
-	composeFile, err := os.Create(composePath)
+	// Render the docker-compose template
+	random := vars.random()
+	compose, err := render("docker-compose.yml", t.Content, data, random)
 	if err != nil {
+		return err
+	}
+	composePath := filepath.Join(outputDir, "docker-compose.yml")
+	if err := writeFile(composePath, compose); err != nil {
 		return fmt.Errorf("failed to create docker-compose.yml: %w", err)
 	}
-	defer composeFile.Close()
+	logging.Logger().Info("rendered compose template", "path", composePath, "project_name", vars.ProjectName)
 
-	if err := tmpl.Execute(composeFile, vars); err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
+	// Create the files the template ships
+	if err := t.createFiles(outputDir, data, random); err != nil {
+		return err
 	}
 
-	// Create .env file
-	if err := t.createEnvFile(outputDir); err != nil {
-		return fmt.Errorf("failed to create .env file: %w", err)
+	// Derive .env.example from the variables the compose file interpolates
+	if !t.hasFile(".env.example") {
+		envExample, err := core.EnvExample(compose, t.Env)
+		if err != nil {
+			return fmt.Errorf("failed to derive .env.example: %w", err)
+		}
+		if err := writeFile(filepath.Join(outputDir, ".env.example"), envExample); err != nil {
+			return fmt.Errorf("failed to create .env.example: %w", err)
+		}
 	}
 
 	// Create README
-	if err := t.createReadme(outputDir, vars); err != nil {
-		return fmt.Errorf("failed to create README: %w", err)
+	if !t.hasFile("README.md") {
+		if err := t.createReadme(outputDir, vars); err != nil {
+			return fmt.Errorf("failed to create README: %w", err)
+		}
 	}
 
 	// Create .gitignore
-	if err := t.createGitignore(outputDir); err != nil {
-		return fmt.Errorf("failed to create .gitignore: %w", err)
+	if !t.hasFile(".gitignore") {
+		if err := t.createGitignore(outputDir); err != nil {
+			return fmt.Errorf("failed to create .gitignore: %w", err)
+		}
 	}
 
 	// Create necessary directories based on template
@@ -218,99 +226,51 @@ func (t *Template) Generate(outputDir string, vars TemplateVars) error {
 		return fmt.Errorf("failed to create directories: %w", err)
 	}
 
+	// Every path the compose file bind-mounts must exist, or Docker creates
+	// it as an empty root-owned directory
+	if err := ensureBindMounts(outputDir, compose); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// createEnvFile creates a .env file with default values
-func (t *Template) createEnvFile(outputDir string) error {
-	envPath := filepath.Join(outputDir, ".env.example")
-
-	var envContent string
-	switch t.Name {
-	case "lamp", "lemp":
-		envContent = `# Database Configuration
-DB_ROOT_PASSWORD=rootpassword
-DB_DATABASE=myapp
-DB_USER=dbuser
-DB_PASSWORD=dbpassword
-`
-	case "mean":
-		envContent = `# MongoDB Configuration
-MONGO_USER=admin
-MONGO_PASSWORD=password
-`
-	case "django", "rails", "nodejs":
-		envContent = `# Database Configuration
-DB_NAME=myapp
-DB_USER=postgres
-DB_PASSWORD=postgres
-
-# Application Configuration
-DEBUG=True
-`
-	case "microservices":
-		envContent = `# Service Configuration
-POSTGRES_PASSWORD=postgres
-GRAFANA_PASSWORD=admin
-`
-	case "empty":
-		envContent = `# Environment variables for your project
-# Add your variables here
-# Example:
-# DATABASE_URL=postgresql://user:password@db:5432/dbname
-# API_KEY=your-api-key-here
-`
-	default:
-		envContent = "# Environment variables\n"
+// createFiles renders the template's files with the template variables and
+// the helper functions in funcMap
+func (t *Template) createFiles(outputDir string, data map[string]interface{}, random io.Reader) error {
+	for _, file := range t.Files {
+		content, err := render(file.Path, file.Content, data, random)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(outputDir, filepath.FromSlash(file.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", file.Path, err)
+		}
+		if err := writeFile(path, content); err != nil {
+			return fmt.Errorf("failed to create %s: %w", file.Path, err)
+		}
 	}
+	return nil
+}
 
-	return os.WriteFile(envPath, []byte(envContent), 0644)
+// hasFile reports whether the template ships a file at path
+func (t *Template) hasFile(path string) bool {
+	for _, file := range t.Files {
+		if filepath.ToSlash(filepath.Clean(file.Path)) == path {
+			return true
+		}
+	}
+	return false
 }
 
-// createReadme creates a README file with instructions
+// createReadme creates a default README file with instructions for templates
+// that don't ship their own
 func (t *Template) createReadme(outputDir string, vars TemplateVars) error {
 	readmePath := filepath.Join(outputDir, "README.md")
 
-	var readmeContent string
-
-	// Special README for empty template
-	if t.Name == "empty" {
-		readmeContent = fmt.Sprintf(`# %s
-
-This is an empty Docker Compose project created with Container Composer.
-
-## Project Structure
-
-- `+"`docker-compose.yml`"+` - Your service definitions
-- `+"`"+`.env.example`+"`"+` - Environment variable templates
-- `+"`services/`"+` - Place service-specific files here
-- `+"`volumes/`"+` - Volume data and mount points
-- `+"`config/`"+` - Configuration files
-
-## Getting Started
-
-1. Copy `+"`"+`.env.example`+"`"+` to `+"`"+`.env`+"`"+` and configure your environment variables
-2. Add your services to `+"`docker-compose.yml`"+`
-3. Start your services: `+"`docker-compose up -d`"+`
-
-## Next Steps
-
-- Define your services in docker-compose.yml
-- Add environment variables in .env
-- Organize service files in the services/ directory
-- Configure volumes and networks as needed
-
-## Container Composer Commands
-
-- `+"`container-composer up`"+` - Start services
-- `+"`container-composer down`"+` - Stop services
-- `+"`container-composer logs`"+` - View logs
-- `+"`container-composer status`"+` - Check service health
-
-For more information, visit: https://github.com/firasmosbahi/container-composer
-`, vars.ProjectName)
-	} else {
-		readmeContent = fmt.Sprintf("# %s\n\n"+
+	readmeContent := fmt.Sprintf("# %s\n\n"+
 		"This project was generated using Container Composer with the **%s** template.\n\n"+
 		"## Description\n\n"+
 		"%s\n\n"+
@@ -358,12 +318,12 @@ For more information, visit: https://github.com/firasmosbahi/container-composer
 		"## License\n\n"+
 		"MIT\n",
 		vars.ProjectName, t.Name, t.Description)
-	}
 
-	return os.WriteFile(readmePath, []byte(readmeContent), 0644)
+	return writeFile(readmePath, []byte(readmeContent))
 }
 
-// createGitignore creates a .gitignore file
+// createGitignore creates a default .gitignore file for templates that don't
+// ship their own
 func (t *Template) createGitignore(outputDir string) error {
 	gitignorePath := filepath.Join(outputDir, ".gitignore")
 
@@ -388,40 +348,39 @@ Thumbs.db
 *.swo
 `
 
-	return os.WriteFile(gitignorePath, []byte(gitignoreContent), 0644)
+	return writeFile(gitignorePath, []byte(gitignoreContent))
 }
 
-// createDirectories creates necessary directories for the template
-func (t *Template) createDirectories(outputDir string) error {
-	var dirs []string
-
-	switch t.Name {
-	case "lamp":
-		dirs = []string{"src"}
-	case "lemp":
-		dirs = []string{"src", "nginx/conf.d"}
-	case "mean":
-		dirs = []string{"frontend", "backend"}
-	case "django":
-		dirs = []string{"app"}
-	case "rails":
-		dirs = []string{"app"}
-	case "nodejs":
-		dirs = []string{"app"}
-	case "microservices":
-		dirs = []string{
-			"services/user-service",
-			"services/product-service",
-			"services/order-service",
-			"gateway",
-			"monitoring",
-			"postgres/init",
+// writeFile writes a generated file and logs it
+func writeFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	logging.Logger().Debug("created file", "path", path, "bytes", len(data))
+	return nil
+}
+
+// OutputFiles returns the paths, relative to the output directory, of the
+// files Generate creates
+func (t *Template) OutputFiles() []string {
+	files := []string{"docker-compose.yml"}
+	for _, file := range t.Files {
+		files = append(files, filepath.ToSlash(filepath.Clean(file.Path)))
+	}
+	for _, name := range []string{".env.example", "README.md", ".gitignore"} {
+		if !t.hasFile(name) {
+			files = append(files, name)
 		}
-	case "empty":
-		dirs = []string{"services", "volumes", "config"}
 	}
+	for _, dir := range t.Directories {
+		files = append(files, filepath.ToSlash(filepath.Join(dir, ".gitkeep")))
+	}
+	return files
+}
 
-	for _, dir := range dirs {
+// createDirectories creates necessary directories for the template
+func (t *Template) createDirectories(outputDir string) error {
+	for _, dir := range t.Directories {
 		dirPath := filepath.Join(outputDir, dir)
 		if err := os.MkdirAll(dirPath, 0755); err != nil {
 			return err
@@ -432,7 +391,72 @@ func (t *Template) createDirectories(outputDir string) error {
 		if err := os.WriteFile(gitkeepPath, []byte(""), 0644); err != nil {
 			return err
 		}
+		logging.Logger().Debug("created directory", "path", dirPath)
+	}
+
+	return nil
+}
+
+// ensureBindMounts checks that every relative bind mount source in the
+// rendered compose file exists in outputDir. Missing directories are created
+// with a .gitkeep; a missing file means the template is broken, since only
+// the template knows its content.
+func ensureBindMounts(outputDir string, compose []byte) error {
+	var parsed struct {
+		Services map[string]struct {
+			Volumes []interface{} `yaml:"volumes"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(compose, &parsed); err != nil {
+		return fmt.Errorf("rendered docker-compose.yml is not valid YAML: %w", err)
 	}
 
+	var missing []string
+	for _, service := range parsed.Services {
+		for _, volume := range service.Volumes {
+			source := bindMountSource(volume)
+			if source == "" {
+				continue
+			}
+			target := filepath.Join(outputDir, filepath.FromSlash(source))
+			if _, err := os.Stat(target); err == nil {
+				continue
+			}
+			if filepath.Ext(source) != "" {
+				missing = append(missing, "./"+source)
+				continue
+			}
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create bind-mounted directory %s: %w", source, err)
+			}
+			if err := writeFile(filepath.Join(target, ".gitkeep"), nil); err != nil {
+				return fmt.Errorf("failed to create bind-mounted directory %s: %w", source, err)
+			}
+			logging.Logger().Warn("created bind-mounted directory the template does not declare", "path", source)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("template bind-mounts files it does not create: %s", strings.Join(missing, ", "))
+	}
 	return nil
 }
+
+// bindMountSource returns the source of a volume entry if it is a bind mount
+// of a path relative to the project, in short (./src:/dst:ro) or long syntax
+func bindMountSource(volume interface{}) string {
+	var source string
+	switch v := volume.(type) {
+	case string:
+		source, _, _ = strings.Cut(v, ":")
+	case map[string]interface{}:
+		if v["type"] != "bind" {
+			return ""
+		}
+		source, _ = v["source"].(string)
+	}
+	if !strings.HasPrefix(source, "./") || !isRelativePath(source) {
+		return ""
+	}
+	return path.Clean(source)
+}
@@ -0,0 +1,20 @@
+// example API: replace with your Express/Mongoose application.
+// MONGODB_URI is set in docker-compose.yml.
+const http = require("http");
+
+const port = Number(process.env.PORT || 3000);
+
+const server = http.createServer((req, res) => {
+  // The frontend runs on another port, so allow cross-origin requests
+  res.setHeader("Access-Control-Allow-Origin", "*");
+
+  const body = req.url === "/health"
+    ? { status: "ok" }
+    : { name: "example", path: req.url };
+  res.writeHead(200, { "Content-Type": "application/json" });
+  res.end(JSON.stringify(body));
+});
+
+server.listen(port, () => console.log(`API listening on port ${port}`));
+
+process.on("SIGTERM", () => server.close(() => process.exit(0)));
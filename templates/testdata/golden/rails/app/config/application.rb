@@ -0,0 +1,20 @@
+require_relative "boot"
+
+require "rails"
+require "active_model/railtie"
+require "active_record/railtie"
+require "action_controller/railtie"
+
+Bundler.require(*Rails.groups)
+
+module Example
+  # A minimal API-only application; add frameworks and generate code with
+  # bin/rails generate as the project grows
+  class Application < Rails::Application
+    config.load_defaults 7.1
+    config.api_only = true
+    config.eager_load = Rails.env.production?
+    config.logger = ActiveSupport::Logger.new($stdout)
+    config.hosts.clear if Rails.env.development?
+  end
+end
@@ -0,0 +1,362 @@
+package templates
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/core"
+	"github.com/firasmosbahi/container-composer/internal/logging"
+	"gopkg.in/yaml.v3"
+)
+
+// Lock file locations, relative to the project directory. The base directory
+// holds the files as the template last rendered them, the common ancestor of
+// three-way merges during upgrades.
+const (
+	LockDir      = ".container-composer"
+	LockFileName = "template.lock"
+	lockBaseDir  = "template-base"
+)
+
+// Upgrade outcomes of a project file
+const (
+	UpgradeUnchanged = "unchanged"  // the template did not change the file
+	UpgradeUpToDate  = "up-to-date" // the project already has the new content
+	UpgradeUpdated   = "updated"    // the file was not modified, so it was replaced
+	UpgradeAdded     = "added"      // the template added the file
+	UpgradeRemoved   = "removed"    // the template dropped the unmodified file
+	UpgradeKept      = "kept"       // the project modified or deleted the file, so its version was kept
+	UpgradeMerged    = "merged"     // both sides changed the file without conflicts
+	UpgradeConflict  = "conflict"   // both sides changed the same lines
+)
+
+// Lock records the template a project was generated from, so the project can
+// be upgraded when the template changes. It is meant to be committed, so
+// secret variables are recorded by name only.
+type Lock struct {
+	Template    string                 `yaml:"template" json:"template"`
+	Version     string                 `yaml:"version,omitempty" json:"version,omitempty"`
+	Source      string                 `yaml:"source" json:"source"`
+	ProjectName string                 `yaml:"project_name" json:"project_name"`
+	Seed        string                 `yaml:"seed" json:"-"` // seeds randAlphaNum so upgrades render the same values
+	Variables   map[string]interface{} `yaml:"variables,omitempty" json:"variables,omitempty"`
+	Secrets     []string               `yaml:"secrets,omitempty" json:"secrets,omitempty"` // secret variables, whose values are not recorded
+	Files       []string               `yaml:"files" json:"files"`
+}
+
+// FileUpgrade is the outcome of upgrading one project file
+type FileUpgrade struct {
+	Path      string `json:"path"`
+	Status    string `json:"status"`
+	Conflicts int    `json:"conflicts,omitempty"`
+}
+
+// UpgradeReport describes a template upgrade
+type UpgradeReport struct {
+	Template    string        `json:"template"`
+	FromVersion string        `json:"from_version,omitempty"`
+	ToVersion   string        `json:"to_version,omitempty"`
+	Files       []FileUpgrade `json:"files"`
+	Conflicts   int           `json:"conflicts"`
+}
+
+// NewSeed returns a random seed for TemplateVars.Seed
+func NewSeed() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate seed: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// WriteLock records in projectDir that the project was generated from the
+// template with vars, and keeps a copy of the generated files as the base of
+// later upgrades. vars.Seed should be set so upgrades can render the same
+// random values.
+func (t *Template) WriteLock(projectDir string, vars TemplateVars) error {
+	data, err := t.renderData(vars)
+	if err != nil {
+		return err
+	}
+	delete(data, "ProjectName")
+	lock := &Lock{
+		Template:    t.Name,
+		Version:     t.Version,
+		Source:      t.Source,
+		ProjectName: vars.ProjectName,
+		Seed:        vars.Seed,
+		Files:       t.OutputFiles(),
+	}
+	lock.Variables, lock.Secrets = t.lockVariables(data)
+
+	files := make(map[string][]byte)
+	for _, file := range lock.Files {
+		content, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(file)))
+		if err != nil {
+			return fmt.Errorf("failed to read generated file %s: %w", file, err)
+		}
+		files[file] = content
+	}
+	return writeLock(projectDir, lock, files)
+}
+
+// writeLock writes the lock file and replaces the base files
+func writeLock(projectDir string, lock *Lock, base map[string][]byte) error {
+	lockDir := filepath.Join(projectDir, LockDir)
+	baseDir := filepath.Join(lockDir, lockBaseDir)
+	if err := os.RemoveAll(baseDir); err != nil {
+		return fmt.Errorf("failed to clear template base files: %w", err)
+	}
+	for name, content := range base {
+		target := filepath.Join(baseDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to write template base files: %w", err)
+		}
+		if err := os.WriteFile(target, content, 0644); err != nil {
+			return fmt.Errorf("failed to write template base files: %w", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Written by container-composer init; used by 'container-composer template upgrade'.\n")
+	buf.WriteString("# " + lockBaseDir + "/ holds the files as the template rendered them.\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(lock); err != nil {
+		return fmt.Errorf("failed to encode template lock: %w", err)
+	}
+	if err := writeFile(filepath.Join(lockDir, LockFileName), buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write template lock: %w", err)
+	}
+	return nil
+}
+
+// ReadLock reads the template lock of the project in projectDir
+func ReadLock(projectDir string) (*Lock, error) {
+	lockPath := filepath.Join(projectDir, LockDir, LockFileName)
+	data, err := os.ReadFile(lockPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("template lock %w (%s); the project was not created by init", core.ErrNotFound, lockPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", lockPath, err)
+	}
+	var lock Lock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("invalid template lock %s: %w", lockPath, err)
+	}
+	if lock.Template == "" {
+		return nil, fmt.Errorf("invalid template lock %s: no template", lockPath)
+	}
+	return &lock, nil
+}
+
+// lockVariables splits variable values into the values a lock records and
+// the names of the secret variables, whose values it leaves out
+func (t *Template) lockVariables(values map[string]interface{}) (map[string]interface{}, []string) {
+	recorded := make(map[string]interface{}, len(values))
+	var secrets []string
+	for name, value := range values {
+		recorded[name] = value
+	}
+	for _, v := range t.Variables {
+		if v.Type == VarSecret {
+			delete(recorded, v.Name)
+			secrets = append(secrets, v.Name)
+		}
+	}
+	return recorded, secrets
+}
+
+// LockedValues returns the variable values recorded in a lock for the
+// variables the template still declares, as raw strings for ResolveVariables
+func (t *Template) LockedValues(lock *Lock) map[string]string {
+	values := make(map[string]string)
+	for _, v := range t.Variables {
+		if value, ok := lock.Variables[v.Name]; ok {
+			values[v.Name] = fmt.Sprint(value)
+		}
+	}
+	return values
+}
+
+// LockedSecrets looks up the secret variables the lock records by name, and
+// the template still declares, in the .env file of the project in
+// projectDir. A variable is found under its name or its upper-case name, e.g.
+// DB_PASSWORD for db_password. It returns the values found, as raw strings
+// for ResolveVariables, and the variables still missing, which upgrades must
+// be given again so the secrets do not change.
+func (t *Template) LockedSecrets(projectDir string, lock *Lock) (map[string]string, []Variable, error) {
+	values := make(map[string]string)
+	var missing []Variable
+	if len(lock.Secrets) == 0 {
+		return values, missing, nil
+	}
+
+	env, err := core.ReadEnvFile(filepath.Join(projectDir, ".env"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, err
+	}
+	for _, v := range t.Variables {
+		if v.Type != VarSecret || !slices.Contains(lock.Secrets, v.Name) {
+			continue
+		}
+		if env != nil {
+			value, ok := env.Lookup(v.Name)
+			if !ok {
+				value, ok = env.Lookup(strings.ToUpper(v.Name))
+			}
+			if ok {
+				logging.Logger().Info("read secret variable from .env", "name", v.Name)
+				values[v.Name] = value
+				continue
+			}
+		}
+		missing = append(missing, v)
+	}
+	return values, missing, nil
+}
+
+// Upgrade re-renders the template into the project in projectDir and merges
+// the result with the project files, using the files the template rendered
+// last time as the base. Files the user did not modify are replaced; files
+// both sides changed are merged line by line, with git-style conflict
+// markers where the changes overlap. Unless dryRun is set, the files and the
+// lock are written.
+func (t *Template) Upgrade(projectDir string, lock *Lock, values map[string]interface{}, dryRun bool) (*UpgradeReport, error) {
+	done := logging.Timed("upgrade project", "template", t.Name, "from", lock.Version, "to", t.Version)
+	defer done()
+
+	renderDir, err := os.MkdirTemp("", "container-composer-upgrade-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(renderDir)
+
+	vars := TemplateVars{ProjectName: lock.ProjectName, Values: values, Seed: lock.Seed}
+	if err := t.Generate(renderDir, vars); err != nil {
+		return nil, fmt.Errorf("failed to render template '%s': %w", t.Name, err)
+	}
+	rendered, err := readTree(renderDir)
+	if err != nil {
+		return nil, err
+	}
+	base, err := readTree(filepath.Join(projectDir, LockDir, lockBaseDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	report := &UpgradeReport{Template: t.Name, FromVersion: lock.Version, ToVersion: t.Version, Files: []FileUpgrade{}}
+	paths := make(map[string]bool)
+	for _, file := range append(lock.Files, sortedKeys(rendered)...) {
+		paths[file] = true
+	}
+	oursLabel := "project"
+	theirsLabel := strings.TrimSpace("template " + t.Name + " " + t.Version)
+
+	type change struct {
+		path    string
+		content []byte // nil removes the file
+	}
+	var changes []change
+	for _, file := range sortedKeys(paths) {
+		baseContent, inBase := base[file]
+		theirs, inTemplate := rendered[file]
+		ours, err := os.ReadFile(filepath.Join(projectDir, filepath.FromSlash(file)))
+		inProject := err == nil
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		upgrade := FileUpgrade{Path: file}
+		switch {
+		case inTemplate && inProject && bytes.Equal(ours, theirs):
+			upgrade.Status = UpgradeUpToDate
+		case inBase && inTemplate && bytes.Equal(baseContent, theirs),
+			!inBase && !inTemplate:
+			upgrade.Status = UpgradeUnchanged
+		case !inTemplate:
+			if inProject && !bytes.Equal(ours, baseContent) {
+				upgrade.Status = UpgradeKept
+			} else {
+				upgrade.Status = UpgradeRemoved
+				if inProject {
+					changes = append(changes, change{path: file})
+				}
+			}
+		case !inProject && !inBase:
+			upgrade.Status = UpgradeAdded
+			changes = append(changes, change{path: file, content: theirs})
+		case !inProject:
+			// Deleted in the project: keep it deleted
+			upgrade.Status = UpgradeKept
+		case inBase && bytes.Equal(ours, baseContent):
+			upgrade.Status = UpgradeUpdated
+			changes = append(changes, change{path: file, content: theirs})
+		default:
+			merged, conflicts := core.Merge3(string(baseContent), string(ours), string(theirs), oursLabel, theirsLabel)
+			upgrade.Status, upgrade.Conflicts = UpgradeMerged, conflicts
+			if conflicts > 0 {
+				upgrade.Status = UpgradeConflict
+				report.Conflicts += conflicts
+			}
+			changes = append(changes, change{path: file, content: []byte(merged)})
+		}
+		logging.Logger().Debug("upgraded file", "path", file, "status", upgrade.Status)
+		report.Files = append(report.Files, upgrade)
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	for _, c := range changes {
+		target := filepath.Join(projectDir, filepath.FromSlash(c.path))
+		if c.content == nil {
+			if err := os.Remove(target); err != nil {
+				return nil, fmt.Errorf("failed to remove %s: %w", c.path, err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", c.path, err)
+		}
+		if err := writeFile(target, c.content); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", c.path, err)
+		}
+	}
+
+	// The new render is the base of the next upgrade
+	lock.Version, lock.Source = t.Version, t.Source
+	lock.Variables, lock.Secrets = t.lockVariables(values)
+	lock.Files = sortedKeys(rendered)
+	if err := writeLock(projectDir, lock, rendered); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// upgradeStatusOrder is the order upgrade statuses are summarized in
+var upgradeStatusOrder = []string{UpgradeConflict, UpgradeMerged, UpgradeUpdated, UpgradeAdded,
+	UpgradeRemoved, UpgradeKept, UpgradeUpToDate, UpgradeUnchanged}
+
+// Summary counts the files per status, e.g. "2 updated, 1 conflict"
+func (r *UpgradeReport) Summary() string {
+	counts := make(map[string]int)
+	for _, file := range r.Files {
+		counts[file.Status]++
+	}
+	var parts []string
+	for _, status := range upgradeStatusOrder {
+		if counts[status] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
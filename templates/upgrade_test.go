@@ -0,0 +1,224 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestUpgrade generates a project, modifies it, and upgrades it to a new
+// template version
+func TestUpgrade(t *testing.T) {
+	projectDir := t.TempDir()
+	v1 := &Template{
+		Name:    "svc",
+		Version: "1.0.0",
+		Content: "services:\n  web:\n    image: nginx:1.25\n    container_name: {{.ProjectName}}_web\n    restart: always\n",
+		Files: []TemplateFile{
+			{Path: "app.conf", Content: "a=1\nb=2\nc=3\nd=4\ne=5\nf=6\ng=7\nkey={{randAlphaNum 8}}\n"},
+			{Path: "notes.txt", Content: "first\n"},
+			{Path: "old.txt", Content: "old\n"},
+		},
+	}
+	vars := TemplateVars{ProjectName: "shop", Seed: "seed"}
+	if err := v1.Generate(projectDir, vars); err != nil {
+		t.Fatalf("failed to generate project: %v", err)
+	}
+	if err := v1.WriteLock(projectDir, vars); err != nil {
+		t.Fatalf("failed to write lock: %v", err)
+	}
+
+	// The project changes the restart policy and two settings
+	replaceInFile(t, filepath.Join(projectDir, "docker-compose.yml"), "restart: always", "restart: unless-stopped")
+	replaceInFile(t, filepath.Join(projectDir, "app.conf"), "b=2", "b=20")
+	replaceInFile(t, filepath.Join(projectDir, "app.conf"), "f=6", "f=60")
+
+	// The template changes the image, one setting the project kept and one
+	// the project changed, replaces a file and adds one
+	v2 := *v1
+	v2.Version = "1.1.0"
+	v2.Content = strings.Replace(v1.Content, "nginx:1.25", "nginx:1.27", 1)
+	v2.Files = []TemplateFile{
+		{Path: "app.conf", Content: "a=1\nb=2\nc=3\nd=40\ne=5\nf=600\ng=7\nkey={{randAlphaNum 8}}\n"},
+		{Path: "notes.txt", Content: "first\nsecond\n"},
+		{Path: "new.txt", Content: "new\n"},
+	}
+
+	lock, err := ReadLock(projectDir)
+	if err != nil {
+		t.Fatalf("failed to read lock: %v", err)
+	}
+	report, err := v2.Upgrade(projectDir, lock, nil, false)
+	if err != nil {
+		t.Fatalf("failed to upgrade: %v", err)
+	}
+
+	want := map[string]string{
+		"docker-compose.yml": UpgradeMerged,
+		"app.conf":           UpgradeConflict,
+		"notes.txt":          UpgradeUpdated,
+		"old.txt":            UpgradeRemoved,
+		"new.txt":            UpgradeAdded,
+		"README.md":          UpgradeUpToDate,
+	}
+	for _, file := range report.Files {
+		if status, ok := want[file.Path]; ok && file.Status != status {
+			t.Errorf("%s: expected %s, got %s", file.Path, status, file.Status)
+		}
+	}
+	if report.Conflicts != 1 {
+		t.Errorf("expected 1 conflict, got %d", report.Conflicts)
+	}
+
+	compose := readFile(t, filepath.Join(projectDir, "docker-compose.yml"))
+	if !strings.Contains(compose, "nginx:1.27") || !strings.Contains(compose, "restart: unless-stopped") {
+		t.Errorf("expected both changes in docker-compose.yml, got:\n%s", compose)
+	}
+	conf := readFile(t, filepath.Join(projectDir, "app.conf"))
+	wantConf := "a=1\nb=20\nc=3\nd=40\ne=5\n" +
+		"<<<<<<< project\nf=60\n=======\nf=600\n>>>>>>> template svc 1.1.0\ng=7\n"
+	if !strings.HasPrefix(conf, wantConf) {
+		t.Errorf("unexpected app.conf:\n%s", conf)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, "old.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected old.txt to be removed")
+	}
+
+	// The new render is the base of the next upgrade
+	lock, err = ReadLock(projectDir)
+	if err != nil {
+		t.Fatalf("failed to read lock: %v", err)
+	}
+	if lock.Version != "1.1.0" {
+		t.Errorf("expected lock version 1.1.0, got %s", lock.Version)
+	}
+	report, err = v2.Upgrade(projectDir, lock, nil, true)
+	if err != nil {
+		t.Fatalf("failed to upgrade: %v", err)
+	}
+	for _, file := range report.Files {
+		if file.Status != UpgradeUnchanged && file.Status != UpgradeUpToDate {
+			t.Errorf("%s: expected no change on a second upgrade, got %s", file.Path, file.Status)
+		}
+	}
+}
+
+// TestUpgradeSecrets records secrets by name only and reads them back from
+// .env on upgrade
+func TestUpgradeSecrets(t *testing.T) {
+	tmpl := &Template{
+		Name:    "db",
+		Version: "1.0.0",
+		Content: "services:\n  db:\n    image: postgres\n    environment:\n      POSTGRES_PASSWORD: {{.db_password}}\n      POSTGRES_DB: {{.db_name}}\n",
+		Variables: []Variable{
+			{Name: "db_name", Type: VarString, Default: "app"},
+			{Name: "db_password", Type: VarSecret},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		env     *string // nil when the project has no .env
+		found   map[string]string
+		missing []string
+	}{
+		{name: "upper-case name", env: strPtr("DB_PASSWORD=s3cret\n"), found: map[string]string{"db_password": "s3cret"}},
+		{name: "variable name", env: strPtr("db_password=s3cret\n"), found: map[string]string{"db_password": "s3cret"}},
+		{name: "not in .env", env: strPtr("OTHER=1\n"), found: map[string]string{}, missing: []string{"db_password"}},
+		{name: "no .env", found: map[string]string{}, missing: []string{"db_password"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			projectDir := t.TempDir()
+			vars := TemplateVars{ProjectName: "shop", Seed: "seed",
+				Values: map[string]interface{}{"db_name": "app", "db_password": "s3cret"}}
+			if err := tmpl.Generate(projectDir, vars); err != nil {
+				t.Fatalf("failed to generate project: %v", err)
+			}
+			if err := tmpl.WriteLock(projectDir, vars); err != nil {
+				t.Fatalf("failed to write lock: %v", err)
+			}
+			if test.env != nil {
+				if err := os.WriteFile(filepath.Join(projectDir, ".env"), []byte(*test.env), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if content := readFile(t, filepath.Join(projectDir, LockDir, LockFileName)); strings.Contains(content, "s3cret") {
+				t.Fatalf("lock records the secret value:\n%s", content)
+			}
+			lock, err := ReadLock(projectDir)
+			if err != nil {
+				t.Fatalf("failed to read lock: %v", err)
+			}
+			if !reflect.DeepEqual(lock.Secrets, []string{"db_password"}) || lock.Variables["db_name"] != "app" {
+				t.Fatalf("lock secrets = %v, variables = %v", lock.Secrets, lock.Variables)
+			}
+
+			found, missing, err := tmpl.LockedSecrets(projectDir, lock)
+			if err != nil {
+				t.Fatalf("failed to read secrets: %v", err)
+			}
+			if !reflect.DeepEqual(found, test.found) {
+				t.Errorf("found = %v, want %v", found, test.found)
+			}
+			var missingNames []string
+			for _, v := range missing {
+				missingNames = append(missingNames, v.Name)
+			}
+			if !reflect.DeepEqual(missingNames, test.missing) {
+				t.Errorf("missing = %v, want %v", missingNames, test.missing)
+			}
+			if len(missing) > 0 {
+				return
+			}
+
+			// With the secret read back, the upgrade renders the same files
+			given := tmpl.LockedValues(lock)
+			for name, value := range found {
+				given[name] = value
+			}
+			values, err := tmpl.ResolveVariables(given, nil)
+			if err != nil {
+				t.Fatalf("failed to resolve variables: %v", err)
+			}
+			report, err := tmpl.Upgrade(projectDir, lock, values, false)
+			if err != nil {
+				t.Fatalf("failed to upgrade: %v", err)
+			}
+			for _, file := range report.Files {
+				if file.Status != UpgradeUnchanged && file.Status != UpgradeUpToDate {
+					t.Errorf("%s: expected no change, got %s", file.Path, file.Status)
+				}
+			}
+			if content := readFile(t, filepath.Join(projectDir, LockDir, LockFileName)); strings.Contains(content, "s3cret") {
+				t.Errorf("upgraded lock records the secret value:\n%s", content)
+			}
+		})
+	}
+}
+
+// replaceInFile replaces old with new in a file
+func replaceInFile(t *testing.T, path, old, new string) {
+	t.Helper()
+	content := readFile(t, path)
+	if err := os.WriteFile(path, []byte(strings.Replace(content, old, new, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readFile returns the content of a file
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// strPtr returns a pointer to a string
+func strPtr(s string) *string { return &s }
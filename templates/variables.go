@@ -0,0 +1,276 @@
+package templates
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/firasmosbahi/container-composer/internal/logging"
+)
+
+// Variable types
+const (
+	VarString = "string"
+	VarInt    = "int"
+	VarBool   = "bool"
+	VarChoice = "choice"
+	VarSecret = "secret" // a string that is never echoed; generated when left empty
+)
+
+// ErrUnknownVariable is returned when a value is given for a variable the
+// template does not declare
+var ErrUnknownVariable = errors.New("unknown variable")
+
+// validVariableName matches variable names, which are used as {{.name}} in
+// templates
+var validVariableName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Variable is a value a template can be customized with. Templates use it as
+// {{.name}}, next to {{.ProjectName}}.
+type Variable struct {
+	Name        string   `yaml:"name"`
+	Type        string   `yaml:"type,omitempty"` // default string
+	Description string   `yaml:"description,omitempty"`
+	Default     string   `yaml:"default,omitempty"`
+	Choices     []string `yaml:"choices,omitempty"` // for choice
+	Pattern     string   `yaml:"pattern,omitempty"` // regular expression string and secret values must match
+	Min         *int     `yaml:"min,omitempty"`     // lower bound for int
+	Max         *int     `yaml:"max,omitempty"`     // upper bound for int
+	Required    bool     `yaml:"required,omitempty"`
+	// When hides the variable unless a condition on earlier variables holds:
+	// "name", "!name", "name == value" or "name != value". Hidden variables
+	// keep their default.
+	When string `yaml:"when,omitempty"`
+}
+
+// check validates a variable declaration. declared holds the variables
+// declared before it, the only ones When may refer to.
+func (v *Variable) check(declared map[string]bool) error {
+	if !validVariableName.MatchString(v.Name) || v.Name == "ProjectName" {
+		return fmt.Errorf("invalid variable name '%s'", v.Name)
+	}
+	if declared[v.Name] {
+		return fmt.Errorf("variable '%s' is declared twice", v.Name)
+	}
+	if v.Type == "" {
+		v.Type = VarString
+	}
+
+	switch v.Type {
+	case VarString, VarSecret, VarInt, VarBool:
+	case VarChoice:
+		if len(v.Choices) == 0 {
+			return fmt.Errorf("choice variable '%s' has no choices", v.Name)
+		}
+	default:
+		return fmt.Errorf("variable '%s' has unknown type '%s' (supported: string, int, bool, choice, secret)", v.Name, v.Type)
+	}
+	if v.Pattern != "" {
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			return fmt.Errorf("variable '%s' has an invalid pattern: %w", v.Name, err)
+		}
+	}
+	if v.Default != "" {
+		if _, err := v.Parse(v.Default); err != nil {
+			return fmt.Errorf("default of variable '%s' is invalid: %w", v.Name, err)
+		}
+	}
+	if v.When != "" {
+		name, _, _, err := parseCondition(v.When)
+		if err != nil {
+			return fmt.Errorf("variable '%s': %w", v.Name, err)
+		}
+		if !declared[name] {
+			return fmt.Errorf("variable '%s' depends on '%s', which is not declared before it", v.Name, name)
+		}
+	}
+	return nil
+}
+
+// Parse converts a raw value to the variable's type and validates it
+func (v Variable) Parse(raw string) (interface{}, error) {
+	switch v.Type {
+	case VarInt:
+		value, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a number", raw)
+		}
+		if v.Min != nil && value < *v.Min {
+			return nil, fmt.Errorf("%d is less than %d", value, *v.Min)
+		}
+		if v.Max != nil && value > *v.Max {
+			return nil, fmt.Errorf("%d is greater than %d", value, *v.Max)
+		}
+		return value, nil
+
+	case VarBool:
+		value, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not true or false", raw)
+		}
+		return value, nil
+
+	case VarChoice:
+		for _, choice := range v.Choices {
+			if raw == choice {
+				return raw, nil
+			}
+		}
+		return nil, fmt.Errorf("'%s' is not one of %s", raw, strings.Join(v.Choices, ", "))
+
+	default:
+		if v.Required && raw == "" {
+			return nil, fmt.Errorf("a value is required")
+		}
+		if v.Pattern != "" && raw != "" && !regexp.MustCompile("^(?:"+v.Pattern+")$").MatchString(raw) {
+			return nil, fmt.Errorf("value does not match %s", v.Pattern)
+		}
+		return raw, nil
+	}
+}
+
+// Visible reports whether the variable applies given the values of the
+// variables before it
+func (v Variable) Visible(values map[string]interface{}) bool {
+	if v.When == "" {
+		return true
+	}
+	name, op, expected, err := parseCondition(v.When)
+	if err != nil {
+		return false
+	}
+	value, ok := values[name]
+	if !ok {
+		return false
+	}
+	switch op {
+	case "==":
+		return fmt.Sprint(value) == expected
+	case "!=":
+		return fmt.Sprint(value) != expected
+	case "!":
+		return !truthy(value)
+	default:
+		return truthy(value)
+	}
+}
+
+// DefaultValue returns the parsed default, generating one for secrets
+func (v Variable) DefaultValue() (interface{}, error) {
+	if v.Type == VarSecret && v.Default == "" {
+		return generateSecret()
+	}
+	if v.Default == "" {
+		switch v.Type {
+		case VarInt:
+			return 0, nil
+		case VarBool:
+			return false, nil
+		case VarChoice:
+			return v.Choices[0], nil
+		}
+	}
+	return v.Parse(v.Default)
+}
+
+// ResolveVariables computes the value of every template variable. Values
+// come from given (raw strings, e.g. from --set), then from ask if it is not
+// nil, then from the defaults. Variables hidden by their When condition keep
+// their default. Unknown names in given are rejected.
+func (t *Template) ResolveVariables(given map[string]string, ask func(v Variable) (string, error)) (map[string]interface{}, error) {
+	known := make(map[string]bool, len(t.Variables))
+	for _, v := range t.Variables {
+		known[v.Name] = true
+	}
+	for name := range given {
+		if !known[name] {
+			return nil, fmt.Errorf("%w '%s' for template '%s'%s", ErrUnknownVariable, name, t.Name, t.variableHint())
+		}
+	}
+
+	values := make(map[string]interface{}, len(t.Variables))
+	for _, v := range t.Variables {
+		raw, isGiven := given[v.Name]
+		switch {
+		case !v.Visible(values):
+			logging.Logger().Debug("variable hidden", "name", v.Name, "when", v.When)
+			isGiven = false
+		case !isGiven && ask != nil:
+			answer, err := ask(v)
+			if err != nil {
+				return nil, fmt.Errorf("input for variable '%s' cancelled: %w", v.Name, err)
+			}
+			raw, isGiven = answer, true
+		}
+
+		var value interface{}
+		var err error
+		if isGiven && !(raw == "" && v.Type == VarSecret && !v.Required) {
+			value, err = v.Parse(raw)
+		} else {
+			value, err = v.DefaultValue()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for variable '%s': %w", v.Name, err)
+		}
+		values[v.Name] = value
+	}
+	return values, nil
+}
+
+// variableHint lists the declared variables for error messages
+func (t *Template) variableHint() string {
+	if len(t.Variables) == 0 {
+		return " (it has no variables)"
+	}
+	names := make([]string, len(t.Variables))
+	for i, v := range t.Variables {
+		names[i] = v.Name
+	}
+	return " (available: " + strings.Join(names, ", ") + ")"
+}
+
+// parseCondition splits a When condition into a variable name, an operator
+// ("", "!", "==" or "!=") and the expected value
+func parseCondition(condition string) (string, string, string, error) {
+	condition = strings.TrimSpace(condition)
+	for _, op := range []string{"==", "!="} {
+		if name, expected, found := strings.Cut(condition, op); found {
+			expected = strings.Trim(strings.TrimSpace(expected), `"'`)
+			return strings.TrimSpace(name), op, expected, nil
+		}
+	}
+	if name, found := strings.CutPrefix(condition, "!"); found {
+		return strings.TrimSpace(name), "!", "", nil
+	}
+	if !validVariableName.MatchString(condition) {
+		return "", "", "", fmt.Errorf("invalid condition '%s'", condition)
+	}
+	return condition, "", "", nil
+}
+
+// truthy reports whether a variable value counts as set
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case string:
+		return v != "" && v != "false"
+	}
+	return value != nil
+}
+
+// generateSecret returns a random hex string for secrets left empty
+func generateSecret() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -17,6 +17,11 @@ func newAddMenuModel() addMenuModel {
 			desc:  "Add a new container/service to docker-compose.yml",
 			id:    "service",
 		},
+		menuItem{
+			title: "✏️  Edit Service",
+			desc:  "Edit an existing service, pre-filled with its current values",
+			id:    "edit-service",
+		},
 		menuItem{
 			title: "🌐 Add Network",
 			desc:  "Add a new network definition",
@@ -64,6 +69,9 @@ func (m addMenuModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				case "service":
 					newModel := newAddServiceModel()
 					return newModel, newModel.Init()
+				case "edit-service":
+					newModel := newEditServiceModel()
+					return newModel, newModel.Init()
 				case "network":
 					newModel := newAddNetworkModel()
 					return newModel, newModel.Init()
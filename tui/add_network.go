@@ -9,7 +9,6 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/firasmosbahi/container-composer/core"
-	"gopkg.in/yaml.v3"
 )
 
 // State constants for add network wizard
@@ -40,6 +39,7 @@ type addNetworkModel struct {
 
 	// Preview
 	yamlPreview string
+	previewData []byte // compose file content with the network added
 
 	// Status
 	err      error
@@ -49,7 +49,7 @@ type addNetworkModel struct {
 func newAddNetworkModel() addNetworkModel {
 	return addNetworkModel{
 		state:       stateAddNetworkInit,
-		composePath: "docker-compose.yml",
+		composePath: options.ComposePath,
 	}
 }
 
@@ -230,12 +230,12 @@ func (m addNetworkModel) handleEnter() (tea.Model, tea.Cmd) {
 	case stateAddNetworkConfirm:
 		if m.confirmInput.Value() {
 			// Save changes
-			m.composeFile.AddNetwork(m.networkName, m.network)
-			if err := m.composeFile.WriteComposeFile(m.composePath); err != nil {
-				m.err = err
+			if _, err := core.SaveFile(m.composePath, m.previewData); err != nil {
+				m.err = fmt.Errorf("failed to write %s: %w", m.composePath, err)
 				m.state = stateAddNetworkSuccess
 				return m, nil
 			}
+			m.composeFile.AddNetwork(m.networkName, m.network)
 			m.state = stateAddNetworkSuccess
 		} else {
 			return newAddMenuModel(), nil
@@ -249,24 +249,20 @@ func (m addNetworkModel) handleEnter() (tea.Model, tea.Cmd) {
 }
 
 func (m addNetworkModel) generatePreview() (tea.Model, tea.Cmd) {
-	// Add network to compose file temporarily for preview
-	tempCompose := *m.composeFile
-	tempCompose.AddNetwork(m.networkName, m.network)
-
-	// Marshal to YAML
-	yamlData, err := yaml.Marshal(&tempCompose)
+	// Diff the file on disk against a copy with the network added
+	var err error
+	m.previewData, m.yamlPreview, err = previewEntry(m.composePath, "networks", m.networkName, m.network)
 	if err != nil {
 		m.err = fmt.Errorf("failed to generate preview: %w", err)
 		m.state = stateAddNetworkSuccess
 		return m, nil
 	}
 
-	m.yamlPreview = string(yamlData)
 	m.state = stateAddNetworkPreview
 
 	// Create viewport for preview
 	m.previewPort = viewport.New(80, 20)
-	m.previewPort.SetContent(m.yamlPreview)
+	m.previewPort.SetContent(ColorizeDiff(m.yamlPreview))
 
 	return m, nil
 }
@@ -290,7 +286,7 @@ func (m addNetworkModel) View() string {
 		return docStyle.Render(m.selectList.View())
 
 	case stateAddNetworkPreview:
-		s := titleStyle.Render("Preview docker-compose.yml") + "\n\n"
+		s := titleStyle.Render("Changes to "+m.composePath) + "\n\n"
 		s += m.previewPort.View() + "\n\n"
 		s += helpStyle.Render("↑↓ to scroll • 'enter' to continue • 'esc' to go back")
 		return docStyle.Render(s)
@@ -3,13 +3,15 @@ package tui
 import (
 	"fmt"
 	"os"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/firasmosbahi/container-composer/core"
-	"gopkg.in/yaml.v3"
 )
 
 // State constants for add service wizard
@@ -39,6 +41,7 @@ const (
 	stateAddServicePreview
 	stateAddServiceConfirm
 	stateAddServiceSuccess
+	stateEditServiceSelect
 )
 
 type addServiceModel struct {
@@ -63,8 +66,14 @@ type addServiceModel struct {
 	selectedServices []string // For dependencies
 	selectedNetworks []string
 
+	// Editing an existing service
+	editing       bool
+	original      core.Service
+	changedFields []string
+
 	// Preview
 	yamlPreview string
+	previewData []byte // compose file content with the service added or edited
 
 	// Status
 	err      error
@@ -74,10 +83,18 @@ type addServiceModel struct {
 func newAddServiceModel() addServiceModel {
 	return addServiceModel{
 		state:       stateAddServiceInit,
-		composePath: "docker-compose.yml",
+		composePath: options.ComposePath,
 	}
 }
 
+// newEditServiceModel creates the wizard in edit mode: an existing service is
+// selected and every step is pre-filled with its current values
+func newEditServiceModel() addServiceModel {
+	m := newAddServiceModel()
+	m.editing = true
+	return m
+}
+
 func (m addServiceModel) Init() tea.Cmd {
 	return func() tea.Msg {
 		return m.loadComposeFile()
@@ -114,6 +131,9 @@ func (m addServiceModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case addServiceComposeFileLoaded:
 		m.composeFile = msg.file
+		if m.editing {
+			return m.createServiceSelect()
+		}
 		m.state = stateAddServiceName
 		// Initialize service name input
 		m.textInput = newTextInputForm(
@@ -200,7 +220,7 @@ func (m addServiceModel) updateComponent(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if keyMsg, ok := msg.(tea.KeyMsg); ok && keyMsg.String() == "enter" && m.multiInput.IsDone() {
 			m.service.Ports = m.multiInput.Values()
 			m.state = stateAddServiceEnvConfirm
-			m.confirmInput = newConfirmForm("Add environment variables?", false)
+			m.confirmInput = newConfirmForm("Add environment variables?", m.confirmDefault(false, len(m.service.Environment) > 0))
 			return m, nil
 		}
 
@@ -210,7 +230,7 @@ func (m addServiceModel) updateComponent(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if keyMsg, ok := msg.(tea.KeyMsg); ok && keyMsg.String() == "enter" && m.multiInput.IsDone() {
 			m.service.Volumes = m.multiInput.Values()
 			m.state = stateAddServiceNetworksConfirm
-			m.confirmInput = newConfirmForm("Connect to networks?", false)
+			m.confirmInput = newConfirmForm("Connect to networks?", m.confirmDefault(false, len(m.service.Networks) > 0))
 			return m, nil
 		}
 
@@ -231,11 +251,11 @@ func (m addServiceModel) updateComponent(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Save values and move to next state
 			m.service.Environment = m.kvInput.Values()
 			m.state = stateAddServiceVolumesConfirm
-			m.confirmInput = newConfirmForm("Mount volumes?", false)
+			m.confirmInput = newConfirmForm("Mount volumes?", m.confirmDefault(false, len(m.service.Volumes) > 0))
 			return m, nil
 		}
 
-	case stateAddServiceDeps, stateAddServiceRestart:
+	case stateAddServiceDeps, stateAddServiceRestart, stateEditServiceSelect:
 		m.selectList, cmd = m.selectList.Update(msg)
 
 	case stateAddServicePreview:
@@ -247,9 +267,12 @@ func (m addServiceModel) updateComponent(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m addServiceModel) handleBack() (tea.Model, tea.Cmd) {
 	switch m.state {
-	case stateAddServiceName, stateAddServiceSuccess:
+	case stateAddServiceName, stateAddServiceSuccess, stateEditServiceSelect:
 		return newAddMenuModel(), nil
 	case stateAddServiceImageOrBuild:
+		if m.editing {
+			return m.createServiceSelect()
+		}
 		m.state = stateAddServiceName
 	case stateAddServiceImageName:
 		m.state = stateAddServiceImageOrBuild
@@ -300,6 +323,17 @@ func (m addServiceModel) handleBack() (tea.Model, tea.Cmd) {
 
 func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 	switch m.state {
+	case stateEditServiceSelect:
+		i, ok := m.selectList.SelectedItem().(menuItem)
+		if !ok {
+			return m, nil
+		}
+		m.original = m.composeFile.Services[i.id]
+		m.original.Name = i.id
+		m.service = m.original.Clone()
+		m.state = stateAddServiceImageOrBuild
+		m.confirmInput = newConfirmForm("Use a pre-built image? (No = build from Dockerfile)", m.service.Build == nil)
+
 	case stateAddServiceName:
 		serviceName := m.textInput.Value()
 		if serviceName == "" {
@@ -319,11 +353,12 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 	case stateAddServiceImageOrBuild:
 		m.useImage = m.confirmInput.Value()
 		if m.useImage {
+			m.service.Build = nil
 			m.state = stateAddServiceImageName
 			m.textInput = newTextInputForm(
 				"Docker Image",
 				"nginx:latest",
-				"",
+				m.service.Image,
 				"e.g., nginx:latest, postgres:15, node:20-alpine",
 				func(s string) error {
 					if s == "" {
@@ -333,11 +368,18 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 				},
 			)
 		} else {
+			if m.editing && m.original.Build == nil {
+				m.service.Image = ""
+			}
+			buildContext := "."
+			if m.service.Build != nil && m.service.Build.Context != "" {
+				buildContext = m.service.Build.Context
+			}
 			m.state = stateAddServiceBuildContext
 			m.textInput = newTextInputForm(
 				"Build Context Path",
 				".",
-				".",
+				buildContext,
 				"Path to directory containing Dockerfile",
 				nil,
 			)
@@ -346,18 +388,22 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 	case stateAddServiceImageName:
 		m.service.Image = m.textInput.Value()
 		m.state = stateAddServicePortsConfirm
-		m.confirmInput = newConfirmForm("Expose ports?", true)
+		m.confirmInput = newConfirmForm("Expose ports?", m.confirmDefault(true, len(m.service.Ports) > 0))
 
 	case stateAddServiceBuildContext:
 		if m.service.Build == nil {
 			m.service.Build = &core.BuildConfig{}
 		}
 		m.service.Build.Context = m.textInput.Value()
+		dockerfile := "Dockerfile"
+		if m.service.Build.Dockerfile != "" {
+			dockerfile = m.service.Build.Dockerfile
+		}
 		m.state = stateAddServiceBuildDockerfile
 		m.textInput = newTextInputForm(
 			"Dockerfile Name",
 			"Dockerfile",
-			"Dockerfile",
+			dockerfile,
 			"Name of the Dockerfile",
 			nil,
 		)
@@ -365,7 +411,7 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 	case stateAddServiceBuildDockerfile:
 		m.service.Build.Dockerfile = m.textInput.Value()
 		m.state = stateAddServicePortsConfirm
-		m.confirmInput = newConfirmForm("Expose ports?", true)
+		m.confirmInput = newConfirmForm("Expose ports?", m.confirmDefault(true, len(m.service.Ports) > 0))
 
 	case stateAddServicePortsConfirm:
 		if m.confirmInput.Value() {
@@ -375,17 +421,18 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 				"8080:80 or 3000",
 				"Format: host:container or just container port",
 				nil,
-			)
+			).WithValues(m.service.Ports)
 		} else {
+			m.service.Ports = nil
 			m.state = stateAddServiceEnvConfirm
-			m.confirmInput = newConfirmForm("Add environment variables?", false)
+			m.confirmInput = newConfirmForm("Add environment variables?", m.confirmDefault(false, len(m.service.Environment) > 0))
 		}
 
 	case stateAddServicePorts:
 		if len(m.multiInput.Values()) > 0 || !m.multiInput.HasValues() {
 			m.service.Ports = m.multiInput.Values()
 			m.state = stateAddServiceEnvConfirm
-			m.confirmInput = newConfirmForm("Add environment variables?", false)
+			m.confirmInput = newConfirmForm("Add environment variables?", m.confirmDefault(false, len(m.service.Environment) > 0))
 		}
 
 	case stateAddServiceEnvConfirm:
@@ -394,17 +441,18 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 			m.kvInput = newKeyValueInputForm(
 				"Environment Variables",
 				"Enter key-value pairs. Tab to switch fields.",
-			)
+			).WithPairs(m.service.Environment)
 		} else {
+			m.service.Environment = nil
 			m.state = stateAddServiceVolumesConfirm
-			m.confirmInput = newConfirmForm("Mount volumes?", false)
+			m.confirmInput = newConfirmForm("Mount volumes?", m.confirmDefault(false, len(m.service.Volumes) > 0))
 		}
 
 	case stateAddServiceEnv:
 		if len(m.kvInput.Values()) > 0 || (m.kvInput.Values() == nil) {
 			m.service.Environment = m.kvInput.Values()
 			m.state = stateAddServiceVolumesConfirm
-			m.confirmInput = newConfirmForm("Mount volumes?", false)
+			m.confirmInput = newConfirmForm("Mount volumes?", m.confirmDefault(false, len(m.service.Volumes) > 0))
 		}
 
 	case stateAddServiceVolumesConfirm:
@@ -415,17 +463,18 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 				"./app:/app or data:/var/lib/data",
 				"Format: host:container or volume:container",
 				nil,
-			)
+			).WithValues(m.service.Volumes)
 		} else {
+			m.service.Volumes = nil
 			m.state = stateAddServiceNetworksConfirm
-			m.confirmInput = newConfirmForm("Connect to networks?", false)
+			m.confirmInput = newConfirmForm("Connect to networks?", m.confirmDefault(false, len(m.service.Networks) > 0))
 		}
 
 	case stateAddServiceVolumes:
 		if len(m.multiInput.Values()) > 0 || !m.multiInput.HasValues() {
 			m.service.Volumes = m.multiInput.Values()
 			m.state = stateAddServiceNetworksConfirm
-			m.confirmInput = newConfirmForm("Connect to networks?", false)
+			m.confirmInput = newConfirmForm("Connect to networks?", m.confirmDefault(false, len(m.service.Networks) > 0))
 		}
 
 	case stateAddServiceNetworksConfirm:
@@ -436,8 +485,9 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 				"network-name",
 				"Enter network names to connect this service to",
 				nil,
-			)
+			).WithValues(m.service.Networks)
 		} else {
+			m.service.Networks = nil
 			m.state = stateAddServiceDepsConfirm
 			m.confirmInput = newConfirmForm("Add service dependencies (depends_on)?", false)
 		}
@@ -492,11 +542,12 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 	case stateAddServiceAdvancedConfirm:
 		if m.confirmInput.Value() {
 			m.hasAdvanced = true
+			command, _ := m.service.Command.(string)
 			m.state = stateAddServiceAdvancedCommand
 			m.textInput = newTextInputForm(
 				"Override Default Command",
 				"",
-				"",
+				command,
 				"Command to run when container starts (leave empty to skip)",
 				nil,
 			)
@@ -505,48 +556,45 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 		}
 
 	case stateAddServiceAdvancedCommand:
+		// Leaving a list-form command empty keeps it unchanged
 		if m.textInput.Value() != "" {
 			m.service.Command = m.textInput.Value()
+		} else if _, isString := m.service.Command.(string); isString {
+			m.service.Command = nil
 		}
 		m.state = stateAddServiceAdvancedWorkdir
 		m.textInput = newTextInputForm(
 			"Working Directory",
 			"/app",
-			"",
+			m.service.WorkingDir,
 			"Working directory in the container (leave empty to skip)",
 			nil,
 		)
 
 	case stateAddServiceAdvancedWorkdir:
-		if m.textInput.Value() != "" {
-			m.service.WorkingDir = m.textInput.Value()
-		}
+		m.service.WorkingDir = m.textInput.Value()
 		m.state = stateAddServiceAdvancedUser
 		m.textInput = newTextInputForm(
 			"User",
 			"",
-			"",
+			m.service.User,
 			"User to run as (uid:gid or username, leave empty to skip)",
 			nil,
 		)
 
 	case stateAddServiceAdvancedUser:
-		if m.textInput.Value() != "" {
-			m.service.User = m.textInput.Value()
-		}
+		m.service.User = m.textInput.Value()
 		m.state = stateAddServiceAdvancedHostname
 		m.textInput = newTextInputForm(
 			"Custom Hostname",
 			"",
-			"",
+			m.service.Hostname,
 			"Container hostname (leave empty to skip)",
 			nil,
 		)
 
 	case stateAddServiceAdvancedHostname:
-		if m.textInput.Value() != "" {
-			m.service.Hostname = m.textInput.Value()
-		}
+		m.service.Hostname = m.textInput.Value()
 		return m.generatePreview()
 
 	case stateAddServicePreview:
@@ -555,12 +603,11 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 
 	case stateAddServiceConfirm:
 		if m.confirmInput.Value() {
-			// Save changes
-			m.composeFile.AddService(m.service)
-			if err := m.composeFile.WriteComposeFile(m.composePath); err != nil {
-				m.err = err
-				m.state = stateAddServiceSuccess
-				return m, nil
+			// Write the previewed content: only the edited fields, or the new entry
+			if _, err := core.SaveFile(m.composePath, m.previewData); err != nil {
+				m.err = fmt.Errorf("failed to write %s: %w", m.composePath, err)
+			} else {
+				m.composeFile.AddService(m.service)
 			}
 			m.state = stateAddServiceSuccess
 		} else {
@@ -576,36 +623,131 @@ func (m addServiceModel) handleEnter() (tea.Model, tea.Cmd) {
 
 func (m addServiceModel) createRestartPolicySelect() (tea.Model, tea.Cmd) {
 	policies := []string{"no", "always", "on-failure", "unless-stopped"}
+	selected := m.service.Restart
+	if !m.editing {
+		selected = options.DefaultRestart
+		if !slices.Contains(policies, selected) {
+			policies = append(policies, selected)
+		}
+	}
+	if m.editing {
+		// Keep unset or custom policies (e.g. on-failure:3) selectable
+		if m.service.Restart == "" {
+			policies = append([]string{""}, policies...)
+		} else if !slices.Contains(policies, m.service.Restart) {
+			policies = append(policies, m.service.Restart)
+		}
+	}
 	items := make([]list.Item, len(policies))
 	for i, policy := range policies {
-		items[i] = menuItem{title: policy, desc: "Restart policy", id: policy}
+		title := policy
+		if policy == "" {
+			title = "(not set)"
+		}
+		items[i] = menuItem{title: title, desc: "Restart policy", id: policy}
 	}
 	m.selectList = list.New(items, list.NewDefaultDelegate(), 0, 0)
 	m.selectList.Title = "Select Restart Policy"
 	m.selectList.SetShowStatusBar(false)
 	m.selectList.Styles.Title = titleStyle
+	for i, policy := range policies {
+		if policy == selected {
+			m.selectList.Select(i)
+		}
+	}
+	return m, nil
+}
+
+// createServiceSelect lists the existing services to pick one for editing
+func (m addServiceModel) createServiceSelect() (tea.Model, tea.Cmd) {
+	var services []string
+	for name := range m.composeFile.Services {
+		services = append(services, name)
+	}
+	if len(services) == 0 {
+		m.err = fmt.Errorf("no services found in %s", m.composePath)
+		m.state = stateAddServiceSuccess
+		return m, nil
+	}
+	sort.Strings(services)
+
+	items := make([]list.Item, len(services))
+	for i, name := range services {
+		desc := m.composeFile.Services[name].Image
+		if desc == "" {
+			desc = "built from Dockerfile"
+		}
+		items[i] = menuItem{title: name, desc: desc, id: name}
+	}
+	m.selectList = list.New(items, list.NewDefaultDelegate(), 100, 20)
+	m.selectList.Title = "Select Service to Edit"
+	m.selectList.SetShowStatusBar(false)
+	m.selectList.Styles.Title = titleStyle
+	m.state = stateEditServiceSelect
+	return m, nil
+}
+
+// confirmDefault picks the default answer of a yes/no step: the wizard's
+// default when adding, and whether the field has values when editing
+func (m addServiceModel) confirmDefault(addDefault, hasValues bool) bool {
+	if m.editing {
+		return hasValues
+	}
+	return addDefault
+}
+
+// generateEditPreview renders a unified diff of the edited fields
+func (m addServiceModel) generateEditPreview() (tea.Model, tea.Cmd) {
+	source, err := os.ReadFile(m.composePath)
+	if err != nil {
+		m.err = fmt.Errorf("failed to read %s: %w", m.composePath, err)
+		m.state = stateAddServiceSuccess
+		return m, nil
+	}
+	document, err := core.ParseComposeDocument(source)
+	if err == nil {
+		m.changedFields, err = document.UpdateService(m.original.Name, m.original, m.service)
+	}
+	if err == nil {
+		m.previewData, err = document.Bytes()
+	}
+	if err != nil {
+		m.err = fmt.Errorf("failed to generate preview: %w", err)
+		m.state = stateAddServiceSuccess
+		return m, nil
+	}
+
+	m.yamlPreview = core.UnifiedDiff("a/"+m.composePath, "b/"+m.composePath, string(source), string(m.previewData))
+	if m.yamlPreview == "" {
+		m.yamlPreview = "No changes."
+	}
+	m.state = stateAddServicePreview
+
+	m.previewPort = viewport.New(80, 20)
+	m.previewPort.SetContent(ColorizeDiff(m.yamlPreview))
+
 	return m, nil
 }
 
 func (m addServiceModel) generatePreview() (tea.Model, tea.Cmd) {
-	// Add service to compose file temporarily for preview
-	tempCompose := *m.composeFile
-	tempCompose.AddService(m.service)
+	if m.editing {
+		return m.generateEditPreview()
+	}
 
-	// Marshal to YAML
-	yamlData, err := yaml.Marshal(&tempCompose)
+	// Diff the file on disk against a copy with the service added
+	var err error
+	m.previewData, m.yamlPreview, err = previewEntry(m.composePath, "services", m.service.Name, m.service)
 	if err != nil {
 		m.err = fmt.Errorf("failed to generate preview: %w", err)
 		m.state = stateAddServiceSuccess
 		return m, nil
 	}
 
-	m.yamlPreview = string(yamlData)
 	m.state = stateAddServicePreview
 
 	// Create viewport for preview
 	m.previewPort = viewport.New(80, 20)
-	m.previewPort.SetContent(m.yamlPreview)
+	m.previewPort.SetContent(ColorizeDiff(m.yamlPreview))
 
 	return m, nil
 }
@@ -640,11 +782,17 @@ func (m addServiceModel) View() string {
 	case stateAddServiceEnv:
 		return m.kvInput.View()
 
-	case stateAddServiceDeps, stateAddServiceRestart:
+	case stateAddServiceDeps, stateAddServiceRestart, stateEditServiceSelect:
 		return docStyle.Render(m.selectList.View())
 
 	case stateAddServicePreview:
-		s := titleStyle.Render("Preview docker-compose.yml") + "\n\n"
+		title := "Changes to " + m.composePath
+		if m.editing {
+			if len(m.changedFields) > 0 {
+				title += " (" + strings.Join(m.changedFields, ", ") + ")"
+			}
+		}
+		s := titleStyle.Render(title) + "\n\n"
 		s += m.previewPort.View() + "\n\n"
 		s += helpStyle.Render("↑↓ to scroll • 'enter' to continue • 'esc' to go back")
 		return docStyle.Render(s)
@@ -656,6 +804,12 @@ func (m addServiceModel) View() string {
 			s += helpStyle.Render("Press 'enter' or 'esc' to return")
 			return docStyle.Render(s)
 		}
+		if m.editing {
+			s := lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render(fmt.Sprintf("✅ Service '%s' updated successfully!", m.original.Name)) + "\n\n"
+			s += "Only the changed fields were written to docker-compose.yml\n\n"
+			s += helpStyle.Render("Press 'enter' or 'esc' to return to menu")
+			return docStyle.Render(s)
+		}
 		s := lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render(fmt.Sprintf("✅ Service '%s' added successfully!", m.service.Name)) + "\n\n"
 		s += fmt.Sprintf("Service has been added to docker-compose.yml\n\n")
 		s += helpStyle.Render("Press 'enter' or 'esc' to return to menu")
@@ -9,7 +9,6 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/firasmosbahi/container-composer/core"
-	"gopkg.in/yaml.v3"
 )
 
 // State constants for add volume wizard
@@ -42,6 +41,7 @@ type addVolumeModel struct {
 
 	// Preview
 	yamlPreview string
+	previewData []byte // compose file content with the volume added
 
 	// Status
 	err      error
@@ -51,7 +51,7 @@ type addVolumeModel struct {
 func newAddVolumeModel() addVolumeModel {
 	return addVolumeModel{
 		state:       stateAddVolumeInit,
-		composePath: "docker-compose.yml",
+		composePath: options.ComposePath,
 	}
 }
 
@@ -260,12 +260,12 @@ func (m addVolumeModel) handleEnter() (tea.Model, tea.Cmd) {
 	case stateAddVolumeConfirm:
 		if m.confirmInput.Value() {
 			// Save changes
-			m.composeFile.AddVolume(m.volumeName, m.volume)
-			if err := m.composeFile.WriteComposeFile(m.composePath); err != nil {
-				m.err = err
+			if _, err := core.SaveFile(m.composePath, m.previewData); err != nil {
+				m.err = fmt.Errorf("failed to write %s: %w", m.composePath, err)
 				m.state = stateAddVolumeSuccess
 				return m, nil
 			}
+			m.composeFile.AddVolume(m.volumeName, m.volume)
 			m.state = stateAddVolumeSuccess
 		} else {
 			return newAddMenuModel(), nil
@@ -279,24 +279,20 @@ func (m addVolumeModel) handleEnter() (tea.Model, tea.Cmd) {
 }
 
 func (m addVolumeModel) generatePreview() (tea.Model, tea.Cmd) {
-	// Add volume to compose file temporarily for preview
-	tempCompose := *m.composeFile
-	tempCompose.AddVolume(m.volumeName, m.volume)
-
-	// Marshal to YAML
-	yamlData, err := yaml.Marshal(&tempCompose)
+	// Diff the file on disk against a copy with the volume added
+	var err error
+	m.previewData, m.yamlPreview, err = previewEntry(m.composePath, "volumes", m.volumeName, m.volume)
 	if err != nil {
 		m.err = fmt.Errorf("failed to generate preview: %w", err)
 		m.state = stateAddVolumeSuccess
 		return m, nil
 	}
 
-	m.yamlPreview = string(yamlData)
 	m.state = stateAddVolumePreview
 
 	// Create viewport for preview
 	m.previewPort = viewport.New(80, 20)
-	m.previewPort.SetContent(m.yamlPreview)
+	m.previewPort.SetContent(ColorizeDiff(m.yamlPreview))
 
 	return m, nil
 }
@@ -320,7 +316,7 @@ func (m addVolumeModel) View() string {
 		return docStyle.Render(m.selectList.View())
 
 	case stateAddVolumePreview:
-		s := titleStyle.Render("Preview docker-compose.yml") + "\n\n"
+		s := titleStyle.Render("Changes to "+m.composePath) + "\n\n"
 		s += m.previewPort.View() + "\n\n"
 		s += helpStyle.Render("↑↓ to scroll • 'enter' to continue • 'esc' to go back")
 		return docStyle.Render(s)
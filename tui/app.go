@@ -10,8 +10,32 @@ type App struct {
 	model tea.Model
 }
 
+// Options holds the defaults every screen starts with
+type Options struct {
+	ComposePath      string // compose file the wizards and graph view operate on
+	DefaultRestart   string // restart policy preselected by the add service wizard
+	ShowNetworks     bool   // graph view toggles
+	ShowVolumes      bool
+	ShowHealthChecks bool
+}
+
+// DefaultOptions returns the built-in defaults
+func DefaultOptions() Options {
+	return Options{
+		ComposePath:      "docker-compose.yml",
+		DefaultRestart:   "unless-stopped",
+		ShowNetworks:     true,
+		ShowVolumes:      true,
+		ShowHealthChecks: true,
+	}
+}
+
+// options are the defaults of the running application
+var options = DefaultOptions()
+
 // NewApp creates a new TUI application
-func NewApp() *App {
+func NewApp(opts Options) *App {
+	options = opts
 	return &App{
 		model: newMainMenuModel(),
 	}
@@ -32,6 +32,7 @@ const (
 	highlightDependent
 	highlightNetwork
 	highlightVolume
+	highlightPath
 )
 
 // dependencyGraphModel is the main TUI model
@@ -59,6 +60,10 @@ type dependencyGraphModel struct {
 	// Highlighted services
 	highlightedServices map[string]highlightType
 
+	// Path query
+	pathStart  string
+	pathReport *core.PathReport
+
 	// Export
 	exportPath   string
 	exportFormat string
@@ -72,10 +77,10 @@ type dependencyGraphModel struct {
 func newDependencyGraphModel() dependencyGraphModel {
 	return dependencyGraphModel{
 		state:               stateGraphInit,
-		composePath:         "docker-compose.yml",
-		showNetworks:        true,
-		showVolumes:         true,
-		showHealthChecks:    true,
+		composePath:         options.ComposePath,
+		showNetworks:        options.ShowNetworks,
+		showVolumes:         options.ShowVolumes,
+		showHealthChecks:    options.ShowHealthChecks,
 		highlightedServices: make(map[string]highlightType),
 	}
 }
@@ -158,6 +163,7 @@ func (m dependencyGraphModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd
 				m.highlightMode = false
 				m.selectedService = ""
 				m.highlightedServices = make(map[string]highlightType)
+				m.pathReport = nil
 				m.viewport.SetContent(m.renderGraph())
 				m.message = "Highlight cleared"
 				return m, nil
@@ -166,10 +172,12 @@ func (m dependencyGraphModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd
 			return m, tea.Quit
 
 		case "esc":
-			if m.highlightMode {
+			if m.highlightMode || m.pathStart != "" {
 				m.highlightMode = false
 				m.selectedService = ""
 				m.highlightedServices = make(map[string]highlightType)
+				m.pathStart = ""
+				m.pathReport = nil
 				m.viewport.SetContent(m.renderGraph())
 				m.message = "Highlight cleared"
 				return m, nil
@@ -189,6 +197,7 @@ func (m dependencyGraphModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd
 			// Toggle highlight mode
 			if i, ok := m.serviceList.SelectedItem().(menuItem); ok {
 				m.selectedService = i.id
+				m.pathReport = nil
 				m.highlightMode = !m.highlightMode
 				if m.highlightMode {
 					m.calculateHighlights()
@@ -201,11 +210,24 @@ func (m dependencyGraphModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd
 				return m, nil
 			}
 
+		case "p":
+			// Pick path endpoints: first press marks the start, second the target
+			if i, ok := m.serviceList.SelectedItem().(menuItem); ok {
+				if m.pathStart == "" {
+					m.pathStart = i.id
+					m.message = fmt.Sprintf("Path from %s: select target and press 'p'", i.id)
+					return m, nil
+				}
+				return m.showPaths(m.pathStart, i.id), nil
+			}
+
 		case "f":
 			// Enter filter mode
 			m.state = stateGraphFilter
 			m.filterInput = textinput.New()
-			m.filterInput.Placeholder = "service name"
+			m.filterInput.Placeholder = "api depth=1 direction=up exclude=*-db only-type=depends_on"
+			m.filterInput.CharLimit = 256
+			m.filterInput.Width = 70
 			m.filterInput.Focus()
 			return m, nil
 
@@ -269,15 +291,20 @@ func (m dependencyGraphModel) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd
 
 		case "enter":
 			// Apply filter
-			serviceName := strings.TrimSpace(m.filterInput.Value())
-			if serviceName != "" {
-				filtered, err := m.graph.FilterByService(serviceName, -1)
+			expression := strings.TrimSpace(m.filterInput.Value())
+			if expression != "" {
+				spec, err := core.ParseGraphFilter(expression)
+				if err == nil {
+					var filtered *core.DependencyGraph
+					filtered, err = spec.Apply(m.graph)
+					if err == nil {
+						m.filteredGraph = filtered
+						m.viewport.SetContent(m.renderGraph())
+						m.message = fmt.Sprintf("Filter: %s", spec)
+					}
+				}
 				if err != nil {
 					m.message = fmt.Sprintf("Error: %v", err)
-				} else {
-					m.filteredGraph = filtered
-					m.viewport.SetContent(m.renderGraph())
-					m.message = fmt.Sprintf("Filtered to service: %s", serviceName)
 				}
 			} else {
 				// Empty = reset filter
@@ -418,6 +445,63 @@ func (m dependencyGraphModel) calculateHighlights() {
 	}
 }
 
+// showPaths highlights every dependency path between two services
+func (m dependencyGraphModel) showPaths(from, to string) dependencyGraphModel {
+	m.pathStart = ""
+
+	report, err := m.filteredGraph.FindPaths(from, to, false)
+	if err != nil {
+		m.message = fmt.Sprintf("Error: %v", err)
+		return m
+	}
+
+	m.pathReport = report
+	m.selectedService = from
+	m.highlightMode = true
+	m.highlightedServices = make(map[string]highlightType)
+	for _, name := range report.Services() {
+		m.highlightedServices[name] = highlightPath
+	}
+	m.highlightedServices[from] = highlightSelected
+	if len(report.Paths) == 0 {
+		m.highlightedServices[to] = highlightNetwork
+	}
+
+	switch {
+	case len(report.Paths) > 0:
+		m.message = fmt.Sprintf("%d path(s) between %s and %s", len(report.Paths), from, to)
+	case report.SharedOnly():
+		m.message = fmt.Sprintf("%s and %s only share networks/volumes", from, to)
+	default:
+		m.message = fmt.Sprintf("%s and %s are not connected", from, to)
+	}
+
+	m.viewport.SetContent(m.renderGraph())
+	return m
+}
+
+// renderPaths lists the paths of the current path query
+func (m dependencyGraphModel) renderPaths() string {
+	report := m.pathReport
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("\nPaths: %s ⇄ %s\n", report.From, report.To))
+	if report.Reversed {
+		builder.WriteString(fmt.Sprintf("  (%s depends on %s)\n", report.To, report.From))
+	}
+	for _, path := range report.Paths {
+		builder.WriteString("  " + strings.Join(path, " → ") + "\n")
+	}
+	if len(report.SharedNetworks) > 0 {
+		builder.WriteString("  🌐 shared networks: " + strings.Join(report.SharedNetworks, ", ") + "\n")
+	}
+	if len(report.SharedVolumes) > 0 {
+		builder.WriteString("  💾 shared volumes: " + strings.Join(report.SharedVolumes, ", ") + "\n")
+	}
+
+	return builder.String()
+}
+
 func (m dependencyGraphModel) renderGraph() string {
 	options := core.ASCIIOptions{
 		ShowNetworks:     m.showNetworks,
@@ -427,6 +511,10 @@ func (m dependencyGraphModel) renderGraph() string {
 
 	output := m.filteredGraph.FormatASCII(options)
 
+	if m.pathReport != nil {
+		output += m.renderPaths()
+	}
+
 	// Apply highlighting if in highlight mode
 	if m.highlightMode {
 		output = m.applyHighlighting(output)
@@ -456,6 +544,8 @@ func (m dependencyGraphModel) applyHighlighting(content string) string {
 					style = style.Foreground(lipgloss.Color("214"))
 				case highlightVolume:
 					style = style.Foreground(lipgloss.Color("208"))
+				case highlightPath:
+					style = style.Foreground(lipgloss.Color("#FF5F87")).Bold(true)
 				}
 				newLine = strings.Replace(newLine, serviceName,
 					style.Render(serviceName), 1)
@@ -667,6 +757,9 @@ func (m dependencyGraphModel) renderStatusBar() string {
 	if m.highlightMode {
 		parts = append(parts, fmt.Sprintf("🎯 Highlighting: %s", m.selectedService))
 	}
+	if m.pathStart != "" {
+		parts = append(parts, fmt.Sprintf("🧭 Path from: %s", m.pathStart))
+	}
 
 	status := strings.Join(parts, " | ")
 	if m.message != "" {
@@ -684,25 +777,28 @@ func (m dependencyGraphModel) renderHelpText() string {
 			lipgloss.NewStyle().Foreground(lipgloss.Color("#00D7D7")).Render("█ Dependency") + " " +
 			lipgloss.NewStyle().Foreground(lipgloss.Color("#04B575")).Render("█ Dependent") + " " +
 			lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render("█ Network") + " " +
-			lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Render("█ Volume")
+			lipgloss.NewStyle().Foreground(lipgloss.Color("208")).Render("█ Volume") + " " +
+			lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5F87")).Render("█ Path")
 
 		return helpStyle.Render(
-			"↑↓ navigate • 'h' clear highlight • 'enter' details • 'f' filter • 'e' export\n" +
+			"↑↓ navigate • 'h' clear highlight • 'enter' details • 'p' path • 'f' filter • 'e' export\n" +
 				"'n' toggle networks • 'v' toggle volumes • 'c' toggle health • 'esc' back • 'q' quit\n" +
 				legend,
 		)
 	}
 
 	return helpStyle.Render(
-		"↑↓ navigate • 'h' highlight • 'enter' details • 'f' filter • 'e' export\n" +
+		"↑↓ navigate • 'h' highlight • 'enter' details • 'p' path • 'f' filter • 'e' export\n" +
 			"'n' toggle networks • 'v' toggle volumes • 'c' toggle health • 'esc' back • 'q' quit",
 	)
 }
 
 func (m dependencyGraphModel) renderFilterView() string {
-	s := titleStyle.Render("Filter by Service") + "\n\n"
-	s += "Enter service name to show only that service and its dependencies:\n\n"
+	s := titleStyle.Render("Filter Graph") + "\n\n"
+	s += "Enter a filter expression (same syntax as the graph command flags):\n\n"
 	s += m.filterInput.View() + "\n\n"
+	s += helpStyle.Render("service=a,b (or bare names) • depth=N • direction=up|down|both\n"+
+		"exclude=glob,... • only-type=depends_on|network|volume") + "\n"
 	s += helpStyle.Render("Leave empty to show all • 'enter' to apply • 'esc' to cancel")
 	return docStyle.Render(s)
 }
@@ -2,11 +2,14 @@ package tui
 
 import (
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/firasmosbahi/container-composer/core"
 )
 
 // Validation function type
@@ -180,6 +183,16 @@ func (m multiInputForm) Update(msg tea.Msg) (multiInputForm, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.Type {
+		case tea.KeyBackspace:
+			// Backspace on an empty input takes back the last value for editing
+			if m.input.Value() == "" && len(m.values) > 0 {
+				last := m.values[len(m.values)-1]
+				m.values = m.values[:len(m.values)-1]
+				m.input.SetValue(last)
+				m.input.CursorEnd()
+				return m, nil
+			}
+
 		case tea.KeyEnter:
 			value := strings.TrimSpace(m.input.Value())
 			if value != "" {
@@ -224,10 +237,16 @@ func (m multiInputForm) View() string {
 		s += helpStyle.Render(m.help) + "\n\n"
 	}
 
-	s += helpStyle.Render("Press 'enter' to add • leave empty and press 'enter' to finish • 'esc' to go back")
+	s += helpStyle.Render("Press 'enter' to add • 'backspace' on empty input to edit the last entry • leave empty and press 'enter' to finish • 'esc' to go back")
 	return docStyle.Render(s)
 }
 
+// WithValues pre-fills the form with existing values
+func (m multiInputForm) WithValues(values []string) multiInputForm {
+	m.values = append([]string{}, values...)
+	return m
+}
+
 func (m multiInputForm) Values() []string {
 	return m.values
 }
@@ -303,6 +322,18 @@ func (m keyValueInputForm) Update(msg tea.Msg) (keyValueInputForm, tea.Cmd) {
 			}
 			return m, nil
 
+		case tea.KeyBackspace:
+			// Backspace on empty inputs takes back the last pair for editing
+			if m.focusedInput == 0 && m.keyInput.Value() == "" && m.valueInput.Value() == "" && len(m.pairKeys) > 0 {
+				key := m.pairKeys[len(m.pairKeys)-1]
+				m.pairKeys = m.pairKeys[:len(m.pairKeys)-1]
+				m.keyInput.SetValue(key)
+				m.keyInput.CursorEnd()
+				m.valueInput.SetValue(m.pairs[key])
+				delete(m.pairs, key)
+				return m, nil
+			}
+
 		case tea.KeyEnter:
 			key := strings.TrimSpace(m.keyInput.Value())
 			value := strings.TrimSpace(m.valueInput.Value())
@@ -362,10 +393,22 @@ func (m keyValueInputForm) View() string {
 		s += helpStyle.Render(m.help) + "\n\n"
 	}
 
-	s += helpStyle.Render("'tab' to switch fields • 'enter' to add • leave empty and press 'enter' to finish • 'esc' to go back")
+	s += helpStyle.Render("'tab' to switch fields • 'enter' to add • 'backspace' on empty fields to edit the last pair • leave empty and press 'enter' to finish • 'esc' to go back")
 	return docStyle.Render(s)
 }
 
+// WithPairs pre-fills the form with existing pairs, sorted by key
+func (m keyValueInputForm) WithPairs(pairs map[string]string) keyValueInputForm {
+	m.pairs = make(map[string]string)
+	m.pairKeys = []string{}
+	for key, value := range pairs {
+		m.pairs[key] = value
+		m.pairKeys = append(m.pairKeys, key)
+	}
+	sort.Strings(m.pairKeys)
+	return m
+}
+
 func (m keyValueInputForm) Values() map[string]string {
 	return m.pairs
 }
@@ -378,3 +421,48 @@ func (m keyValueInputForm) IsDone() bool {
 func (m keyValueInputForm) HasValues() bool {
 	return len(m.pairs) > 0
 }
+
+// previewEntry sets an entry of the compose file on disk through the compose
+// document, as the CLI add commands do, so the rest of the file keeps its
+// formatting. It returns the new content and its diff against the file.
+func previewEntry(composePath, section, name string, value interface{}) ([]byte, string, error) {
+	source, err := os.ReadFile(composePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", composePath, err)
+	}
+	document, err := core.ParseComposeDocument(source)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", composePath, err)
+	}
+	if err := document.SetEntry(section, name, value); err != nil {
+		return nil, "", err
+	}
+	data, err := document.Bytes()
+	if err != nil {
+		return nil, "", err
+	}
+	return data, core.UnifiedDiff("a/"+composePath, "b/"+composePath, string(source), string(data)), nil
+}
+
+// ColorizeDiff highlights the headers, hunks, added and removed lines of a
+// unified diff. It is shared by the TUI previews and the CLI write pipeline.
+func ColorizeDiff(diff string) string {
+	added := lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	removed := lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	hunk := lipgloss.NewStyle().Foreground(lipgloss.Color("14"))
+
+	lines := strings.Split(strings.TrimSuffix(diff, "\n"), "\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			lines[i] = lipgloss.NewStyle().Bold(true).Render(line)
+		case strings.HasPrefix(line, "@@"):
+			lines[i] = hunk.Render(line)
+		case strings.HasPrefix(line, "+"):
+			lines[i] = added.Render(line)
+		case strings.HasPrefix(line, "-"):
+			lines[i] = removed.Render(line)
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
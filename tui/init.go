@@ -15,6 +15,7 @@ import (
 
 const (
 	stateInitSelection = iota
+	stateInitVariables
 	stateInitCreating
 	stateInitSuccess
 )
@@ -29,6 +30,15 @@ type initModel struct {
 	quitting       bool
 	err            error
 	focusOnInput   bool
+
+	// Template variables, asked one at a time after the project name
+	tmpl       *templates.Template
+	varIndex   int
+	varInput   textinput.Model
+	varAnswers map[string]string      // raw answers by variable name
+	varValues  map[string]interface{} // parsed values, for When conditions
+	varErr     string
+	values     map[string]interface{}
 }
 
 // Custom list item with category badge
@@ -157,6 +167,9 @@ func (m initModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 
+		case stateInitVariables:
+			return m.updateVariables(msg)
+
 		case stateInitSuccess:
 			// Allow manual return to main menu even during auto-redirect
 			if msg.String() == "enter" || msg.String() == "esc" || msg.String() == "q" {
@@ -190,6 +203,8 @@ func (m initModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			m.templates, cmd = m.templates.Update(msg)
 		}
+	} else if m.state == stateInitVariables {
+		m.varInput, cmd = m.varInput.Update(msg)
 	}
 
 	return m, cmd
@@ -211,23 +226,157 @@ func (m initModel) startCreation() (tea.Model, tea.Cmd) {
 		}
 	}
 
+	tmpl, err := templates.GetTemplate(m.selectedTmpl)
+	if err != nil {
+		m.err = err
+		m.state = stateInitSuccess // Show error state
+		return m, nil
+	}
+	m.tmpl = tmpl
+
+	if len(tmpl.Variables) > 0 {
+		m.varAnswers = make(map[string]string)
+		m.varValues = make(map[string]interface{})
+		m.varIndex = -1
+		m.state = stateInitVariables
+		return m.nextVariable()
+	}
+
 	m.state = stateInitCreating
 	return m.createProject()
 }
 
+// nextVariable moves to the next visible template variable, or resolves all
+// of them and creates the project after the last one
+func (m initModel) nextVariable() (tea.Model, tea.Cmd) {
+	m.varErr = ""
+	for m.varIndex++; m.varIndex < len(m.tmpl.Variables); m.varIndex++ {
+		v := m.tmpl.Variables[m.varIndex]
+		if v.Visible(m.varValues) {
+			m.varInput = newVariableInput(v)
+			return m, m.varInput.Focus()
+		}
+		// Hidden variables keep their default
+		if value, err := v.DefaultValue(); err == nil {
+			m.varValues[v.Name] = value
+		}
+	}
+
+	values, err := m.tmpl.ResolveVariables(m.varAnswers, nil)
+	if err != nil {
+		m.err = err
+		m.state = stateInitSuccess
+		return m, nil
+	}
+	m.values = values
+	m.state = stateInitCreating
+	return m.createProject()
+}
+
+// newVariableInput creates the input for a template variable, pre-filled
+// with its default. Secrets are masked and start empty.
+func newVariableInput(v templates.Variable) textinput.Model {
+	ti := textinput.New()
+	ti.CharLimit = 200
+	ti.Width = 50
+	switch v.Type {
+	case templates.VarSecret:
+		ti.EchoMode = textinput.EchoPassword
+		ti.EchoCharacter = '•'
+		ti.Placeholder = "leave empty to generate"
+	case templates.VarChoice:
+		ti.SetValue(v.Default)
+		if v.Default == "" {
+			ti.SetValue(v.Choices[0])
+		}
+	case templates.VarBool:
+		ti.SetValue("false")
+		if v.Default != "" {
+			ti.SetValue(v.Default)
+		}
+	default:
+		ti.SetValue(v.Default)
+	}
+	return ti
+}
+
+// variableOptions returns the values ←/→ cycle through, or nil for free
+// text variables
+func variableOptions(v templates.Variable) []string {
+	switch v.Type {
+	case templates.VarChoice:
+		return v.Choices
+	case templates.VarBool:
+		return []string{"true", "false"}
+	}
+	return nil
+}
+
+func (m initModel) updateVariables(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	v := m.tmpl.Variables[m.varIndex]
+	options := variableOptions(v)
+
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+
+	case "esc":
+		// Back to the template list and project name
+		m.state = stateInitSelection
+		m.focusOnInput = true
+		return m, m.projectInput.Focus()
+
+	case "left", "right":
+		if options != nil {
+			current := 0
+			for i, option := range options {
+				if option == m.varInput.Value() {
+					current = i
+				}
+			}
+			step := 1
+			if msg.String() == "left" {
+				step = len(options) - 1
+			}
+			m.varInput.SetValue(options[(current+step)%len(options)])
+			m.varErr = ""
+			return m, nil
+		}
+
+	case "enter":
+		raw := m.varInput.Value()
+		if v.Type == templates.VarSecret && raw == "" && !v.Required {
+			m.varAnswers[v.Name] = raw
+			return m.nextVariable()
+		}
+		value, err := v.Parse(raw)
+		if err != nil {
+			m.varErr = err.Error()
+			return m, nil
+		}
+		m.varAnswers[v.Name] = raw
+		m.varValues[v.Name] = value
+		return m.nextVariable()
+	}
+
+	if options != nil {
+		// Choices and booleans are only changed with the arrows
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.varInput, cmd = m.varInput.Update(msg)
+	m.varErr = ""
+	return m, cmd
+}
+
 type projectCreatedMsg struct {
 	projectName string
 	templateName string
 }
 
 func (m initModel) createProject() (tea.Model, tea.Cmd) {
-	// Get the template
-	tmpl, err := templates.GetTemplate(m.selectedTmpl)
-	if err != nil {
-		m.err = err
-		m.state = stateInitSuccess // Show error state
-		return m, nil
-	}
+	tmpl := m.tmpl
 
 	// Check if directory exists and is not empty
 	projectDir := m.projectName
@@ -247,13 +396,26 @@ func (m initModel) createProject() (tea.Model, tea.Cmd) {
 	}
 
 	// Generate project from template
-	vars := templates.TemplateVars{ProjectName: m.projectName}
+	seed, err := templates.NewSeed()
+	if err != nil {
+		m.err = err
+		m.state = stateInitSuccess
+		return m, nil
+	}
+	vars := templates.TemplateVars{ProjectName: m.projectName, Values: m.values, Seed: seed}
 	if err := tmpl.Generate(m.projectName, vars); err != nil {
 		m.err = err
 		m.state = stateInitSuccess
 		return m, nil
 	}
 
+	// Record the template so the project can be upgraded later
+	if err := tmpl.WriteLock(m.projectName, vars); err != nil {
+		m.err = fmt.Errorf("failed to write template lock: %w", err)
+		m.state = stateInitSuccess
+		return m, nil
+	}
+
 	// Change to the newly created project directory
 	if err := os.Chdir(m.projectName); err != nil {
 		m.err = fmt.Errorf("project created but failed to navigate to directory: %w", err)
@@ -343,6 +505,9 @@ func (m initModel) View() string {
 
 		return docStyle.Render(b.String())
 
+	case stateInitVariables:
+		return m.viewVariables()
+
 	case stateInitCreating:
 		var b strings.Builder
 
@@ -450,6 +615,69 @@ func (m initModel) View() string {
 	}
 }
 
+func (m initModel) viewVariables() string {
+	var b strings.Builder
+	v := m.tmpl.Variables[m.varIndex]
+
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("#7D56F4")).
+		Render("⚙️  CONFIGURE TEMPLATE")
+	b.WriteString(title + "\n")
+
+	info := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#9CA3AF")).
+		Render(fmt.Sprintf("%s template · project %s · variable %d of %d",
+			m.selectedTmpl, m.projectName, m.varIndex+1, len(m.tmpl.Variables)))
+	b.WriteString(info + "\n\n")
+
+	label := v.Name
+	if v.Description != "" {
+		label = v.Description
+	}
+	inputLabel := lipgloss.NewStyle().
+		Foreground(lipgloss.Color("#00D7D7")).
+		Bold(true).
+		Render("📝 " + label + ":")
+	b.WriteString(inputLabel + "\n")
+
+	inputBox := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(getInputBorderColor(true))).
+		Padding(0, 1).
+		Render(m.varInput.View())
+	b.WriteString(inputBox + "\n")
+
+	var hint string
+	switch {
+	case variableOptions(v) != nil:
+		hint = "Options: " + strings.Join(variableOptions(v), ", ")
+	case v.Type == templates.VarInt && v.Min != nil && v.Max != nil:
+		hint = fmt.Sprintf("Number between %d and %d", *v.Min, *v.Max)
+	case v.Pattern != "":
+		hint = "Must match " + v.Pattern
+	}
+	if hint != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#626262")).Render(hint) + "\n")
+	}
+	if m.varErr != "" {
+		b.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("#FF5F87")).Render("✗ "+m.varErr) + "\n")
+	}
+	b.WriteString("\n")
+
+	helpText := lipgloss.NewStyle().Foreground(lipgloss.Color("#00D7D7")).Render("⏎") + " " +
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#9CA3AF")).Render("next  ")
+	if variableOptions(v) != nil {
+		helpText += lipgloss.NewStyle().Foreground(lipgloss.Color("#00D7D7")).Render("←/→") + " " +
+			lipgloss.NewStyle().Foreground(lipgloss.Color("#9CA3AF")).Render("change  ")
+	}
+	helpText += lipgloss.NewStyle().Foreground(lipgloss.Color("#00D7D7")).Render("ESC") + " " +
+		lipgloss.NewStyle().Foreground(lipgloss.Color("#9CA3AF")).Render("back")
+	b.WriteString(helpText)
+
+	return docStyle.Render(b.String())
+}
+
 func getInputBorderColor(focused bool) string {
 	if focused {
 		return "#7D56F4" // Purple when focused
@@ -30,7 +30,7 @@ func newMainMenuModel() mainMenuModel {
 		},
 		menuItem{
 			title: "➕ Add Resources",
-			desc:  "Add services, networks, or volumes to existing project",
+			desc:  "Add or edit services, networks, or volumes in an existing project",
 			id:    "add",
 		},
 		menuItem{